@@ -12,7 +12,9 @@
 package cloudformation
 
 import (
+	"errors"
 	"os"
+	"strconv"
 
 	"github.com/adobe-platform/porter/constants"
 	"github.com/aws/aws-sdk-go/aws"
@@ -75,3 +77,116 @@ func UpdateStack(client *cfnlib.CloudFormation, stackName string, cfnTemplateUrl
 	_, err := client.UpdateStack(input)
 	return err
 }
+
+// CreateChangeSet creates a change set that updates an existing stack
+func CreateChangeSet(client *cfnlib.CloudFormation, stackName, changeSetName string, cfnTemplateUrl string, parameters []*cfnlib.Parameter) (string, error) {
+	input := &cfnlib.CreateChangeSetInput{
+		StackName:     aws.String(stackName),
+		ChangeSetName: aws.String(changeSetName),
+		ChangeSetType: aws.String(cfnlib.ChangeSetTypeUpdate),
+		TemplateURL:   aws.String(cfnTemplateUrl),
+		Capabilities:  []*string{aws.String("CAPABILITY_IAM")},
+		Parameters:    parameters,
+	}
+
+	output, err := client.CreateChangeSet(input)
+	if err != nil {
+		return "", err
+	}
+
+	return *output.Id, nil
+}
+
+// DescribeChangeSet returns a change set with every page of its changes
+func DescribeChangeSet(client *cfnlib.CloudFormation, changeSetId string) (*cfnlib.DescribeChangeSetOutput, error) {
+	input := &cfnlib.DescribeChangeSetInput{
+		ChangeSetName: aws.String(changeSetId),
+	}
+
+	var changeSet *cfnlib.DescribeChangeSetOutput
+	for {
+		output, err := client.DescribeChangeSet(input)
+		if err != nil {
+			return nil, err
+		}
+
+		if changeSet == nil {
+			changeSet = output
+		} else {
+			changeSet.Changes = append(changeSet.Changes, output.Changes...)
+		}
+
+		if output.NextToken == nil {
+			break
+		}
+		input.NextToken = output.NextToken
+	}
+
+	return changeSet, nil
+}
+
+func ExecuteChangeSet(client *cfnlib.CloudFormation, changeSetId string) error {
+	input := &cfnlib.ExecuteChangeSetInput{
+		ChangeSetName: aws.String(changeSetId),
+	}
+
+	_, err := client.ExecuteChangeSet(input)
+	return err
+}
+
+func DeleteChangeSet(client *cfnlib.CloudFormation, changeSetId string) error {
+	input := &cfnlib.DeleteChangeSetInput{
+		ChangeSetName: aws.String(changeSetId),
+	}
+
+	_, err := client.DeleteChangeSet(input)
+	return err
+}
+
+// GetTemplate returns the template body of an existing stack
+func GetTemplate(client *cfnlib.CloudFormation, stackName string) (string, error) {
+	input := &cfnlib.GetTemplateInput{
+		StackName: aws.String(stackName),
+	}
+
+	output, err := client.GetTemplate(input)
+	if err != nil {
+		return "", err
+	}
+
+	if output.TemplateBody == nil {
+		return "", nil
+	}
+
+	return *output.TemplateBody, nil
+}
+
+// Outputs returns a stack's outputs by OutputKey
+func Outputs(stack *cfnlib.Stack) map[string]string {
+	outputs := make(map[string]string)
+	for _, output := range stack.Outputs {
+		if output == nil || output.OutputKey == nil {
+			continue
+		}
+		outputs[*output.OutputKey] = aws.StringValue(output.OutputValue)
+	}
+	return outputs
+}
+
+// GetOutputs returns the outputs of an existing stack by OutputKey
+func GetOutputs(client *cfnlib.CloudFormation, stackName string) (map[string]string, error) {
+	input := &cfnlib.DescribeStacksInput{
+		StackName: aws.String(stackName),
+	}
+
+	output, err := client.DescribeStacks(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(output.Stacks) != 1 {
+		return nil, errors.New("DescribeStacks returned " + strconv.Itoa(len(output.Stacks)) + " stacks")
+	}
+
+	return Outputs(output.Stacks[0]), nil
+}
@@ -12,6 +12,8 @@
 package cloudformation
 
 import (
+	"sort"
+
 	"github.com/aws/aws-sdk-go/aws"
 	cfnlib "github.com/aws/aws-sdk-go/service/cloudformation"
 )
@@ -34,12 +36,17 @@ type StackEventState struct {
 	client    *cfnlib.CloudFormation
 	stackName *string
 	nextToken *string
+
+	// used by NewStackEvents
+	eventIds map[string]interface{}
+	events   []*cfnlib.StackEvent
 }
 
 func NewStackEventState(client *cfnlib.CloudFormation, stackName string) *StackEventState {
 	return &StackEventState{
 		client:    client,
 		stackName: aws.String(stackName),
+		eventIds:  make(map[string]interface{}),
 	}
 }
 
@@ -58,3 +65,55 @@ func (recv *StackEventState) DescribeStackEvents() ([]*cfnlib.StackEvent, error)
 
 	return output.StackEvents, nil
 }
+
+// NewStackEvents returns the events that haven't been returned by a previous
+// call, oldest first.
+//
+// DescribeStackEvents pages from the newest event to the oldest so pages are
+// read until one contains an event that was already seen.
+func (recv *StackEventState) NewStackEvents() ([]*cfnlib.StackEvent, error) {
+	newEvents := make([]*cfnlib.StackEvent, 0)
+
+	var nextToken *string
+	for {
+		input := &cfnlib.DescribeStackEventsInput{
+			StackName: recv.stackName,
+			NextToken: nextToken,
+		}
+
+		output, err := recv.client.DescribeStackEvents(input)
+		if err != nil {
+			return nil, err
+		}
+
+		seen := false
+		for _, stackEvent := range output.StackEvents {
+			if stackEvent == nil || stackEvent.EventId == nil {
+				continue
+			}
+
+			if _, exists := recv.eventIds[*stackEvent.EventId]; exists {
+				seen = true
+				continue
+			}
+
+			recv.eventIds[*stackEvent.EventId] = nil
+			newEvents = append(newEvents, stackEvent)
+		}
+
+		if seen || output.NextToken == nil {
+			break
+		}
+		nextToken = output.NextToken
+	}
+
+	sort.Stable(StackEventByTime(newEvents))
+	recv.events = append(recv.events, newEvents...)
+
+	return newEvents, nil
+}
+
+// StackEvents are every event returned by NewStackEvents, oldest first
+func (recv *StackEventState) StackEvents() []*cfnlib.StackEvent {
+	return recv.events
+}
@@ -0,0 +1,93 @@
+/*
+ * (c) 2016-2018 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+package cloudformation
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	cfnlib "github.com/aws/aws-sdk-go/service/cloudformation"
+)
+
+var (
+	waitConditionTimeoutRegex = regexp.MustCompile(`Received (\d+) conditions? when expecting (\d+)`)
+	waitConditionFailureRegex = regexp.MustCompile(`for uniqueId: (\S+)`)
+)
+
+// FailedResources returns the first event of each resource that failed,
+// oldest first. Resources that were cancelled because another resource failed
+// are left out unless nothing else failed.
+//
+// events must be sorted oldest first
+func FailedResources(events []*cfnlib.StackEvent) []*cfnlib.StackEvent {
+	failed := make([]*cfnlib.StackEvent, 0)
+	cancelled := make([]*cfnlib.StackEvent, 0)
+	logicalIds := make(map[string]interface{})
+
+	for _, event := range events {
+		if event == nil ||
+			!strings.HasSuffix(aws.StringValue(event.ResourceStatus), "_FAILED") ||
+			aws.StringValue(event.LogicalResourceId) == aws.StringValue(event.StackName) {
+			continue
+		}
+
+		logicalId := aws.StringValue(event.LogicalResourceId)
+		if _, exists := logicalIds[logicalId]; exists {
+			continue
+		}
+		logicalIds[logicalId] = nil
+
+		if strings.HasSuffix(aws.StringValue(event.ResourceStatusReason), " cancelled") {
+			cancelled = append(cancelled, event)
+		} else {
+			failed = append(failed, event)
+		}
+	}
+
+	if len(failed) == 0 {
+		return cancelled
+	}
+
+	return failed
+}
+
+// WaitConditionSignals parses how many signals a WaitCondition received and
+// expected from the reason it timed out. ok is false for any other reason.
+func WaitConditionSignals(reason string) (received int, expected int, ok bool) {
+	matches := waitConditionTimeoutRegex.FindStringSubmatch(reason)
+	if matches == nil {
+		return
+	}
+
+	received, _ = strconv.Atoi(matches[1])
+	expected, _ = strconv.Atoi(matches[2])
+	ok = true
+	return
+}
+
+// WaitConditionFailure parses the unique id, which porter sets to the EC2
+// instance id, that signaled failure to a WaitCondition. ok is false for any
+// other reason.
+func WaitConditionFailure(reason string) (uniqueId string, ok bool) {
+	if !strings.Contains(reason, "received failed message") {
+		return
+	}
+
+	matches := waitConditionFailureRegex.FindStringSubmatch(reason)
+	if matches != nil {
+		uniqueId = matches[1]
+	}
+	ok = true
+	return
+}
@@ -0,0 +1,60 @@
+package cloudformation_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/adobe-platform/porter/aws/cloudformation"
+	"github.com/aws/aws-sdk-go/aws"
+	cfnlib "github.com/aws/aws-sdk-go/service/cloudformation"
+)
+
+func stackEvent(logicalId, status, reason string) *cfnlib.StackEvent {
+	return &cfnlib.StackEvent{
+		StackName:            aws.String("my-stack"),
+		LogicalResourceId:    aws.String(logicalId),
+		ResourceStatus:       aws.String(status),
+		ResourceStatusReason: aws.String(reason),
+	}
+}
+
+var _ = Describe("Root cause", func() {
+
+	It("returns the first failure of each resource and skips cancellations", func() {
+		events := []*cfnlib.StackEvent{
+			stackEvent("WaitCondition", "CREATE_IN_PROGRESS", ""),
+			stackEvent("WaitCondition", "CREATE_FAILED", "WaitCondition timed out. Received 1 conditions when expecting 2"),
+			stackEvent("Queue", "CREATE_FAILED", "Resource creation cancelled"),
+			stackEvent("my-stack", "ROLLBACK_IN_PROGRESS", "The following resource(s) failed to create: [WaitCondition]."),
+			stackEvent("WaitCondition", "DELETE_FAILED", "some later failure"),
+		}
+
+		failed := cloudformation.FailedResources(events)
+		Expect(failed).To(HaveLen(1))
+		Expect(*failed[0].ResourceStatus).To(Equal("CREATE_FAILED"))
+	})
+
+	It("returns cancellations if nothing else failed", func() {
+		events := []*cfnlib.StackEvent{
+			stackEvent("Queue", "CREATE_FAILED", "Resource creation cancelled"),
+		}
+
+		Expect(cloudformation.FailedResources(events)).To(HaveLen(1))
+	})
+
+	It("parses WaitCondition signals from a timeout", func() {
+		received, expected, ok := cloudformation.WaitConditionSignals("WaitCondition timed out. Received 1 conditions when expecting 2")
+		Expect(ok).To(BeTrue())
+		Expect(received).To(Equal(1))
+		Expect(expected).To(Equal(2))
+
+		_, _, ok = cloudformation.WaitConditionSignals("Resource creation cancelled")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("parses the instance that signaled a WaitCondition failure", func() {
+		instanceId, ok := cloudformation.WaitConditionFailure("WaitCondition received failed message: 'Failed' for uniqueId: i-0123456789")
+		Expect(ok).To(BeTrue())
+		Expect(instanceId).To(Equal("i-0123456789"))
+	})
+})
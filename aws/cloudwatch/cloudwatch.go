@@ -0,0 +1,69 @@
+/*
+ * (c) 2016-2018 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+package cloudwatch
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	cwlib "github.com/aws/aws-sdk-go/service/cloudwatch"
+)
+
+const (
+	NamespaceELB = "AWS/ELB"
+
+	MetricBackend5XX = "HTTPCode_Backend_5XX"
+	MetricELB5XX     = "HTTPCode_ELB_5XX"
+)
+
+// Don't force clients of this package to import
+// "github.com/aws/aws-sdk-go/service/cloudwatch"
+func New(config *session.Session) *cwlib.CloudWatch {
+	return cwlib.New(config)
+}
+
+// SumELBMetric returns the Sum statistic of a classic ELB metric between
+// startTime and endTime. Missing datapoints (no traffic) count as 0
+//
+// http://docs.aws.amazon.com/AmazonCloudWatch/latest/APIReference/API_GetMetricStatistics.html
+func SumELBMetric(client *cwlib.CloudWatch, elbName, metricName string, startTime, endTime time.Time) (float64, error) {
+
+	input := &cwlib.GetMetricStatisticsInput{
+		Namespace:  aws.String(NamespaceELB),
+		MetricName: aws.String(metricName),
+		Dimensions: []*cwlib.Dimension{
+			{
+				Name:  aws.String("LoadBalancerName"),
+				Value: aws.String(elbName),
+			},
+		},
+		StartTime:  aws.Time(startTime),
+		EndTime:    aws.Time(endTime),
+		Period:     aws.Int64(60),
+		Statistics: []*string{aws.String(cwlib.StatisticSum)},
+	}
+
+	output, err := client.GetMetricStatistics(input)
+	if err != nil {
+		return 0, err
+	}
+
+	var sum float64
+	for _, datapoint := range output.Datapoints {
+		if datapoint != nil && datapoint.Sum != nil {
+			sum += *datapoint.Sum
+		}
+	}
+
+	return sum, nil
+}
@@ -17,6 +17,7 @@ import (
 
 	"github.com/adobe-platform/porter/aws/util"
 	"github.com/adobe-platform/porter/aws_session"
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	ec2lib "github.com/aws/aws-sdk-go/service/ec2"
 )
@@ -379,3 +380,43 @@ func kvpsToFilters(kvps map[string][]string) []*ec2lib.Filter {
 
 	return filters
 }
+
+// NewestImage returns the most recently created available image owned by
+// owner whose name matches namePattern, which can contain * wildcards
+//
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeImages.html
+func NewestImage(client *ec2lib.EC2, owner, namePattern string) (*ec2lib.Image, error) {
+
+	input := &ec2lib.DescribeImagesInput{
+		Owners: []*string{aws.String(owner)},
+		Filters: []*ec2lib.Filter{
+			{
+				Name:   aws.String("name"),
+				Values: []*string{aws.String(namePattern)},
+			},
+			{
+				Name:   aws.String("state"),
+				Values: []*string{aws.String(ec2lib.ImageStateAvailable)},
+			},
+		},
+	}
+
+	output, err := client.DescribeImages(input)
+	if err != nil {
+		return nil, err
+	}
+
+	var newest *ec2lib.Image
+	for _, image := range output.Images {
+		// CreationDate is ISO 8601 so it sorts lexically
+		if newest == nil || aws.StringValue(image.CreationDate) > aws.StringValue(newest.CreationDate) {
+			newest = image
+		}
+	}
+
+	if newest == nil {
+		return nil, fmt.Errorf("no images owned by %s match %s", owner, namePattern)
+	}
+
+	return newest, nil
+}
@@ -125,6 +125,31 @@ func DescribeTags(client *elblib.ELB, elbNames ...string) ([]*elblib.TagDescript
 	return output.TagDescriptions, nil
 }
 
+// GetTags returns the tags of a single ELB as a map
+func GetTags(client *elblib.ELB, elbName string) (map[string]string, error) {
+
+	tagDescriptions, err := DescribeTags(client, elbName)
+	if err != nil {
+		return nil, err
+	}
+
+	kvps := make(map[string]string)
+	for _, tagDescription := range tagDescriptions {
+		if tagDescription == nil {
+			continue
+		}
+
+		for _, tag := range tagDescription.Tags {
+			if tag == nil || tag.Key == nil || tag.Value == nil {
+				continue
+			}
+			kvps[*tag.Key] = *tag.Value
+		}
+	}
+
+	return kvps, nil
+}
+
 // RegisterInstancesWithLoadBalancer using http://docs.aws.amazon.com/sdk-for-go/api/service/elb/ELB.html#RegisterInstancesWithLoadBalancer-instance_method
 func RegisterInstancesWithLoadBalancer(client *elblib.ELB, elbName string, instanceIds []string) (*elblib.RegisterInstancesWithLoadBalancerOutput, error) {
 
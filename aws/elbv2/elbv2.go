@@ -0,0 +1,400 @@
+/*
+ * (c) 2016-2018 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+package elbv2
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	elbv2lib "github.com/aws/aws-sdk-go/service/elbv2"
+)
+
+const (
+	//target states
+	Healthy  = elbv2lib.TargetHealthStateEnumHealthy
+	Initial  = elbv2lib.TargetHealthStateEnumInitial
+	Draining = elbv2lib.TargetHealthStateEnumDraining
+
+	maxRulePriority = 50000
+)
+
+// Don't force clients of this package to import
+// "github.com/aws/aws-sdk-go/service/elbv2"
+func New(config *session.Session) *elbv2lib.ELBV2 {
+	return elbv2lib.New(config)
+}
+
+func AddTags(client *elbv2lib.ELBV2, arn string, kvps map[string]string) error {
+
+	if len(kvps) == 0 {
+		return errors.New("must specify at least one tag")
+	}
+
+	tags := make([]*elbv2lib.Tag, 0)
+	for key, value := range kvps {
+		tag := &elbv2lib.Tag{
+			Key:   aws.String(key),
+			Value: aws.String(value),
+		}
+		tags = append(tags, tag)
+	}
+
+	input := &elbv2lib.AddTagsInput{
+		ResourceArns: []*string{aws.String(arn)},
+		Tags:         tags,
+	}
+
+	_, err := client.AddTags(input)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetTags returns the tags of a single target group or load balancer as a map
+func GetTags(client *elbv2lib.ELBV2, arn string) (map[string]string, error) {
+
+	input := &elbv2lib.DescribeTagsInput{
+		ResourceArns: []*string{aws.String(arn)},
+	}
+
+	output, err := client.DescribeTags(input)
+	if err != nil {
+		return nil, err
+	}
+
+	kvps := make(map[string]string)
+	for _, tagDescription := range output.TagDescriptions {
+		if tagDescription == nil {
+			continue
+		}
+
+		for _, tag := range tagDescription.Tags {
+			if tag == nil || tag.Key == nil || tag.Value == nil {
+				continue
+			}
+			kvps[*tag.Key] = *tag.Value
+		}
+	}
+
+	return kvps, nil
+}
+
+// DescribeTargetHealth using http://docs.aws.amazon.com/sdk-for-go/api/service/elbv2/ELBV2.html#DescribeTargetHealth-instance_method
+func DescribeTargetHealth(client *elbv2lib.ELBV2, targetGroupArn string) ([]*elbv2lib.TargetHealthDescription, error) {
+
+	input := &elbv2lib.DescribeTargetHealthInput{
+		TargetGroupArn: aws.String(targetGroupArn),
+	}
+
+	output, err := client.DescribeTargetHealth(input)
+	if err != nil {
+		return nil, err
+	}
+
+	return output.TargetHealthDescriptions, nil
+}
+
+// RegisterTargets using http://docs.aws.amazon.com/sdk-for-go/api/service/elbv2/ELBV2.html#RegisterTargets-instance_method
+func RegisterTargets(client *elbv2lib.ELBV2, targetGroupArn string, instanceIds []string) error {
+
+	input := &elbv2lib.RegisterTargetsInput{
+		TargetGroupArn: aws.String(targetGroupArn),
+		Targets:        targets(instanceIds),
+	}
+
+	_, err := client.RegisterTargets(input)
+	return err
+}
+
+// DeregisterTargets using http://docs.aws.amazon.com/sdk-for-go/api/service/elbv2/ELBV2.html#DeregisterTargets-instance_method
+func DeregisterTargets(client *elbv2lib.ELBV2, targetGroupArn string, instanceIds []string) error {
+
+	input := &elbv2lib.DeregisterTargetsInput{
+		TargetGroupArn: aws.String(targetGroupArn),
+		Targets:        targets(instanceIds),
+	}
+
+	_, err := client.DeregisterTargets(input)
+	return err
+}
+
+// GetListenerTargetGroupArn returns the target group of a listener's forward
+// default action
+func GetListenerTargetGroupArn(client *elbv2lib.ELBV2, listenerArn string) (string, error) {
+
+	listener, err := describeListener(client, listenerArn)
+	if err != nil {
+		return "", err
+	}
+
+	for _, action := range listener.DefaultActions {
+		if action == nil || action.Type == nil || action.TargetGroupArn == nil {
+			continue
+		}
+
+		if *action.Type == elbv2lib.ActionTypeEnumForward {
+			return *action.TargetGroupArn, nil
+		}
+	}
+
+	return "", fmt.Errorf("listener %s has no forward default action", listenerArn)
+}
+
+// SetListenerTargetGroupArn replaces a listener's default actions with a
+// single forward to targetGroupArn
+func SetListenerTargetGroupArn(client *elbv2lib.ELBV2, listenerArn, targetGroupArn string) error {
+
+	input := &elbv2lib.ModifyListenerInput{
+		ListenerArn: aws.String(listenerArn),
+		DefaultActions: []*elbv2lib.Action{
+			{
+				Type:           aws.String(elbv2lib.ActionTypeEnumForward),
+				TargetGroupArn: aws.String(targetGroupArn),
+			},
+		},
+	}
+
+	_, err := client.ModifyListener(input)
+	return err
+}
+
+// ListenerSupportsRules is true for ALB listeners. NLB listeners only have a
+// default action
+func ListenerSupportsRules(client *elbv2lib.ELBV2, listenerArn string) (bool, error) {
+
+	listener, err := describeListener(client, listenerArn)
+	if err != nil {
+		return false, err
+	}
+
+	switch aws.StringValue(listener.Protocol) {
+	case elbv2lib.ProtocolEnumHttp, elbv2lib.ProtocolEnumHttps:
+		return true, nil
+	}
+	return false, nil
+}
+
+// CreateStagingRule attaches a target group to a listener's load balancer with
+// a rule that only matches a host name under the reserved .invalid TLD. The
+// load balancer health checks the target group without sending it any real
+// traffic. The rule has the lowest available priority
+func CreateStagingRule(client *elbv2lib.ELBV2, listenerArn, targetGroupArn string) (string, error) {
+
+	describeRulesOutput, err := client.DescribeRules(&elbv2lib.DescribeRulesInput{
+		ListenerArn: aws.String(listenerArn),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	usedPriorities := make(map[int64]interface{})
+	for _, rule := range describeRulesOutput.Rules {
+		if rule == nil || aws.BoolValue(rule.IsDefault) {
+			continue
+		}
+
+		priority, err := strconv.ParseInt(aws.StringValue(rule.Priority), 10, 64)
+		if err == nil {
+			usedPriorities[priority] = nil
+		}
+	}
+
+	priority, err := UnusedRulePriority(usedPriorities)
+	if err != nil {
+		return "", err
+	}
+
+	input := &elbv2lib.CreateRuleInput{
+		ListenerArn: aws.String(listenerArn),
+		Priority:    aws.Int64(priority),
+		Conditions: []*elbv2lib.RuleCondition{
+			{
+				Field:  aws.String("host-header"),
+				Values: []*string{aws.String(StagingHost(targetGroupArn))},
+			},
+		},
+		Actions: []*elbv2lib.Action{
+			{
+				Type:           aws.String(elbv2lib.ActionTypeEnumForward),
+				TargetGroupArn: aws.String(targetGroupArn),
+			},
+		},
+	}
+
+	output, err := client.CreateRule(input)
+	if err != nil {
+		return "", err
+	}
+
+	if len(output.Rules) == 0 || output.Rules[0] == nil || output.Rules[0].RuleArn == nil {
+		return "", errors.New("CreateRule returned no rule")
+	}
+
+	return *output.Rules[0].RuleArn, nil
+}
+
+func DeleteRule(client *elbv2lib.ELBV2, ruleArn string) error {
+
+	input := &elbv2lib.DeleteRuleInput{
+		RuleArn: aws.String(ruleArn),
+	}
+
+	_, err := client.DeleteRule(input)
+	return err
+}
+
+// UnusedRulePriority returns the highest priority number, which is evaluated
+// last, that isn't used by a listener's rules
+func UnusedRulePriority(usedPriorities map[int64]interface{}) (int64, error) {
+
+	for priority := int64(maxRulePriority); priority > 0; priority-- {
+		if _, exists := usedPriorities[priority]; !exists {
+			return priority, nil
+		}
+	}
+
+	return 0, errors.New("the listener has no unused rule priorities")
+}
+
+// StagingHost is the host header matched by a target group's staging rule.
+// The .invalid TLD never resolves so clients can't send it by accident
+func StagingHost(targetGroupArn string) string {
+	name := targetGroupArn
+
+	// arn:aws:elasticloadbalancing:region:account:targetgroup/name/id
+	parts := strings.Split(targetGroupArn, "/")
+	if len(parts) == 3 {
+		name = parts[1] + "-" + parts[2]
+	}
+
+	return strings.ToLower(name) + ".porter-staging.invalid"
+}
+
+// GetLiveTargetGroupArn returns the target group receiving traffic.
+//
+// If listenerArn is defined it's the target group of the listener's default
+// action. Otherwise it's targetGroupArn
+func GetLiveTargetGroupArn(client *elbv2lib.ELBV2, targetGroupArn, listenerArn string) (string, error) {
+	if listenerArn == "" {
+		return targetGroupArn, nil
+	}
+
+	return GetListenerTargetGroupArn(client, listenerArn)
+}
+
+// GetLiveTags returns the tags of the target group receiving traffic. See
+// GetLiveTargetGroupArn
+func GetLiveTags(client *elbv2lib.ELBV2, targetGroupArn, listenerArn string) (map[string]string, error) {
+
+	liveArn, err := GetLiveTargetGroupArn(client, targetGroupArn, listenerArn)
+	if err != nil {
+		return nil, err
+	}
+
+	return GetTags(client, liveArn)
+}
+
+// GetLoadBalancerArns returns the load balancers that send traffic to a target
+// group, or in the case of a listener the load balancer it belongs to
+func GetLoadBalancerArns(client *elbv2lib.ELBV2, targetGroupArn, listenerArn string) ([]string, error) {
+	arns := make([]string, 0)
+
+	if listenerArn != "" {
+
+		listener, err := describeListener(client, listenerArn)
+		if err != nil {
+			return nil, err
+		}
+
+		if listener.LoadBalancerArn != nil {
+			arns = append(arns, *listener.LoadBalancerArn)
+		}
+
+		return arns, nil
+	}
+
+	input := &elbv2lib.DescribeTargetGroupsInput{
+		TargetGroupArns: []*string{aws.String(targetGroupArn)},
+	}
+
+	output, err := client.DescribeTargetGroups(input)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, targetGroup := range output.TargetGroups {
+		if targetGroup == nil {
+			continue
+		}
+
+		for _, arn := range targetGroup.LoadBalancerArns {
+			if arn != nil {
+				arns = append(arns, *arn)
+			}
+		}
+	}
+
+	return arns, nil
+}
+
+// DescribeLoadBalancers using http://docs.aws.amazon.com/sdk-for-go/api/service/elbv2/ELBV2.html#DescribeLoadBalancers-instance_method
+func DescribeLoadBalancers(client *elbv2lib.ELBV2, arns ...string) ([]*elbv2lib.LoadBalancer, error) {
+
+	if len(arns) == 0 {
+		return nil, errors.New("must specify at least one load balancer arn")
+	}
+
+	input := &elbv2lib.DescribeLoadBalancersInput{
+		LoadBalancerArns: aws.StringSlice(arns),
+	}
+
+	output, err := client.DescribeLoadBalancers(input)
+	if err != nil {
+		return nil, err
+	}
+
+	return output.LoadBalancers, nil
+}
+
+func describeListener(client *elbv2lib.ELBV2, listenerArn string) (*elbv2lib.Listener, error) {
+
+	input := &elbv2lib.DescribeListenersInput{
+		ListenerArns: []*string{aws.String(listenerArn)},
+	}
+
+	output, err := client.DescribeListeners(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(output.Listeners) != 1 || output.Listeners[0] == nil {
+		return nil, fmt.Errorf("listener %s not found", listenerArn)
+	}
+
+	return output.Listeners[0], nil
+}
+
+func targets(instanceIds []string) []*elbv2lib.TargetDescription {
+	targetList := make([]*elbv2lib.TargetDescription, len(instanceIds))
+	for i := 0; i < len(instanceIds); i++ {
+		targetList[i] = &elbv2lib.TargetDescription{
+			Id: aws.String(instanceIds[i]),
+		}
+	}
+	return targetList
+}
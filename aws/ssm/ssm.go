@@ -0,0 +1,161 @@
+/*
+ * (c) 2016-2018 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+// Package ssm is a minimal client for the parts of the SSM API porter uses.
+//
+// The vendored aws-sdk-go predates porter needing SSM so this speaks the JSON
+// 1.1 protocol with the SDK's client, signer, and endpoints
+package ssm
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/client/metadata"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/signer/v4"
+)
+
+const (
+	serviceName  = "ssm"
+	apiVersion   = "2014-11-06"
+	targetPrefix = "AmazonSSM"
+)
+
+type (
+	SSM struct {
+		*client.Client
+	}
+
+	getParameterInput struct {
+		Name string `json:"Name"`
+	}
+
+	getParameterOutput struct {
+		Parameter struct {
+			Name  string `json:"Name"`
+			Type  string `json:"Type"`
+			Value string `json:"Value"`
+		} `json:"Parameter"`
+	}
+
+	errorResponse struct {
+		Type    string `json:"__type"`
+		Message string `json:"message"`
+	}
+)
+
+func New(p client.ConfigProvider, cfgs ...*aws.Config) *SSM {
+	c := p.ClientConfig(serviceName, cfgs...)
+
+	svc := &SSM{
+		Client: client.New(
+			*c.Config,
+			metadata.ClientInfo{
+				ServiceName:   serviceName,
+				SigningName:   c.SigningName,
+				SigningRegion: c.SigningRegion,
+				Endpoint:      c.Endpoint,
+				APIVersion:    apiVersion,
+				JSONVersion:   "1.1",
+				TargetPrefix:  targetPrefix,
+			},
+			c.Handlers,
+		),
+	}
+
+	svc.Handlers.Sign.PushBackNamed(v4.SignRequestHandler)
+	svc.Handlers.Build.PushBack(build)
+	svc.Handlers.Unmarshal.PushBack(unmarshal)
+	svc.Handlers.UnmarshalMeta.PushBack(unmarshalMeta)
+	svc.Handlers.UnmarshalError.PushBack(unmarshalError)
+
+	return svc
+}
+
+// GetParameter returns the value of a parameter such as the public parameters
+// AWS publishes for its AMIs
+//
+// http://docs.aws.amazon.com/systems-manager/latest/APIReference/API_GetParameter.html
+func GetParameter(client *SSM, name string) (string, error) {
+	op := &request.Operation{
+		Name:       "GetParameter",
+		HTTPMethod: "POST",
+		HTTPPath:   "/",
+	}
+
+	output := &getParameterOutput{}
+	req := client.NewRequest(op, &getParameterInput{Name: name}, output)
+
+	err := req.Send()
+	if err != nil {
+		return "", err
+	}
+
+	return output.Parameter.Value, nil
+}
+
+func build(r *request.Request) {
+	body, err := json.Marshal(r.Params)
+	if err != nil {
+		r.Error = awserr.New("SerializationError", "failed encoding JSON RPC request", err)
+		return
+	}
+
+	r.SetBufferBody(body)
+	r.HTTPRequest.Header.Set("X-Amz-Target", r.ClientInfo.TargetPrefix+"."+r.Operation.Name)
+	r.HTTPRequest.Header.Set("Content-Type", "application/x-amz-json-"+r.ClientInfo.JSONVersion)
+}
+
+func unmarshal(r *request.Request) {
+	defer r.HTTPResponse.Body.Close()
+
+	err := json.NewDecoder(r.HTTPResponse.Body).Decode(r.Data)
+	if err != nil {
+		r.Error = awserr.New("SerializationError", "failed decoding JSON RPC response", err)
+	}
+}
+
+func unmarshalMeta(r *request.Request) {
+	r.RequestID = r.HTTPResponse.Header.Get("X-Amzn-Requestid")
+}
+
+func unmarshalError(r *request.Request) {
+	defer r.HTTPResponse.Body.Close()
+
+	bodyBytes, err := ioutil.ReadAll(r.HTTPResponse.Body)
+	if err != nil {
+		r.Error = awserr.New("SerializationError", "failed reading JSON RPC error response", err)
+		return
+	}
+
+	resp := errorResponse{}
+	err = json.NewDecoder(bytes.NewReader(bodyBytes)).Decode(&resp)
+	if err != nil {
+		r.Error = awserr.New("SerializationError", "failed decoding JSON RPC error response", err)
+		return
+	}
+
+	// __type is sometimes prefixed with a namespace
+	// e.g. com.amazonaws.ssm#ParameterNotFound
+	code := resp.Type[strings.LastIndex(resp.Type, "#")+1:]
+
+	r.Error = awserr.NewRequestFailure(
+		awserr.New(code, resp.Message, nil),
+		r.HTTPResponse.StatusCode,
+		r.RequestID,
+	)
+}
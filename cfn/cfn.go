@@ -37,6 +37,7 @@ type (
 		AllowedValues         []string `json:"AllowedValues,omitempty"`
 		Default               string   `json:"Default,omitempty"`
 		ConstraintDescription string   `json:"ConstraintDescription,omitempty"`
+		NoEcho                bool     `json:"NoEcho,omitempty"`
 	}
 )
 
@@ -0,0 +1,145 @@
+/*
+ * (c) 2016-2018 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+package cfn
+
+import (
+	"reflect"
+	"sort"
+)
+
+type (
+	// TemplateDiff is the difference between the resources of a deployed
+	// template and a template that would be deployed
+	TemplateDiff struct {
+		Added    []ResourceChange `json:"Added"`
+		Removed  []ResourceChange `json:"Removed"`
+		Modified []ResourceChange `json:"Modified"`
+	}
+
+	ResourceChange struct {
+		LogicalId string           `json:"LogicalId"`
+		Type      string           `json:"Type"`
+		Changes   []PropertyChange `json:"Changes,omitempty"`
+	}
+
+	// PropertyChange is a change to a single key of a resource. Path is
+	// dot-delimited from the resource root e.g. Properties.ImageId
+	PropertyChange struct {
+		Path string      `json:"Path"`
+		Old  interface{} `json:"Old"`
+		New  interface{} `json:"New"`
+	}
+)
+
+func (recv TemplateDiff) Empty() bool {
+	return len(recv.Added) == 0 && len(recv.Removed) == 0 && len(recv.Modified) == 0
+}
+
+// DiffResources compares the Resources section of two templates.
+//
+// Both inputs should be decoded from JSON so numbers compare equally. Lists
+// are compared as a whole rather than element by element
+func DiffResources(oldResources, newResources map[string]interface{}) (diff TemplateDiff) {
+
+	diff.Added = make([]ResourceChange, 0)
+	diff.Removed = make([]ResourceChange, 0)
+	diff.Modified = make([]ResourceChange, 0)
+
+	for _, logicalId := range sortedKeys(newResources) {
+
+		newResource := newResources[logicalId]
+		oldResource, exists := oldResources[logicalId]
+		if !exists {
+			diff.Added = append(diff.Added, ResourceChange{
+				LogicalId: logicalId,
+				Type:      resourceType(newResource),
+			})
+			continue
+		}
+
+		changes := diffValues("", oldResource, newResource)
+		if len(changes) > 0 {
+			diff.Modified = append(diff.Modified, ResourceChange{
+				LogicalId: logicalId,
+				Type:      resourceType(newResource),
+				Changes:   changes,
+			})
+		}
+	}
+
+	for _, logicalId := range sortedKeys(oldResources) {
+
+		if _, exists := newResources[logicalId]; !exists {
+			diff.Removed = append(diff.Removed, ResourceChange{
+				LogicalId: logicalId,
+				Type:      resourceType(oldResources[logicalId]),
+			})
+		}
+	}
+
+	return
+}
+
+func diffValues(path string, oldValue, newValue interface{}) []PropertyChange {
+	changes := make([]PropertyChange, 0)
+
+	oldMap, oldIsMap := oldValue.(map[string]interface{})
+	newMap, newIsMap := newValue.(map[string]interface{})
+
+	if !oldIsMap || !newIsMap {
+		if !reflect.DeepEqual(oldValue, newValue) {
+			changes = append(changes, PropertyChange{
+				Path: path,
+				Old:  oldValue,
+				New:  newValue,
+			})
+		}
+		return changes
+	}
+
+	keys := make(map[string]interface{})
+	for key := range oldMap {
+		keys[key] = nil
+	}
+	for key := range newMap {
+		keys[key] = nil
+	}
+
+	for _, key := range sortedKeys(keys) {
+		keyPath := key
+		if path != "" {
+			keyPath = path + "." + key
+		}
+
+		changes = append(changes, diffValues(keyPath, oldMap[key], newMap[key])...)
+	}
+
+	return changes
+}
+
+func resourceType(resource interface{}) string {
+	if resourceMap, ok := resource.(map[string]interface{}); ok {
+		if resourceType, ok := resourceMap["Type"].(string); ok {
+			return resourceType
+		}
+	}
+	return ""
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
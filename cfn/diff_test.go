@@ -0,0 +1,52 @@
+package cfn_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"encoding/json"
+
+	"github.com/adobe-platform/porter/cfn"
+)
+
+func resources(s string) map[string]interface{} {
+	r := make(map[string]interface{})
+	Expect(json.Unmarshal([]byte(s), &r)).To(Succeed())
+	return r
+}
+
+var _ = Describe("DiffResources", func() {
+
+	It("finds added, removed, and modified resources", func() {
+		oldResources := resources(`{
+			"Keep": {"Type": "AWS::EC2::SecurityGroup", "Properties": {"VpcId": "vpc-1"}},
+			"Change": {"Type": "AWS::AutoScaling::AutoScalingGroup", "Properties": {"MinSize": 1, "MaxSize": 2}},
+			"Gone": {"Type": "AWS::SQS::Queue"}
+		}`)
+		newResources := resources(`{
+			"Keep": {"Type": "AWS::EC2::SecurityGroup", "Properties": {"VpcId": "vpc-1"}},
+			"Change": {"Type": "AWS::AutoScaling::AutoScalingGroup", "Properties": {"MinSize": 1, "MaxSize": 4, "Cooldown": "60"}},
+			"New": {"Type": "AWS::SQS::Queue"}
+		}`)
+
+		diff := cfn.DiffResources(oldResources, newResources)
+
+		Expect(diff.Added).To(Equal([]cfn.ResourceChange{
+			{LogicalId: "New", Type: "AWS::SQS::Queue"},
+		}))
+		Expect(diff.Removed).To(Equal([]cfn.ResourceChange{
+			{LogicalId: "Gone", Type: "AWS::SQS::Queue"},
+		}))
+		Expect(diff.Modified).To(HaveLen(1))
+		Expect(diff.Modified[0].LogicalId).To(Equal("Change"))
+		Expect(diff.Modified[0].Changes).To(Equal([]cfn.PropertyChange{
+			{Path: "Properties.Cooldown", Old: nil, New: "60"},
+			{Path: "Properties.MaxSize", Old: float64(2), New: float64(4)},
+		}))
+	})
+
+	It("is empty for identical resources", func() {
+		r := `{"A": {"Type": "AWS::SQS::Queue", "Properties": {"Tags": [{"Key": "a"}]}}}`
+		Expect(cfn.DiffResources(resources(r), resources(r)).Empty()).To(BeTrue())
+	})
+})
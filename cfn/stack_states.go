@@ -70,6 +70,18 @@ func CheckHotswapStatus(status string) bool {
 	}
 }
 
+// Stacks in these states are complete and can be rolled back to
+func RollbackStatus(status string) bool {
+	switch status {
+	case CREATE_COMPLETE,
+		UPDATE_COMPLETE,
+		UPDATE_ROLLBACK_COMPLETE:
+		return true
+	default:
+		return false
+	}
+}
+
 func AnyStatus(status string) bool {
 	return true
 }
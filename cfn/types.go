@@ -45,6 +45,7 @@ const (
 	EC2_EIPAssociation                     = "AWS::EC2::EIPAssociation"
 	EC2_Instance                           = "AWS::EC2::Instance"
 	EC2_InternetGateway                    = "AWS::EC2::InternetGateway"
+	EC2_LaunchTemplate                     = "AWS::EC2::LaunchTemplate"
 	EC2_NetworkAcl                         = "AWS::EC2::NetworkAcl"
 	EC2_NetworkAclEntry                    = "AWS::EC2::NetworkAclEntry"
 	EC2_NetworkInterface                   = "AWS::EC2::NetworkInterface"
@@ -86,6 +87,10 @@ const (
 	ElasticBeanstalk_ConfigurationTemplate = "AWS::ElasticBeanstalk::ConfigurationTemplate"
 	ElasticBeanstalk_Environment           = "AWS::ElasticBeanstalk::Environment"
 	ElasticLoadBalancing_LoadBalancer      = "AWS::ElasticLoadBalancing::LoadBalancer"
+	ElasticLoadBalancingV2_Listener        = "AWS::ElasticLoadBalancingV2::Listener"
+	ElasticLoadBalancingV2_ListenerRule    = "AWS::ElasticLoadBalancingV2::ListenerRule"
+	ElasticLoadBalancingV2_LoadBalancer    = "AWS::ElasticLoadBalancingV2::LoadBalancer"
+	ElasticLoadBalancingV2_TargetGroup     = "AWS::ElasticLoadBalancingV2::TargetGroup"
 	IAM_AccessKey                          = "AWS::IAM::AccessKey"
 	IAM_Group                              = "AWS::IAM::Group"
 	IAM_InstanceProfile                    = "AWS::IAM::InstanceProfile"
@@ -185,6 +190,7 @@ func init() {
 	allTypes[EC2_EIPAssociation] = nil
 	allTypes[EC2_Instance] = nil
 	allTypes[EC2_InternetGateway] = nil
+	allTypes[EC2_LaunchTemplate] = nil
 	allTypes[EC2_NetworkAcl] = nil
 	allTypes[EC2_NetworkAclEntry] = nil
 	allTypes[EC2_NetworkInterface] = nil
@@ -226,6 +232,10 @@ func init() {
 	allTypes[ElasticBeanstalk_ConfigurationTemplate] = nil
 	allTypes[ElasticBeanstalk_Environment] = nil
 	allTypes[ElasticLoadBalancing_LoadBalancer] = nil
+	allTypes[ElasticLoadBalancingV2_Listener] = nil
+	allTypes[ElasticLoadBalancingV2_ListenerRule] = nil
+	allTypes[ElasticLoadBalancingV2_LoadBalancer] = nil
+	allTypes[ElasticLoadBalancingV2_TargetGroup] = nil
 	allTypes[IAM_AccessKey] = nil
 	allTypes[IAM_Group] = nil
 	allTypes[IAM_InstanceProfile] = nil
@@ -25,6 +25,18 @@ import (
 type (
 	UserDataContext struct {
 		LogicalId string
+		Prebaked  bool
+		Packages  []string
+	}
+
+	// HostImageContext is what porter bootstrap ami needs to render the
+	// install steps that are baked into an image
+	HostImageContext struct {
+		ContainerUserUid string
+		PorterBinaryUrl  string
+		Packages         []string
+		FailureMarker    string
+		SuccessMarker    string
 	}
 
 	AWSCloudFormationInitCtx struct {
@@ -46,8 +58,7 @@ type (
 		RegistryDeployment bool
 		InsecureRegistry   string
 
-		InetHealthCheckMethod string
-		InetHealthCheckPath   string
+		InetHealthCheck string
 
 		ImageNames []string
 
@@ -58,12 +69,25 @@ type (
 
 		EC2BootstrapScript string
 
-		Elbs string
+		Elbs         string
+		TargetGroups string
 
 		ContainerUserUid string
+
+		// Prebaked is set when the AMI was produced by porter bootstrap ami
+		// and already has docker, porter, and rsyslog set up
+		Prebaked bool
 	}
 )
 
+// HostPackages are installed by cloud-init on every instance unless the AMI
+// was prebaked in which case they're installed when the image is baked
+var HostPackages = []string{
+	"haproxy-1.5.2",
+	"docker-18.03.1ce",
+	"sysstat-9.0.4",
+}
+
 // ImageIdInMap works with a mapping like the following to select an AMI id for
 // the current region
 //
@@ -98,7 +122,7 @@ func ImageIdInMap(mapId string) map[string]interface{} {
 //
 // http://docs.aws.amazon.com/AWSEC2/latest/UserGuide/user-data.html
 // http://docs.aws.amazon.com/AWSEC2/latest/UserGuide/AmazonLinuxAMIBasics.html
-func UserData(autoScalingLaunchConfigurationLogicalId string) (map[string]interface{}, error) {
+func UserData(autoScalingLaunchConfigurationLogicalId string, prebaked bool) (map[string]interface{}, error) {
 
 	tmpl, err := template.New("").Parse(files.CloudInitJson)
 	if err != nil {
@@ -109,6 +133,8 @@ func UserData(autoScalingLaunchConfigurationLogicalId string) (map[string]interf
 
 	context := UserDataContext{
 		LogicalId: autoScalingLaunchConfigurationLogicalId,
+		Prebaked:  prebaked,
+		Packages:  HostPackages,
 	}
 
 	err = tmpl.Execute(&buf, context)
@@ -224,6 +250,11 @@ func AWSCloudFormationInit(autoScalingLaunchConfigurationLogicalId string, conte
 		return nil, err
 	}
 
+	_, err = tmpl.New("porter_install").Parse(files.PorterInstall)
+	if err != nil {
+		return nil, err
+	}
+
 	var buf bytes.Buffer
 
 	err = tmpl.Execute(&buf, context)
@@ -108,7 +108,8 @@ func ELBToInstance(vpc, httpsOnly bool, httpsPort int, elbName, elbSecurityGroup
 }
 
 // Allow internet traffic to the ELB. This is only use in a custom VPC.
-func InetSg(vpc, https, httpsOnly bool, metadataKey string) map[string]interface{} {
+// Traffic is allowed from cidrs or anywhere if there are none
+func InetSg(vpc, https, httpsOnly bool, metadataKey string, cidrs []string) map[string]interface{} {
 
 	properties := map[string]interface{}{
 		"GroupDescription": "Allow internet traffic",
@@ -121,6 +122,10 @@ func InetSg(vpc, https, httpsOnly bool, metadataKey string) map[string]interface
 		"Metadata":   metadata,
 	}
 
+	if len(cidrs) == 0 {
+		cidrs = []string{"0.0.0.0/0"}
+	}
+
 	// Only associate this sg and create ingress rules in a custom VPC.
 	// EC2-Classic and Default VPC don't need this
 	if vpc {
@@ -128,27 +133,30 @@ func InetSg(vpc, https, httpsOnly bool, metadataKey string) map[string]interface
 
 		sgIngress := make([]interface{}, 0)
 
-		if !httpsOnly {
-			httpIngress := map[string]interface{}{
-				"IpProtocol": "tcp",
-				"CidrIp":     "0.0.0.0/0",
-				"FromPort":   constants.HTTP_Port,
-				"ToPort":     constants.HTTP_Port,
+		for _, cidr := range cidrs {
+
+			if !httpsOnly {
+				httpIngress := map[string]interface{}{
+					"IpProtocol": "tcp",
+					"CidrIp":     cidr,
+					"FromPort":   constants.HTTP_Port,
+					"ToPort":     constants.HTTP_Port,
+				}
+
+				sgIngress = append(sgIngress, httpIngress)
 			}
 
-			sgIngress = append(sgIngress, httpIngress)
-		}
+			if https {
 
-		if https {
+				httpsIngress := map[string]interface{}{
+					"IpProtocol": "tcp",
+					"CidrIp":     cidr,
+					"FromPort":   constants.HTTPS_Port,
+					"ToPort":     constants.HTTPS_Port,
+				}
 
-			httpsIngress := map[string]interface{}{
-				"IpProtocol": "tcp",
-				"CidrIp":     "0.0.0.0/0",
-				"FromPort":   constants.HTTPS_Port,
-				"ToPort":     constants.HTTPS_Port,
+				sgIngress = append(sgIngress, httpsIngress)
 			}
-
-			sgIngress = append(sgIngress, httpsIngress)
 		}
 
 		properties["SecurityGroupIngress"] = sgIngress
@@ -0,0 +1,64 @@
+/*
+ * (c) 2016-2018 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+package cfn_template
+
+import (
+	"bytes"
+	"text/template"
+
+	"github.com/adobe-platform/porter/files"
+)
+
+const hostImageScript = `#!/bin/bash -e
+# Bakes porter's host install steps into an image. The instance shuts itself
+# down when it's done so porter bootstrap ami knows it can create the image.
+# It only creates the image if the success marker is in the console output
+trap 'echo {{ .FailureMarker }}; shutdown -h now' ERR
+
+# tracing starts after the trap so the failure marker is only printed on failure
+set -x
+
+yum install -y{{ range .Packages }} {{ . }}{{ end }}
+chkconfig docker on
+
+{{ template "porter_install" . }}
+# instances launched from this image run cloud-init again
+rm -rf /var/lib/cloud/instances /var/lib/cloud/instance
+
+echo {{ .SuccessMarker }}
+shutdown -h now
+`
+
+// HostImageUserData is the user data of the instance porter bootstrap ami
+// launches to bake an image. It shares the install steps of porter_bootstrap
+// so prebaked and non-prebaked instances end up with the same host setup
+func HostImageUserData(context HostImageContext) (string, error) {
+
+	tmpl, err := template.New("").Parse(hostImageScript)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = tmpl.New("porter_install").Parse(files.PorterInstall)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+
+	err = tmpl.Execute(&buf, context)
+	if err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
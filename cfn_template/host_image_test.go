@@ -0,0 +1,89 @@
+package cfn_template_test
+
+import (
+	"encoding/json"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/adobe-platform/porter/cfn_template"
+)
+
+var _ = Describe("Prebaked AMIs", func() {
+
+	// porter_install's first step
+	const porterInstall = "adduser porter-docker"
+
+	render := func(value interface{}, err error) string {
+		Expect(err).To(BeNil())
+
+		valueBytes, err := json.Marshal(value)
+		Expect(err).To(BeNil())
+		return string(valueBytes)
+	}
+
+	Describe("UserData", func() {
+
+		It("installs packages at boot", func() {
+			userData := render(cfn_template.UserData("LaunchConfiguration", false))
+			Expect(userData).To(ContainSubstring(`packages:\n`))
+			Expect(userData).To(ContainSubstring(`  - haproxy-1.5.2\n`))
+		})
+
+		It("omits packages when prebaked", func() {
+			userData := render(cfn_template.UserData("LaunchConfiguration", true))
+			Expect(userData).ToNot(ContainSubstring("packages:"))
+			Expect(userData).To(ContainSubstring("porter_bootstrap"))
+		})
+	})
+
+	Describe("AWSCloudFormationInit", func() {
+
+		It("runs porter_install at boot", func() {
+			init := render(cfn_template.AWSCloudFormationInit("LaunchConfiguration",
+				cfn_template.AWSCloudFormationInitCtx{ContainerUserUid: "601"}))
+			Expect(init).To(ContainSubstring(porterInstall + " -u 601"))
+		})
+
+		It("doesn't run porter_install when prebaked", func() {
+			init := render(cfn_template.AWSCloudFormationInit("LaunchConfiguration",
+				cfn_template.AWSCloudFormationInitCtx{ContainerUserUid: "601", Prebaked: true}))
+			Expect(init).ToNot(ContainSubstring(porterInstall))
+			Expect(init).To(ContainSubstring("baked into the image"))
+		})
+	})
+
+	Describe("HostImageUserData", func() {
+
+		It("installs the packages and runs porter_install", func() {
+			userData, err := cfn_template.HostImageUserData(cfn_template.HostImageContext{
+				ContainerUserUid: "601",
+				PorterBinaryUrl:  "https://example.com/porter",
+				Packages:         cfn_template.HostPackages,
+				FailureMarker:    "PORTER_AMI_FAILED",
+				SuccessMarker:    "PORTER_AMI_SUCCEEDED",
+			})
+			Expect(err).To(BeNil())
+
+			Expect(userData).To(ContainSubstring("yum install -y haproxy-1.5.2 docker-18.03.1ce sysstat-9.0.4\n"))
+			Expect(userData).To(ContainSubstring(porterInstall + " -u 601"))
+			Expect(userData).To(ContainSubstring("curl --compressed -so /usr/bin/porter https://example.com/porter"))
+			Expect(userData).To(ContainSubstring("echo PORTER_AMI_FAILED"))
+		})
+
+		It("prints the success marker once the install steps finish", func() {
+			userData, err := cfn_template.HostImageUserData(cfn_template.HostImageContext{
+				Packages:      cfn_template.HostPackages,
+				FailureMarker: "PORTER_AMI_FAILED",
+				SuccessMarker: "PORTER_AMI_SUCCEEDED",
+			})
+			Expect(err).To(BeNil())
+
+			Expect(userData).To(HaveSuffix("echo PORTER_AMI_SUCCEEDED\nshutdown -h now\n"))
+
+			// tracing the trap would print the failure marker on success
+			Expect(strings.Index(userData, "set -x")).To(BeNumerically(">", strings.Index(userData, "trap ")))
+		})
+	})
+})
@@ -0,0 +1,333 @@
+/*
+ * (c) 2016-2018 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+package bootstrap
+
+import (
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	porterec2 "github.com/adobe-platform/porter/aws/ec2"
+	"github.com/adobe-platform/porter/cfn_template"
+	"github.com/adobe-platform/porter/constants"
+	"github.com/adobe-platform/porter/logger"
+	"github.com/adobe-platform/porter/util"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/phylake/go-cli"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+// printed to the console by the bake script when the install steps finish
+const (
+	amiFailureMarker = "porter bootstrap ami failed"
+	amiSuccessMarker = "porter bootstrap ami succeeded"
+)
+
+type AmiCmd struct{}
+
+func (recv *AmiCmd) Name() string {
+	return "ami"
+}
+
+func (recv *AmiCmd) ShortHelp() string {
+	return "Bake an AMI with porter's host dependencies installed"
+}
+
+func (recv *AmiCmd) LongHelp() string {
+	return `NAME
+    ami -- Bake an AMI with porter's host dependencies installed
+
+SYNOPSIS
+    ami -region <string> -base-ami <string> [-name <string>] [-instance-type <string>] [-subnet-id <string> -sg-id <string>]
+
+DESCRIPTION
+    Launch an instance from a base Amazon Linux AMI, install docker, haproxy,
+    porter, and porter's rsyslog and log rotation config on it, and create an
+    AMI from it. The install steps are the same ones every instance otherwise
+    runs at boot.
+
+    The instance is terminated whether or not baking succeeds. The id of the
+    new AMI is printed to stdout.
+
+    Use the AMI by setting prebaked: true alongside it in .porter/config
+
+        ami:
+          id: ami-12345678
+          prebaked: true
+
+    Instances launched from a prebaked AMI skip the install steps. If the
+    porter baked into the image is a different version than the one that
+    provisioned the stack the matching version is downloaded at boot.
+
+OPTIONS
+    -region
+        The region in which to bake the AMI
+
+    -base-ami
+        The id of the Amazon Linux AMI to start from
+
+    -name
+        The name of the new AMI. Defaults to porter-<version>-<unix time>
+
+    -instance-type
+        The instance type used to bake the AMI. Defaults to m4.large
+
+    -subnet-id
+        The id of a subnet to launch the instance into. The subnet needs
+        internet access to download packages and porter
+        (VPC only)
+
+    -sg-id
+        The id of a security group to attach to the instance
+        (VPC only)`
+}
+
+func (recv *AmiCmd) SubCommands() []cli.Command {
+	return nil
+}
+
+func (recv *AmiCmd) Execute(args []string) bool {
+	if len(args) > 0 {
+		var region, baseAMI, name, instanceType, subnetId, securityGroupId string
+
+		flagSet := flag.NewFlagSet("", flag.ContinueOnError)
+		flagSet.StringVar(&region, "region", "", "")
+		flagSet.StringVar(&baseAMI, "base-ami", "", "")
+		flagSet.StringVar(&name, "name", "", "")
+		flagSet.StringVar(&instanceType, "instance-type", "m4.large", "")
+		flagSet.StringVar(&subnetId, "subnet-id", "", "")
+		flagSet.StringVar(&securityGroupId, "sg-id", "", "")
+		flagSet.Usage = func() {
+			fmt.Println(recv.LongHelp())
+		}
+		flagSet.Parse(args)
+
+		if _, exists := constants.AwsRegions[region]; !exists {
+			return false
+		}
+
+		if !strings.HasPrefix(baseAMI, "ami-") {
+			return false
+		}
+
+		if securityGroupId != "" && subnetId == "" {
+			return false
+		}
+
+		if subnetId != "" && !strings.HasPrefix(subnetId, "subnet-") {
+			subnetId = "subnet-" + subnetId
+		}
+
+		if securityGroupId != "" && !strings.HasPrefix(securityGroupId, "sg-") {
+			securityGroupId = "sg-" + securityGroupId
+		}
+
+		if name == "" {
+			name = fmt.Sprintf("porter-%s-%d", constants.Version, time.Now().Unix())
+		}
+
+		imageId, success := bakeAMI(region, baseAMI, name, instanceType, subnetId, securityGroupId)
+		if !success {
+			os.Exit(1)
+		}
+
+		fmt.Println(imageId)
+		return true
+	}
+	return false
+}
+
+func bakeAMI(region, baseAMI, name, instanceType, subnetId, securityGroupId string) (imageId string, success bool) {
+	log := logger.CLI("BaseAMI", baseAMI, "Name", name)
+
+	client := ec2.New(session.New(aws.NewConfig().WithRegion(region)))
+
+	userData, err := cfn_template.HostImageUserData(cfn_template.HostImageContext{
+		ContainerUserUid: constants.ContainerUserUid,
+		PorterBinaryUrl:  constants.BinaryUrl,
+		Packages:         cfn_template.HostPackages,
+		FailureMarker:    amiFailureMarker,
+		SuccessMarker:    amiSuccessMarker,
+	})
+	if err != nil {
+		log.Error("cfn_template.HostImageUserData", "Error", err)
+		return
+	}
+
+	runInstancesInput := &ec2.RunInstancesInput{
+		ImageId:      aws.String(baseAMI),
+		InstanceType: aws.String(instanceType),
+		MinCount:     aws.Int64(1),
+		MaxCount:     aws.Int64(1),
+		UserData:     aws.String(base64.StdEncoding.EncodeToString([]byte(userData))),
+
+		// the bake script shuts the instance down when it's done. It has to
+		// stop rather than terminate so an image can be made from it
+		InstanceInitiatedShutdownBehavior: aws.String(ec2.ShutdownBehaviorStop),
+	}
+
+	if subnetId != "" {
+		runInstancesInput.SubnetId = aws.String(subnetId)
+	}
+
+	if securityGroupId != "" {
+		runInstancesInput.SecurityGroupIds = []*string{aws.String(securityGroupId)}
+	}
+
+	log.Info("ec2:RunInstances")
+	reservation, err := client.RunInstances(runInstancesInput)
+	if err != nil {
+		log.Error("ec2:RunInstances", "Error", err)
+		return
+	}
+
+	instanceId := aws.StringValue(reservation.Instances[0].InstanceId)
+	log = log.New("InstanceId", instanceId)
+
+	defer terminateInstance(log, client, instanceId)
+
+	err = porterec2.NameResource(client, instanceId, "porter bootstrap ami "+name)
+	if err != nil {
+		log.Warn("Failed to name instance", "Error", err)
+	}
+
+	describeInstancesInput := &ec2.DescribeInstancesInput{
+		InstanceIds: []*string{aws.String(instanceId)},
+	}
+
+	log.Info("Waiting for the instance to run")
+	err = client.WaitUntilInstanceRunning(describeInstancesInput)
+	if err != nil {
+		log.Error("ec2:WaitUntilInstanceRunning", "Error", err)
+		return
+	}
+
+	log.Info("Waiting for the install steps to finish and the instance to stop")
+	err = client.WaitUntilInstanceStopped(describeInstancesInput)
+	if err != nil {
+		log.Error("ec2:WaitUntilInstanceStopped", "Error", err)
+		return
+	}
+
+	// console output lags behind the instance stopping so keep checking until
+	// one of the markers shows up
+	var (
+		output    string
+		finished  bool
+		succeeded bool
+	)
+	retryMsg := func(i int) { log.Info("Waiting for the instance's console output", "Count", i) }
+	util.SuccessRetryer(9, retryMsg, func() bool {
+		consoleOutput, err := client.GetConsoleOutput(&ec2.GetConsoleOutputInput{
+			InstanceId: aws.String(instanceId),
+		})
+		if err != nil {
+			log.Warn("ec2:GetConsoleOutput", "Error", err)
+			return false
+		}
+
+		if consoleOutput.Output == nil {
+			return false
+		}
+
+		outputBytes, err := base64.StdEncoding.DecodeString(*consoleOutput.Output)
+		if err != nil {
+			log.Warn("base64.DecodeString", "Error", err)
+			return false
+		}
+
+		output = string(outputBytes)
+		finished, succeeded = installResult(output)
+		return finished
+	})
+
+	if !finished {
+		log.Error("Never found whether the install steps succeeded in the instance's console output")
+		return
+	}
+
+	if !succeeded {
+		log.Error("An install step failed. The instance's console output follows")
+		fmt.Fprintln(os.Stderr, output)
+		return
+	}
+
+	log.Info("ec2:CreateImage")
+	createImageOutput, err := client.CreateImage(&ec2.CreateImageInput{
+		InstanceId:  aws.String(instanceId),
+		Name:        aws.String(name),
+		Description: aws.String("porter " + constants.Version + " prebaked from " + baseAMI),
+	})
+	if err != nil {
+		log.Error("ec2:CreateImage", "Error", err)
+		return
+	}
+
+	imageId = aws.StringValue(createImageOutput.ImageId)
+	log = log.New("ImageId", imageId)
+
+	_, err = client.CreateTags(&ec2.CreateTagsInput{
+		Resources: []*string{aws.String(imageId)},
+		Tags: []*ec2.Tag{
+			{
+				Key:   aws.String(constants.PorterVersionTag),
+				Value: aws.String(constants.Version),
+			},
+		},
+	})
+	if err != nil {
+		log.Warn("Failed to tag image", "Error", err)
+	}
+
+	log.Info("Waiting for the image to be available")
+	err = client.WaitUntilImageAvailable(&ec2.DescribeImagesInput{
+		ImageIds: []*string{aws.String(imageId)},
+	})
+	if err != nil {
+		log.Error("ec2:WaitUntilImageAvailable", "Error", err)
+		return
+	}
+
+	log.Info("Baked AMI")
+
+	success = true
+	return
+}
+
+// installResult reads the bake script's markers from an instance's console
+// output. The install isn't finished until one of them is printed
+func installResult(consoleOutput string) (finished bool, succeeded bool) {
+	if strings.Contains(consoleOutput, amiFailureMarker) {
+		return true, false
+	}
+
+	if strings.Contains(consoleOutput, amiSuccessMarker) {
+		return true, true
+	}
+
+	return false, false
+}
+
+func terminateInstance(log log15.Logger, client *ec2.EC2, instanceId string) {
+	log.Info("ec2:TerminateInstances")
+	_, err := client.TerminateInstances(&ec2.TerminateInstancesInput{
+		InstanceIds: []*string{aws.String(instanceId)},
+	})
+	if err != nil {
+		log.Error("ec2:TerminateInstances", "Error", err)
+	}
+}
@@ -0,0 +1,24 @@
+package bootstrap_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	"github.com/adobe-platform/porter/commands/bootstrap"
+)
+
+var _ = Describe("AMI", func() {
+
+	DescribeTable("installResult",
+		func(consoleOutput string, finished, succeeded bool) {
+			actualFinished, actualSucceeded := bootstrap.InstallResult(consoleOutput)
+			Expect(actualFinished).To(Equal(finished))
+			Expect(actualSucceeded).To(Equal(succeeded))
+		},
+		Entry("no console output yet", "", false, false),
+		Entry("install still running", "+ yum install -y haproxy-1.5.2\n", false, false),
+		Entry("success marker", "+ echo porter bootstrap ami succeeded\nporter bootstrap ami succeeded\n", true, true),
+		Entry("failure marker", "+ yum install -y haproxy-1.5.2\nporter bootstrap ami failed\n", true, false),
+	)
+})
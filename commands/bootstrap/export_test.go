@@ -0,0 +1,4 @@
+package bootstrap
+
+// exported for bootstrap_test
+var InstallResult = installResult
@@ -0,0 +1,49 @@
+/*
+ * (c) 2016-2018 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+package build
+
+import (
+	"time"
+
+	"github.com/adobe-platform/porter/conf"
+	"github.com/adobe-platform/porter/hook"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+// checkBlackoutWindow fails if a blackout window is active unless the caller
+// gave a reason to override it. Overrides are logged and passed to hooks.
+func checkBlackoutWindow(log log15.Logger, environment *conf.Environment, overrideReason string) (success bool) {
+
+	window, err := environment.ActiveBlackoutWindow(time.Now())
+	if window == nil {
+		success = true
+		return
+	}
+
+	log = log.New("Environment", environment.Name, "BlackoutWindow", window.String())
+
+	if overrideReason == "" {
+		if err != nil {
+			log.Error("Blackout window is invalid and considered active", "Error", err)
+		} else {
+			log.Error("Blackout window is active", "Reason", window.Reason)
+		}
+		log.Error(`Use --override-blackout "<reason>" to run anyway`)
+		return
+	}
+
+	log.Warn("Overriding blackout window", "Reason", window.Reason, "OverrideReason", overrideReason)
+	hook.SetBlackoutOverride(window.String(), overrideReason)
+
+	success = true
+	return
+}
@@ -0,0 +1,3 @@
+package build
+
+var Compensation = compensation
@@ -14,11 +14,11 @@ package build
 import (
 	"flag"
 	"fmt"
-	"os"
 
 	"github.com/adobe-platform/porter/constants"
 	"github.com/adobe-platform/porter/hook"
 	"github.com/adobe-platform/porter/logger"
+	"github.com/adobe-platform/porter/output"
 	"github.com/phylake/go-cli"
 )
 
@@ -84,10 +84,14 @@ func (recv *HookCmd) Execute(args []string) bool {
 			return true
 		}
 
+		output.Begin("hook")
+		output.SetEnvironment(environment)
+
 		if !hook.Execute(log, hookName, environment, nil, true) {
-			os.Exit(1)
+			output.Exit(1)
 		}
 
+		output.Finish(true)
 		return true
 	}
 
@@ -15,43 +15,61 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
-	"net/http"
-	"net/url"
+	"io/ioutil"
 	"os"
 
 	"github.com/adobe-platform/porter/conf"
+	"github.com/adobe-platform/porter/constants"
 	"github.com/adobe-platform/porter/logger"
+	"github.com/adobe-platform/porter/notify"
+	"github.com/adobe-platform/porter/provision_state"
 
 	"github.com/phylake/go-cli"
+	"gopkg.in/inconshreveable/log15.v2"
 )
 
-type (
-	NotifyCmd struct{}
-	SlackBody struct {
-		Text string `json:"text"`
-	}
-)
+type NotifyCmd struct{}
 
 func (recv *NotifyCmd) Name() string {
 	return "notify"
 }
 
 func (recv *NotifyCmd) ShortHelp() string {
-	return "CI Slack notifications"
+	return "CI notifications"
 }
 
 func (recv *NotifyCmd) LongHelp() string {
 	return `NAME:
-    notify -- CI Slack notifications
+    notify -- CI notifications
 
 SYNOPSIS
-    notify -go-ci -phase <pack | provision | promote> -success=<t|f>
+    notify -phase <pack | provision | promote | prune | rollback> -success=<t|f> [-e <environment>]
 
 DESCRIPTION
-    Post message to a configured incoming webhook.
-    https://api.slack.com/incoming-webhooks
+    Send a message to every sink in the notifications section of .porter/config
+    that's routed to the phase and outcome. Supported sinks are generic JSON
+    webhooks, Slack, Microsoft Teams, PagerDuty, and email.
+
+    The environment, regions, stack ids, and ELB DNS names are read from the
+    output of provision if it exists.
+
+    CI metadata is discovered from the environment variables set by Jenkins,
+    GitLab, GitHub Actions, and GO.
+
+    Failing to notify never fails this command.
 
-    Currently GO CI is the only supported CI system.`
+OPTIONS
+    -phase
+        The build phase that ran
+
+    -success
+        The outcome of the build phase
+
+    -e  Environment from .porter/config. Defaults to the provisioned
+        environment
+
+    -go-ci
+        Deprecated. CI systems are detected automatically`
 }
 
 func (recv *NotifyCmd) SubCommands() []cli.Command {
@@ -61,134 +79,70 @@ func (recv *NotifyCmd) SubCommands() []cli.Command {
 func (recv *NotifyCmd) Execute(args []string) bool {
 	log := logger.CLI("cmd", "notify")
 
-	if len(args) > 1 && args[0] == "-go-ci" {
-		var buildPhase, webhookURL string
-		var phaseSuccess bool
+	if len(args) > 0 {
+		var buildPhase, environment string
+		var phaseSuccess, goCI bool
 
 		flagSet := flag.NewFlagSet("", flag.ContinueOnError)
 		flagSet.StringVar(&buildPhase, "phase", "", "")
 		flagSet.BoolVar(&phaseSuccess, "success", false, "")
+		flagSet.StringVar(&environment, "e", "", "")
+		flagSet.BoolVar(&goCI, "go-ci", false, "")
 		flagSet.Usage = func() {
 			fmt.Println(recv.LongHelp())
 		}
-		err := flagSet.Parse(args[1:])
+		err := flagSet.Parse(args)
 		if err != nil {
 			log.Warn("flagSet.Parse", "Error", err)
 			return true
 		}
 
-		config, success := conf.GetAlteredConfig(log)
-		if !success {
-			return true
-		}
-
 		switch buildPhase {
-		case "pack":
-			if phaseSuccess {
-				webhookURL = config.Slack.PackSuccessHook
-			} else {
-				webhookURL = config.Slack.PackFailureHook
-			}
-		case "provision":
-			if phaseSuccess {
-				webhookURL = config.Slack.ProvisionSuccessHook
-			} else {
-				webhookURL = config.Slack.ProvisionFailureHook
-			}
-		case "promote":
-			if phaseSuccess {
-				webhookURL = config.Slack.PromoteSuccessHook
-			} else {
-				webhookURL = config.Slack.PromoteFailureHook
-			}
+		case "pack", "provision", "promote", "prune", "rollback":
 		default:
 			log.Warn("invalid -phase", "phase", buildPhase)
 			return true
 		}
 
-		if webhookURL == "" {
+		config, success := getNotifyConfig(log)
+		if !success {
 			return true
 		}
 
-		slackPost(webhookURL, goCIMessage(buildPhase, phaseSuccess))
-		return true
-	}
-
-	return false
-}
+		stack := &provision_state.Stack{}
 
-func slackPost(slackURL string, message string) {
+		stackBytes, err := ioutil.ReadFile(constants.ProvisionOutputPath)
+		if err == nil {
+			err = json.Unmarshal(stackBytes, stack)
+			if err != nil {
+				log.Warn("json.Unmarshal", "Path", constants.ProvisionOutputPath, "Error", err)
+				stack = &provision_state.Stack{}
+			}
+		}
 
-	log := logger.CLI()
-	messageBody := &SlackBody{
-		Text: message,
-	}
+		if environment != "" && environment != stack.Environment {
+			stack = &provision_state.Stack{
+				Environment: environment,
+			}
+		}
 
-	messageBodyBytes, err := json.Marshal(messageBody)
-	if err != nil {
-		log.Error("Slack message body json.Marshal failed", "Error", err)
-		return
-	}
+		ctx := notify.NewContext(log, config, buildPhase, phaseSuccess, stack)
 
-	resp, err := http.PostForm(slackURL, url.Values{"payload": {string(messageBodyBytes)}})
-	if err != nil {
-		log.Error("Post to slack failed", "Error", err)
-		return
-	}
-	if resp.StatusCode != 200 {
-		log.Error("Post to slack failed", "StatusCode", resp.StatusCode)
-		return
+		if !notify.Send(log, config, ctx) {
+			log.Warn("Some notifications failed")
+		}
+		return true
 	}
 
-	log.Info("Message posted to slack", "StatusCode", resp.StatusCode)
-
+	return false
 }
 
-func goCIMessage(buildPhase string, phaseSuccess bool) string {
-
-	pipelineUrl := fmt.Sprintf("%s/%s/%s",
-		os.Getenv("GO_NOTIFICATION_URL"),
-		"tab/pipeline/history",
-		os.Getenv("GO_PIPELINE_NAME"))
-
-	buildUrl := fmt.Sprintf("%s/%s/%s/%s", os.Getenv("GO_NOTIFICATION_URL"),
-		"pipelines/value_stream_map",
-		os.Getenv("GO_PIPELINE_NAME"),
-		os.Getenv("GO_PIPELINE_COUNTER"))
-
-	stageUrl := fmt.Sprintf("%s/%s/%s/%s/%s/%s", os.Getenv("GO_NOTIFICATION_URL"),
-		"pipelines",
-		os.Getenv("GO_PIPELINE_NAME"),
-		os.Getenv("GO_PIPELINE_COUNTER"),
-		os.Getenv("GO_STAGE_NAME"),
-		os.Getenv("GO_STAGE_COUNTER"))
-
-	jobUrl := fmt.Sprintf("%s/%s/%s/%s/%s/%s/%s%s",
-		os.Getenv("GO_NOTIFICATION_URL"),
-		"tab/build/detail",
-		os.Getenv("GO_PIPELINE_NAME"),
-		os.Getenv("GO_PIPELINE_COUNTER"),
-		os.Getenv("GO_STAGE_NAME"),
-		os.Getenv("GO_STAGE_COUNTER"),
-		os.Getenv("GO_JOB_NAME"),
-		"#tab-console")
-
-	msg := fmt.Sprintf("<%s|%s> >> <%s|%s> >> <%s|%s/%s> >> <%s|%s>",
-		pipelineUrl,
-		os.Getenv("GO_PIPELINE_NAME"),
-		buildUrl,
-		os.Getenv("GO_PIPELINE_COUNTER"),
-		stageUrl,
-		os.Getenv("GO_STAGE_NAME"),
-		os.Getenv("GO_STAGE_COUNTER"),
-		jobUrl,
-		os.Getenv("GO_JOB_NAME"))
-
-	if phaseSuccess {
-		msg = msg + " passed"
-	} else {
-		msg = "*FAILED:* " + msg
+// Notifications can be sent for phases that don't need a pack (e.g. rollback)
+func getNotifyConfig(log log15.Logger) (*conf.Config, bool) {
+
+	if _, err := os.Stat(constants.AlteredConfigPath); err == nil {
+		return conf.GetAlteredConfig(log)
 	}
 
-	return msg
+	return conf.GetConfig(log, true)
 }
@@ -18,6 +18,7 @@ import (
 	"github.com/adobe-platform/porter/constants"
 	"github.com/adobe-platform/porter/hook"
 	"github.com/adobe-platform/porter/logger"
+	"github.com/adobe-platform/porter/output"
 	"github.com/adobe-platform/porter/provision"
 
 	"github.com/phylake/go-cli"
@@ -43,10 +44,13 @@ func (recv *PackCmd) SubCommands() []cli.Command {
 
 func (recv *PackCmd) Execute(args []string) bool {
 
+	output.Begin("pack")
+
 	if !doPack() {
-		os.Exit(1)
+		output.Exit(1)
 	}
 
+	output.Finish(true)
 	return true
 }
 
@@ -81,6 +85,7 @@ func doPack() (success bool) {
 	}
 
 	log.Info("Packaged service", "FilePath", constants.PayloadPath)
+	output.SetServicePayload(constants.PayloadPath)
 
 	success = true
 	return
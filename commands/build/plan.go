@@ -0,0 +1,105 @@
+/*
+ * (c) 2016-2018 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+package build
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/adobe-platform/porter/conf"
+	"github.com/adobe-platform/porter/constants"
+	"github.com/adobe-platform/porter/logger"
+	"github.com/adobe-platform/porter/provision"
+)
+
+func PlanStack(env string) (success bool) {
+	log := logger.CLI("cmd", "provision", "plan", true)
+
+	config, getAlteredConfigSuccess := conf.GetAlteredConfig(log)
+	if !getAlteredConfigSuccess {
+		return
+	}
+
+	environment, err := config.GetEnvironment(env)
+	if err != nil {
+		log.Error("GetEnvironment", "Error", err)
+		return
+	}
+
+	plans, planSuccess := provision.Plan(log, config, environment)
+	if !planSuccess {
+		return
+	}
+
+	planBytes, err := json.Marshal(plans)
+	if err != nil {
+		log.Error("json.Marshal", "Error", err)
+		return
+	}
+
+	err = ioutil.WriteFile(constants.PlanOutputPath, planBytes, 0644)
+	if err != nil {
+		log.Error("Unable to write plan output", "Error", err)
+		return
+	}
+
+	for _, plan := range plans {
+		printRegionPlan(plan)
+	}
+
+	success = true
+	return
+}
+
+func printRegionPlan(plan provision.RegionPlan) {
+
+	fmt.Println()
+	if plan.StackId == "" {
+		fmt.Printf("Region %s (no promoted stack)\n", plan.Region)
+	} else {
+		fmt.Printf("Region %s (compared to %s)\n", plan.Region, plan.StackId)
+	}
+
+	if plan.Diff.Empty() {
+		fmt.Println("  No resource changes")
+		return
+	}
+
+	for _, change := range plan.Diff.Added {
+		fmt.Printf("  + %s (%s)\n", change.LogicalId, change.Type)
+	}
+
+	for _, change := range plan.Diff.Removed {
+		fmt.Printf("  - %s (%s)\n", change.LogicalId, change.Type)
+	}
+
+	for _, change := range plan.Diff.Modified {
+		fmt.Printf("  ~ %s (%s)\n", change.LogicalId, change.Type)
+		for _, propertyChange := range change.Changes {
+			fmt.Printf("      %s: %s => %s\n", propertyChange.Path,
+				planValue(propertyChange.Old), planValue(propertyChange.New))
+		}
+	}
+}
+
+func planValue(value interface{}) string {
+	if value == nil {
+		return "(none)"
+	}
+
+	valueBytes, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(valueBytes)
+}
@@ -15,12 +15,12 @@ import (
 	"encoding/json"
 	"flag"
 	"io/ioutil"
-	"os"
 
 	"github.com/adobe-platform/porter/conf"
 	"github.com/adobe-platform/porter/constants"
 	"github.com/adobe-platform/porter/hook"
 	"github.com/adobe-platform/porter/logger"
+	"github.com/adobe-platform/porter/output"
 	"github.com/adobe-platform/porter/promote"
 	"github.com/adobe-platform/porter/provision_state"
 	"github.com/phylake/go-cli"
@@ -75,30 +75,39 @@ func (recv *PromoteCmd) Execute(args []string) bool {
 	}
 
 	log := logger.CLI("cmd", "promote")
+	output.Begin("promote")
 
 	stackBytes, err := ioutil.ReadFile(provisionOutputPath)
 	if err != nil {
 		log.Error("Unable to read provision output file", "Error", err)
-		os.Exit(1)
+		output.Exit(1)
 	}
 
 	stack := &provision_state.Stack{}
 	err = json.Unmarshal(stackBytes, stack)
 	if err != nil {
 		log.Error("json unmarshal error on provision output", "Error", err)
-		os.Exit(1)
+		output.Exit(1)
+	}
+	output.SetStack(*stack)
+
+	if stack.Phase == provision_state.Phase_Provisioning {
+		log.Error("Provisioning didn't finish. Run porter build provision --resume first")
+		output.Exit(1)
 	}
 
 	if stack.Hotswap {
 		log.Info("No promotion occurs during a hot swap")
+		output.Finish(true)
 		return true
 	}
 
 	if !doPromote(log, stack, elbTag) {
-		os.Exit(1)
+		output.Exit(1)
 	}
 
 	log.Info("Promote complete")
+	output.Finish(true)
 	return true
 }
 
@@ -17,8 +17,12 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"sort"
+	"strings"
 	"time"
 
+	cfnapi "github.com/adobe-platform/porter/aws/cloudformation"
+	"github.com/adobe-platform/porter/aws/elbv2"
 	awsutil "github.com/adobe-platform/porter/aws/util"
 	"github.com/adobe-platform/porter/aws_session"
 	"github.com/adobe-platform/porter/cfn"
@@ -26,6 +30,7 @@ import (
 	"github.com/adobe-platform/porter/constants"
 	"github.com/adobe-platform/porter/hook"
 	"github.com/adobe-platform/porter/logger"
+	"github.com/adobe-platform/porter/output"
 	"github.com/adobe-platform/porter/provision"
 	"github.com/adobe-platform/porter/provision_state"
 	"github.com/adobe-platform/porter/util"
@@ -41,6 +46,9 @@ import (
 
 var sleepDuration = constants.StackCreationPollInterval()
 
+// the number of failed resources summarized when a stack fails to create
+const maxRootCauses = 3
+
 type (
 	ProvisionStackCmd struct{}
 
@@ -66,13 +74,50 @@ func (recv *ProvisionStackCmd) LongHelp() string {
     provision -- Provision a new stack
 
 SYNOPSIS
-    provision -e <environment out of .porter/config>
+    provision -e <environment out of .porter/config> [--plan] [--override-blackout <reason>]
+              [--allow-replacement] [--approval-file <path>] [--resume]
 
 DESCRIPTION
     Provision a new stack for a given environment.
 
     This command is similar to create-stack but it works with multiple regions
-    and should be run from a build box.`
+    and should be run from a build box.
+
+OPTIONS
+    -e  Environment from .porter/config
+
+    --plan
+        Create the CloudFormation template for every region without uploading
+        anything to S3 or calling CloudFormation. The resources of each
+        template are diffed against the currently promoted stack's template.
+
+        The diff is printed and written as JSON to ` + constants.PlanOutputPath + `.
+        Hooks are not run with the exception of ec2_bootstrap which is needed
+        to create the template.
+
+    --override-blackout
+        Provision even though a blackout window is active. The reason is
+        logged and passed to hooks as PORTER_BLACKOUT_OVERRIDE_REASON along
+        with PORTER_BLACKOUT_OVERRIDE=true and PORTER_BLACKOUT_WINDOW.
+
+    --allow-replacement
+        Hot swaps update stacks through CloudFormation change sets. The
+        summary of each change set is printed and a hot swap fails if any
+        resource would be replaced or removed. This flag allows it.
+
+    --approval-file
+        Pause after every region's change set is created and before any is
+        executed. The change sets are executed once the file is created or
+        touched and deleted if that doesn't happen within the stack creation
+        timeout.
+
+    --resume
+        Finish a provision that porter didn't, for example because the build
+        box died while stacks were being created. State is written to
+        ` + constants.ProvisionOutputPath + ` as stacks are created. This reloads
+        it, waits for the existing stacks, and then either writes the final
+        provision state or cleans up like a normal provision would. No new
+        stacks are created and the pre_provision hook isn't run.`
 }
 
 func (recv *ProvisionStackCmd) SubCommands() []cli.Command {
@@ -82,24 +127,52 @@ func (recv *ProvisionStackCmd) SubCommands() []cli.Command {
 func (recv *ProvisionStackCmd) Execute(args []string) bool {
 
 	if len(args) > 0 {
-		var environment string
+		var environment, overrideBlackout string
+		var plan, resume bool
+		var changeSetOpts provision.ChangeSetOptions
 		flagSet := flag.NewFlagSet("", flag.ExitOnError)
 		flagSet.StringVar(&environment, "e", "", "")
+		flagSet.BoolVar(&plan, "plan", false, "")
+		flagSet.StringVar(&overrideBlackout, "override-blackout", "", "")
+		flagSet.BoolVar(&changeSetOpts.AllowReplacement, "allow-replacement", false, "")
+		flagSet.StringVar(&changeSetOpts.ApprovalFile, "approval-file", "", "")
+		flagSet.BoolVar(&resume, "resume", false, "")
 		flagSet.Usage = func() {
 			fmt.Println(recv.LongHelp())
 		}
 		flagSet.Parse(args)
 
-		if !ProvisionOrHotswapStack(environment) {
-			os.Exit(1)
+		if plan {
+			if !PlanStack(environment) {
+				os.Exit(1)
+			}
+			return true
 		}
+
+		output.Begin("provision")
+		output.SetEnvironment(environment)
+
+		if resume {
+			if !ResumeProvisionStack(environment) {
+				output.Exit(1)
+			}
+
+			output.Finish(true)
+			return true
+		}
+
+		if !ProvisionOrHotswapStack(environment, overrideBlackout, changeSetOpts) {
+			output.Exit(1)
+		}
+
+		output.Finish(true)
 		return true
 	}
 
 	return false
 }
 
-func ProvisionOrHotswapStack(env string) (success bool) {
+func ProvisionOrHotswapStack(env, overrideBlackout string, changeSetOpts provision.ChangeSetOptions) (success bool) {
 	log := logger.CLI("cmd", "provision")
 
 	config, getAlteredConfigSuccess := conf.GetAlteredConfig(log)
@@ -113,9 +186,7 @@ func ProvisionOrHotswapStack(env string) (success bool) {
 		return
 	}
 
-	err = environment.IsWithinBlackoutWindow()
-	if err != nil {
-		log.Error("Blackout window is active", "Error", err, "Environment", environment.Name)
+	if !checkBlackoutWindow(log, environment, overrideBlackout) {
 		return
 	}
 
@@ -182,7 +253,7 @@ func ProvisionOrHotswapStack(env string) (success bool) {
 		}
 
 		if shouldHotswap {
-			success = HotswapStack(log, config, environment, hotswapStructs)
+			success = HotswapStack(log, config, environment, hotswapStructs, changeSetOpts)
 		} else {
 			success = ProvisionStack(log, config, environment)
 		}
@@ -206,7 +277,7 @@ func checkShouldHotswapRegion(log log15.Logger, config *conf.Config,
 
 	hotswapData.region = region.Name
 
-	if len(region.ELBs) > 1 {
+	if len(region.ELBs) > 1 || len(region.TargetGroups) > 1 {
 		success = true
 		return
 	}
@@ -301,6 +372,53 @@ func checkShouldHotswapRegion(log log15.Logger, config *conf.Config,
 			success = true
 			return
 		}
+	} else if region.HasTargetGroup() {
+
+		targetGroup := region.TargetGroups[0]
+
+		log = log.New("TargetGroupARN", targetGroup.ARN, "ListenerARN", targetGroup.ListenerARN)
+
+		elbv2Client := elbv2.New(roleSession)
+
+		var tags map[string]string
+
+		retryMsg := func(i int) { log.Warn("elbv2.GetLiveTags retrying", "Count", i) }
+		if !util.SuccessRetryer(3, retryMsg, func() bool {
+
+			log.Info("elbv2.GetLiveTags")
+			tags, err = elbv2.GetLiveTags(elbv2Client, targetGroup.ARN, targetGroup.ListenerARN)
+			if err != nil {
+				log.Error("elbv2.GetLiveTags", "Error", err)
+				return false
+			}
+
+			return true
+		}) {
+			log.Crit("Failed to elbv2.GetLiveTags")
+			return
+		}
+
+		promotedStackId, foundStackId := tags[constants.PorterStackIdTag]
+		porterVersion, foundPorterVersion := tags[constants.PorterVersionTag]
+
+		if !foundStackId || !foundPorterVersion {
+			log.Info("Target group missing required tags to determine hot swap eligibility")
+			hotswapData.shouldHotswap = false
+			success = true
+			return
+		}
+
+		if porterVersion != constants.Version {
+			log.Info("porter version mismatch will skip hot swap",
+				"porter_deployed_version", porterVersion)
+
+			hotswapData.shouldHotswap = false
+			success = true
+			return
+		}
+
+		log.Info("Found target group tag of currently promoted stack. Getting stack info")
+		stackId = aws.String(promotedStackId)
 	}
 
 	// stackId may be null in which case we're going to retrieve all stacks and
@@ -352,7 +470,7 @@ func checkShouldHotswapRegion(log log15.Logger, config *conf.Config,
 	// in the case we don't have an ELB then we need to get the stack's ASG
 	// which was tagged w/ porter version to do the last determination of
 	// hotswap eligibility
-	if !region.HasELB() {
+	if !region.HasELB() && !region.HasTargetGroup() {
 
 		describeStackResourcesInput := &cloudformation.DescribeStackResourcesInput{
 			StackName: stack.StackName,
@@ -454,7 +572,8 @@ func checkShouldHotswapRegion(log log15.Logger, config *conf.Config,
 }
 
 func HotswapStack(log log15.Logger, config *conf.Config,
-	environment *conf.Environment, hotswapStructs []hotswapStruct) (success bool) {
+	environment *conf.Environment, hotswapStructs []hotswapStruct,
+	changeSetOpts provision.ChangeSetOptions) (success bool) {
 
 	var stackName string
 	stackRegions := make(map[string]*provision_state.Region)
@@ -493,6 +612,8 @@ func HotswapStack(log log15.Logger, config *conf.Config,
 
 		log.Debug("defer post-hook execute")
 
+		output.SetStack(stack)
+
 		postHookSuccess := hook.Execute(log, constants.HookPostHotswap,
 			environment.Name, stack.Regions, success)
 
@@ -503,24 +624,24 @@ func HotswapStack(log log15.Logger, config *conf.Config,
 		return
 	}
 
-	if !provision.UpdateStack(log, config, stack) {
-		return
-	}
+	// regions whose change set was executed are polled even if another
+	// region's wasn't so the hot swap is seen through where it started
+	executedRegions, updateSuccess := provision.UpdateStack(log, config, stack, changeSetOpts)
 
 	successChan := make(chan bool)
 
-	for regionName, regionState := range stack.Regions {
+	for _, regionName := range executedRegions {
 
 		go func(environment *conf.Environment, regionName string, regionState *provision_state.Region) {
 
 			successChan <- hotswapStackPoll(log, environment, regionName, regionState)
 
-		}(environment, regionName, regionState)
+		}(environment, regionName, stack.Regions[regionName])
 	}
 
-	success = true
+	success = updateSuccess
 
-	for i := 0; i < len(environment.Regions); i++ {
+	for i := 0; i < len(executedRegions); i++ {
 		regionSuccess := <-successChan
 		success = success && regionSuccess
 	}
@@ -617,6 +738,20 @@ func hotswapStackPoll(log log15.Logger, environment *conf.Environment,
 
 	log.Info("All EC2 instances in this region reported hot swap success")
 
+	// hot swap only changes metadata so the stack update finishes before the
+	// instances do
+	retryMsg := func(i int) { log.Warn("cloudformation:DescribeStacks retrying", "Count", i) }
+	if !util.SuccessRetryer(3, retryMsg, func() bool {
+		regionState.Outputs, err = cfnapi.GetOutputs(cloudformation.New(roleSession), regionState.StackId)
+		if err != nil {
+			log.Error("cloudformation:DescribeStacks", "Error", err)
+			return false
+		}
+		return true
+	}) {
+		return
+	}
+
 	success = true
 	return
 }
@@ -672,75 +807,316 @@ func getQueueUrl(log log15.Logger, roleSession *session.Session,
 func ProvisionStack(log log15.Logger, config *conf.Config, environment *conf.Environment) (success bool) {
 
 	stack := &provision_state.Stack{
-		Environment: environment.Name,
+		Environment:   environment.Name,
+		FailedRegions: make(map[string]string),
 	}
 
 	defer func() {
 
 		log.Debug("defer post-hook execute")
 
+		output.SetStack(*stack)
+
 		postHookSuccess := hook.Execute(log, constants.HookPostProvision,
 			environment.Name, stack.Regions, success)
 
 		success = success && postHookSuccess
 	}()
 
+	// state is written as stacks are created so that provisioning can be
+	// resumed if porter dies before it's finished. This also replaces the
+	// state of a previous provision so it can't be mistaken for this one
+	stack.Phase = provision_state.Phase_Provisioning
+	checkpoint := func() {
+		writeProvisionOutput(log, *stack)
+	}
+	checkpoint()
+
 	if !hook.Execute(log, constants.HookPreProvision, environment.Name, nil, true) {
+		// nothing was created so there's nothing to resume
+		stack.Phase = ""
+		checkpoint()
 		return
 	}
 
-	if !provision.CreateStack(log, config, stack) {
+	// CreateStack fails if any region fails but the regions that succeeded
+	// have stacks that are being created
+	if !provision.CreateStack(log, config, stack, checkpoint) && len(stack.FailedRegions) == 0 {
 		return
 	}
 
-	successChan := make(chan bool)
+	success = finishProvisionStack(log, environment, stack)
+	return
+}
+
+// finishProvisionStack waits for the stacks to be created, cleans up after
+// failed regions, and writes the final provision state
+func finishProvisionStack(log log15.Logger, environment *conf.Environment, stack *provision_state.Stack) (success bool) {
+
+	type pollResult struct {
+		regionName string
+		success    bool
+	}
 
+	// buffered so polls that are no longer waited on don't block
+	resultChan := make(chan pollResult, len(stack.Regions))
+
+	pending := make(map[string]interface{})
 	for regionName, regionState := range stack.Regions {
+		if _, failed := stack.FailedRegions[regionName]; failed || regionState.StackId == "" {
+			continue
+		}
 
+		pending[regionName] = nil
 		go func(environment *conf.Environment, regionName string, regionState *provision_state.Region) {
 
-			successChan <- provisionStackPoll(log, environment, regionName, regionState)
+			resultChan <- pollResult{
+				regionName: regionName,
+				success:    provisionStackPoll(log, environment, regionName, regionState),
+			}
 
 		}(environment, regionName, regionState)
 	}
 
-	success = true
+	required := environment.RequiredRegions()
 
-	for i := 0; i < len(environment.Regions); i++ {
-		regionSuccess := <-successChan
-		success = success && regionSuccess
+	decided, keep, deleteRegions := compensation(stack, pending, required)
+	for !decided {
+		result := <-resultChan
+		delete(pending, result.regionName)
+
+		if !result.success {
+			stack.FailedRegions[result.regionName] = stackFailureReason(log, environment,
+				result.regionName, stack.Regions[result.regionName].StackId)
+		}
+
+		decided, keep, deleteRegions = compensation(stack, pending, required)
 	}
 
-	if success {
+	success = compensate(log, environment, stack, keep, deleteRegions)
+
+	stack.Phase = ""
 
+	if success {
 		success = writeProvisionOutput(log, *stack)
+	} else {
+		// record which regions failed and why
+		writeProvisionOutput(log, *stack)
+	}
+
+	return
+}
+
+// compensation decides what to do with a provision's stacks given the regions
+// that are still being created. Once too few regions can succeed to meet
+// min_successful_regions every stack is deleted without waiting on the pending
+// ones. Otherwise it's decided when nothing is pending and only the failed
+// regions are deleted.
+func compensation(stack *provision_state.Stack, pending map[string]interface{}, required int) (decided, keep bool, deleteRegions []string) {
+
+	succeeded := 0
+	for regionName, regionState := range stack.Regions {
+		_, failed := stack.FailedRegions[regionName]
+		_, isPending := pending[regionName]
+		if !failed && !isPending && regionState.StackId != "" {
+			succeeded++
+		}
+	}
+
+	possible := succeeded + len(pending)
+
+	deleteRegions = make([]string, 0)
+
+	if possible < required || possible == 0 {
+
+		for regionName := range stack.Regions {
+			deleteRegions = append(deleteRegions, regionName)
+		}
+		sort.Strings(deleteRegions)
+
+		decided = true
+		return
+	}
 
+	if len(pending) > 0 {
+		return
+	}
+
+	for regionName, regionState := range stack.Regions {
+		if _, failed := stack.FailedRegions[regionName]; failed || regionState.StackId == "" {
+			deleteRegions = append(deleteRegions, regionName)
+		}
+	}
+	sort.Strings(deleteRegions)
+
+	decided = true
+	keep = true
+	return
+}
+
+// compensate deletes the stacks of deleteRegions and removes them from the
+// stack's regions
+func compensate(log log15.Logger, environment *conf.Environment, stack *provision_state.Stack,
+	keep bool, deleteRegions []string) (success bool) {
+
+	if len(stack.FailedRegions) == 0 && len(deleteRegions) == 0 {
+		success = keep
+		return
+	}
+
+	provision.LogFailedRegions(log, stack.FailedRegions)
+
+	if keep {
+		log.Warn(fmt.Sprintf("%d of %d regions succeeded which meets min_successful_regions. Deleting the failed ones",
+			len(stack.Regions)-len(deleteRegions), len(environment.Regions)))
 	} else {
+		log.Error(fmt.Sprintf("%d of %d regions failed and %d must succeed. Deleting all of them",
+			len(stack.FailedRegions), len(environment.Regions), environment.RequiredRegions()))
+	}
 
-		if len(stack.Regions) > 0 {
-			log.Warn("Some regions failed to create. Deleting the successful ones")
+	for _, regionName := range deleteRegions {
+		if regionState := stack.Regions[regionName]; regionState.StackId != "" {
+			deleteStack(log, environment, regionName, regionState.StackId)
+		}
 
-			for regionName, regionState := range stack.Regions {
-				roleARN, err := environment.GetRoleARN(regionName)
-				if err != nil {
-					log.Error("GetRoleARN", "Error", err)
-					continue
-				}
+		delete(stack.Regions, regionName)
+	}
 
-				roleSession := aws_session.STS(regionName, roleARN, 0)
-				cfnClient := cloudformation.New(roleSession)
+	success = keep
+	return
+}
 
-				log.Info("cloudformation:DeleteStack", "StackId", regionState.StackId)
-				deleteStackInput := &cloudformation.DeleteStackInput{
-					StackName: aws.String(regionState.StackId),
-				}
+func deleteStack(log log15.Logger, environment *conf.Environment, regionName, stackId string) {
+	log = log.New("Region", regionName)
+
+	roleARN, err := environment.GetRoleARN(regionName)
+	if err != nil {
+		log.Error("GetRoleARN", "Error", err)
+		return
+	}
+
+	roleSession := aws_session.STS(regionName, roleARN, 0)
+	cfnClient := cloudformation.New(roleSession)
+
+	log.Info("cloudformation:DeleteStack", "StackId", stackId)
+	deleteStackInput := &cloudformation.DeleteStackInput{
+		StackName: aws.String(stackId),
+	}
+
+	_, err = cfnClient.DeleteStack(deleteStackInput)
+	if err != nil {
+		log.Error("cloudformation:DeleteStack", "StackId", stackId, "Error", err)
+	}
+}
+
+// stackFailureReason describes why a stack failed to create. The first
+// resources that failed are logged and summarized.
+func stackFailureReason(log log15.Logger, environment *conf.Environment, regionName, stackId string) string {
+	log = log.New("Region", regionName)
+
+	reason := "stack creation failed"
+
+	roleARN, err := environment.GetRoleARN(regionName)
+	if err != nil {
+		return reason
+	}
 
-				cfnClient.DeleteStack(deleteStackInput)
+	roleSession := aws_session.STS(regionName, roleARN, 0)
+	cfnClient := cloudformation.New(roleSession)
+
+	stackEventState := cfnapi.NewStackEventState(cfnClient, stackId)
+	if _, err = stackEventState.NewStackEvents(); err != nil {
+		log.Warn("cloudformation:DescribeStackEvents", "Error", err)
+	}
+
+	failedResources := cfnapi.FailedResources(stackEventState.StackEvents())
+	if len(failedResources) == 0 {
+		return stackStatusReason(log, cfnClient, stackId, reason)
+	}
+
+	causes := make([]string, 0)
+	for i, stackEvent := range failedResources {
+		if i == maxRootCauses {
+			break
+		}
+
+		logicalId := aws.StringValue(stackEvent.LogicalResourceId)
+		resourceType := aws.StringValue(stackEvent.ResourceType)
+		status := aws.StringValue(stackEvent.ResourceStatus)
+		statusReason := aws.StringValue(stackEvent.ResourceStatusReason)
+
+		cause := fmt.Sprintf("%s (%s) %s: %s", logicalId, resourceType, status, statusReason)
+		ctx := []interface{}{
+			"LogicalId", logicalId,
+			"Type", resourceType,
+			"Status", status,
+			"Reason", statusReason,
+		}
+
+		if resourceType == cfn.CloudFormation_WaitCondition {
+			if received, expected, ok := cfnapi.WaitConditionSignals(statusReason); ok {
+				cause += fmt.Sprintf(". %d of %d instances signaled", received, expected)
+				ctx = append(ctx, "Signaled", received, "Expected", expected)
+			} else if instanceId, ok := cfnapi.WaitConditionFailure(statusReason); ok {
+				cause += ". Instance " + instanceId + " signaled failure"
+				ctx = append(ctx, "InstanceId", instanceId)
 			}
 		}
+
+		log.Error("Root cause", ctx...)
+		causes = append(causes, cause)
 	}
 
-	return
+	return strings.Join(causes, "; ")
+}
+
+// stackStatusReason is the stack's status and the reason for it
+func stackStatusReason(log log15.Logger, cfnClient *cloudformation.CloudFormation, stackId, reason string) string {
+
+	describeStacksOutput, err := cfnClient.DescribeStacks(&cloudformation.DescribeStacksInput{
+		StackName: aws.String(stackId),
+	})
+	if err != nil || len(describeStacksOutput.Stacks) != 1 {
+		log.Warn("cloudformation:DescribeStacks", "Error", err)
+		return reason
+	}
+
+	cfnStack := describeStacksOutput.Stacks[0]
+	reason = aws.StringValue(cfnStack.StackStatus)
+	if cfnStack.StackStatusReason != nil {
+		reason += ": " + *cfnStack.StackStatusReason
+	}
+
+	return reason
+}
+
+// logStackEvents logs the stack's events that haven't been logged yet
+func logStackEvents(log log15.Logger, stackEventState *cfnapi.StackEventState) {
+
+	stackEvents, err := stackEventState.NewStackEvents()
+	if err != nil {
+		log.Warn("cloudformation:DescribeStackEvents", "Error", err)
+		return
+	}
+
+	for _, stackEvent := range stackEvents {
+		status := aws.StringValue(stackEvent.ResourceStatus)
+
+		ctx := []interface{}{
+			"LogicalId", aws.StringValue(stackEvent.LogicalResourceId),
+			"Type", aws.StringValue(stackEvent.ResourceType),
+			"Status", status,
+		}
+		if stackEvent.ResourceStatusReason != nil {
+			ctx = append(ctx, "Reason", *stackEvent.ResourceStatusReason)
+		}
+
+		if strings.HasSuffix(status, "_FAILED") {
+			log.Error("Stack event", ctx...)
+		} else {
+			log.Info("Stack event", ctx...)
+		}
+	}
 }
 
 func provisionStackPoll(log log15.Logger, environment *conf.Environment,
@@ -769,11 +1145,15 @@ func provisionStackPoll(log log15.Logger, environment *conf.Environment,
 	roleSession := aws_session.STS(region.Name, roleARN, constants.StackCreationTimeout())
 	cfnClient := cloudformation.New(roleSession)
 
+	stackEventState := cfnapi.NewStackEventState(cfnClient, regionState.StackId)
+
 	n := int(constants.StackCreationTimeout().Seconds() / sleepDuration.Seconds())
 
 stackEventPoll:
 	for i := 0; i < n; i++ {
 
+		logStackEvents(log, stackEventState)
+
 		describeStacksInput := &cloudformation.DescribeStacksInput{
 			StackName: aws.String(regionState.StackId),
 		}
@@ -793,11 +1173,12 @@ stackEventPoll:
 		switch *describeStackOutput.Stacks[0].StackStatus {
 		case cfn.CREATE_COMPLETE:
 			stackProvisioned = true
+			regionState.Outputs = cfnapi.Outputs(describeStackOutput.Stacks[0])
 			break stackEventPoll
-		case cfn.CREATE_FAILED:
+		case cfn.CREATE_FAILED, cfn.ROLLBACK_COMPLETE, cfn.ROLLBACK_FAILED:
 			log.Error("Stack creation failed")
 			return
-		case cfn.DELETE_IN_PROGRESS:
+		case cfn.DELETE_IN_PROGRESS, cfn.DELETE_COMPLETE:
 			log.Error("Stack is being deleted")
 			return
 		case cfn.ROLLBACK_IN_PROGRESS:
@@ -814,6 +1195,12 @@ stackEventPoll:
 	}
 
 	cfnTemplateBytes, err := ioutil.ReadFile(constants.CloudFormationTemplatePath)
+	if os.IsNotExist(err) {
+		// provisioning was resumed somewhere the template wasn't written
+		var templateBody string
+		templateBody, err = cfnapi.GetTemplate(cfnClient, regionState.StackId)
+		cfnTemplateBytes = []byte(templateBody)
+	}
 	if err != nil {
 		log.Error("CloudFormationTemplate read file error", "Error", err)
 		return
@@ -829,9 +1216,19 @@ stackEventPoll:
 
 	cfnTemplate.ParseResources()
 
-	if region.PrimaryTopology() == conf.Topology_Inet && region.HasELB() {
+	// the provisioned ELB or target group is the source of a promotion
+	var loadBalancingType string
+	if region.PrimaryTopology() == conf.Topology_Inet {
+		if region.HasELB() {
+			loadBalancingType = cfn.ElasticLoadBalancing_LoadBalancer
+		} else if region.HasTargetGroup() {
+			loadBalancingType = cfn.ElasticLoadBalancingV2_TargetGroup
+		}
+	}
+
+	if loadBalancingType != "" {
 
-		elbLogicalId, err = cfnTemplate.GetResourceName(cfn.ElasticLoadBalancing_LoadBalancer)
+		elbLogicalId, err = cfnTemplate.GetResourceName(loadBalancingType)
 		if err != nil {
 			log.Error("GetResourceName", "Error", err)
 			return
@@ -857,8 +1254,26 @@ stackEventPoll:
 	}
 
 	if describeStackResourceOutput != nil {
-		regionState.ProvisionedELBName =
-			*describeStackResourceOutput.StackResourceDetail.PhysicalResourceId
+		physicalId := *describeStackResourceOutput.StackResourceDetail.PhysicalResourceId
+
+		if loadBalancingType == cfn.ElasticLoadBalancingV2_TargetGroup {
+			regionState.ProvisionedTargetGroupARN = physicalId
+		} else {
+			regionState.ProvisionedELBName = physicalId
+
+			// the DNS name is only needed for --output json
+			if output.JSON() {
+				describeLoadBalancersOutput, err := elb.New(roleSession).DescribeLoadBalancers(&elb.DescribeLoadBalancersInput{
+					LoadBalancerNames: []*string{aws.String(physicalId)},
+				})
+				if err != nil {
+					log.Warn("elb:DescribeLoadBalancers", "Error", err)
+				} else if len(describeLoadBalancersOutput.LoadBalancerDescriptions) == 1 {
+					output.AddLoadBalancer(regionName, output.LoadBalancer_Provisioned, physicalId,
+						aws.StringValue(describeLoadBalancersOutput.LoadBalancerDescriptions[0].DNSName))
+				}
+			}
+		}
 	}
 
 	success = true
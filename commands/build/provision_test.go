@@ -0,0 +1,86 @@
+package build_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/adobe-platform/porter/commands/build"
+	"github.com/adobe-platform/porter/provision_state"
+)
+
+var _ = Describe("Compensation", func() {
+
+	var stack *provision_state.Stack
+
+	BeforeEach(func() {
+		stack = &provision_state.Stack{
+			Regions: map[string]*provision_state.Region{
+				"us-east-1": {StackId: "stack-use1"},
+				"us-west-2": {StackId: "stack-usw2"},
+				"eu-west-1": {StackId: "stack-euw1"},
+			},
+			FailedRegions: make(map[string]string),
+		}
+	})
+
+	pending := func(regionNames ...string) map[string]interface{} {
+		set := make(map[string]interface{})
+		for _, regionName := range regionNames {
+			set[regionName] = nil
+		}
+		return set
+	}
+
+	It("keeps every region when they all succeed", func() {
+		decided, keep, deleteRegions := build.Compensation(stack, pending(), 3)
+		Expect(decided).To(BeTrue())
+		Expect(keep).To(BeTrue())
+		Expect(deleteRegions).To(BeEmpty())
+	})
+
+	It("waits while the required regions can still succeed", func() {
+		stack.FailedRegions["eu-west-1"] = "CREATE_FAILED"
+
+		decided, _, _ := build.Compensation(stack, pending("us-east-1", "us-west-2"), 2)
+		Expect(decided).To(BeFalse())
+	})
+
+	It("deletes the failed regions once enough regions succeed", func() {
+		stack.FailedRegions["eu-west-1"] = "CREATE_FAILED"
+
+		decided, keep, deleteRegions := build.Compensation(stack, pending(), 2)
+		Expect(decided).To(BeTrue())
+		Expect(keep).To(BeTrue())
+		Expect(deleteRegions).To(Equal([]string{"eu-west-1"}))
+	})
+
+	It("deletes every region without waiting once too few can succeed", func() {
+		stack.Regions["eu-west-1"].StackId = ""
+		stack.FailedRegions["eu-west-1"] = "failed to upload the service payload"
+
+		decided, keep, deleteRegions := build.Compensation(stack, pending("us-east-1", "us-west-2"), 3)
+		Expect(decided).To(BeTrue())
+		Expect(keep).To(BeFalse())
+		Expect(deleteRegions).To(Equal([]string{"eu-west-1", "us-east-1", "us-west-2"}))
+	})
+
+	It("counts a region that's missing from the stack against min_successful_regions", func() {
+		delete(stack.Regions, "eu-west-1")
+		stack.FailedRegions["eu-west-1"] = "stack was never created"
+
+		decided, keep, _ := build.Compensation(stack, pending("us-east-1"), 3)
+		Expect(decided).To(BeTrue())
+		Expect(keep).To(BeFalse())
+	})
+
+	It("deletes every region when none succeed", func() {
+		for regionName := range stack.Regions {
+			stack.FailedRegions[regionName] = "CREATE_FAILED"
+		}
+
+		decided, keep, deleteRegions := build.Compensation(stack, pending(), 0)
+		Expect(decided).To(BeTrue())
+		Expect(keep).To(BeFalse())
+		Expect(deleteRegions).To(HaveLen(3))
+	})
+})
@@ -15,12 +15,12 @@ import (
 	"encoding/json"
 	"flag"
 	"io/ioutil"
-	"os"
 
 	"github.com/adobe-platform/porter/conf"
 	"github.com/adobe-platform/porter/constants"
 	"github.com/adobe-platform/porter/hook"
 	"github.com/adobe-platform/porter/logger"
+	"github.com/adobe-platform/porter/output"
 	"github.com/adobe-platform/porter/provision_state"
 	"github.com/adobe-platform/porter/prune"
 	"github.com/phylake/go-cli"
@@ -42,11 +42,12 @@ func (recv *PruneCmd) LongHelp() string {
     prune -- Delete extra CloudFormation stacks
 
 SYNOPSIS
-    prune [--keep <stacks to keep>]
+    prune [--keep <stacks to keep>] [--override-blackout <reason>]
 
 DESCRIPTION
     Delete extra CloudFormation stacks. Instances attached to the configured
-    ELB are not candidates for deletion.
+    ELB are not candidates for deletion. Neither is the stack that was promoted
+    before the current one since it's the target of rollback.
 
 OPTIONS
     --keep
@@ -58,7 +59,12 @@ OPTIONS
         configured ELB will be kept.
 
         Eligible stacks will be sorted by creation time with the oldest being
-        deleted first.`
+        deleted first.
+
+    --override-blackout
+        Prune even though a blackout window is active. The reason is logged
+        and passed to hooks as PORTER_BLACKOUT_OVERRIDE_REASON along with
+        PORTER_BLACKOUT_OVERRIDE=true and PORTER_BLACKOUT_WINDOW.`
 }
 
 func (recv *PruneCmd) SubCommands() []cli.Command {
@@ -71,7 +77,7 @@ func (recv *PruneCmd) Execute(args []string) bool {
 		return false
 	}
 
-	var elbTag string
+	var elbTag, overrideBlackout string
 	var keepCount int
 
 	if len(args) > 0 {
@@ -80,6 +86,7 @@ func (recv *PruneCmd) Execute(args []string) bool {
 
 		flagSet.IntVar(&keepCount, "keep", 0, "")
 		flagSet.StringVar(&elbTag, "elb", "", "")
+		flagSet.StringVar(&overrideBlackout, "override-blackout", "", "")
 		flagSet.Parse(args)
 
 		if keepCount < 0 {
@@ -88,29 +95,33 @@ func (recv *PruneCmd) Execute(args []string) bool {
 	}
 
 	log := logger.CLI("cmd", "prune")
+	output.Begin("prune")
 
 	provisionEnvBytes, err := ioutil.ReadFile(constants.ProvisionOutputPath)
 	if err != nil {
 		log.Error("ioutil.ReadFile", "Error", err)
-		os.Exit(1)
+		output.Exit(1)
 	}
 
 	stack := &provision_state.Stack{}
 	err = json.Unmarshal(provisionEnvBytes, stack)
 	if err != nil {
 		log.Error("json.Unmarshal", "Error", err)
-		os.Exit(1)
+		output.Exit(1)
 	}
+	output.SetEnvironment(stack.Environment)
 
-	if !doPrune(log, stack, keepCount, elbTag) {
-		os.Exit(1)
+	if !doPrune(log, stack, keepCount, elbTag, overrideBlackout) {
+		output.Exit(1)
 	}
 
 	log.Info("Prune complete")
+	output.Finish(true)
 	return true
 }
 
-func doPrune(log log15.Logger, stack *provision_state.Stack, keepCount int, elbTag string) (success bool) {
+func doPrune(log log15.Logger, stack *provision_state.Stack, keepCount int,
+	elbTag, overrideBlackout string) (success bool) {
 
 	defer func() {
 
@@ -131,6 +142,10 @@ func doPrune(log log15.Logger, stack *provision_state.Stack, keepCount int, elbT
 		return
 	}
 
+	if !checkBlackoutWindow(log, env, overrideBlackout) {
+		return
+	}
+
 	if !hook.Execute(log, constants.HookPrePrune, stack.Environment, stack.Regions, true) {
 		return
 	}
@@ -0,0 +1,156 @@
+/*
+ * (c) 2016-2018 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+package build
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/adobe-platform/porter/aws_session"
+	"github.com/adobe-platform/porter/conf"
+	"github.com/adobe-platform/porter/constants"
+	"github.com/adobe-platform/porter/hook"
+	"github.com/adobe-platform/porter/logger"
+	"github.com/adobe-platform/porter/output"
+	"github.com/adobe-platform/porter/provision_state"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+// ResumeProvisionStack reattaches to the stacks of a provision that didn't
+// finish and waits for them instead of creating new ones
+func ResumeProvisionStack(env string) (success bool) {
+	log := logger.CLI("cmd", "provision")
+
+	config, getAlteredConfigSuccess := conf.GetAlteredConfig(log)
+	if !getAlteredConfigSuccess {
+		return
+	}
+
+	environment, err := config.GetEnvironment(env)
+	if err != nil {
+		log.Error("GetEnvironment", "Error", err)
+		return
+	}
+
+	stackBytes, err := ioutil.ReadFile(constants.ProvisionOutputPath)
+	if err != nil {
+		log.Error("Unable to read provision state", "Path", constants.ProvisionOutputPath, "Error", err)
+		return
+	}
+
+	stack := &provision_state.Stack{}
+	err = json.Unmarshal(stackBytes, stack)
+	if err != nil {
+		log.Error("json.Unmarshal", "Path", constants.ProvisionOutputPath, "Error", err)
+		return
+	}
+
+	if stack.Environment != environment.Name {
+		log.Error("Provision state is for a different environment",
+			"Environment", environment.Name, "StateEnvironment", stack.Environment)
+		return
+	}
+
+	if stack.Phase != provision_state.Phase_Provisioning {
+		log.Info("Provisioning already finished. Nothing to resume", "StackName", stack.Name)
+		output.SetStack(*stack)
+		success = len(stack.Regions) > 0
+		if !success {
+			log.Error("The finished provisioning failed")
+		}
+		return
+	}
+
+	log.Info("Resuming provisioning", "StackName", stack.Name)
+
+	if stack.Regions == nil {
+		stack.Regions = make(map[string]*provision_state.Region)
+	}
+
+	if stack.FailedRegions == nil {
+		stack.FailedRegions = make(map[string]string)
+	}
+
+	defer func() {
+
+		log.Debug("defer post-hook execute")
+
+		output.SetStack(*stack)
+
+		postHookSuccess := hook.Execute(log, constants.HookPostProvision,
+			environment.Name, stack.Regions, success)
+
+		success = success && postHookSuccess
+	}()
+
+	for _, region := range environment.Regions {
+		if _, failed := stack.FailedRegions[region.Name]; failed {
+			continue
+		}
+
+		regionState, exists := stack.Regions[region.Name]
+		if exists && regionState.StackId != "" {
+			continue
+		}
+
+		// porter may have died after CreateStack but before the stack id
+		// was written
+		stackId := findStack(log, environment, region.Name, stack.Name)
+		if stackId == "" {
+			stack.FailedRegions[region.Name] = "stack was never created"
+			continue
+		}
+
+		stack.Regions[region.Name] = &provision_state.Region{
+			StackId: stackId,
+		}
+	}
+
+	success = finishProvisionStack(log, environment, stack)
+	return
+}
+
+// findStack returns the id of the stack with the given name or "" if it
+// doesn't exist
+func findStack(log log15.Logger, environment *conf.Environment, regionName, stackName string) string {
+	log = log.New("Region", regionName)
+
+	if stackName == "" {
+		return ""
+	}
+
+	roleARN, err := environment.GetRoleARN(regionName)
+	if err != nil {
+		log.Error("GetRoleARN", "Error", err)
+		return ""
+	}
+
+	roleSession := aws_session.STS(regionName, roleARN, 0)
+	cfnClient := cloudformation.New(roleSession)
+
+	log.Info("cloudformation:DescribeStacks", "StackName", stackName)
+	describeStacksOutput, err := cfnClient.DescribeStacks(&cloudformation.DescribeStacksInput{
+		StackName: aws.String(stackName),
+	})
+	if err != nil {
+		log.Warn("cloudformation:DescribeStacks", "StackName", stackName, "Error", err)
+		return ""
+	}
+
+	if len(describeStacksOutput.Stacks) != 1 {
+		return ""
+	}
+
+	return aws.StringValue(describeStacksOutput.Stacks[0].StackId)
+}
@@ -0,0 +1,102 @@
+/*
+ * (c) 2016-2018 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+package build
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/adobe-platform/porter/conf"
+	"github.com/adobe-platform/porter/logger"
+	"github.com/adobe-platform/porter/output"
+	"github.com/adobe-platform/porter/promote"
+	"github.com/phylake/go-cli"
+)
+
+type RollbackCmd struct{}
+
+func (recv *RollbackCmd) Name() string {
+	return "rollback"
+}
+
+func (recv *RollbackCmd) ShortHelp() string {
+	return "Promote the previously promoted stack"
+}
+
+func (recv *RollbackCmd) LongHelp() string {
+	return `NAME
+    rollback -- Promote the previously promoted stack
+
+SYNOPSIS
+    rollback -e <environment> [-elb <elb tag>]
+
+DESCRIPTION
+    Every promotion records the stack it replaced in a tag on the destination
+    ELB. rollback finds that stack in every region, verifies it still exists in
+    a complete state, waits for its instances to be InService in its own ELB,
+    and then promotes it exactly like promote would.
+
+    prune never deletes the stack recorded for rollback.
+
+    Only one previous stack is recorded so running rollback twice returns to
+    the stack that was rolled back from.
+
+OPTIONS
+    -e  Environment from .porter/config
+
+    -elb
+        The tag of the destination ELB if more than one is configured`
+}
+
+func (recv *RollbackCmd) SubCommands() []cli.Command {
+	return nil
+}
+
+func (recv *RollbackCmd) Execute(args []string) bool {
+	if len(args) > 0 {
+
+		var environmentName, elbTag string
+		flagSet := flag.NewFlagSet("", flag.ExitOnError)
+		flagSet.StringVar(&environmentName, "e", "", "")
+		flagSet.StringVar(&elbTag, "elb", "", "")
+		flagSet.Usage = func() {
+			fmt.Println(recv.LongHelp())
+		}
+		flagSet.Parse(args)
+
+		log := logger.CLI("cmd", "rollback")
+
+		output.Begin("rollback")
+		output.SetEnvironment(environmentName)
+
+		config, success := conf.GetConfig(log, true)
+		if !success {
+			output.Exit(1)
+		}
+
+		environment, err := config.GetEnvironment(environmentName)
+		if err != nil {
+			log.Error("GetEnvironment", "Error", err)
+			output.Exit(1)
+		}
+
+		if !promote.Rollback(log, config, environment, elbTag) {
+			output.Exit(1)
+		}
+
+		log.Info("Rollback complete")
+		output.Finish(true)
+		return true
+	}
+
+	return false
+}
@@ -18,6 +18,7 @@ import (
 
 	"github.com/adobe-platform/porter/commands/bootstrap"
 	"github.com/adobe-platform/porter/commands/build"
+	"github.com/adobe-platform/porter/commands/config"
 	"github.com/adobe-platform/porter/commands/dev"
 	"github.com/adobe-platform/porter/commands/help"
 	"github.com/adobe-platform/porter/commands/host"
@@ -45,11 +46,31 @@ error-prone clicking around the AWS console.`,
 					&bootstrap.IamCmd{},
 					&bootstrap.ElbCmd{},
 					&bootstrap.S3Cmd{},
+					&bootstrap.AmiCmd{},
+				},
+			},
+			&cmd.Default{
+				NameStr:      "config",
+				ShortHelpStr: "Inspect and migrate .porter/config",
+				LongHelpStr:  `Commands to validate, inspect, and migrate .porter/config.`,
+				SubCommandList: []cli.Command{
+					&config.ValidateCmd{},
+					&config.ShowCmd{},
+					&config.SchemaCmd{},
+					&config.MigrateCmd{},
 				},
 			},
 			// &dev.UpdateCLICmd{},
 			&dev.CreateStackCmd{},
 			&dev.SyncStackCmd{},
+			&cmd.Default{
+				NameStr:      "dev",
+				ShortHelpStr: "Developer box commands",
+				LongHelpStr:  `Commands that run on a developer box without AWS.`,
+				SubCommandList: []cli.Command{
+					&dev.RunCmd{},
+				},
+			},
 			&cmd.Default{
 				NameStr:      "host",
 				ShortHelpStr: "EC2 host commands",
@@ -77,6 +98,7 @@ These commands log to STDOUT and primarily run from files/cloud-init.yaml`,
 					&build.PackCmd{},
 					&build.ProvisionStackCmd{},
 					&build.PromoteCmd{},
+					&build.RollbackCmd{},
 					&build.PruneCmd{},
 					&build.HookCmd{},
 					// &build.HotSwapCmd{},
@@ -0,0 +1,176 @@
+/*
+ * (c) 2016-2018 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+package config
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/adobe-platform/porter/conf"
+	"github.com/adobe-platform/porter/constants"
+	"github.com/adobe-platform/porter/logger"
+	"github.com/phylake/go-cli"
+)
+
+type MigrateCmd struct{}
+
+func (recv *MigrateCmd) Name() string {
+	return "migrate"
+}
+
+func (recv *MigrateCmd) ShortHelp() string {
+	return "Migrate .porter/config to a newer porter version"
+}
+
+func (recv *MigrateCmd) LongHelp() string {
+	return `NAME
+    migrate -- Migrate .porter/config to a newer porter version
+
+SYNOPSIS
+    migrate [-to <version>] [-check] [-hook-env <name>[,<name>]]
+
+DESCRIPTION
+    Apply the migration steps between porter_version and -to to .porter/config
+    and .porter/config.d/, then set porter_version. Comments, ordering, and
+    formatting are preserved.
+
+    The migration steps are
+        v5.4.0  elb is moved into an elbs list
+        v5.4.0  variables hooks receive through the deprecated PORTER_
+                passthrough are added to each hook's environment
+
+    Hooks receive every PORTER_ prefixed environment variable with the prefix
+    removed. Name the variables your hooks depend on with -hook-env. Only
+    those are migrated. -hook-env FOO is added to a hook as
+
+        environment:
+          FOO:
+
+    An empty value is read from the environment when the hook runs so export
+    FOO where your builds run in place of PORTER_FOO
+
+OPTIONS
+    -to
+        The porter version to migrate to. The default is this porter's version
+
+    -check
+        Make no changes. Print what would change and exit with status 1 if the
+        config is behind -to. Use this in CI
+
+    -hook-env
+        Comma-separated names of PORTER_ prefixed variables, without the
+        prefix, that hooks depend on`
+}
+
+func (recv *MigrateCmd) SubCommands() []cli.Command {
+	return nil
+}
+
+func (recv *MigrateCmd) Execute(args []string) bool {
+
+	if len(args) == 1 && args[0] == "--help" {
+		return false
+	}
+
+	var (
+		to      string
+		check   bool
+		hookEnv string
+	)
+	flagSet := flag.NewFlagSet("", flag.ExitOnError)
+	flagSet.StringVar(&to, "to", constants.Version, "")
+	flagSet.BoolVar(&check, "check", false, "")
+	flagSet.StringVar(&hookEnv, "hook-env", "", "")
+	flagSet.Usage = func() {
+		fmt.Println(recv.LongHelp())
+	}
+	flagSet.Parse(args)
+
+	log := logger.CLI("cmd", "config-migrate")
+
+	migration := conf.Migration{
+		To:      to,
+		HookEnv: hookEnvNames(hookEnv),
+	}
+
+	files, err := conf.MigrateConfig(constants.ConfigPath, migration)
+	if err != nil {
+		log.Error("Config migration", "Error", err)
+		os.Exit(1)
+	}
+
+	var changeCount int
+	for _, file := range files {
+		for _, change := range file.Changes {
+			changeCount++
+			if check {
+				log.Warn("Needs migration", "Change", change)
+			} else {
+				log.Info("Migrated", "Change", change)
+			}
+		}
+	}
+
+	if check {
+		if changeCount > 0 {
+			log.Error("Config is behind. Run porter config migrate", "To", to, "ChangeCount", changeCount)
+			os.Exit(1)
+		}
+
+		log.Info("Config is up to date", "To", to)
+		return true
+	}
+
+	for _, file := range files {
+		if len(file.Changes) == 0 {
+			continue
+		}
+
+		fileInfo, err := os.Stat(file.Path)
+		if err != nil {
+			log.Error("os.Stat", "Path", file.Path, "Error", err)
+			os.Exit(1)
+		}
+
+		err = ioutil.WriteFile(file.Path, file.Migrated, fileInfo.Mode())
+		if err != nil {
+			log.Error("ioutil.WriteFile", "Path", file.Path, "Error", err)
+			os.Exit(1)
+		}
+	}
+
+	log.Info("Config migrated", "To", to, "ChangeCount", changeCount)
+	return true
+}
+
+// hookEnvNames returns the names in -hook-env
+func hookEnvNames(hookEnv string) []string {
+	seen := make(map[string]bool)
+	names := make([]string, 0)
+
+	add := func(name string) {
+		name = strings.TrimPrefix(strings.TrimSpace(name), "PORTER_")
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	for _, name := range strings.Split(hookEnv, ",") {
+		add(name)
+	}
+
+	return names
+}
@@ -0,0 +1,70 @@
+/*
+ * (c) 2016-2018 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/adobe-platform/porter/conf"
+	"github.com/adobe-platform/porter/logger"
+	"github.com/phylake/go-cli"
+)
+
+type SchemaCmd struct{}
+
+func (recv *SchemaCmd) Name() string {
+	return "schema"
+}
+
+func (recv *SchemaCmd) ShortHelp() string {
+	return "Print a JSON Schema for .porter/config"
+}
+
+func (recv *SchemaCmd) LongHelp() string {
+	return `NAME
+    schema -- Print a JSON Schema for .porter/config
+
+SYNOPSIS
+    schema
+
+DESCRIPTION
+    Print a JSON Schema (draft-07) generated from the config porter reads.
+    Editors with YAML language support can use it for autocompletion and CI
+    can use it to lint .porter/config and .porter/config.d/ fragments.
+
+    The schema describes structure and types only. Use 'porter config validate'
+    for porter's validation rules.`
+}
+
+func (recv *SchemaCmd) SubCommands() []cli.Command {
+	return nil
+}
+
+func (recv *SchemaCmd) Execute(args []string) bool {
+
+	if len(args) == 1 && args[0] == "--help" {
+		return false
+	}
+
+	log := logger.CLI("cmd", "config-schema")
+
+	schemaBytes, err := json.MarshalIndent(conf.Schema(), "", "  ")
+	if err != nil {
+		log.Error("json.MarshalIndent", "Error", err)
+		os.Exit(1)
+	}
+
+	fmt.Println(string(schemaBytes))
+	return true
+}
@@ -0,0 +1,151 @@
+/*
+ * (c) 2016-2018 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+package config
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/adobe-platform/porter/conf"
+	"github.com/adobe-platform/porter/logger"
+	"github.com/phylake/go-cli"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	format_YAML = "yaml"
+	format_JSON = "json"
+)
+
+type ShowCmd struct{}
+
+func (recv *ShowCmd) Name() string {
+	return "show"
+}
+
+func (recv *ShowCmd) ShortHelp() string {
+	return "Print the effective config of an environment"
+}
+
+func (recv *ShowCmd) LongHelp() string {
+	return `NAME
+    show -- Print the effective config of an environment
+
+SYNOPSIS
+    show -e <environment> [-r <region>] [-format yaml|json]
+
+DESCRIPTION
+    Print the config of an environment after .porter/config.d/ fragments,
+    extends, and vars are resolved and defaults are applied. This is the
+    config porter acts on.
+
+    The output is itself a valid .porter/config with a single environment.
+
+OPTIONS
+    -e  Environment from .porter/config
+
+    -r  Only include this region
+
+    -format
+        yaml or json. The default is yaml`
+}
+
+func (recv *ShowCmd) SubCommands() []cli.Command {
+	return nil
+}
+
+func (recv *ShowCmd) Execute(args []string) bool {
+	if len(args) > 0 {
+
+		var environmentName, regionName, format string
+		flagSet := flag.NewFlagSet("", flag.ExitOnError)
+		flagSet.StringVar(&environmentName, "e", "", "")
+		flagSet.StringVar(&regionName, "r", "", "")
+		flagSet.StringVar(&format, "format", format_YAML, "")
+		flagSet.Usage = func() {
+			fmt.Println(recv.LongHelp())
+		}
+		flagSet.Parse(args)
+
+		if format != format_YAML && format != format_JSON {
+			return false
+		}
+
+		log := logger.CLI("cmd", "config-show")
+
+		config, success := conf.GetConfig(log, false)
+		if !success {
+			os.Exit(1)
+		}
+
+		environment, err := config.GetEnvironment(environmentName)
+		if err != nil {
+			log.Error("GetEnvironment", "Error", err)
+			os.Exit(1)
+		}
+
+		if regionName != "" {
+			region, err := environment.GetRegion(regionName)
+			if err != nil {
+				log.Error("GetRegion", "Error", err)
+				os.Exit(1)
+			}
+
+			environmentCopy := *environment
+			environmentCopy.Regions = []*conf.Region{region}
+			environment = &environmentCopy
+		}
+
+		configCopy := *config
+		configCopy.Environments = []*conf.Environment{environment}
+		doc := conf.Document(configCopy)
+
+		var docBytes []byte
+		if format == format_JSON {
+			docBytes, err = json.MarshalIndent(jsonValue(doc), "", "  ")
+		} else {
+			docBytes, err = yaml.Marshal(doc)
+		}
+		if err != nil {
+			log.Error("Marshal", "Error", err)
+			os.Exit(1)
+		}
+
+		fmt.Println(string(docBytes))
+		return true
+	}
+
+	return false
+}
+
+// jsonValue converts YAML documents to values encoding/json can marshal
+func jsonValue(value interface{}) interface{} {
+	switch typedValue := value.(type) {
+	case yaml.MapSlice:
+		object := make(map[string]interface{})
+		for _, item := range typedValue {
+			object[fmt.Sprint(item.Key)] = jsonValue(item.Value)
+		}
+		return object
+
+	case []interface{}:
+		array := make([]interface{}, 0, len(typedValue))
+		for _, item := range typedValue {
+			array = append(array, jsonValue(item))
+		}
+		return array
+	}
+
+	return value
+}
@@ -0,0 +1,76 @@
+/*
+ * (c) 2016-2018 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+package config
+
+import (
+	"os"
+
+	"github.com/adobe-platform/porter/conf"
+	"github.com/adobe-platform/porter/logger"
+	"github.com/phylake/go-cli"
+)
+
+type ValidateCmd struct{}
+
+func (recv *ValidateCmd) Name() string {
+	return "validate"
+}
+
+func (recv *ValidateCmd) ShortHelp() string {
+	return "Validate .porter/config"
+}
+
+func (recv *ValidateCmd) LongHelp() string {
+	return `NAME
+    validate -- Validate .porter/config
+
+SYNOPSIS
+    validate
+
+DESCRIPTION
+    Compose .porter/config with .porter/config.d/, apply defaults, and run the
+    same validation the build commands do. Unlike the build commands every
+    error is reported rather than just the first.
+
+    Exits with status 1 if the config is invalid.`
+}
+
+func (recv *ValidateCmd) SubCommands() []cli.Command {
+	return nil
+}
+
+func (recv *ValidateCmd) Execute(args []string) bool {
+
+	if len(args) == 1 && args[0] == "--help" {
+		return false
+	}
+
+	log := logger.CLI("cmd", "config-validate")
+
+	config, success := conf.GetConfig(log, false)
+	if !success {
+		os.Exit(1)
+	}
+
+	errs := config.ValidateAll()
+	for _, err := range errs {
+		log.Error("Config validation", "Error", err)
+	}
+
+	if len(errs) > 0 {
+		log.Error("Config is invalid", "ErrorCount", len(errs))
+		os.Exit(1)
+	}
+
+	log.Info("Config is valid")
+	return true
+}
@@ -159,6 +159,12 @@ func CreateStack(environmentStr string, keepCount int, block bool) {
 		os.Exit(1)
 	}
 
+	err = environment.IsWithinBlackoutWindow()
+	if err != nil {
+		log.Error("Blackout window is active", "Error", err, "Environment", environment.Name)
+		os.Exit(1)
+	}
+
 	if !prune.Do(log, config, environment, keepCount, false, "") {
 		os.Exit(1)
 	}
@@ -182,7 +188,7 @@ func CreateStack(environmentStr string, keepCount int, block bool) {
 		Environment: environmentStr,
 	}
 
-	if !provision.CreateStack(log, config, stack) {
+	if !provision.CreateStack(log, config, stack, nil) {
 		log.Error("Create stack failed")
 		os.Exit(1)
 	}
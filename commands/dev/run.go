@@ -0,0 +1,462 @@
+/*
+ * (c) 2016-2018 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+package dev
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/adobe-platform/porter/commands/host"
+	"github.com/adobe-platform/porter/conf"
+	"github.com/adobe-platform/porter/constants"
+	"github.com/adobe-platform/porter/logger"
+	"github.com/adobe-platform/porter/provision"
+	"github.com/phylake/go-cli"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+// Containers started by `porter dev run` are labeled with this and the
+// service name so they can be found and removed by the next run
+const devRunLabel = "porter-dev-run"
+
+type RunCmd struct{}
+
+func (recv *RunCmd) Name() string {
+	return "run"
+}
+
+func (recv *RunCmd) ShortHelp() string {
+	return "Run a region's containers locally like an EC2 host"
+}
+
+func (recv *RunCmd) LongHelp() string {
+	return `NAME
+    run -- Run a region's containers locally like an EC2 host
+
+SYNOPSIS
+    run -e <environment> -r <region> [-block=f]
+
+DESCRIPTION
+    run reproduces what happens on an EC2 host with the service payload created
+    by 'porter build pack' without using AWS.
+
+    1. Load the docker images in .porter-tmp/payload.tar.gz
+    2. Start the region's containers with the same docker run arguments, secrets,
+       pids limit, read-only, and uid settings as an EC2 host
+    3. Wait for each inet container to pass its health check the way porterd
+       does before it signals the WaitCondition
+    4. Render .porter-tmp/dev-run/haproxy.cfg for the containers
+
+    These differ from an EC2 host
+
+    - Containers use the json-file log driver so 'docker logs' works
+    - src_env_file secrets come from exec_name. s3_bucket and s3_key are skipped
+    - There's no ec2-bootstrap hook so the --env-file is empty
+    - HAProxy isn't started. If haproxy is on the PATH the rendered config is
+      checked with 'haproxy -c'. Host-only settings like the haproxy user may
+      fail the check
+
+    Containers from a previous run of the same service are removed first.
+
+OPTIONS
+    -e  Environment from .porter/config
+
+    -r  AWS region
+
+    -block
+        Wait for Ctrl+C and then remove the containers.
+        The default is t. Set -block=f to leave them running`
+}
+
+func (recv *RunCmd) SubCommands() []cli.Command {
+	return nil
+}
+
+func (recv *RunCmd) Execute(args []string) bool {
+	if len(args) > 0 {
+
+		var (
+			environment string
+			region      string
+			block       bool
+		)
+
+		flagSet := flag.NewFlagSet("", flag.ContinueOnError)
+		flagSet.StringVar(&environment, "e", "", "")
+		flagSet.StringVar(&region, "r", "", "")
+		flagSet.BoolVar(&block, "block", true, "")
+		flagSet.Usage = func() {
+			fmt.Println(recv.LongHelp())
+		}
+		flagSet.Parse(args)
+
+		if environment == "" || region == "" {
+			return false
+		}
+
+		Run(environment, region, block)
+		return true
+	}
+	return false
+}
+
+func Run(environmentStr, regionStr string, block bool) {
+
+	log := logger.CLI("cmd", "dev-run")
+
+	_, err := os.Stat(constants.PayloadPath)
+	if err != nil {
+		log.Error("Service payload not found. Run porter build pack first",
+			"ServicePayloadPath", constants.PayloadPath, "Error", err)
+		os.Exit(1)
+	}
+
+	exec.Command("rm", "-rf", constants.DevRunDir).Run()
+	exec.Command("mkdir", "-p", constants.DevRunDir).Run()
+
+	// read the config out of the service payload the same way porter_hotswap
+	// does
+	err = exec.Command("tar", "-xz", "-C", constants.DevRunDir,
+		"-f", constants.PayloadPath, "./"+constants.ServicePayloadConfigPath).Run()
+	if err != nil {
+		log.Error("tar", "Error", err)
+		os.Exit(1)
+	}
+
+	os.Setenv(constants.EnvConfigPath, filepath.Join(constants.DevRunDir, constants.ServicePayloadConfigPath))
+
+	config, success := conf.GetHostConfig(log)
+	if !success {
+		os.Exit(1)
+	}
+
+	environment, err := config.GetEnvironment(environmentStr)
+	if err != nil {
+		log.Error("GetEnvironment", "Error", err)
+		os.Exit(1)
+	}
+
+	region, err := environment.GetRegion(regionStr)
+	if err != nil {
+		log.Error("GetRegion", "Error", err)
+		os.Exit(1)
+	}
+
+	secretsPayload, success := provision.LocalSecrets(log, environment, region)
+	if !success {
+		os.Exit(1)
+	}
+
+	if !loadImages(log, region) {
+		os.Exit(1)
+	}
+
+	if !removeContainers(log, config.ServiceName) {
+		os.Exit(1)
+	}
+
+	envFile, err := filepath.Abs(filepath.Join(constants.DevRunDir, "dockerfile.env"))
+	if err != nil {
+		log.Error("filepath.Abs", "Error", err)
+		os.Exit(1)
+	}
+
+	err = ioutil.WriteFile(envFile, []byte{}, 0444)
+	if err != nil {
+		log.Error("WriteFile", "Path", envFile, "Error", err)
+		os.Exit(1)
+	}
+
+	dockerIPv4, success := bridgeGateway(log)
+	if !success {
+		os.Exit(1)
+	}
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt)
+	go func() {
+		<-sigChan
+		log.Warn("Received SIGINT. Removing containers")
+		removeContainers(log, config.ServiceName)
+
+		// http://tldp.org/LDP/abs/html/exitcodes.html
+		os.Exit(130)
+	}()
+
+	runCtx := host.RunContext{
+		DockerIPv4: dockerIPv4,
+		EnvFile:    envFile,
+		LogDriver:  "json-file",
+		Labels: map[string]string{
+			devRunLabel: config.ServiceName,
+		},
+		Secrets: secretsPayload,
+
+		// docker logs only works with json-file and journald
+		ForceLogDriver: true,
+	}
+
+	log.Info("starting docker containers")
+
+	hapStdin, success := host.StartContainers(log, environment, region, runCtx)
+	if !success {
+		removeContainers(log, config.ServiceName)
+		os.Exit(1)
+	}
+
+	if !healthGate(log, hapStdin) {
+		removeContainers(log, config.ServiceName)
+		os.Exit(1)
+	}
+
+	if !writeHAProxyConfig(log, config, environment, region, hapStdin, secretsPayload.PemFile) {
+		removeContainers(log, config.ServiceName)
+		os.Exit(1)
+	}
+
+	for _, container := range hapStdin.Containers {
+		log.Info("inet container is healthy",
+			"ContainerId", container.Id,
+			"URL", fmt.Sprintf("http://127.0.0.1:%d", container.HostPort))
+	}
+
+	if !block {
+		return
+	}
+
+	log.Info("Press Ctrl+C to stop and remove the containers")
+	select {}
+}
+
+// loadImages loads the images in the service payload. Images pushed to a
+// registry aren't in the payload and are pulled by docker run
+func loadImages(log log15.Logger, region *conf.Region) (success bool) {
+
+	tarList, err := exec.Command("tar", "-tzf", constants.PayloadPath).Output()
+	if err != nil {
+		log.Error("tar", "Error", err)
+		return
+	}
+
+	payloadFiles := make(map[string]interface{})
+	for _, payloadFile := range strings.Split(string(tarList), "\n") {
+		payloadFiles[strings.TrimSpace(payloadFile)] = nil
+	}
+
+	loaded := make(map[string]interface{})
+
+	for _, container := range region.Containers {
+
+		if _, exists := loaded[container.Name]; exists {
+			continue
+		}
+		loaded[container.Name] = nil
+
+		imagePath := "./" + container.Name + ".docker"
+
+		if _, exists := payloadFiles[imagePath]; !exists {
+			log.Info("Image isn't in the service payload", "ImageName", container.Name)
+			continue
+		}
+
+		log.Info("docker load", "ImageName", container.Name)
+
+		tarCmd := exec.Command("tar", "-xzOf", constants.PayloadPath, imagePath)
+		loadCmd := exec.Command("docker", "load")
+		loadCmd.Stdin, err = tarCmd.StdoutPipe()
+		if err != nil {
+			log.Error("StdoutPipe", "Error", err)
+			return
+		}
+		loadCmd.Stderr = os.Stderr
+
+		err = loadCmd.Start()
+		if err != nil {
+			log.Error("docker load", "Error", err)
+			return
+		}
+
+		err = tarCmd.Run()
+		if err != nil {
+			log.Error("tar", "Error", err)
+			return
+		}
+
+		err = loadCmd.Wait()
+		if err != nil {
+			log.Error("docker load", "Error", err)
+			return
+		}
+	}
+
+	success = true
+	return
+}
+
+func removeContainers(log log15.Logger, serviceName string) (success bool) {
+
+	psOutput, err := exec.Command("docker", "ps", "-aq",
+		"--filter", "label="+devRunLabel+"="+serviceName).Output()
+	if err != nil {
+		log.Error("docker ps", "Error", err)
+		return
+	}
+
+	containerIds := strings.Fields(string(psOutput))
+	if len(containerIds) == 0 {
+		success = true
+		return
+	}
+
+	log.Info("removing containers", "ContainerIds", containerIds)
+
+	for _, containerId := range host.StopOrder(log, containerIds) {
+		err = exec.Command("docker", "rm", "-f", containerId).Run()
+		if err != nil {
+			log.Error("docker rm", "ContainerId", containerId, "Error", err)
+			return
+		}
+	}
+
+	success = true
+	return
+}
+
+// bridgeGateway is the local equivalent of the docker0 interface's address
+// which rsyslog and porterd listen on
+func bridgeGateway(log log15.Logger) (gateway string, success bool) {
+
+	inspectFilter := "{{ range .IPAM.Config }}{{ .Gateway }}{{ end }}"
+	inspectOutput, err := exec.Command("docker", "network", "inspect", "-f", inspectFilter, "bridge").Output()
+	if err != nil {
+		log.Error("docker network inspect", "Error", err)
+		return
+	}
+
+	gateway = strings.TrimSpace(string(inspectOutput))
+	if gateway == "" {
+		log.Error("docker bridge network has no gateway")
+		return
+	}
+
+	success = true
+	return
+}
+
+// healthGate is the local equivalent of porterd polling the health check
+// before it signals the WaitCondition. There's no HAProxy so each inet
+// container is polled on its published port
+func healthGate(log log15.Logger, hapStdin host.HAPStdin) (success bool) {
+
+	for _, container := range hapStdin.Containers {
+
+		log := log.New("ContainerId", container.Id)
+		healthCheck := container.HealthCheck
+		addr := fmt.Sprintf("127.0.0.1:%d", container.HostPort)
+
+		healthCheckClient := &http.Client{
+			Timeout: time.Duration(healthCheck.Timeout) * time.Second,
+		}
+
+		sleepDuration := 2 * time.Second
+		consecutiveHealth := 0
+		deadline := time.Now().Add(constants.StackCreationTimeout())
+
+		for consecutiveHealth < healthCheck.HealthyThreshold {
+
+			if time.Now().After(deadline) {
+				log.Error("health threshold wasn't met in " + constants.StackCreationTimeout().String())
+				return
+			}
+
+			time.Sleep(sleepDuration)
+
+			err := healthCheck.Probe(healthCheckClient, addr)
+			if err == nil {
+
+				consecutiveHealth++
+				sleepDuration = time.Duration(healthCheck.Interval) * time.Second
+
+				log.Info(fmt.Sprintf("successful health check %d/%d",
+					consecutiveHealth, healthCheck.HealthyThreshold))
+			} else {
+
+				consecutiveHealth = 0
+				sleepDuration = 2 * time.Second
+				log.Warn(healthCheck.String(), "Error", err)
+			}
+		}
+	}
+
+	success = true
+	return
+}
+
+func writeHAProxyConfig(log log15.Logger, config *conf.Config, environment *conf.Environment,
+	region *conf.Region, hapStdin host.HAPStdin, pemFile []byte) (success bool) {
+
+	if len(pemFile) > 0 {
+
+		certPath, err := filepath.Abs(filepath.Join(constants.DevRunDir, "porter.pem"))
+		if err != nil {
+			log.Error("filepath.Abs", "Error", err)
+			return
+		}
+
+		err = ioutil.WriteFile(certPath, pemFile, 0400)
+		if err != nil {
+			log.Error("WriteFile", "Path", certPath, "Error", err)
+			return
+		}
+
+		environment.HAProxy.SSL.CertPath = certPath
+	}
+
+	configBytes, success := host.RenderHAProxyConfig(log, config, environment, region, hapStdin)
+	if !success {
+		return
+	}
+	success = false
+
+	configPath := filepath.Join(constants.DevRunDir, "haproxy.cfg")
+
+	err := ioutil.WriteFile(configPath, configBytes, constants.HAProxyConfigPerms)
+	if err != nil {
+		log.Error("WriteFile", "Path", configPath, "Error", err)
+		return
+	}
+
+	log.Info("Rendered HAProxy config", "Path", configPath)
+
+	if _, err = exec.LookPath("haproxy"); err != nil {
+		log.Info("haproxy isn't on the PATH. Skipping config check")
+	} else {
+
+		checkOutput, err := exec.Command("haproxy", "-c", "-f", configPath).CombinedOutput()
+		if err != nil {
+			log.Warn("haproxy -c", "Error", err, "Output", string(checkOutput))
+		} else {
+			log.Info("haproxy -c", "Output", strings.TrimSpace(string(checkOutput)))
+		}
+	}
+
+	success = true
+	return
+}
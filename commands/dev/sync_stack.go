@@ -119,7 +119,10 @@ func UpdateStack(environmentStr string) {
 		os.Exit(1)
 	}
 
-	if success := provision.UpdateStack(log, config, stack); !success {
+	// dev stacks are disposable so replacing resources is fine
+	changeSetOpts := provision.ChangeSetOptions{AllowReplacement: true}
+
+	if _, success := provision.UpdateStack(log, config, stack, changeSetOpts); !success {
 		log.Error("Update stack failed")
 		os.Exit(1)
 	}
@@ -13,6 +13,8 @@ package host
 
 import (
 	"bytes"
+	"encoding/base64"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io/ioutil"
@@ -23,6 +25,7 @@ import (
 	"text/template"
 	"time"
 
+	"github.com/adobe-platform/porter/conf"
 	"github.com/adobe-platform/porter/constants"
 	"github.com/adobe-platform/porter/daemon"
 	"github.com/adobe-platform/porter/daemon/flags"
@@ -45,8 +48,8 @@ func (recv *DaemonCmd) LongHelp() string {
     daemon -- Install porterd
 
 SYNOPSIS
-    daemon --init -e <environment> -sn <service name> -hm <health check method> -hp <health check port>
-    daemon --run -e <environment> -sn <service name> -hm <health check method> -hp <health check port>
+    daemon --init -e <environment> -sn <service name> -hc <health check>
+    daemon --run -e <environment> -sn <service name> -hc <health check>
 
 DESCRIPTION
     daemon is a host-level HTTP service
@@ -61,7 +64,15 @@ OPTIONS
 		Write out the init script so porterd can be managed by PID 1
 
 	--run
-		In the init script, run the daemon`
+		In the init script, run the daemon
+
+	-hc
+		The base64 encoded JSON health check of the default HAProxy backend.
+		Empty if there are no inet containers
+
+	-hm, -hp
+		Deprecated. The health check method and path written by older
+		versions of porter. Used as an HTTP health check when -hc is empty`
 }
 
 func (recv *DaemonCmd) SubCommands() []cli.Command {
@@ -77,29 +88,48 @@ func (recv *DaemonCmd) Execute(args []string) bool {
 			var (
 				environment       string
 				serviceName       string
+				healthCheck       string
 				healthCheckMethod string
 				healthCheckPath   string
 				elbs              string
+				targetGroups      string
 			)
 
 			flagSet := flag.NewFlagSet("", flag.ExitOnError)
 			flagSet.StringVar(&environment, "e", "", "")
 			flagSet.StringVar(&serviceName, "sn", "", "")
+			flagSet.StringVar(&healthCheck, "hc", "", "")
 			flagSet.StringVar(&healthCheckMethod, "hm", "", "")
 			flagSet.StringVar(&healthCheckPath, "hp", "", "")
 			flagSet.StringVar(&elbs, "elbs", "", "")
+			flagSet.StringVar(&targetGroups, "tgs", "", "")
 			flagSet.Usage = func() {
 				fmt.Println(recv.LongHelp())
 			}
 			flagSet.Parse(args[1:])
 
+			if healthCheck == "" {
+				legacy := legacyHealthCheck(healthCheckMethod, healthCheckPath)
+				if legacy != nil {
+					log := logger.Host("cmd", "daemon")
+					log.Warn("-hm and -hp are deprecated. Use -hc")
+
+					healthCheckBytes, err := json.Marshal(legacy)
+					if err != nil {
+						log.Error("json.Marshal", "Error", err)
+						return false
+					}
+					healthCheck = base64.StdEncoding.EncodeToString(healthCheckBytes)
+				}
+			}
+
 			context := initConfigContext{
-				AwsStackId:        os.Getenv("AWS_STACKID"),
-				Environment:       environment,
-				ServiceName:       serviceName,
-				HealthCheckMethod: strconv.Quote(healthCheckMethod),
-				HealthCheckPath:   strconv.Quote(healthCheckPath),
-				Elbs:              elbs,
+				AwsStackId:   os.Getenv("AWS_STACKID"),
+				Environment:  environment,
+				ServiceName:  serviceName,
+				HealthCheck:  strconv.Quote(healthCheck),
+				Elbs:         elbs,
+				TargetGroups: targetGroups,
 			}
 
 			installDaemon(context)
@@ -107,11 +137,18 @@ func (recv *DaemonCmd) Execute(args []string) bool {
 
 		case "--run":
 
+			var (
+				healthCheck       string
+				healthCheckMethod string
+				healthCheckPath   string
+			)
+
 			flagSet := flag.NewFlagSet("", flag.ContinueOnError)
 			flagSet.StringVar(&flags.Environment, "e", "", "")
 			flagSet.StringVar(&flags.ServiceName, "sn", "", "")
-			flagSet.StringVar(&flags.HealthCheckMethod, "hm", "", "")
-			flagSet.StringVar(&flags.HealthCheckPath, "hp", "", "")
+			flagSet.StringVar(&healthCheck, "hc", "", "")
+			flagSet.StringVar(&healthCheckMethod, "hm", "", "")
+			flagSet.StringVar(&healthCheckPath, "hp", "", "")
 			flagSet.Parse(args[1:])
 
 			if flags.Environment == "" ||
@@ -119,6 +156,27 @@ func (recv *DaemonCmd) Execute(args []string) bool {
 				return false
 			}
 
+			if healthCheck != "" {
+				log := logger.Host("cmd", "daemon")
+
+				healthCheckBytes, err := base64.StdEncoding.DecodeString(healthCheck)
+				if err != nil {
+					log.Error("base64.DecodeString", "Error", err)
+					return false
+				}
+
+				flags.HealthCheck = &conf.HealthCheck{}
+				err = json.Unmarshal(healthCheckBytes, flags.HealthCheck)
+				if err != nil {
+					log.Error("json.Unmarshal", "Error", err)
+					return false
+				}
+			} else {
+
+				// an init script written before -hc
+				flags.HealthCheck = legacyHealthCheck(healthCheckMethod, healthCheckPath)
+			}
+
 			daemon.Run()
 			return true
 		}
@@ -127,13 +185,32 @@ func (recv *DaemonCmd) Execute(args []string) bool {
 	return false
 }
 
+// legacyHealthCheck is the HTTP health check described by the deprecated -hm
+// and -hp flags. It's nil if they weren't passed
+func legacyHealthCheck(method, path string) *conf.HealthCheck {
+	if method == "" || path == "" {
+		return nil
+	}
+
+	return &conf.HealthCheck{
+		Type:               conf.HealthCheck_HTTP,
+		Method:             method,
+		Path:               path,
+		ExpectedStatus:     "200",
+		Interval:           constants.HC_Interval,
+		Timeout:            constants.HC_Timeout,
+		HealthyThreshold:   constants.HC_HealthyThreshold,
+		UnhealthyThreshold: constants.HC_UnhealthyThreshold,
+	}
+}
+
 type initConfigContext struct {
-	Environment       string
-	ServiceName       string
-	HealthCheckMethod string
-	HealthCheckPath   string
-	Elbs              string
-	AwsStackId        string
+	Environment  string
+	ServiceName  string
+	HealthCheck  string
+	Elbs         string
+	TargetGroups string
+	AwsStackId   string
 }
 
 const porterdInitConfigTemplate = `description "porterd"
@@ -143,9 +220,10 @@ start on (local-filesystems and net-device-up)
 stop on runlevel [!2345]
 
 env ELBS={{ .Elbs }}
+env TARGET_GROUPS={{ .TargetGroups }}
 env AWS_STACKID={{ .AwsStackId }}
 respawn
-exec /usr/bin/porter host daemon --run -e {{ .Environment }} -sn {{ .ServiceName }} -hm {{ .HealthCheckMethod }} -hp {{ .HealthCheckPath }}
+exec /usr/bin/porter host daemon --run -e {{ .Environment }} -sn {{ .ServiceName }} -hc {{ .HealthCheck }}
 `
 
 func installDaemon(context initConfigContext) {
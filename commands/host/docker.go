@@ -16,9 +16,11 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"math"
 	"net"
 	"os"
 	"os/exec"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -32,6 +34,9 @@ import (
 	"gopkg.in/inconshreveable/log15.v2"
 )
 
+// the position of a container in its region's start order
+const startOrderLabel = "porter-start-order"
+
 // This implementation is tightly coupled with HAProxyCmd and how these commands
 // are called together
 type DockerCmd struct{}
@@ -130,12 +135,23 @@ func (recv *DockerCmd) Execute(args []string) bool {
 	return false
 }
 
+// RunContext is what differs between running a region's containers on an EC2
+// host and on a developer box with `porter dev run`
+type RunContext struct {
+	// Address of rsyslog and porterd from a container's perspective
+	DockerIPv4 string
+
+	EnvFile   string
+	LogDriver string
+	Labels    map[string]string
+	Secrets   secrets.Payload
+
+	// Use LogDriver even if a container configures its own
+	ForceLogDriver bool
+}
+
 func startContainers(environmentStr, regionStr string) {
-	var (
-		err          error
-		stdoutBuf    bytes.Buffer
-		haproxyStdin HAPStdin
-	)
+	var err error
 
 	log := logger.Host("cmd", "docker")
 
@@ -160,10 +176,6 @@ func startContainers(environmentStr, regionStr string) {
 
 	dockerIPv4 := dockerIfaceIPv4(log)
 
-	if !prepareNetwork(log) {
-		os.Exit(1)
-	}
-
 	secretsPayload, downloadSuccess := secrets.Download(log, region)
 	if !downloadSuccess {
 		os.Exit(1)
@@ -185,92 +197,96 @@ func startContainers(environmentStr, regionStr string) {
 		}
 	}
 
-	for _, container := range region.Containers {
-
-		runArgs := []string{
-			"run",
+	runCtx := RunContext{
+		DockerIPv4: dockerIPv4,
+		EnvFile:    constants.EnvFile,
 
-			// daemonize
-			"-d",
+		// log driver with defaults since facility override doesn't work
+		LogDriver: "syslog",
 
-			// log driver with defaults since facility override doesn't work
-			"--log-driver=syslog",
+		Secrets: secretsPayload,
+	}
 
-			// try to keep the container alive
-			// CIS Docker Benchmark 1.11.0 5.14
-			"--restart=on-failure:5",
+	haproxyStdin, startSuccess := StartContainers(log, environment, region, runCtx)
+	if !startSuccess {
+		os.Exit(1)
+	}
 
-			// CIS Docker Benchmark 1.11.0 5.25
-			"--security-opt=no-new-privileges",
+	stdoutBytes, err := json.Marshal(haproxyStdin)
+	if err != nil {
+		log.Error("json.Marshal", "Error", err)
+		os.Exit(1)
+	}
 
-			// set ulimit for container
-			// TODO calculate this
-			"--ulimit", "nofile=200000",
+	_, err = os.Stdout.Write(stdoutBytes)
+	if err != nil {
+		log.Error("os.Stdout.Write", "Error", err)
+		os.Exit(1)
+	}
+}
 
-			"--net", "porter",
+// StartContainers runs all of a region's containers in their start order and
+// returns the inet containers to be put behind HAProxy
+func StartContainers(log log15.Logger, environment *conf.Environment, region *conf.Region, runCtx RunContext) (haproxyStdin HAPStdin, success bool) {
+	var stdoutBuf bytes.Buffer
 
-			// prevent fork bombs
-			"--pids-limit", strconv.Itoa(container.PidsLimit),
+	if !prepareNetwork(log) {
+		return
+	}
 
-			// Read in additional variables written during bootstrap
-			"--env-file", constants.EnvFile,
+	containers, err := region.StartOrder()
+	if err != nil {
+		log.Crit("StartOrder", "Error", err)
+		return
+	}
 
-			// who and where am i?
-			"-e", "PORTER_ENVIRONMENT=" + environment.Name,
-			"-e", "AWS_REGION=" + region.Name,
+	started := make(map[string]startedContainer)
 
-			// rsyslog
-			"-e", "RSYSLOG_TCP_ADDR=" + dockerIPv4,
-			"-e", "RSYSLOG_TCP_PORT=514",
-			"-e", "RSYSLOG_UDP_ADDR=" + dockerIPv4,
-			"-e", "RSYSLOG_UDP_PORT=514",
+	for i, container := range containers {
 
-			// porterd
-			"-e", "PORTERD_TCP_ADDR=" + dockerIPv4,
-			"-e", "PORTERD_TCP_PORT=" + constants.PorterDaemonBindPort,
+		for _, dependency := range container.DependsOn {
+			if !waitForDependency(log, started[dependency.Container], dependency.Condition) {
+				return
+			}
 		}
 
-		if container.Topology == conf.Topology_Inet {
-			// publish to an ephemeral port
-			runArgs = append(runArgs, "-P")
+		// label containers with their start order so they can be stopped in
+		// reverse by a later hot swap
+		containerRunCtx := runCtx
+		containerRunCtx.Labels = map[string]string{
+			startOrderLabel: strconv.Itoa(i),
 		}
-
-		if container.ReadOnly == nil || *container.ReadOnly == true {
-			// CIS Docker Benchmark 1.11.0 5.12
-			runArgs = append(runArgs, "--read-only")
+		for key, value := range runCtx.Labels {
+			containerRunCtx.Labels[key] = value
 		}
 
-		// TODO revisit --cap-drop=ALL with override https://docs.docker.com/engine/reference/run/#runtime-privilege-and-linux-capabilities
-		if container.Uid == nil {
-			runArgs = append(runArgs, "-u", constants.ContainerUserUid)
-		} else {
-			runArgs = append(runArgs, "-u", strconv.Itoa(*container.Uid))
+		runArgs := RunArgs(log, environment, region, container, containerRunCtx)
+
+		cmd := exec.Command("docker", runArgs...)
+		cmd.Stdout = &stdoutBuf
+		err := cmd.Run()
+		if err != nil {
+			log.Crit("docker run", "Error", err)
+			return
 		}
 
-		runArgs = append(runArgs, getSecretEnvVars(log, container, secretsPayload)...)
+		containerId := strings.TrimSpace(stdoutBuf.String())
+		if containerId == "" {
+			log.Crit("missing container id")
+			return
+		}
+		stdoutBuf.Reset()
 
-		runArgs = append(runArgs, container.Name)
+		startedContainer := startedContainer{
+			id:        containerId,
+			container: container,
+		}
 
 		if container.Topology == conf.Topology_Inet {
 
-			cmd := exec.Command("docker", runArgs...)
-			cmd.Stdout = &stdoutBuf
-			err = cmd.Run()
-			if err != nil {
-				log.Crit("docker run", "Error", err)
-				os.Exit(1)
-			}
-
-			containerId := strings.TrimSpace(stdoutBuf.String())
-			if containerId == "" {
-				log.Crit("missing container id")
-				os.Exit(1)
-			}
-			stdoutBuf.Reset()
-
 			hostPort, hostPortsuccess := getInetHostPort(log, container.InetPort, containerId)
 			if !hostPortsuccess {
-				os.Exit(1)
+				return
 			}
 
 			cmdComplete := make(chan struct{})
@@ -288,34 +304,293 @@ func startContainers(environmentStr, regionStr string) {
 			}
 
 			hapContainer := HAPContainer{
-				Id:                containerId,
-				HealthCheckMethod: container.HealthCheck.Method,
-				HealthCheckPath:   container.HealthCheck.Path,
-				HostPort:          hostPort,
+				Id:          containerId,
+				Name:        container.ConfigName(),
+				HealthCheck: container.HealthCheck,
+				HostPort:    hostPort,
 			}
 
 			haproxyStdin.Containers = append(haproxyStdin.Containers, hapContainer)
-		} else {
+			startedContainer.hapContainer = &hapContainer
+		}
 
-			err = exec.Command("docker", runArgs...).Run()
-			if err != nil {
-				log.Crit("docker run", "Error", err)
-				os.Exit(1)
+		started[container.ConfigName()] = startedContainer
+	}
+
+	success = true
+	return
+}
+
+type startedContainer struct {
+	id        string
+	container *conf.Container
+
+	// inet containers only
+	hapContainer *HAPContainer
+}
+
+// waitForDependency waits for a container another container depends on to
+// reach a condition.
+//
+// healthy uses the container's Docker HEALTHCHECK. An inet container without
+// one falls back to its health_check
+func waitForDependency(log log15.Logger, dependency startedContainer, condition string) (success bool) {
+	log = log.New("Dependency", dependency.container.ConfigName(), "Condition", condition)
+
+	if condition == conf.Condition_Started {
+
+		running, err := inspectContainer(dependency.id, "{{ .State.Running }}")
+		if err != nil {
+			log.Error("docker inspect", "Error", err)
+			return
+		}
+
+		if running != "true" {
+			log.Error("dependency isn't running")
+			return
+		}
+
+		success = true
+		return
+	}
+
+	log.Info("waiting for dependency to be healthy")
+
+	sleepDuration := 2 * time.Second
+	n := int(constants.StackCreationTimeout().Seconds() / sleepDuration.Seconds())
+	for i := 0; i < n; i++ {
+
+		status, err := inspectContainer(dependency.id, "{{ if .State.Health }}{{ .State.Health.Status }}{{ end }}")
+		if err != nil {
+			log.Error("docker inspect", "Error", err)
+			return
+		}
+
+		switch status {
+		case "healthy":
+			success = true
+			return
+		case "":
+			if dependency.hapContainer != nil {
+				return healthCheckContainer(log, *dependency.hapContainer)
 			}
+
+			log.Error("dependency has no HEALTHCHECK to wait on")
+			return
 		}
+
+		log.Debug("dependency health", "Status", status)
+		time.Sleep(sleepDuration)
 	}
 
-	stdoutBytes, err := json.Marshal(haproxyStdin)
+	log.Error("dependency never became healthy")
+	return
+}
+
+func inspectContainer(containerId, format string) (string, error) {
+	inspectOutput, err := exec.Command("docker", "inspect", "-f", format, containerId).Output()
 	if err != nil {
-		log.Error("json.Marshal", "Error", err)
-		os.Exit(1)
+		return "", err
 	}
+	return strings.TrimSpace(string(inspectOutput)), nil
+}
 
-	_, err = os.Stdout.Write(stdoutBytes)
-	if err != nil {
-		log.Error("os.Stdout.Write", "Error", err)
-		os.Exit(1)
+// StopOrder sorts container ids in the reverse of the order they were started.
+// Containers started by older versions of porter have no start order and are
+// stopped first
+func StopOrder(log log15.Logger, containerIds []string) []string {
+
+	startOrders := make(map[string]int)
+	for _, containerId := range containerIds {
+
+		startOrder, err := inspectContainer(containerId,
+			`{{ index .Config.Labels "`+startOrderLabel+`" }}`)
+		if err != nil {
+			log.Warn("docker inspect", "ContainerId", containerId, "Error", err)
+		}
+
+		order, err := strconv.Atoi(startOrder)
+		if err != nil {
+			order = math.MaxInt32
+		}
+		startOrders[containerId] = order
 	}
+
+	sorted := make([]string, len(containerIds))
+	copy(sorted, containerIds)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return startOrders[sorted[i]] > startOrders[sorted[j]]
+	})
+	return sorted
+}
+
+// RunArgs are the arguments to `docker run` for a container
+func RunArgs(log log15.Logger, environment *conf.Environment, region *conf.Region, container *conf.Container, runCtx RunContext) []string {
+
+	runArgs := []string{
+		"run",
+
+		// daemonize
+		"-d",
+
+		// try to keep the container alive
+		// CIS Docker Benchmark 1.11.0 5.14
+		"--restart=on-failure:5",
+
+		// CIS Docker Benchmark 1.11.0 5.25
+		"--security-opt=no-new-privileges",
+
+		"--net", "porter",
+
+		// prevent fork bombs
+		"--pids-limit", strconv.Itoa(container.PidsLimit),
+
+		// Read in additional variables written during bootstrap
+		"--env-file", runCtx.EnvFile,
+
+		// who and where am i?
+		"-e", "PORTER_ENVIRONMENT=" + environment.Name,
+		"-e", "AWS_REGION=" + region.Name,
+
+		// rsyslog
+		"-e", "RSYSLOG_TCP_ADDR=" + runCtx.DockerIPv4,
+		"-e", "RSYSLOG_TCP_PORT=514",
+		"-e", "RSYSLOG_UDP_ADDR=" + runCtx.DockerIPv4,
+		"-e", "RSYSLOG_UDP_PORT=514",
+
+		// porterd
+		"-e", "PORTERD_TCP_ADDR=" + runCtx.DockerIPv4,
+		"-e", "PORTERD_TCP_PORT=" + constants.PorterDaemonBindPort,
+	}
+
+	runArgs = append(runArgs, logArgs(container, runCtx)...)
+
+	runArgs = append(runArgs, runtimeArgs(container)...)
+
+	for key, value := range runCtx.Labels {
+		runArgs = append(runArgs, "--label", key+"="+value)
+	}
+
+	if container.Topology == conf.Topology_Inet {
+		// publish to an ephemeral port
+		runArgs = append(runArgs, "-P")
+	}
+
+	for _, alias := range container.Aliases {
+		runArgs = append(runArgs, "--network-alias", alias)
+	}
+
+	if container.ReadOnly == nil || *container.ReadOnly == true {
+		// CIS Docker Benchmark 1.11.0 5.12
+		runArgs = append(runArgs, "--read-only")
+	}
+
+	if container.Uid == nil {
+		runArgs = append(runArgs, "-u", constants.ContainerUserUid)
+	} else {
+		runArgs = append(runArgs, "-u", strconv.Itoa(*container.Uid))
+	}
+
+	runArgs = append(runArgs, getSecretEnvVars(log, container, runCtx.Secrets)...)
+
+	runArgs = append(runArgs, container.Name)
+
+	return runArgs
+}
+
+func logArgs(container *conf.Container, runCtx RunContext) []string {
+
+	if runCtx.ForceLogDriver || (container.LogDriver == "" && len(container.LogOptions) == 0) {
+		return []string{"--log-driver=" + runCtx.LogDriver}
+	}
+
+	logDriver := runCtx.LogDriver
+	if container.LogDriver != "" {
+		logDriver = container.LogDriver
+	}
+
+	runArgs := []string{"--log-driver=" + logDriver}
+	for _, key := range sortedKeys(container.LogOptions) {
+		runArgs = append(runArgs, "--log-opt", key+"="+container.LogOptions[key])
+	}
+
+	return runArgs
+}
+
+// runtimeArgs translates a container's resource limits, capabilities, mounts,
+// and environment into docker run arguments
+func runtimeArgs(container *conf.Container) []string {
+
+	runArgs := make([]string, 0)
+
+	if container.Memory != "" {
+		runArgs = append(runArgs, "--memory", container.Memory)
+	}
+
+	if container.MemoryReservation != "" {
+		runArgs = append(runArgs, "--memory-reservation", container.MemoryReservation)
+	}
+
+	if container.CPUs > 0 {
+		runArgs = append(runArgs, "--cpus", strconv.FormatFloat(container.CPUs, 'f', -1, 64))
+	}
+
+	if container.CPUShares > 0 {
+		runArgs = append(runArgs, "--cpu-shares", strconv.Itoa(container.CPUShares))
+	}
+
+	// CIS Docker Benchmark 1.11.0 5.3
+	for _, capability := range container.CapDrop {
+		runArgs = append(runArgs, "--cap-drop", conf.Capability(capability))
+	}
+
+	for _, capability := range container.CapAdd {
+		runArgs = append(runArgs, "--cap-add", conf.Capability(capability))
+	}
+
+	for _, ulimit := range container.Ulimits {
+		runArgs = append(runArgs, "--ulimit",
+			fmt.Sprintf("%s=%d:%d", ulimit.Name, ulimit.Soft, ulimit.Hard))
+	}
+
+	for _, tmpfs := range container.Tmpfs {
+		options := make([]string, 0)
+		if tmpfs.Size != "" {
+			options = append(options, "size="+tmpfs.Size)
+		}
+		if tmpfs.Mode != "" {
+			options = append(options, "mode="+tmpfs.Mode)
+		}
+
+		mount := tmpfs.Path
+		if len(options) > 0 {
+			mount += ":" + strings.Join(options, ",")
+		}
+		runArgs = append(runArgs, "--tmpfs", mount)
+	}
+
+	for _, volume := range container.Volumes {
+		mount := volume.HostPath + ":" + volume.ContainerPath
+		if volume.ReadOnly == nil || *volume.ReadOnly == true {
+			mount += ":ro"
+		}
+		runArgs = append(runArgs, "-v", mount)
+	}
+
+	for _, key := range sortedKeys(container.Environment) {
+		runArgs = append(runArgs, "-e", key+"="+container.Environment[key])
+	}
+
+	return runArgs
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
 func prepareNetwork(log log15.Logger) (success bool) {
@@ -464,8 +739,8 @@ func cleanContainers(environmentStr, regionStr string) {
 
 	anyError := false
 
-	containerIds := strings.Split(strings.TrimSpace(string(psOutput)), "\n")
-	for _, containerId := range containerIds {
+	containerIds := strings.Fields(string(psOutput))
+	for _, containerId := range StopOrder(log, containerIds) {
 
 		inspectOutput, err := exec.Command("docker", "inspect", "-f", "{{ .Config.Image }}", containerId).Output()
 		if err != nil {
@@ -0,0 +1,16 @@
+package host
+
+import (
+	"github.com/adobe-platform/porter/conf"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+// RenderHAProxyConfigWithBlacklist renders the config as if the IP blacklist
+// file existed at ipBlacklistPath
+func RenderHAProxyConfigWithBlacklist(log log15.Logger, config *conf.Config, environment *conf.Environment,
+	region *conf.Region, hapStdin HAPStdin, ipBlacklistPath string) ([]byte, bool) {
+
+	context := newHAProxyConfigContext(config, environment, region, hapStdin)
+	context.IpBlacklistPath = ipBlacklistPath
+	return renderConfig(log, context)
+}
@@ -20,6 +20,9 @@ import (
 	"net/http"
 	"os"
 	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"text/template"
 	"time"
@@ -54,6 +57,10 @@ type (
 		HaveELB           bool
 		MaxConn           uint64
 
+		// Backends[0] is the default backend
+		Backends []hapBackend
+		Routes   []hapRoute
+
 		TimeoutClient        uint64
 		TimeoutServer        uint64
 		TimeoutTunnel        uint64
@@ -66,15 +73,37 @@ type (
 		Crt string
 	}
 
+	hapBackend struct {
+		Name       string
+		Containers []HAPContainer
+
+		// HealthCheck sets the check timing. HTTPCheck and HTTPCheckExpect are
+		// empty for TCP checks
+		HealthCheck     *conf.HealthCheck
+		HTTPCheck       string
+		HTTPCheckExpect string
+
+		// matches a path prefix to remove before forwarding
+		StripPrefixRegex string
+	}
+
+	// hapRoute sends requests matching HostRegex and PathPrefix to Backend
+	hapRoute struct {
+		ACL        string
+		Backend    string
+		HostRegex  string
+		PathPrefix string
+	}
+
 	HAPStdin struct {
 		Containers []HAPContainer `json:"containers"`
 	}
 
 	HAPContainer struct {
-		Id                string `json:"id"`
-		HealthCheckMethod string `json:"healthCheckMethod"`
-		HealthCheckPath   string `json:"healthCheckPath"`
-		HostPort          uint16 `json:"hostPort"`
+		Id          string            `json:"id"`
+		Name        string            `json:"name"`
+		HealthCheck *conf.HealthCheck `json:"healthCheck"`
+		HostPort    uint16            `json:"hostPort"`
 	}
 
 	hostSignal struct {
@@ -115,8 +144,17 @@ DESCRIPTION
       "containers": [
         {
           "id": "abc123",
-          "healthCheckMethod": "GET",
-          "healthCheckPath": "/health",
+          "name": "primary",
+          "healthCheck": {
+            "type": "http",
+            "method": "GET",
+            "path": "/health",
+            "expectedStatus": "200",
+            "interval": 5,
+            "timeout": 3,
+            "healthyThreshold": 3,
+            "unhealthyThreshold": 2
+          },
           "hostPort": 12345
         }
       ]
@@ -187,6 +225,36 @@ func hotswap(log log15.Logger, environmentStr, regionStr string, hapStdin HAPStd
 		return
 	}
 
+	context := newHAProxyConfigContext(config, environment, region, hapStdin)
+
+	if !healthCheckContainers(log, context.HAPStdin) {
+		return
+	}
+
+	if environment.HAProxy.SSL.Pem != nil {
+		if !downloadCert(log, environment, region) {
+			return
+		}
+	}
+
+	if !writeNewConfig(log, context) {
+		return
+	}
+
+	if !reloadHaproxy(log) {
+		return
+	}
+
+	if !signalHost(log, context) {
+		return
+	}
+
+	success = true
+	return
+}
+
+func newHAProxyConfigContext(config *conf.Config, environment *conf.Environment, region *conf.Region, hapStdin HAPStdin) haProxyConfigContext {
+
 	var ipBlacklistPath string
 	if _, err := os.Stat(constants.HAProxyIpBlacklistPath); err == nil {
 		ipBlacklistPath = constants.HAProxyIpBlacklistPath
@@ -211,7 +279,11 @@ func hotswap(log log15.Logger, environmentStr, regionStr string, hapStdin HAPStd
 		frontendPorts = append(frontendPorts, frontendPort)
 	}
 
-	context := haProxyConfigContext{
+	backends, routes := hapBackends(config, region, hapStdin)
+
+	return haProxyConfigContext{
+		Backends:             backends,
+		Routes:               routes,
 		ServiceName:          config.ServiceName,
 		FrontEndPorts:        frontendPorts,
 		HAPStdin:             hapStdin,
@@ -225,7 +297,7 @@ func hotswap(log log15.Logger, environmentStr, regionStr string, hapStdin HAPStd
 		ReqHeaderCaptures:    environment.HAProxy.ReqHeaderCaptures,
 		ResHeaderCaptures:    environment.HAProxy.ResHeaderCaptures,
 		HTTPS_Redirect:       environment.HAProxy.SSL.HTTPS_Redirect,
-		HaveELB:              region.HasELB(),
+		HaveELB:              region.HasELB() || region.TargetGroupType() == conf.TargetGroup_Application,
 		MaxConn:              environment.HAProxy.MaxConn,
 		TimeoutClient:        uint64(environment.HAProxy.Timeout.Client_.Seconds() * 1000),
 		TimeoutServer:        uint64(environment.HAProxy.Timeout.Server_.Seconds() * 1000),
@@ -233,33 +305,165 @@ func hotswap(log log15.Logger, environmentStr, regionStr string, hapStdin HAPStd
 		TimeoutHttpRequest:   uint64(environment.HAProxy.Timeout.HttpRequest_.Seconds() * 1000),
 		TimeoutHttpKeepAlive: uint64(environment.HAProxy.Timeout.HttpKeepAlive_.Seconds() * 1000),
 	}
+}
 
-	if !healthCheckContainers(log, context.HAPStdin) {
-		return
+// hapBackends groups inet containers into HAProxy backends. Containers without
+// routing share the default backend. Each routed container gets its own
+// backend and a route to it
+func hapBackends(config *conf.Config, region *conf.Region, hapStdin HAPStdin) ([]hapBackend, []hapRoute) {
+
+	routing := make(map[string]*conf.Routing)
+	for _, container := range region.Containers {
+		if container.Routing != nil {
+			routing[container.ConfigName()] = container.Routing
+		}
 	}
 
-	if environment.HAProxy.SSL.Pem != nil {
-		if !downloadCert(log, environment, region) {
-			return
+	// the default backend keeps TCP checks. its containers are health checked
+	// before traffic is sent to them and the load balancer checks it through
+	// HAProxy
+	backends := []hapBackend{
+		{
+			Name:        config.ServiceName + "-backend",
+			HealthCheck: region.HealthCheck(),
+		},
+	}
+	routes := make([]hapRoute, 0)
+
+	for _, container := range hapStdin.Containers {
+
+		containerRouting, exists := routing[container.Name]
+		if !exists {
+			backends[0].Containers = append(backends[0].Containers, container)
+			continue
+		}
+
+		backend := hapBackend{
+			Name:        config.ServiceName + "-" + container.Name + "-backend",
+			Containers:  []HAPContainer{container},
+			HealthCheck: container.HealthCheck,
+		}
+		if container.HealthCheck.Type == conf.HealthCheck_HTTP {
+			backend.HTTPCheck, backend.HTTPCheckExpect = httpCheck(container.HealthCheck)
+		}
+		if containerRouting.StripPrefix {
+			backend.StripPrefixRegex = literalRegex(containerRouting.PathPrefix)
+		}
+		backends = append(backends, backend)
+
+		routes = append(routes, hapRoute{
+			ACL:        "route-" + container.Name,
+			Backend:    backend.Name,
+			HostRegex:  hostRegex(containerRouting.Hosts),
+			PathPrefix: containerRouting.PathPrefix,
+		})
+	}
+
+	// most specific first: host and path, then path by longest prefix, then
+	// host
+	sort.SliceStable(routes, func(i, j int) bool {
+		iHostAndPath := routes[i].HostRegex != "" && routes[i].PathPrefix != ""
+		jHostAndPath := routes[j].HostRegex != "" && routes[j].PathPrefix != ""
+		if iHostAndPath != jHostAndPath {
+			return iHostAndPath
 		}
+		return len(routes[i].PathPrefix) > len(routes[j].PathPrefix)
+	})
+
+	return backends, routes
+}
+
+// hostRegex matches a Host header that's any of hosts with or without a port.
+// hdr(host) only matches exactly so example.com wouldn't match
+// example.com:8080. HAProxy 1.5 has no converter to strip the port.
+func hostRegex(hosts []string) string {
+	if len(hosts) == 0 {
+		return ""
 	}
 
-	if !writeNewConfig(log, context) {
-		return
+	escaped := make([]string, len(hosts))
+	for i, host := range hosts {
+		escaped[i] = literalRegex(host)
 	}
+	return "^(" + strings.Join(escaped, "|") + ")(:[0-9]+)?$"
+}
 
-	if !reloadHaproxy(log) {
-		return
+// literalRegex matches s literally. Hosts and path prefixes are validated so
+// . is the only metacharacter they can contain
+func literalRegex(s string) string {
+	// [.] rather than \. so HAProxy's config parser leaves it alone
+	return strings.Replace(regexp.QuoteMeta(s), `\.`, "[.]", -1)
+}
+
+// httpCheck is the option httpchk arguments and http-check expect rule for a
+// health check. Headers are appended to the HTTP version which is the only
+// way HAProxy 1.5 sends them
+func httpCheck(healthCheck *conf.HealthCheck) (check string, expect string) {
+
+	check = healthCheck.Method + " " + escapeHAProxy(healthCheck.Path)
+
+	if len(healthCheck.Headers) > 0 {
+
+		version := "HTTP/1.0"
+		headers := ""
+		for _, name := range sortedKeys(healthCheck.Headers) {
+			if strings.EqualFold(name, "Host") {
+				version = "HTTP/1.1"
+			}
+			headers += `\r\n` + escapeHAProxy(name+": "+healthCheck.Headers[name])
+		}
+		check += " " + version + headers
 	}
 
-	if !signalHost(log, context) {
+	// validation already parsed expected_status
+	ranges, _ := healthCheck.StatusRanges()
+	if len(ranges) == 1 && ranges[0][0] == ranges[0][1] {
+		expect = "status " + strconv.Itoa(ranges[0][0])
 		return
 	}
 
-	success = true
+	codes := make([]string, 0)
+	for _, statusRange := range ranges {
+		codes = append(codes, statusRegex(statusRange[0], statusRange[1])...)
+	}
+	expect = "rstatus ^(" + strings.Join(codes, "|") + ")$"
 	return
 }
 
+// statusRegex matches the status codes from low to high with as few
+// alternatives as possible e.g. 200-399 is 2[0-9][0-9] and 3[0-9][0-9]
+func statusRegex(low, high int) []string {
+	codes := make([]string, 0)
+
+	for code := low; code <= high; {
+		digits := strconv.Itoa(code)
+
+		switch {
+		case code%100 == 0 && code+99 <= high:
+			codes = append(codes, digits[:1]+"[0-9][0-9]")
+			code += 100
+		case code%10 == 0 && code+9 <= high:
+			codes = append(codes, digits[:2]+"[0-9]")
+			code += 10
+		default:
+			codes = append(codes, digits)
+			code++
+		}
+	}
+	return codes
+}
+
+// escapeHAProxy escapes the characters HAProxy 1.5 treats specially in an
+// unquoted argument
+func escapeHAProxy(arg string) string {
+	return strings.NewReplacer(`\`, `\\`, " ", `\ `, "#", `\#`).Replace(arg)
+}
+
+// RenderHAProxyConfig renders files/haproxy.cfg for the given containers
+func RenderHAProxyConfig(log log15.Logger, config *conf.Config, environment *conf.Environment, region *conf.Region, hapStdin HAPStdin) ([]byte, bool) {
+	return renderConfig(log, newHAProxyConfigContext(config, environment, region, hapStdin))
+}
+
 func downloadCert(log log15.Logger, environment *conf.Environment, region *conf.Region) (success bool) {
 
 	secretsPayload, downloadSuccess := secrets.Download(log, region)
@@ -281,6 +485,23 @@ func writeNewConfig(log log15.Logger, context haProxyConfigContext) (success boo
 
 	log.Info("writing new config")
 
+	configBytes, renderSuccess := renderConfig(log, context)
+	if !renderSuccess {
+		return
+	}
+
+	err := ioutil.WriteFile(constants.HAProxyConfigPath, configBytes, constants.HAProxyConfigPerms)
+	if err != nil {
+		log.Error("WriteFile failed", "Path", constants.HAProxyConfigPath, "Error", err)
+		return
+	}
+
+	success = true
+	return
+}
+
+func renderConfig(log log15.Logger, context haProxyConfigContext) (configBytes []byte, success bool) {
+
 	tmpl, err := template.New("").Parse(files.HaproxyCfg)
 	if err != nil {
 		log.Error("template parsing failed", "Error", err)
@@ -295,12 +516,7 @@ func writeNewConfig(log log15.Logger, context haProxyConfigContext) (success boo
 		return
 	}
 
-	err = ioutil.WriteFile(constants.HAProxyConfigPath, buf.Bytes(), constants.HAProxyConfigPerms)
-	if err != nil {
-		log.Error("WriteFile failed", "Path", constants.HAProxyConfigPath, "Error", err)
-		return
-	}
-
+	configBytes = buf.Bytes()
 	success = true
 	return
 }
@@ -428,29 +644,21 @@ func healthCheckContainers(log log15.Logger, stdin HAPStdin) (success bool) {
 
 func healthCheckContainer(log log15.Logger, container HAPContainer) (success bool) {
 	log = log.New("ContainerId", container.Id)
-	methodPath := container.HealthCheckMethod + " " + container.HealthCheckPath
-	healthURL := fmt.Sprintf("http://127.0.0.1:%d%s", container.HostPort, container.HealthCheckPath)
+	healthCheck := container.HealthCheck
+	addr := fmt.Sprintf("127.0.0.1:%d", container.HostPort)
+
+	healthCheckClient := &http.Client{
+		Timeout: time.Duration(healthCheck.Timeout) * time.Second,
+	}
 
 	sleepDuration := 2 * time.Second
 	n := int(constants.StackCreationTimeout().Seconds() / sleepDuration.Seconds())
 	for i := 0; i < n; i++ {
 		time.Sleep(sleepDuration)
 
-		req, err := http.NewRequest(container.HealthCheckMethod, healthURL, nil)
+		err := healthCheck.Probe(healthCheckClient, addr)
 		if err != nil {
-			log.Warn("http.NewRequest", "Error", err)
-			continue
-		}
-
-		resp, err := http.DefaultClient.Do(req)
-
-		if err != nil {
-			log.Warn(methodPath, "Error", err)
-			continue
-		}
-
-		if resp.StatusCode != 200 {
-			log.Warn(methodPath, "StatusCode", resp.StatusCode)
+			log.Warn(healthCheck.String(), "Error", err)
 			continue
 		}
 
@@ -460,7 +668,7 @@ func healthCheckContainer(log log15.Logger, container HAPContainer) (success boo
 	}
 
 	if !success {
-		log.Error("never received a 200 response for " + methodPath)
+		log.Error("never passed " + healthCheck.String())
 	}
 
 	return
@@ -0,0 +1,131 @@
+package host_test
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/adobe-platform/porter/commands/host"
+	"github.com/adobe-platform/porter/conf"
+	"github.com/adobe-platform/porter/logger"
+)
+
+var _ = Describe("RenderHAProxyConfig", func() {
+
+	healthCheck := func() *conf.HealthCheck {
+		return &conf.HealthCheck{
+			Type:               conf.HealthCheck_HTTP,
+			Method:             "GET",
+			Path:               "/health",
+			Interval:           5,
+			Timeout:            3,
+			HealthyThreshold:   2,
+			UnhealthyThreshold: 3,
+		}
+	}
+
+	render := func() string {
+		config := &conf.Config{ServiceName: "foo"}
+		environment := &conf.Environment{Name: "stage"}
+		region := &conf.Region{
+			Name: "us-west-2",
+			Containers: []*conf.Container{
+				{Name: "web", Topology: conf.Topology_Inet, HealthCheck: healthCheck()},
+				{
+					Name:        "admin",
+					Topology:    conf.Topology_Inet,
+					HealthCheck: healthCheck(),
+					Routing: &conf.Routing{
+						Hosts:      []string{"admin.example.com", "ops.example.com"},
+						PathPrefix: "/admin",
+					},
+				},
+				{
+					Name:        "api",
+					Topology:    conf.Topology_Inet,
+					HealthCheck: healthCheck(),
+					Routing:     &conf.Routing{Hosts: []string{"api.example.com"}},
+				},
+			},
+		}
+		hapStdin := host.HAPStdin{
+			Containers: []host.HAPContainer{
+				{Id: "1", Name: "web", HealthCheck: healthCheck(), HostPort: 32768},
+				{Id: "2", Name: "admin", HealthCheck: healthCheck(), HostPort: 32769},
+				{Id: "3", Name: "api", HealthCheck: healthCheck(), HostPort: 32770},
+			},
+		}
+
+		configBytes, success := host.RenderHAProxyConfig(logger.CLI(), config, environment, region, hapStdin)
+		Expect(success).To(BeTrue())
+		return string(configBytes)
+	}
+
+	It("matches hosts with or without a port", func() {
+		haproxyCfg := render()
+
+		Expect(haproxyCfg).To(ContainSubstring(
+			`acl route-admin-host hdr_reg(host) -i ^(admin[.]example[.]com|ops[.]example[.]com)(:[0-9]+)?$`))
+		Expect(haproxyCfg).To(ContainSubstring(
+			`acl route-api-host hdr_reg(host) -i ^(api[.]example[.]com)(:[0-9]+)?$`))
+		Expect(haproxyCfg).ToNot(ContainSubstring("hdr(host)"))
+	})
+
+	It("routes host and path before host alone", func() {
+		haproxyCfg := render()
+
+		admin := strings.Index(haproxyCfg, "use_backend foo-admin-backend if route-admin-host route-admin-path !porter-stats")
+		api := strings.Index(haproxyCfg, "use_backend foo-api-backend if route-api-host !porter-stats")
+		Expect(admin).To(BeNumerically(">", -1))
+		Expect(api).To(BeNumerically(">", admin))
+		Expect(haproxyCfg).To(ContainSubstring("acl route-admin-path path_beg /admin/"))
+		Expect(haproxyCfg).To(ContainSubstring("default_backend foo-backend"))
+	})
+})
+
+var _ = Describe("RenderHAProxyConfig with strip_prefix", func() {
+
+	render := func() string {
+		healthCheck := &conf.HealthCheck{Type: conf.HealthCheck_TCP, Interval: 5, Timeout: 3, HealthyThreshold: 2, UnhealthyThreshold: 3}
+
+		config := &conf.Config{ServiceName: "foo"}
+		environment := &conf.Environment{Name: "stage"}
+		region := &conf.Region{
+			Name: "us-west-2",
+			Containers: []*conf.Container{
+				{
+					Name:        "v1",
+					Topology:    conf.Topology_Inet,
+					HealthCheck: healthCheck,
+					Routing:     &conf.Routing{PathPrefix: "/v1.0", StripPrefix: true},
+				},
+			},
+		}
+		hapStdin := host.HAPStdin{
+			Containers: []host.HAPContainer{
+				{Id: "1", Name: "v1", HealthCheck: healthCheck, HostPort: 32768},
+			},
+		}
+
+		configBytes, success := host.RenderHAProxyConfigWithBlacklist(logger.CLI(), config, environment,
+			region, hapStdin, "/etc/haproxy/ip_blacklist")
+		Expect(success).To(BeTrue())
+		return string(configBytes)
+	}
+
+	It("strips the path prefix literally", func() {
+		Expect(render()).To(ContainSubstring(`reqrep ^([^\ :]*)\ /v1[.]0/?([^\ ]*)\ (.*)$ \1\ /\2\ \3`))
+	})
+
+	It("denies blacklisted IPs before choosing a backend", func() {
+		haproxyCfg := render()
+
+		deny := strings.Index(haproxyCfg, "http-request deny if ip_blacklist")
+		useBackend := strings.Index(haproxyCfg, "use_backend foo-v1-backend")
+		defaultBackend := strings.Index(haproxyCfg, "default_backend foo-backend")
+		Expect(deny).To(BeNumerically(">", -1))
+		Expect(useBackend).To(BeNumerically(">", deny))
+		Expect(defaultBackend).To(BeNumerically(">", deny))
+	})
+})
@@ -0,0 +1,72 @@
+/*
+ * (c) 2016-2018 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+var amiIdRegex = regexp.MustCompile(`^ami-[0-9a-f]{8}([0-9a-f]{9})?$`)
+
+// String describes where the AMI comes from
+func (recv *AMI) String() string {
+	switch {
+	case recv.ID != "":
+		return recv.ID
+	case recv.SSMParameter != "":
+		return "ssm_parameter " + recv.SSMParameter
+	case recv.Filter != nil:
+		return fmt.Sprintf("filter owner %s name %s", recv.Filter.Owner, recv.Filter.Name)
+	}
+	return ""
+}
+
+// Validate checks that exactly one of id, ssm_parameter, or filter is set.
+// ami is optional so a nil AMI is valid
+func (recv *AMI) Validate() error {
+	if recv == nil {
+		return nil
+	}
+
+	count := 0
+	if recv.ID != "" {
+		count++
+	}
+	if recv.SSMParameter != "" {
+		count++
+	}
+	if recv.Filter != nil {
+		count++
+	}
+
+	if count != 1 {
+		return errors.New("ami needs exactly one of id, ssm_parameter, or filter")
+	}
+
+	if recv.ID != "" && !amiIdRegex.MatchString(recv.ID) {
+		return errors.New("Invalid ami id " + recv.ID)
+	}
+
+	if recv.Filter != nil && (recv.Filter.Owner == "" || recv.Filter.Name == "") {
+		return errors.New("ami filter needs an owner and name")
+	}
+
+	// SSM parameters point at public images that porter bootstrap ami didn't
+	// bake
+	if recv.Prebaked && recv.SSMParameter != "" {
+		return errors.New("ami prebaked can't be used with ssm_parameter. Use the id or a filter for an image made by porter bootstrap ami")
+	}
+
+	return nil
+}
@@ -0,0 +1,165 @@
+package conf_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	"github.com/adobe-platform/porter/conf"
+)
+
+var _ = Describe("AutoScaling", func() {
+
+	var (
+		autoScaling *conf.AutoScaling
+		region      *conf.Region
+	)
+
+	uintPtr := func(u uint) *uint { return &u }
+	floatPtr := func(f float64) *float64 { return &f }
+
+	BeforeEach(func() {
+		region = &conf.Region{
+			Name: "us-west-2",
+			TargetGroups: []*conf.TargetGroup{
+				{Tag: "primary", Type: conf.TargetGroup_Application},
+			},
+		}
+
+		autoScaling = &conf.AutoScaling{
+			MinSize: 2,
+			MaxSize: 10,
+			TargetTracking: []*conf.TargetTrackingPolicy{
+				{Name: "cpu", Metric: conf.ScalingMetric_CPU, TargetValue: 50},
+				{Name: "requests", Metric: conf.ScalingMetric_RequestCount, TargetValue: 1000},
+			},
+			StepScaling: []*conf.StepScalingPolicy{
+				{
+					Name:                  "queue",
+					AdjustmentType:        conf.ScalingAdjustment_Change,
+					MetricAggregationType: "Average",
+					Steps: []*conf.ScalingStep{
+						{LowerBound: floatPtr(0), UpperBound: floatPtr(100), Adjustment: 1},
+						{LowerBound: floatPtr(100), Adjustment: 3},
+					},
+					Alarm: conf.ScalingAlarm{
+						Namespace:          "AWS/SQS",
+						MetricName:         "ApproximateNumberOfMessagesVisible",
+						Statistic:          "Average",
+						Period:             60,
+						EvaluationPeriods:  2,
+						Threshold:          100,
+						ComparisonOperator: "GreaterThanOrEqualToThreshold",
+					},
+				},
+			},
+			ScheduledActions: []*conf.ScheduledAction{
+				{Name: "nightly", Recurrence: "0 2 * * *", MinSize: uintPtr(1), MaxSize: uintPtr(4)},
+				{Name: "launch", StartTime: "2018-01-02T15:04:05Z", DesiredCapacity: uintPtr(8)},
+			},
+		}
+	})
+
+	It("accepts a valid auto_scaling", func() {
+		Expect(autoScaling.Validate(region, 4)).To(BeNil())
+	})
+
+	It("requires instance_count between min_size and max_size", func() {
+		Expect(autoScaling.Validate(region, 1)).To(MatchError("instance_count must be between min_size and max_size"))
+		Expect(autoScaling.Validate(region, 11)).To(MatchError("instance_count must be between min_size and max_size"))
+	})
+
+	DescribeTable("Validate errors",
+		func(modify func(*conf.AutoScaling, *conf.Region), expected string) {
+			modify(autoScaling, region)
+			Expect(autoScaling.Validate(region, 4)).To(MatchError(expected))
+		},
+		Entry("missing max_size", func(a *conf.AutoScaling, r *conf.Region) {
+			a.MaxSize = 0
+		}, "max_size must be greater than 0"),
+		Entry("min_size greater than max_size", func(a *conf.AutoScaling, r *conf.Region) {
+			a.MinSize = 11
+		}, "min_size is greater than max_size"),
+
+		Entry("an invalid policy name", func(a *conf.AutoScaling, r *conf.Region) {
+			a.TargetTracking[0].Name = "cpu-policy"
+		}, "Invalid name [cpu-policy]. Valid characters are [0-9a-zA-Z]"),
+		Entry("a policy and scheduled action with the same name", func(a *conf.AutoScaling, r *conf.Region) {
+			a.ScheduledActions[0].Name = "queue"
+		}, "Duplicate name queue"),
+		Entry("an invalid target_tracking metric", func(a *conf.AutoScaling, r *conf.Region) {
+			a.TargetTracking[0].Metric = "memory"
+		}, "Invalid target_tracking metric. Valid values are [cpu, request_count_per_target]"),
+		Entry("request_count_per_target with network target_groups", func(a *conf.AutoScaling, r *conf.Region) {
+			r.TargetGroups[0].Type = conf.TargetGroup_Network
+		}, "request_count_per_target requires application target_groups"),
+		Entry("request_count_per_target with multiple target_groups", func(a *conf.AutoScaling, r *conf.Region) {
+			r.TargetGroups = append(r.TargetGroups, &conf.TargetGroup{Tag: "secondary", Type: conf.TargetGroup_Application})
+		}, "request_count_per_target is incompatible with multiple target_groups"),
+		Entry("a non-positive target_value", func(a *conf.AutoScaling, r *conf.Region) {
+			a.TargetTracking[0].TargetValue = 0
+		}, "target_value must be positive"),
+		Entry("a negative target_tracking estimated_instance_warmup", func(a *conf.AutoScaling, r *conf.Region) {
+			a.TargetTracking[0].EstimatedInstanceWarmup = -1
+		}, "estimated_instance_warmup must be positive"),
+
+		Entry("an invalid adjustment_type", func(a *conf.AutoScaling, r *conf.Region) {
+			a.StepScaling[0].AdjustmentType = "Double"
+		}, "Invalid adjustment_type. Valid values are [ChangeInCapacity, PercentChangeInCapacity, ExactCapacity]"),
+		Entry("an invalid metric_aggregation_type", func(a *conf.AutoScaling, r *conf.Region) {
+			a.StepScaling[0].MetricAggregationType = "Sum"
+		}, "Invalid metric_aggregation_type. Valid values are [Average, Minimum, Maximum]"),
+		Entry("a negative step_scaling estimated_instance_warmup", func(a *conf.AutoScaling, r *conf.Region) {
+			a.StepScaling[0].EstimatedInstanceWarmup = -1
+		}, "estimated_instance_warmup must be positive"),
+		Entry("no steps", func(a *conf.AutoScaling, r *conf.Region) {
+			a.StepScaling[0].Steps = nil
+		}, "At least one step is required for step_scaling policy queue"),
+		Entry("an unbounded step", func(a *conf.AutoScaling, r *conf.Region) {
+			a.StepScaling[0].Steps[0].LowerBound = nil
+			a.StepScaling[0].Steps[0].UpperBound = nil
+		}, "Each step requires a lower_bound, upper_bound, or both"),
+		Entry("a step with inverted bounds", func(a *conf.AutoScaling, r *conf.Region) {
+			a.StepScaling[0].Steps[0].LowerBound = floatPtr(100)
+		}, "A step's lower_bound must be less than its upper_bound"),
+
+		Entry("an alarm without a metric_name", func(a *conf.AutoScaling, r *conf.Region) {
+			a.StepScaling[0].Alarm.MetricName = ""
+		}, "Missing alarm metric_name for step_scaling policy queue"),
+		Entry("an invalid alarm statistic", func(a *conf.AutoScaling, r *conf.Region) {
+			a.StepScaling[0].Alarm.Statistic = "p99"
+		}, "Invalid alarm statistic. Valid values are [Average, Sum, SampleCount, Minimum, Maximum]"),
+		Entry("an invalid alarm comparison_operator", func(a *conf.AutoScaling, r *conf.Region) {
+			a.StepScaling[0].Alarm.ComparisonOperator = ">="
+		}, "Invalid alarm comparison_operator. Valid values are [GreaterThanOrEqualToThreshold, GreaterThanThreshold, LessThanThreshold, LessThanOrEqualToThreshold]"),
+		Entry("an alarm period that isn't a multiple of 60", func(a *conf.AutoScaling, r *conf.Region) {
+			a.StepScaling[0].Alarm.Period = 90
+		}, "alarm period must be a multiple of 60"),
+		Entry("a missing alarm period", func(a *conf.AutoScaling, r *conf.Region) {
+			a.StepScaling[0].Alarm.Period = 0
+		}, "alarm period must be a multiple of 60"),
+		Entry("non-positive alarm evaluation_periods", func(a *conf.AutoScaling, r *conf.Region) {
+			a.StepScaling[0].Alarm.EvaluationPeriods = 0
+		}, "alarm evaluation_periods must be positive"),
+
+		Entry("a scheduled action without recurrence or start_time", func(a *conf.AutoScaling, r *conf.Region) {
+			a.ScheduledActions[0].Recurrence = ""
+		}, "At least one of recurrence or start_time is required for scheduled action nightly"),
+		Entry("a bad scheduled action recurrence", func(a *conf.AutoScaling, r *conf.Region) {
+			a.ScheduledActions[0].Recurrence = "0 2 * *"
+		}, "Invalid recurrence [0 2 * *]. Use a cron expression with 5 fields"),
+		Entry("a bad scheduled action start_time", func(a *conf.AutoScaling, r *conf.Region) {
+			a.ScheduledActions[1].StartTime = "2018-01-02 15:04"
+		}, "Invalid start_time or end_time 2018-01-02 15:04. Use RFC 3339 e.g. 2018-01-02T15:04:05Z"),
+		Entry("a bad scheduled action end_time", func(a *conf.AutoScaling, r *conf.Region) {
+			a.ScheduledActions[1].EndTime = "tomorrow"
+		}, "Invalid start_time or end_time tomorrow. Use RFC 3339 e.g. 2018-01-02T15:04:05Z"),
+		Entry("a scheduled action without sizes", func(a *conf.AutoScaling, r *conf.Region) {
+			a.ScheduledActions[0].MinSize = nil
+			a.ScheduledActions[0].MaxSize = nil
+		}, "At least one of min_size, max_size, or desired_capacity is required for scheduled action nightly"),
+		Entry("a scheduled action with min_size greater than max_size", func(a *conf.AutoScaling, r *conf.Region) {
+			a.ScheduledActions[0].MinSize = uintPtr(5)
+		}, "min_size is greater than max_size for scheduled action nightly"),
+	)
+})
@@ -0,0 +1,308 @@
+/*
+ * (c) 2016-2018 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	BlackoutWindow_Absolute = "absolute"
+	BlackoutWindow_Cron     = "cron"
+	BlackoutWindow_Weekly   = "weekly"
+)
+
+// cron durations are walked a minute at a time so they're capped
+const maxBlackoutCronDuration = 31 * 24 * time.Hour
+
+var blackoutWindowNameRegex = regexp.MustCompile(`^[-_0-9a-zA-Z]+$`)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+type cronSchedule struct {
+	minute     map[int]bool
+	hour       map[int]bool
+	dayOfMonth map[int]bool
+	month      map[int]bool
+	dayOfWeek  map[int]bool
+
+	// standard cron matches either day field when both are restricted
+	anyDayOfMonth bool
+	anyDayOfWeek  bool
+}
+
+// Kind is the type of schedule the window uses which is determined by the
+// keys that are set
+func (recv BlackoutWindow) Kind() string {
+	switch {
+	case recv.Cron != "":
+		return BlackoutWindow_Cron
+	case recv.From != "" || recv.To != "" || len(recv.Days) > 0:
+		return BlackoutWindow_Weekly
+	default:
+		return BlackoutWindow_Absolute
+	}
+}
+
+// String identifies the window in logs
+func (recv BlackoutWindow) String() string {
+	if recv.Name != "" {
+		return recv.Name
+	}
+
+	switch recv.Kind() {
+	case BlackoutWindow_Cron:
+		return fmt.Sprintf("cron %s for %s", recv.Cron, recv.Duration)
+	case BlackoutWindow_Weekly:
+		return fmt.Sprintf("%s %s-%s", strings.Join(recv.Days, ","), recv.From, recv.To)
+	default:
+		return recv.StartTime + " to " + recv.EndTime
+	}
+}
+
+// IsActive returns true if now falls within the window. Windows that can't be
+// parsed return an error and should be treated as active
+func (recv BlackoutWindow) IsActive(now time.Time) (bool, error) {
+
+	switch recv.Kind() {
+	case BlackoutWindow_Cron:
+
+		location, err := recv.location()
+		if err != nil {
+			return false, err
+		}
+
+		schedule, err := parseCron(recv.Cron)
+		if err != nil {
+			return false, err
+		}
+
+		duration, err := time.ParseDuration(recv.Duration)
+		if err != nil {
+			return false, err
+		}
+
+		// the window is active if it started within the last duration
+		start := now.Add(-duration)
+		for t := now.Truncate(time.Minute); t.After(start); t = t.Add(-time.Minute) {
+			if schedule.matches(t.In(location)) {
+				return true, nil
+			}
+		}
+		return false, nil
+
+	case BlackoutWindow_Weekly:
+
+		location, err := recv.location()
+		if err != nil {
+			return false, err
+		}
+
+		days, err := parseWeekdays(recv.Days)
+		if err != nil {
+			return false, err
+		}
+
+		from, err := parseTimeOfDay(recv.From)
+		if err != nil {
+			return false, err
+		}
+
+		to, err := parseTimeOfDay(recv.To)
+		if err != nil {
+			return false, err
+		}
+
+		local := now.In(location)
+		minuteOfDay := local.Hour()*60 + local.Minute()
+		today := days[local.Weekday()]
+
+		if from < to {
+			return today && from <= minuteOfDay && minuteOfDay < to, nil
+		}
+
+		// the window wraps past midnight into the next day
+		yesterday := days[(local.Weekday()+6)%7]
+		return (today && minuteOfDay >= from) || (yesterday && minuteOfDay < to), nil
+
+	default:
+
+		startTime, err := time.Parse(time.RFC3339, recv.StartTime)
+		if err != nil {
+			return false, err
+		}
+
+		endTime, err := time.Parse(time.RFC3339, recv.EndTime)
+		if err != nil {
+			return false, err
+		}
+
+		if startTime.After(endTime) {
+			return false, errors.New("start_time is after end_time")
+		}
+
+		return now.After(startTime) && now.Before(endTime), nil
+	}
+}
+
+func (recv BlackoutWindow) location() (*time.Location, error) {
+	if recv.Timezone == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(recv.Timezone)
+}
+
+// parseWeekdays returns every day of the week if none are given
+func parseWeekdays(names []string) (map[time.Weekday]bool, error) {
+	days := make(map[time.Weekday]bool)
+
+	if len(names) == 0 {
+		for _, day := range weekdays {
+			days[day] = true
+		}
+		return days, nil
+	}
+
+	for _, name := range names {
+		day, exists := weekdays[strings.ToLower(name)]
+		if !exists {
+			return nil, errors.New("invalid day " + name + ". Valid days are sun, mon, tue, wed, thu, fri, and sat")
+		}
+		days[day] = true
+	}
+	return days, nil
+}
+
+// parseTimeOfDay parses HH:MM into minutes past midnight
+func parseTimeOfDay(value string) (int, error) {
+	parsed, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, errors.New("invalid time of day " + value + ". The format is HH:MM")
+	}
+	return parsed.Hour()*60 + parsed.Minute(), nil
+}
+
+// parseCron parses the five standard fields: minute, hour, day of month,
+// month, and day of week. Each field supports *, lists, ranges, and steps.
+func parseCron(expression string) (*cronSchedule, error) {
+	fields := strings.Fields(expression)
+	if len(fields) != 5 {
+		return nil, errors.New("cron expression " + expression + " must have 5 fields")
+	}
+
+	var (
+		schedule cronSchedule
+		err      error
+	)
+
+	if schedule.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, err
+	}
+	if schedule.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, err
+	}
+	if schedule.dayOfMonth, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, err
+	}
+	if schedule.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, err
+	}
+	if schedule.dayOfWeek, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, err
+	}
+
+	// 0 and 7 are both Sunday
+	if schedule.dayOfWeek[7] {
+		schedule.dayOfWeek[0] = true
+	}
+
+	schedule.anyDayOfMonth = fields[2] == "*"
+	schedule.anyDayOfWeek = fields[4] == "*"
+
+	return &schedule, nil
+}
+
+func parseCronField(field string, min, max int) (map[int]bool, error) {
+	values := make(map[int]bool)
+
+	for _, part := range strings.Split(field, ",") {
+
+		step := 1
+		if i := strings.Index(part, "/"); i != -1 {
+			var err error
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step < 1 {
+				return nil, errors.New("invalid step in cron field " + field)
+			}
+			part = part[:i]
+		}
+
+		low, high := min, max
+		if part != "*" {
+			var err error
+			bounds := strings.SplitN(part, "-", 2)
+
+			low, err = strconv.Atoi(bounds[0])
+			if err != nil {
+				return nil, errors.New("invalid cron field " + field)
+			}
+
+			high = low
+			if len(bounds) == 2 {
+				high, err = strconv.Atoi(bounds[1])
+				if err != nil {
+					return nil, errors.New("invalid cron field " + field)
+				}
+			} else if step > 1 {
+				// 5/15 means starting at 5 every 15
+				high = max
+			}
+		}
+
+		if low < min || high > max || low > high {
+			return nil, fmt.Errorf("cron field %s is out of range %d-%d", field, min, max)
+		}
+
+		for value := low; value <= high; value += step {
+			values[value] = true
+		}
+	}
+
+	return values, nil
+}
+
+func (recv *cronSchedule) matches(t time.Time) bool {
+	if !recv.minute[t.Minute()] || !recv.hour[t.Hour()] || !recv.month[int(t.Month())] {
+		return false
+	}
+
+	dayOfMonth := recv.dayOfMonth[t.Day()]
+	dayOfWeek := recv.dayOfWeek[int(t.Weekday())]
+
+	if recv.anyDayOfMonth || recv.anyDayOfWeek {
+		return dayOfMonth && dayOfWeek
+	}
+	return dayOfMonth || dayOfWeek
+}
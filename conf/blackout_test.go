@@ -0,0 +1,98 @@
+package conf_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/adobe-platform/porter/conf"
+)
+
+func mustParse(value string) time.Time {
+	t, err := time.Parse(time.RFC3339, value)
+	Expect(err).To(BeNil())
+	return t
+}
+
+var _ = Describe("BlackoutWindow", func() {
+
+	It("supports absolute windows", func() {
+		window := conf.BlackoutWindow{
+			StartTime: "2019-12-20T00:00:00Z",
+			EndTime:   "2020-01-02T00:00:00Z",
+		}
+		Expect(window.Validate()).To(BeNil())
+
+		active, err := window.IsActive(mustParse("2019-12-25T12:00:00Z"))
+		Expect(err).To(BeNil())
+		Expect(active).To(BeTrue())
+
+		active, err = window.IsActive(mustParse("2020-01-03T12:00:00Z"))
+		Expect(err).To(BeNil())
+		Expect(active).To(BeFalse())
+	})
+
+	It("supports cron windows in a time zone", func() {
+		// Fridays at 16:00 Pacific for 64 hours
+		window := conf.BlackoutWindow{
+			Name:     "weekend",
+			Cron:     "0 16 * * 5",
+			Duration: "64h",
+			Timezone: "America/Los_Angeles",
+		}
+		Expect(window.Validate()).To(BeNil())
+
+		// Saturday 2019-06-08 10:00 Pacific
+		active, err := window.IsActive(mustParse("2019-06-08T17:00:00Z"))
+		Expect(err).To(BeNil())
+		Expect(active).To(BeTrue())
+
+		// Friday 2019-06-07 15:59 Pacific
+		active, err = window.IsActive(mustParse("2019-06-07T22:59:00Z"))
+		Expect(err).To(BeNil())
+		Expect(active).To(BeFalse())
+
+		// Monday 2019-06-10 08:00 Pacific
+		active, err = window.IsActive(mustParse("2019-06-10T15:00:00Z"))
+		Expect(err).To(BeNil())
+		Expect(active).To(BeFalse())
+	})
+
+	It("supports weekly windows that wrap past midnight", func() {
+		window := conf.BlackoutWindow{
+			Days: []string{"fri"},
+			From: "22:00",
+			To:   "02:00",
+		}
+		Expect(window.Validate()).To(BeNil())
+
+		// Friday 23:00
+		active, err := window.IsActive(mustParse("2019-06-07T23:00:00Z"))
+		Expect(err).To(BeNil())
+		Expect(active).To(BeTrue())
+
+		// Saturday 01:00
+		active, err = window.IsActive(mustParse("2019-06-08T01:00:00Z"))
+		Expect(err).To(BeNil())
+		Expect(active).To(BeTrue())
+
+		// Saturday 23:00
+		active, err = window.IsActive(mustParse("2019-06-08T23:00:00Z"))
+		Expect(err).To(BeNil())
+		Expect(active).To(BeFalse())
+	})
+
+	It("rejects invalid windows", func() {
+		Expect(conf.BlackoutWindow{
+			Cron:      "0 16 * * 5",
+			Duration:  "1h",
+			StartTime: "2019-12-20T00:00:00Z",
+		}.Validate()).ToNot(BeNil())
+
+		Expect(conf.BlackoutWindow{Cron: "0 25 * * *", Duration: "1h"}.Validate()).ToNot(BeNil())
+		Expect(conf.BlackoutWindow{Cron: "0 16 * * 5"}.Validate()).ToNot(BeNil())
+		Expect(conf.BlackoutWindow{From: "22:00", To: "02:00", Days: []string{"someday"}}.Validate()).ToNot(BeNil())
+		Expect(conf.BlackoutWindow{From: "22:00", To: "02:00", Timezone: "Nowhere/Special"}.Validate()).ToNot(BeNil())
+	})
+})
@@ -0,0 +1,617 @@
+/*
+ * (c) 2016-2018 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+package conf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	composeVarsKey      = "vars"
+	composeTemplatesKey = "templates"
+	composeExtendsKey   = "extends"
+)
+
+// ${name} is interpolated and $${name} is an escaped literal ${name}
+var interpolationRegex = regexp.MustCompile(`\$?\$\{([^}]*)\}`)
+
+var variableNameRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+type (
+	configSource struct {
+		path  string
+		lines []string
+		doc   yaml.MapSlice
+	}
+
+	composer struct {
+		sources []*configSource
+
+		templateEnvironments map[string]yaml.MapSlice
+		templateRegions      map[string]yaml.MapSlice
+		environments         map[string]yaml.MapSlice
+	}
+)
+
+// Compose reads the config at configPath along with the fragments in
+// configPath.d/ and returns a single config with variables interpolated and
+// extends resolved.
+//
+// Fragments are merged in lexical order of their file names. Mappings are
+// merged recursively with later values winning. Sequences of mappings with a
+// name key are merged by name. Other sequences are appended.
+//
+// Environments and regions can extend an environment or region template from
+// the top-level templates key, and environments can also extend another
+// environment. The same merge rules apply except unnamed sequences in the
+// child replace those in the parent.
+func Compose(configPath string) ([]byte, error) {
+
+	fragmentPaths, err := fragmentPaths(configPath + ".d")
+	if err != nil {
+		return nil, err
+	}
+
+	paths := append([]string{configPath}, fragmentPaths...)
+
+	recv := &composer{}
+	vars := make(map[string]string)
+	needsComposition := len(fragmentPaths) > 0
+
+	for _, path := range paths {
+		configBytes, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		source := &configSource{
+			path:  path,
+			lines: strings.Split(string(configBytes), "\n"),
+		}
+		recv.sources = append(recv.sources, source)
+
+		err = yaml.Unmarshal(configBytes, &source.doc)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", path, err)
+		}
+
+		sourceVars, _ := getKey(source.doc, composeVarsKey).(yaml.MapSlice)
+		for _, item := range sourceVars {
+			key := fmt.Sprint(item.Key)
+			if !variableNameRegex.MatchString(key) {
+				return nil, source.errorf(source.findLine(0, key+":"), "invalid variable name %s", key)
+			}
+
+			// vars can only refer to the process environment
+			value, err := interpolate(fmt.Sprint(item.Value), nil)
+			if err != nil {
+				return nil, source.errorf(source.findLine(0, key+":"), "%s", err)
+			}
+			vars[key] = value
+		}
+
+		if bytes.Contains(configBytes, []byte("${")) || len(sourceVars) > 0 {
+			needsComposition = true
+		}
+	}
+
+	for _, source := range recv.sources {
+
+		interpolated, reference, err := interpolateValue(removeKey(source.doc, composeVarsKey), vars)
+		if err != nil {
+			return nil, source.errorf(source.findLine(0, reference), "%s", err)
+		}
+		source.doc = interpolated.(yaml.MapSlice)
+	}
+
+	doc := recv.sources[0].doc
+	for _, source := range recv.sources[1:] {
+		doc = mergeMapSlice(doc, source.doc, true)
+	}
+
+	if hasKey(doc, composeTemplatesKey) || usesExtends(doc) {
+		needsComposition = true
+	}
+
+	if !needsComposition {
+		return ioutil.ReadFile(configPath)
+	}
+
+	doc, err = recv.resolveExtends(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return yaml.Marshal(removeKey(doc, composeTemplatesKey))
+}
+
+func fragmentPaths(dir string) ([]string, error) {
+	paths := make([]string, 0)
+
+	fileInfos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return paths, nil
+		}
+		return nil, err
+	}
+
+	for _, fileInfo := range fileInfos {
+		ext := filepath.Ext(fileInfo.Name())
+		if fileInfo.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		paths = append(paths, filepath.Join(dir, fileInfo.Name()))
+	}
+
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// interpolate replaces ${name} with a var or the process environment. vars
+// take precedence.
+func interpolate(value string, vars map[string]string) (string, error) {
+	var err error
+
+	interpolated := interpolationRegex.ReplaceAllStringFunc(value, func(match string) string {
+		if strings.HasPrefix(match, "$$") {
+			return match[1:]
+		}
+
+		name := match[2 : len(match)-1]
+		if !variableNameRegex.MatchString(name) {
+			err = errors.New("invalid variable name " + match)
+			return match
+		}
+
+		if varValue, exists := vars[name]; exists {
+			return varValue
+		}
+
+		if envValue, exists := os.LookupEnv(name); exists {
+			return envValue
+		}
+
+		if err == nil {
+			err = errors.New("undefined variable " + match)
+		}
+		return match
+	})
+
+	return interpolated, err
+}
+
+// interpolateValue interpolates every string in a document. A string that's
+// entirely a single reference takes on the type of the value so that
+// instance_count: ${count} is still a number. The offending reference is
+// returned with any error.
+func interpolateValue(value interface{}, vars map[string]string) (interface{}, string, error) {
+
+	switch typedValue := value.(type) {
+	case string:
+
+		interpolated, err := interpolate(typedValue, vars)
+		if err != nil {
+			return nil, typedValue, err
+		}
+
+		if interpolated != typedValue && interpolationRegex.FindString(typedValue) == typedValue {
+			var typed interface{}
+			if yaml.Unmarshal([]byte(interpolated), &typed) == nil {
+				switch typed.(type) {
+				case bool, int, float64:
+					return typed, "", nil
+				}
+			}
+		}
+
+		return interpolated, "", nil
+
+	case yaml.MapSlice:
+
+		interpolated := make(yaml.MapSlice, 0, len(typedValue))
+		for _, item := range typedValue {
+			itemValue, reference, err := interpolateValue(item.Value, vars)
+			if err != nil {
+				return nil, reference, err
+			}
+			interpolated = append(interpolated, yaml.MapItem{Key: item.Key, Value: itemValue})
+		}
+		return interpolated, "", nil
+
+	case []interface{}:
+
+		interpolated := make([]interface{}, 0, len(typedValue))
+		for _, item := range typedValue {
+			itemValue, reference, err := interpolateValue(item, vars)
+			if err != nil {
+				return nil, reference, err
+			}
+			interpolated = append(interpolated, itemValue)
+		}
+		return interpolated, "", nil
+	}
+
+	return value, "", nil
+}
+
+func (recv *composer) resolveExtends(doc yaml.MapSlice) (yaml.MapSlice, error) {
+
+	recv.templateEnvironments = make(map[string]yaml.MapSlice)
+	recv.templateRegions = make(map[string]yaml.MapSlice)
+	recv.environments = make(map[string]yaml.MapSlice)
+
+	if templates, ok := getKey(doc, composeTemplatesKey).(yaml.MapSlice); ok {
+
+		err := indexByName(getKey(templates, "environments"), recv.templateEnvironments, "environment template")
+		if err != nil {
+			return nil, err
+		}
+
+		err = indexByName(getKey(templates, "regions"), recv.templateRegions, "region template")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	environments, _ := getKey(doc, "environments").([]interface{})
+
+	err := indexByName(environments, recv.environments, "environment")
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := make([]interface{}, 0, len(environments))
+	for _, environment := range environments {
+		environmentDoc, ok := environment.(yaml.MapSlice)
+		if !ok {
+			resolved = append(resolved, environment)
+			continue
+		}
+
+		name, _ := getString(environmentDoc, "name")
+		environmentDoc, err = recv.resolveEnvironment(environmentDoc, []string{"environment " + name})
+		if err != nil {
+			return nil, err
+		}
+
+		resolved = append(resolved, environmentDoc)
+	}
+
+	return setKey(doc, "environments", resolved), nil
+}
+
+func (recv *composer) resolveEnvironment(environment yaml.MapSlice, chain []string) (yaml.MapSlice, error) {
+
+	name, _ := getString(environment, "name")
+
+	if parentName, exists := getString(environment, composeExtendsKey); exists {
+
+		// an environment can share its name with the template it extends
+		link := "environment " + parentName
+		parent, exists := recv.environments[parentName]
+		if !exists || parentName == name {
+			link = "template " + parentName
+			parent, exists = recv.templateEnvironments[parentName]
+		}
+		if !exists {
+			return nil, recv.extendsError(parentName, "environment %s extends unknown environment %s", name, parentName)
+		}
+
+		for _, chainLink := range chain {
+			if chainLink == link {
+				return nil, recv.extendsError(parentName, "environment %s has a cycle in extends: %s -> %s",
+					name, strings.Join(chain, " -> "), link)
+			}
+		}
+
+		parent, err := recv.resolveEnvironment(parent, append(chain, link))
+		if err != nil {
+			return nil, err
+		}
+
+		environment = mergeMapSlice(parent, removeKey(environment, composeExtendsKey), false)
+	}
+
+	regions, _ := getKey(environment, "regions").([]interface{})
+	resolvedRegions := make([]interface{}, 0, len(regions))
+
+	for _, region := range regions {
+		regionDoc, ok := region.(yaml.MapSlice)
+		if !ok {
+			resolvedRegions = append(resolvedRegions, region)
+			continue
+		}
+
+		regionDoc, err := recv.resolveRegion(regionDoc, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		resolvedRegions = append(resolvedRegions, regionDoc)
+	}
+
+	if regions != nil {
+		environment = setKey(environment, "regions", resolvedRegions)
+	}
+
+	return environment, nil
+}
+
+func (recv *composer) resolveRegion(region yaml.MapSlice, chain []string) (yaml.MapSlice, error) {
+
+	parentName, exists := getString(region, composeExtendsKey)
+	if !exists {
+		return region, nil
+	}
+
+	name := fmt.Sprint(getKey(region, "name"))
+	for _, link := range chain {
+		if link == parentName {
+			return nil, recv.extendsError(parentName, "region %s has a cycle in extends: %s -> %s",
+				name, strings.Join(chain, " -> "), parentName)
+		}
+	}
+	chain = append(chain, parentName)
+
+	parent, exists := recv.templateRegions[parentName]
+	if !exists {
+		return nil, recv.extendsError(parentName, "region %s extends unknown region template %s", name, parentName)
+	}
+
+	parent, err := recv.resolveRegion(parent, chain)
+	if err != nil {
+		return nil, err
+	}
+
+	return mergeMapSlice(parent, removeKey(region, composeExtendsKey), false), nil
+}
+
+// extendsError points at the first extends of the given parent
+func (recv *composer) extendsError(parentName, format string, args ...interface{}) error {
+	for _, source := range recv.sources {
+		for i, line := range source.lines {
+			value, exists := lineValue(line, composeExtendsKey)
+			if exists && value == parentName {
+				return source.errorf(i+1, format, args...)
+			}
+		}
+	}
+	return fmt.Errorf(format, args...)
+}
+
+func (recv *configSource) errorf(line int, format string, args ...interface{}) error {
+	msg := fmt.Sprintf(format, args...)
+	if line > 0 {
+		return fmt.Errorf("%s:%d: %s", recv.path, line, msg)
+	}
+	return fmt.Errorf("%s: %s", recv.path, msg)
+}
+
+// findLine returns the 1-based line number of the first line after the given
+// line containing substr or 0 if none does
+func (recv *configSource) findLine(after int, substr string) int {
+	for i := after; i < len(recv.lines); i++ {
+		if strings.Contains(recv.lines[i], substr) {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// lineValue parses lines like "  - key: value"
+func lineValue(line, key string) (string, bool) {
+	line = strings.TrimSpace(line)
+	line = strings.TrimSpace(strings.TrimPrefix(line, "-"))
+
+	if !strings.HasPrefix(line, key+":") {
+		return "", false
+	}
+
+	value := strings.TrimSpace(strings.TrimPrefix(line, key+":"))
+	value = strings.Trim(value, `"'`)
+	return value, true
+}
+
+func indexByName(list interface{}, index map[string]yaml.MapSlice, kind string) error {
+	items, _ := list.([]interface{})
+
+	for _, item := range items {
+		itemDoc, ok := item.(yaml.MapSlice)
+		if !ok {
+			continue
+		}
+
+		name, exists := getString(itemDoc, "name")
+		if !exists {
+			continue
+		}
+
+		if _, exists := index[name]; exists {
+			return fmt.Errorf("duplicate %s %s", kind, name)
+		}
+		index[name] = itemDoc
+	}
+
+	return nil
+}
+
+func usesExtends(doc yaml.MapSlice) bool {
+	environments, _ := getKey(doc, "environments").([]interface{})
+
+	for _, environment := range environments {
+		environmentDoc, ok := environment.(yaml.MapSlice)
+		if !ok {
+			continue
+		}
+
+		if hasKey(environmentDoc, composeExtendsKey) {
+			return true
+		}
+
+		regions, _ := getKey(environmentDoc, "regions").([]interface{})
+		for _, region := range regions {
+			if regionDoc, ok := region.(yaml.MapSlice); ok && hasKey(regionDoc, composeExtendsKey) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// mergeMapSlice returns a new MapSlice with overlay merged onto base. Neither
+// argument is modified.
+func mergeMapSlice(base, overlay yaml.MapSlice, appendUnnamed bool) yaml.MapSlice {
+	merged := make(yaml.MapSlice, len(base))
+	copy(merged, base)
+
+	for _, item := range overlay {
+
+		i := indexOfKey(merged, item.Key)
+		if i == -1 {
+			merged = append(merged, item)
+			continue
+		}
+
+		merged[i] = yaml.MapItem{
+			Key:   item.Key,
+			Value: mergeValue(merged[i].Value, item.Value, appendUnnamed),
+		}
+	}
+
+	return merged
+}
+
+func mergeValue(base, overlay interface{}, appendUnnamed bool) interface{} {
+
+	switch overlayValue := overlay.(type) {
+	case yaml.MapSlice:
+
+		if baseValue, ok := base.(yaml.MapSlice); ok {
+			return mergeMapSlice(baseValue, overlayValue, appendUnnamed)
+		}
+
+	case []interface{}:
+
+		if baseValue, ok := base.([]interface{}); ok {
+			return mergeSequence(baseValue, overlayValue, appendUnnamed)
+		}
+	}
+
+	return overlay
+}
+
+func mergeSequence(base, overlay []interface{}, appendUnnamed bool) []interface{} {
+
+	if !namedSequence(base) || !namedSequence(overlay) {
+		if appendUnnamed {
+			merged := make([]interface{}, 0, len(base)+len(overlay))
+			merged = append(merged, base...)
+			return append(merged, overlay...)
+		}
+		return overlay
+	}
+
+	merged := make([]interface{}, len(base))
+	copy(merged, base)
+
+	for _, item := range overlay {
+		itemDoc := item.(yaml.MapSlice)
+		name := getKey(itemDoc, "name")
+
+		found := false
+		for i, mergedItem := range merged {
+			mergedDoc := mergedItem.(yaml.MapSlice)
+			if getKey(mergedDoc, "name") == name {
+				merged[i] = mergeMapSlice(mergedDoc, itemDoc, appendUnnamed)
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			merged = append(merged, itemDoc)
+		}
+	}
+
+	return merged
+}
+
+// namedSequence is true if every item is a mapping with a name
+func namedSequence(items []interface{}) bool {
+	for _, item := range items {
+		itemDoc, ok := item.(yaml.MapSlice)
+		if !ok || !hasKey(itemDoc, "name") {
+			return false
+		}
+	}
+	return true
+}
+
+func indexOfKey(doc yaml.MapSlice, key interface{}) int {
+	for i, item := range doc {
+		if item.Key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func hasKey(doc yaml.MapSlice, key string) bool {
+	return indexOfKey(doc, key) != -1
+}
+
+func getKey(doc yaml.MapSlice, key string) interface{} {
+	if i := indexOfKey(doc, key); i != -1 {
+		return doc[i].Value
+	}
+	return nil
+}
+
+func getString(doc yaml.MapSlice, key string) (string, bool) {
+	if i := indexOfKey(doc, key); i != -1 && doc[i].Value != nil {
+		return fmt.Sprint(doc[i].Value), true
+	}
+	return "", false
+}
+
+func setKey(doc yaml.MapSlice, key string, value interface{}) yaml.MapSlice {
+	set := make(yaml.MapSlice, len(doc))
+	copy(set, doc)
+
+	if i := indexOfKey(set, key); i != -1 {
+		set[i].Value = value
+		return set
+	}
+	return append(set, yaml.MapItem{Key: key, Value: value})
+}
+
+func removeKey(doc yaml.MapSlice, key string) yaml.MapSlice {
+	removed := make(yaml.MapSlice, 0, len(doc))
+	for _, item := range doc {
+		if item.Key != key {
+			removed = append(removed, item)
+		}
+	}
+	return removed
+}
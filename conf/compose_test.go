@@ -0,0 +1,155 @@
+package conf_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v2"
+
+	"github.com/adobe-platform/porter/conf"
+)
+
+var _ = Describe("Compose", func() {
+
+	var dir, configPath string
+
+	write := func(path, contents string) {
+		Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(path, []byte(contents), 0644)).To(Succeed())
+	}
+
+	compose := func() *conf.Config {
+		configBytes, err := conf.Compose(configPath)
+		Expect(err).To(BeNil())
+
+		config := &conf.Config{}
+		Expect(yaml.Unmarshal(configBytes, config)).To(Succeed())
+		return config
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "porter-compose")
+		Expect(err).To(BeNil())
+		configPath = filepath.Join(dir, "config")
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("resolves extends and vars", func() {
+		os.Setenv("PORTER_COMPOSE_TEST_ACCOUNT", "123456789012")
+		defer os.Unsetenv("PORTER_COMPOSE_TEST_ACCOUNT")
+
+		write(configPath, `
+service_name: foo
+vars:
+  role: arn:aws:iam::${PORTER_COMPOSE_TEST_ACCOUNT}:role/deploy
+  count: 3
+templates:
+  environments:
+  - name: base
+    instance_type: m4.large
+    role_arn: ${role}
+    regions:
+    - name: us-west-2
+      instance_count: ${count}
+      elb: base-elb
+  regions:
+  - name: east
+    instance_count: 1
+    elb: east-elb
+environments:
+- name: stage
+  extends: base
+  instance_type: t2.small
+- name: prod
+  extends: base
+  regions:
+  - name: us-west-2
+    elb: prod-elb
+  - name: us-east-1
+    extends: east
+`)
+
+		config := compose()
+		Expect(config.Environments).To(HaveLen(2))
+
+		stage := config.Environments[0]
+		Expect(stage.Name).To(Equal("stage"))
+		Expect(stage.InstanceType).To(Equal("t2.small"))
+		Expect(stage.RoleARN).To(Equal("arn:aws:iam::123456789012:role/deploy"))
+		Expect(stage.Regions).To(HaveLen(1))
+		Expect(stage.Regions[0].InstanceCount).To(BeEquivalentTo(3))
+
+		prod := config.Environments[1]
+		Expect(prod.InstanceType).To(Equal("m4.large"))
+		Expect(prod.Regions).To(HaveLen(2))
+		Expect(prod.Regions[0].ELB).To(Equal("prod-elb"))
+		Expect(prod.Regions[0].InstanceCount).To(BeEquivalentTo(3))
+		Expect(prod.Regions[1].Name).To(Equal("us-east-1"))
+		Expect(prod.Regions[1].ELB).To(Equal("east-elb"))
+	})
+
+	It("merges fragments in lexical order", func() {
+		write(configPath, `
+service_name: foo
+environments:
+- name: stage
+  instance_type: t2.small
+`)
+		write(filepath.Join(dir, "config.d", "20-prod.yaml"), `
+environments:
+- name: prod
+  instance_type: m4.large
+`)
+		write(filepath.Join(dir, "config.d", "10-stage.yml"), `
+environments:
+- name: stage
+  instance_type: t2.medium
+`)
+		write(filepath.Join(dir, "config.d", "README.md"), `ignored`)
+
+		config := compose()
+		Expect(config.Environments).To(HaveLen(2))
+		Expect(config.Environments[0].InstanceType).To(Equal("t2.medium"))
+		Expect(config.Environments[1].Name).To(Equal("prod"))
+	})
+
+	It("points at the file and line of errors", func() {
+		write(configPath, `service_name: foo
+environments:
+- name: stage
+  instance_type: ${undefined_porter_var}
+`)
+		_, err := conf.Compose(configPath)
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(HavePrefix(configPath + ":4:"))
+
+		write(configPath, `service_name: foo
+environments:
+- name: stage
+  extends: nope
+`)
+		_, err = conf.Compose(configPath)
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(HavePrefix(configPath + ":4:"))
+	})
+
+	It("detects cycles", func() {
+		write(configPath, `service_name: foo
+environments:
+- name: a
+  extends: b
+- name: b
+  extends: a
+`)
+		_, err := conf.Compose(configPath)
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("cycle"))
+	})
+})
@@ -31,9 +31,57 @@ import (
 )
 
 const (
-	Topology_Inet   = "inet"
-	Topology_Worker = "worker"
-	Topology_Cron   = "cron"
+	Topology_Inet    = "inet"
+	Topology_Worker  = "worker"
+	Topology_Cron    = "cron"
+	Topology_Sidecar = "sidecar"
+)
+
+const (
+	Condition_Started = "started"
+	Condition_Healthy = "healthy"
+)
+
+const (
+	HealthCheck_HTTP = "http"
+	HealthCheck_TCP  = "tcp"
+)
+
+const (
+	Notification_Webhook   = "webhook"
+	Notification_Slack     = "slack"
+	Notification_Teams     = "teams"
+	Notification_PagerDuty = "pagerduty"
+	Notification_Email     = "email"
+
+	Outcome_Success = "success"
+	Outcome_Failure = "failure"
+)
+
+const (
+	Promotion_All    = "all"
+	Promotion_Canary = "canary"
+)
+
+const (
+	SpotAllocation_LowestPrice       = "lowest-price"
+	SpotAllocation_CapacityOptimized = "capacity-optimized"
+)
+
+const (
+	TargetGroup_Application = "application"
+	TargetGroup_Network     = "network"
+)
+
+const (
+	ScalingMetric_CPU          = "cpu"
+	ScalingMetric_RequestCount = "request_count_per_target"
+)
+
+const (
+	ScalingAdjustment_Change  = "ChangeInCapacity"
+	ScalingAdjustment_Percent = "PercentChangeInCapacity"
+	ScalingAdjustment_Exact   = "ExactCapacity"
 )
 
 // NOTE: It's important to keep a reserved character so that if any of these
@@ -52,6 +100,10 @@ var (
 	commonNameRegex      = regexp.MustCompile(`^(\w+\.)?[a-z]+$`)
 	regionRegex          = regexp.MustCompile(`^[a-z]{2}-[a-z]+-\d$`)
 	instanceTypeRegex    = regexp.MustCompile(`^[a-z0-9]{2}\.[a-z0-9]+$`)
+	targetGroupARNRegex  = regexp.MustCompile(`^arn:aws[-a-z]*:elasticloadbalancing:[-a-z0-9]+:\d+:targetgroup/`)
+	listenerARNRegex     = regexp.MustCompile(`^arn:aws[-a-z]*:elasticloadbalancing:[-a-z0-9]+:\d+:listener/`)
+	scalingNameRegex     = regexp.MustCompile(`^[0-9a-zA-Z]+$`)
+	recurrenceRegex      = regexp.MustCompile(`^\S+ \S+ \S+ \S+ \S+$`)
 
 	// https://github.com/docker/docker/blob/v1.11.2/utils/names.go#L6
 	// minus '-' which is reserved
@@ -66,8 +118,12 @@ type (
 		PorterVersion  string            `yaml:"porter_version"`
 		Environments   []*Environment    `yaml:"environments"`
 		Slack          Slack             `yaml:"slack"`
+		Notifications  []*Notification   `yaml:"notifications"`
 		Hooks          map[string][]Hook `yaml:"hooks"`
 
+		// Run in order on each region's template after porter's own changes
+		TemplateTransforms []*TemplateTransform `yaml:"template_transforms"`
+
 		HAProxyStatsUsername string
 		HAProxyStatsPassword string
 	}
@@ -85,6 +141,62 @@ type (
 		HealthCheck            *HealthCheck `yaml:"health_check"`
 		SrcEnvFile             *SrcEnvFile  `yaml:"src_env_file"`
 		PidsLimit              int          `yaml:"pids_limit"`
+
+		// docker run settings
+		Memory            string            `yaml:"memory"`
+		MemoryReservation string            `yaml:"memory_reservation"`
+		CPUs              float64           `yaml:"cpus"`
+		CPUShares         int               `yaml:"cpu_shares"`
+		CapAdd            []string          `yaml:"cap_add"`
+		CapDrop           []string          `yaml:"cap_drop"`
+		Tmpfs             []*Tmpfs          `yaml:"tmpfs"`
+		Volumes           []*Volume         `yaml:"volumes"`
+		Environment       map[string]string `yaml:"environment"`
+		LogDriver         string            `yaml:"log_driver"`
+		LogOptions        map[string]string `yaml:"log_options"`
+		Ulimits           []*Ulimit         `yaml:"ulimits"`
+
+		DependsOn []*Dependency `yaml:"depends_on"`
+		Aliases   []string      `yaml:"aliases"`
+
+		// Routing sends matching requests to an inet container's own HAProxy
+		// backend. Unrouted inet containers share the default backend
+		Routing *Routing `yaml:"routing"`
+	}
+
+	// Routing matches requests by any of Hosts and by PathPrefix. Both must
+	// match if both are defined
+	Routing struct {
+		Hosts       []string `yaml:"hosts"`
+		PathPrefix  string   `yaml:"path_prefix"`
+		StripPrefix bool     `yaml:"strip_prefix"`
+	}
+
+	// Dependency is a container that must reach a condition before the
+	// dependent container is started
+	Dependency struct {
+		Container string `yaml:"container"`
+		Condition string `yaml:"condition"`
+	}
+
+	// Tmpfs is a `docker run --tmpfs` mount
+	Tmpfs struct {
+		Path string `yaml:"path"`
+		Size string `yaml:"size"`
+		Mode string `yaml:"mode"`
+	}
+
+	// Volume mounts a host directory or file into a container
+	Volume struct {
+		HostPath      string `yaml:"host_path"`
+		ContainerPath string `yaml:"container_path"`
+		ReadOnly      *bool  `yaml:"read_only"`
+	}
+
+	Ulimit struct {
+		Name string `yaml:"name"`
+		Soft int64  `yaml:"soft"`
+		Hard int64  `yaml:"hard"`
 	}
 
 	SrcEnvFile struct {
@@ -96,8 +208,17 @@ type (
 	}
 
 	HealthCheck struct {
-		Method string `yaml:"method"`
-		Path   string `yaml:"path"`
+		Method string `yaml:"method" json:"method"`
+		Path   string `yaml:"path" json:"path"`
+
+		Type               string            `yaml:"type" json:"type"`
+		ReadinessPath      string            `yaml:"readiness_path" json:"readinessPath"`
+		Headers            map[string]string `yaml:"headers" json:"headers"`
+		ExpectedStatus     string            `yaml:"expected_status" json:"expectedStatus"`
+		Interval           int               `yaml:"interval" json:"interval"`
+		Timeout            int               `yaml:"timeout" json:"timeout"`
+		HealthyThreshold   int               `yaml:"healthy_threshold" json:"healthyThreshold"`
+		UnhealthyThreshold int               `yaml:"unhealthy_threshold" json:"unhealthyThreshold"`
 	}
 
 	Environment struct {
@@ -107,10 +228,25 @@ type (
 		Hotswap             bool             `yaml:"hot_swap"`
 		InstanceCount       uint             `yaml:"instance_count"`
 		InstanceType        string           `yaml:"instance_type"`
+		LaunchTemplate      bool             `yaml:"launch_template"`
+		AutoScaling         *AutoScaling     `yaml:"auto_scaling"`
 		BlackoutWindows     []BlackoutWindow `yaml:"blackout_windows"`
 		Regions             []*Region        `yaml:"regions"`
 
-		HAProxy HAProxy `yaml:"haproxy"`
+		HAProxy   HAProxy   `yaml:"haproxy"`
+		Promotion Promotion `yaml:"promotion"`
+
+		// Values for CloudFormation template Parameters. A region's
+		// parameters override the environment's
+		Parameters map[string]*Parameter `yaml:"parameters"`
+
+		// The number of regions that must provision successfully for a
+		// deployment to proceed. 0 means every region
+		MinSuccessfulRegions int `yaml:"min_successful_regions"`
+
+		// The image EC2 instances launch from. A region's AMI overrides the
+		// environment's
+		AMI *AMI `yaml:"ami"`
 
 		// From the client's perspective this relates to SG creation and ELB
 		// inspection that allows the 2 ELBs to communicate with EC2 instances.
@@ -163,9 +299,43 @@ type (
 		Length int    `yaml:"length"`
 	}
 
+	// Promotion controls how instances of a newly provisioned stack are
+	// registered with the destination ELB by `porter build promote`
+	Promotion struct {
+		Strategy string `yaml:"strategy"`
+
+		// Cumulative percentages of the new stack's instances registered at
+		// each step. 100 is implied as the last step
+		CanarySteps []int `yaml:"canary_steps"`
+
+		// How long to watch instance health and error counts after each step
+		BakeTime  string `yaml:"bake_time"`
+		BakeTime_ time.Duration
+
+		// Rollback if the destination ELB's HTTPCode_Backend_5XX sum during a
+		// bake period exceeds this
+		MaxBackend5XX int `yaml:"max_backend_5xx"`
+	}
+
 	BlackoutWindow struct {
+		Name   string `yaml:"name"`
+		Reason string `yaml:"reason"`
+
+		// absolute windows
 		StartTime string `yaml:"start_time"`
 		EndTime   string `yaml:"end_time"`
+
+		// recurring windows that start on a cron schedule
+		Cron     string `yaml:"cron"`
+		Duration string `yaml:"duration"`
+
+		// recurring windows between two times of day
+		Days []string `yaml:"days"`
+		From string   `yaml:"from"`
+		To   string   `yaml:"to"`
+
+		// IANA time zone for recurring windows. The default is UTC
+		Timezone string `yaml:"timezone"`
 	}
 
 	Region struct {
@@ -175,6 +345,7 @@ type (
 		AZs                 []AvailabilityZone `yaml:"azs"`
 		ELBs                []*ELB             `yaml:"elbs"`
 		ELB                 string             `yaml:"elb"`
+		TargetGroups        []*TargetGroup     `yaml:"target_groups"`
 		RoleARN             string             `yaml:"role_arn"`
 		AutoScalingGroup    AutoScalingGroup   `yaml:"auto_scaling_group"`
 		SSLCertARN          string             `yaml:"ssl_cert_arn"`
@@ -184,6 +355,121 @@ type (
 		SSEKMSKeyId         *string            `yaml:"sse_kms_key_id"`
 		Containers          []*Container       `yaml:"containers"`
 		InstanceCount       uint               `yaml:"instance_count"`
+		AutoScaling         *AutoScaling       `yaml:"auto_scaling"`
+
+		MixedInstancesPolicy *MixedInstancesPolicy `yaml:"mixed_instances_policy"`
+
+		Parameters map[string]*Parameter `yaml:"parameters"`
+
+		AMI *AMI `yaml:"ami"`
+	}
+
+	// AMI is the image EC2 instances launch from. Exactly one of ID,
+	// SSMParameter, or Filter is set. It's resolved to an AMI id at provision
+	// time
+	AMI struct {
+		ID           string     `yaml:"id"`
+		SSMParameter string     `yaml:"ssm_parameter"`
+		Filter       *AMIFilter `yaml:"filter"`
+
+		// Prebaked is set for images made by porter bootstrap ami. Instances
+		// skip the install steps that are already baked in
+		Prebaked bool `yaml:"prebaked"`
+	}
+
+	// AMIFilter selects the newest image owned by Owner whose name matches
+	// Name which can contain * wildcards
+	AMIFilter struct {
+		Owner string `yaml:"owner"`
+		Name  string `yaml:"name"`
+	}
+
+	// Parameter is either a literal value or the stdout of secrets_exec_name.
+	// Secret values are marked NoEcho in the template
+	Parameter struct {
+		Value           string   `yaml:"value"`
+		SecretsExecName string   `yaml:"secrets_exec_name"`
+		SecretsExecArgs []string `yaml:"secrets_exec_args"`
+	}
+
+	// AutoScaling lets an ASG scale between MinSize and MaxSize.
+	// instance_count is the DesiredCapacity a new stack starts with
+	AutoScaling struct {
+		MinSize uint `yaml:"min_size"`
+		MaxSize uint `yaml:"max_size"`
+
+		TargetTracking   []*TargetTrackingPolicy `yaml:"target_tracking"`
+		StepScaling      []*StepScalingPolicy    `yaml:"step_scaling"`
+		ScheduledActions []*ScheduledAction      `yaml:"scheduled_actions"`
+	}
+
+	TargetTrackingPolicy struct {
+		Name           string  `yaml:"name"`
+		Metric         string  `yaml:"metric"`
+		TargetValue    float64 `yaml:"target_value"`
+		DisableScaleIn bool    `yaml:"disable_scale_in"`
+
+		// Seconds until a newly launched instance contributes to the metric
+		EstimatedInstanceWarmup int `yaml:"estimated_instance_warmup"`
+	}
+
+	StepScalingPolicy struct {
+		Name                    string         `yaml:"name"`
+		AdjustmentType          string         `yaml:"adjustment_type"`
+		MetricAggregationType   string         `yaml:"metric_aggregation_type"`
+		EstimatedInstanceWarmup int            `yaml:"estimated_instance_warmup"`
+		Steps                   []*ScalingStep `yaml:"steps"`
+		Alarm                   ScalingAlarm   `yaml:"alarm"`
+	}
+
+	// ScalingStep bounds are relative to the alarm threshold
+	ScalingStep struct {
+		LowerBound *float64 `yaml:"lower_bound"`
+		UpperBound *float64 `yaml:"upper_bound"`
+		Adjustment int      `yaml:"adjustment"`
+	}
+
+	// ScalingAlarm is the CloudWatch alarm that triggers a step scaling
+	// policy. Without dimensions an AWS/EC2 metric is scoped to the ASG
+	ScalingAlarm struct {
+		Namespace          string            `yaml:"namespace"`
+		MetricName         string            `yaml:"metric_name"`
+		Statistic          string            `yaml:"statistic"`
+		Dimensions         map[string]string `yaml:"dimensions"`
+		Period             int               `yaml:"period"`
+		EvaluationPeriods  int               `yaml:"evaluation_periods"`
+		Threshold          float64           `yaml:"threshold"`
+		ComparisonOperator string            `yaml:"comparison_operator"`
+	}
+
+	// ScheduledAction changes the size of an ASG on a cron-style schedule
+	// evaluated in UTC. Unset sizes are left unchanged
+	ScheduledAction struct {
+		Name            string `yaml:"name"`
+		Recurrence      string `yaml:"recurrence"`
+		StartTime       string `yaml:"start_time"`
+		EndTime         string `yaml:"end_time"`
+		MinSize         *uint  `yaml:"min_size"`
+		MaxSize         *uint  `yaml:"max_size"`
+		DesiredCapacity *uint  `yaml:"desired_capacity"`
+	}
+
+	// MixedInstancesPolicy spreads an ASG across instance types and Spot.
+	// Defining one implies a launch template
+	MixedInstancesPolicy struct {
+		InstanceTypes []string `yaml:"instance_types"`
+
+		// On-Demand instances launched before any Spot instances are
+		OnDemandBaseCapacity int `yaml:"on_demand_base_capacity"`
+
+		// Percentage of instances above the base capacity that are Spot
+		SpotPercentage int `yaml:"spot_percentage"`
+
+		SpotAllocationStrategy string `yaml:"spot_allocation_strategy"`
+		SpotInstancePools      int    `yaml:"spot_instance_pools"`
+
+		// Defaults to the On-Demand price
+		SpotMaxPrice string `yaml:"spot_max_price"`
 	}
 
 	AutoScalingGroup struct {
@@ -214,6 +500,19 @@ type (
 		RunCondition string            `yaml:"run_condition"`
 	}
 
+	// TemplateTransform is an executable or a Dockerfile that reads a
+	// CloudFormation template and deployment context as JSON on stdin and
+	// writes the modified template to stdout
+	TemplateTransform struct {
+		ExecName string   `yaml:"exec_name"`
+		ExecArgs []string `yaml:"exec_args"`
+
+		Repo        string            `yaml:"repo"`
+		Ref         string            `yaml:"ref"`
+		Dockerfile  string            `yaml:"dockerfile"`
+		Environment map[string]string `yaml:"environment"`
+	}
+
 	Slack struct {
 		PackSuccessHook      string `yaml:"pack_success_webhook_url"`
 		PackFailureHook      string `yaml:"pack_failure_webhook_url"`
@@ -223,16 +522,130 @@ type (
 		PromoteFailureHook   string `yaml:"promote_failure_webhook_url"`
 	}
 
+	// Notification is a sink for `porter build notify`
+	Notification struct {
+		Type string `yaml:"type"`
+
+		// Build phases and outcomes this sink receives. Empty means all
+		Phases   []string `yaml:"phases"`
+		Outcomes []string `yaml:"outcomes"`
+
+		// A text/template for the message. See notify.Context for the fields
+		Template string `yaml:"template"`
+
+		// webhook, teams, and slack
+		URL     string            `yaml:"url"`
+		Headers map[string]string `yaml:"headers"`
+
+		// pagerduty
+		RoutingKeyEnv string `yaml:"routing_key_env"`
+		Severity      string `yaml:"severity"`
+
+		// email
+		SMTP *SMTP `yaml:"smtp"`
+	}
+
+	SMTP struct {
+		Host        string   `yaml:"host"`
+		Port        int      `yaml:"port"`
+		Username    string   `yaml:"username"`
+		PasswordEnv string   `yaml:"password_env"`
+		From        string   `yaml:"from"`
+		To          []string `yaml:"to"`
+		Subject     string   `yaml:"subject"`
+	}
+
 	ELB struct {
 		ELBTag string `yaml:"tag"`
 		Name   string `yaml:"name"`
 	}
+
+	// TargetGroup is the ELBv2 (ALB/NLB) counterpart to ELB. Promotion either
+	// registers instances into the target group ARN, or if ListenerARN is
+	// defined, swaps the listener's default action to the provisioned stack's
+	// target group
+	TargetGroup struct {
+		Tag         string `yaml:"tag"`
+		ARN         string `yaml:"arn"`
+		ListenerARN string `yaml:"listener_arn"`
+
+		// application (ALB) or network (NLB)
+		Type string `yaml:"type"`
+
+		// NLBs preserve the client IP so instances allow these CIDRs directly
+		IngressCIDRs []string `yaml:"ingress_cidrs"`
+	}
 )
 
 func (recv HAProxy) UsingSSL() bool {
 	return recv.SSL.Pem != nil
 }
 
+// The slack block predates notifications. Each webhook URL maps to a slack
+// notification for a single phase and outcome
+func (recv Slack) notifications() []*Notification {
+	notifications := make([]*Notification, 0)
+
+	hooks := []struct {
+		url, phase, outcome string
+	}{
+		{recv.PackSuccessHook, "pack", Outcome_Success},
+		{recv.PackFailureHook, "pack", Outcome_Failure},
+		{recv.ProvisionSuccessHook, "provision", Outcome_Success},
+		{recv.ProvisionFailureHook, "provision", Outcome_Failure},
+		{recv.PromoteSuccessHook, "promote", Outcome_Success},
+		{recv.PromoteFailureHook, "promote", Outcome_Failure},
+	}
+
+	for _, hook := range hooks {
+		if hook.url == "" {
+			continue
+		}
+
+		notifications = append(notifications, &Notification{
+			Type:     Notification_Slack,
+			Phases:   []string{hook.phase},
+			Outcomes: []string{hook.outcome},
+			URL:      hook.url,
+		})
+	}
+
+	return notifications
+}
+
+func (recv *AutoScaling) setDefaults() {
+	if recv == nil {
+		return
+	}
+
+	for _, policy := range recv.StepScaling {
+
+		if policy.AdjustmentType == "" {
+			policy.AdjustmentType = ScalingAdjustment_Change
+		}
+
+		if policy.MetricAggregationType == "" {
+			policy.MetricAggregationType = "Average"
+		}
+
+		if policy.Alarm.Namespace == "" {
+			policy.Alarm.Namespace = "AWS/EC2"
+		}
+
+		if policy.Alarm.Statistic == "" {
+			policy.Alarm.Statistic = "Average"
+		}
+
+		if policy.Alarm.Period == 0 {
+			policy.Alarm.Period = 60
+		}
+
+		if policy.Alarm.EvaluationPeriods == 0 {
+			policy.Alarm.EvaluationPeriods = 1
+		}
+	}
+}
+
 func (recv *Config) GetEnvironment(envName string) (*Environment, error) {
 	for _, env := range recv.Environments {
 		if env.Name == envName {
@@ -255,6 +668,19 @@ func (recv *Config) SetDefaults() {
 		}
 	}
 
+	recv.Notifications = append(recv.Notifications, recv.Slack.notifications()...)
+
+	for _, notification := range recv.Notifications {
+
+		if notification.Type == Notification_PagerDuty && notification.Severity == "" {
+			notification.Severity = "error"
+		}
+
+		if notification.SMTP != nil && notification.SMTP.Port == 0 {
+			notification.SMTP.Port = 25
+		}
+	}
+
 	for _, env := range recv.Environments {
 		if env.InstanceCount == 0 {
 			env.InstanceCount = 1
@@ -299,6 +725,23 @@ func (recv *Config) SetDefaults() {
 			*env.HAProxy.Timeout.HttpKeepAlive = "60s"
 		}
 
+		if env.Promotion.Strategy == "" {
+			env.Promotion.Strategy = Promotion_All
+		}
+
+		env.AutoScaling.setDefaults()
+
+		if env.Promotion.Strategy == Promotion_Canary {
+
+			if len(env.Promotion.CanarySteps) == 0 {
+				env.Promotion.CanarySteps = []int{10, 50}
+			}
+
+			if env.Promotion.BakeTime == "" {
+				env.Promotion.BakeTime = "5m"
+			}
+		}
+
 		env.HAProxy.SSL.CertPath = filepath.Join(env.HAProxy.SSL.CertDirectory, "porter.pem")
 
 		// this is only for porterd which isn't currently informed of HTTPS_Only
@@ -355,11 +798,31 @@ func (recv *Config) SetDefaults() {
 				region.ELB = region.ELBs[0].Name
 			}
 
+			for _, targetGroup := range region.TargetGroups {
+				if targetGroup.Type == "" {
+					targetGroup.Type = TargetGroup_Application
+				}
+			}
+
+			region.AutoScaling.setDefaults()
+
 			if len(region.Containers) == 0 {
 				defaultContainer := &Container{}
 				region.Containers = append(region.Containers, defaultContainer)
 			}
 
+			// before the loop below so a lone container gets inet defaults
+			if len(region.Containers) == 1 {
+
+				if region.Containers[0].Topology == "" {
+					region.Containers[0].Topology = Topology_Inet
+				}
+
+				if region.Containers[0].Name == "" {
+					region.Containers[0].Name = "primary"
+				}
+			}
+
 			for _, container := range region.Containers {
 
 				if container.Dockerfile == "" {
@@ -373,28 +836,40 @@ func (recv *Config) SetDefaults() {
 					container.PidsLimit = 4096
 				}
 
-				if container.Topology == Topology_Inet {
-
-					if container.HealthCheck == nil {
-						container.HealthCheck = &HealthCheck{}
-					}
-					if container.HealthCheck.Method == "" {
-						container.HealthCheck.Method = "GET"
-					}
-					if container.HealthCheck.Path == "" {
-						container.HealthCheck.Path = "/health"
+				for _, dependency := range container.DependsOn {
+					if dependency.Condition == "" {
+						dependency.Condition = Condition_Started
 					}
 				}
-			}
 
-			if len(region.Containers) == 1 {
+				// CIS Docker Benchmark 1.11.0 5.3
+				if container.CapDrop == nil {
+					container.CapDrop = []string{"ALL"}
+				}
 
-				if region.Containers[0].Topology == "" {
-					region.Containers[0].Topology = Topology_Inet
+				var definesNofile bool
+				for _, ulimit := range container.Ulimits {
+					if ulimit.Hard == 0 {
+						ulimit.Hard = ulimit.Soft
+					}
+					if ulimit.Name == "nofile" {
+						definesNofile = true
+					}
+				}
+				if !definesNofile {
+					container.Ulimits = append(container.Ulimits, &Ulimit{
+						Name: "nofile",
+						Soft: 200000,
+						Hard: 200000,
+					})
 				}
 
-				if region.Containers[0].Name == "" {
-					region.Containers[0].Name = "primary"
+				if container.Topology == Topology_Inet {
+
+					if container.HealthCheck == nil {
+						container.HealthCheck = &HealthCheck{}
+					}
+					container.HealthCheck.setDefaults()
 				}
 			}
 		}
@@ -410,6 +885,15 @@ func (recv *Config) Print() {
 		printHooks(hookName, hookVal)
 	}
 
+	fmt.Println(".TemplateTransforms")
+	for _, transform := range recv.TemplateTransforms {
+		fmt.Println("- .ExecName", transform.ExecName)
+		fmt.Println("  .ExecArgs", transform.ExecArgs)
+		fmt.Println("  .Repo", transform.Repo)
+		fmt.Println("  .Ref", transform.Ref)
+		fmt.Println("  .Dockerfile", transform.Dockerfile)
+	}
+
 	fmt.Println(".Environments")
 	for _, environment := range recv.Environments {
 		fmt.Println("- .Name", environment.Name)
@@ -417,6 +901,17 @@ func (recv *Config) Print() {
 		fmt.Println("  .RoleARN", environment.RoleARN)
 		fmt.Println("  .InstanceCount", environment.InstanceCount)
 		fmt.Println("  .InstanceType", environment.InstanceType)
+		fmt.Println("  .LaunchTemplate", environment.LaunchTemplate)
+		if environment.AutoScaling != nil {
+			fmt.Println("  .AutoScaling.MinSize", environment.AutoScaling.MinSize)
+			fmt.Println("  .AutoScaling.MaxSize", environment.AutoScaling.MaxSize)
+		}
+		fmt.Println("  .Promotion.Strategy", environment.Promotion.Strategy)
+		if environment.Promotion.Strategy == Promotion_Canary {
+			fmt.Println("  .Promotion.CanarySteps", environment.Promotion.CanarySteps)
+			fmt.Println("  .Promotion.BakeTime", environment.Promotion.BakeTime)
+			fmt.Println("  .Promotion.MaxBackend5XX", environment.Promotion.MaxBackend5XX)
+		}
 
 		fmt.Println("  .Regions")
 		for _, region := range environment.Regions {
@@ -426,6 +921,18 @@ func (recv *Config) Print() {
 			fmt.Println("    .KeyPairName", region.KeyPairName)
 			fmt.Println("    .S3Bucket", region.S3Bucket)
 
+			if region.AutoScaling != nil {
+				fmt.Println("    .AutoScaling.MinSize", region.AutoScaling.MinSize)
+				fmt.Println("    .AutoScaling.MaxSize", region.AutoScaling.MaxSize)
+			}
+
+			if region.MixedInstancesPolicy != nil {
+				fmt.Println("    .MixedInstancesPolicy.InstanceTypes", region.MixedInstancesPolicy.InstanceTypes)
+				fmt.Println("    .MixedInstancesPolicy.OnDemandBaseCapacity", region.MixedInstancesPolicy.OnDemandBaseCapacity)
+				fmt.Println("    .MixedInstancesPolicy.SpotPercentage", region.MixedInstancesPolicy.SpotPercentage)
+				fmt.Println("    .MixedInstancesPolicy.SpotAllocationStrategy", region.MixedInstancesPolicy.SpotAllocationStrategy)
+			}
+
 			fmt.Println("      .AZs")
 			for _, az := range region.AZs {
 				fmt.Println("      - .Name", az.Name)
@@ -440,6 +947,14 @@ func (recv *Config) Print() {
 				fmt.Println("        .Name", elb.Name)
 			}
 
+			fmt.Println("      .TargetGroups")
+			for _, targetGroup := range region.TargetGroups {
+				fmt.Println("      - .Tag", targetGroup.Tag)
+				fmt.Println("        .ARN", targetGroup.ARN)
+				fmt.Println("        .ListenerARN", targetGroup.ListenerARN)
+				fmt.Println("        .Type", targetGroup.Type)
+			}
+
 			fmt.Println("      .Containers")
 			for _, container := range region.Containers {
 				fmt.Println("      - .Name", container.Name)
@@ -510,19 +1025,34 @@ func GetHostConfig(log log15.Logger) (*Config, bool) {
 }
 
 func getConfigFile(log log15.Logger, filePath string, setDefaults, validate bool) (config *Config, success bool) {
-	var parseConfigSuccess bool
+	var (
+		parseConfigSuccess bool
+		configBytes        []byte
+		err                error
+	)
 
-	file, err := os.Open(filePath)
-	if err != nil {
-		log.Error("os.Open", "FilePath", filePath, "Error", err)
-		return
-	}
-	defer file.Close()
+	// the altered and host configs were composed during pack
+	if filePath == constants.ConfigPath {
 
-	configBytes, err := ioutil.ReadAll(file)
-	if err != nil {
-		log.Error("ioutil.ReadAll", "FilePath", filePath, "Error", err)
-		return
+		configBytes, err = Compose(filePath)
+		if err != nil {
+			log.Error("Config composition", "Error", err)
+			return
+		}
+	} else {
+
+		file, err := os.Open(filePath)
+		if err != nil {
+			log.Error("os.Open", "FilePath", filePath, "Error", err)
+			return
+		}
+		defer file.Close()
+
+		configBytes, err = ioutil.ReadAll(file)
+		if err != nil {
+			log.Error("ioutil.ReadAll", "FilePath", filePath, "Error", err)
+			return
+		}
 	}
 
 	config, parseConfigSuccess = parseConfig(log, configBytes)
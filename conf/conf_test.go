@@ -0,0 +1,53 @@
+package conf_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v2"
+
+	"github.com/adobe-platform/porter/conf"
+)
+
+var _ = Describe("SetDefaults", func() {
+
+	It("gives a lone container without a topology the inet health check defaults", func() {
+		config := &conf.Config{}
+		Expect(yaml.Unmarshal([]byte(`
+service_name: foo
+environments:
+- name: stage
+  regions:
+  - name: us-west-2
+`), config)).To(Succeed())
+
+		config.SetDefaults()
+
+		containers := config.Environments[0].Regions[0].Containers
+		Expect(containers).To(HaveLen(1))
+		Expect(containers[0].Name).To(Equal("primary"))
+		Expect(containers[0].Topology).To(Equal(conf.Topology_Inet))
+		Expect(containers[0].HealthCheck).ToNot(BeNil())
+		Expect(containers[0].HealthCheck.Method).To(Equal("GET"))
+		Expect(containers[0].HealthCheck.Path).To(Equal("/health"))
+	})
+
+	It("leaves a lone worker without a health check", func() {
+		config := &conf.Config{}
+		Expect(yaml.Unmarshal([]byte(`
+service_name: foo
+environments:
+- name: stage
+  regions:
+  - name: us-west-2
+    containers:
+    - name: worker
+      topology: worker
+`), config)).To(Succeed())
+
+		config.SetDefaults()
+
+		containers := config.Environments[0].Regions[0].Containers
+		Expect(containers[0].Topology).To(Equal(conf.Topology_Worker))
+		Expect(containers[0].HealthCheck).To(BeNil())
+	})
+})
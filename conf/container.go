@@ -0,0 +1,170 @@
+/*
+ * (c) 2016-2018 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+package conf
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	memoryRegex       = regexp.MustCompile(`^(\d+)([bkmgBKMG]?)$`)
+	tmpfsModeRegex    = regexp.MustCompile(`^[0-7]{3,4}$`)
+	networkAliasRegex = regexp.MustCompile(`^[a-zA-Z0-9][-a-zA-Z0-9_.]*$`)
+	routeHostRegex    = regexp.MustCompile(`^[a-zA-Z0-9]([-a-zA-Z0-9.]*[a-zA-Z0-9])?$`)
+	pathPrefixRegex   = regexp.MustCompile(`^(/[-a-zA-Z0-9_.~]+)+$`)
+)
+
+// the minimum docker allows for --memory
+const minContainerMemory = 4 * 1024 * 1024
+
+// capabilities are the Linux capabilities docker accepts in --cap-add and
+// --cap-drop without the CAP_ prefix
+var capabilities = map[string]interface{}{
+	"AUDIT_CONTROL":    nil,
+	"AUDIT_READ":       nil,
+	"AUDIT_WRITE":      nil,
+	"BLOCK_SUSPEND":    nil,
+	"CHOWN":            nil,
+	"DAC_OVERRIDE":     nil,
+	"DAC_READ_SEARCH":  nil,
+	"FOWNER":           nil,
+	"FSETID":           nil,
+	"IPC_LOCK":         nil,
+	"IPC_OWNER":        nil,
+	"KILL":             nil,
+	"LEASE":            nil,
+	"LINUX_IMMUTABLE":  nil,
+	"MAC_ADMIN":        nil,
+	"MAC_OVERRIDE":     nil,
+	"MKNOD":            nil,
+	"NET_ADMIN":        nil,
+	"NET_BIND_SERVICE": nil,
+	"NET_BROADCAST":    nil,
+	"NET_RAW":          nil,
+	"SETFCAP":          nil,
+	"SETGID":           nil,
+	"SETPCAP":          nil,
+	"SETUID":           nil,
+	"SYSLOG":           nil,
+	"SYS_ADMIN":        nil,
+	"SYS_BOOT":         nil,
+	"SYS_CHROOT":       nil,
+	"SYS_MODULE":       nil,
+	"SYS_NICE":         nil,
+	"SYS_PACCT":        nil,
+	"SYS_PTRACE":       nil,
+	"SYS_RAWIO":        nil,
+	"SYS_RESOURCE":     nil,
+	"SYS_TIME":         nil,
+	"SYS_TTY_CONFIG":   nil,
+	"WAKE_ALARM":       nil,
+}
+
+// logDrivers are the log drivers available to the docker version porter
+// installs
+var logDrivers = map[string]interface{}{
+	"awslogs":    nil,
+	"fluentd":    nil,
+	"gcplogs":    nil,
+	"gelf":       nil,
+	"journald":   nil,
+	"json-file":  nil,
+	"logentries": nil,
+	"none":       nil,
+	"splunk":     nil,
+	"syslog":     nil,
+}
+
+var ulimitNames = map[string]interface{}{
+	"core":       nil,
+	"cpu":        nil,
+	"data":       nil,
+	"fsize":      nil,
+	"locks":      nil,
+	"memlock":    nil,
+	"msgqueue":   nil,
+	"nice":       nil,
+	"nofile":     nil,
+	"nproc":      nil,
+	"rss":        nil,
+	"rtprio":     nil,
+	"rttime":     nil,
+	"sigpending": nil,
+	"stack":      nil,
+}
+
+// sensitiveHostPaths can't be mounted into a container
+// CIS Docker Benchmark 1.11.0 5.5
+var sensitiveHostPaths = map[string]interface{}{
+	"/":                    nil,
+	"/boot":                nil,
+	"/dev":                 nil,
+	"/etc":                 nil,
+	"/lib":                 nil,
+	"/proc":                nil,
+	"/sys":                 nil,
+	"/usr":                 nil,
+	"/var/run/docker.sock": nil,
+}
+
+// reservedEnvironment is set by porter on every container
+var reservedEnvironment = map[string]interface{}{
+	"PORTER_ENVIRONMENT": nil,
+	"AWS_REGION":         nil,
+	"RSYSLOG_TCP_ADDR":   nil,
+	"RSYSLOG_TCP_PORT":   nil,
+	"RSYSLOG_UDP_ADDR":   nil,
+	"RSYSLOG_UDP_PORT":   nil,
+	"PORTERD_TCP_ADDR":   nil,
+	"PORTERD_TCP_PORT":   nil,
+}
+
+// ConfigName is the container's name in .porter/config. Name is changed to the
+// image name during pack
+func (recv *Container) ConfigName() string {
+	if recv.OriginalName != "" {
+		return recv.OriginalName
+	}
+	return recv.Name
+}
+
+// Capability normalizes a capability name to what docker expects
+func Capability(name string) string {
+	return strings.TrimPrefix(strings.ToUpper(name), "CAP_")
+}
+
+// parseMemory parses docker's memory format (e.g. 512m) into bytes
+func parseMemory(memory string) (int64, error) {
+	matches := memoryRegex.FindStringSubmatch(memory)
+	if matches == nil {
+		return 0, errors.New("invalid memory " + memory + ". Use a number with an optional unit of b, k, m, or g")
+	}
+
+	value, err := strconv.ParseInt(matches[1], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	switch strings.ToLower(matches[2]) {
+	case "k":
+		value *= 1024
+	case "m":
+		value *= 1024 * 1024
+	case "g":
+		value *= 1024 * 1024 * 1024
+	}
+
+	return value, nil
+}
@@ -0,0 +1,190 @@
+package conf_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/adobe-platform/porter/conf"
+)
+
+var _ = Describe("Container runtime settings", func() {
+
+	var container *conf.Container
+
+	validate := func() error {
+		region := &conf.Region{
+			Containers: []*conf.Container{container},
+		}
+		return region.ValidateContainers()
+	}
+
+	BeforeEach(func() {
+		container = &conf.Container{
+			Name:              "worker",
+			Topology:          conf.Topology_Worker,
+			PidsLimit:         4096,
+			Memory:            "512m",
+			MemoryReservation: "256m",
+			CPUs:              0.5,
+			CapAdd:            []string{"net_bind_service"},
+			CapDrop:           []string{"ALL"},
+			Tmpfs: []*conf.Tmpfs{
+				{Path: "/tmp", Size: "64m", Mode: "1777"},
+			},
+			Volumes: []*conf.Volume{
+				{HostPath: "/var/log/app", ContainerPath: "/logs"},
+			},
+			Environment: map[string]string{"LOG_LEVEL": "info"},
+			LogDriver:   "awslogs",
+			LogOptions:  map[string]string{"awslogs-group": "app"},
+			Ulimits: []*conf.Ulimit{
+				{Name: "nofile", Soft: 1024, Hard: 2048},
+			},
+		}
+	})
+
+	It("accepts valid settings", func() {
+		Expect(validate()).To(BeNil())
+	})
+
+	It("rejects a memory reservation above the limit", func() {
+		container.MemoryReservation = "1g"
+		Expect(validate()).ToNot(BeNil())
+	})
+
+	It("rejects adding all capabilities", func() {
+		container.CapAdd = []string{"ALL"}
+		Expect(validate()).ToNot(BeNil())
+	})
+
+	It("rejects mounting sensitive host paths", func() {
+		container.Volumes[0].HostPath = "/etc/"
+		Expect(validate()).ToNot(BeNil())
+	})
+
+	It("rejects environment variables porter sets", func() {
+		container.Environment["AWS_REGION"] = "us-west-2"
+		Expect(validate()).ToNot(BeNil())
+	})
+
+	It("rejects a soft ulimit above the hard limit", func() {
+		container.Ulimits[0].Soft = 4096
+		Expect(validate()).ToNot(BeNil())
+	})
+})
+
+func healthCheck(path string) *conf.HealthCheck {
+	return &conf.HealthCheck{
+		Type:               conf.HealthCheck_HTTP,
+		Method:             "GET",
+		Path:               path,
+		ExpectedStatus:     "200",
+		Interval:           5,
+		Timeout:            3,
+		HealthyThreshold:   3,
+		UnhealthyThreshold: 2,
+	}
+}
+
+var _ = Describe("Container routing", func() {
+
+	inet := func(name, path string, routing *conf.Routing) *conf.Container {
+		return &conf.Container{
+			Name:        name,
+			Topology:    conf.Topology_Inet,
+			PidsLimit:   4096,
+			HealthCheck: healthCheck(path),
+			Routing:     routing,
+		}
+	}
+
+	It("allows routed containers their own health check", func() {
+		region := &conf.Region{
+			Containers: []*conf.Container{
+				inet("api", "/health", nil),
+				inet("admin", "/ping", &conf.Routing{PathPrefix: "/admin", StripPrefix: true}),
+			},
+		}
+		Expect(region.ValidateContainers()).To(BeNil())
+		Expect(region.HealthCheckPath()).To(Equal("/health"))
+	})
+
+	It("requires an unrouted container for the default backend", func() {
+		region := &conf.Region{
+			Containers: []*conf.Container{
+				inet("admin", "/ping", &conf.Routing{Hosts: []string{"admin.example.com"}}),
+			},
+		}
+		Expect(region.ValidateContainers()).ToNot(BeNil())
+	})
+
+	It("rejects a path_prefix ending with /", func() {
+		region := &conf.Region{
+			Containers: []*conf.Container{
+				inet("api", "/health", nil),
+				inet("admin", "/ping", &conf.Routing{PathPrefix: "/admin/"}),
+			},
+		}
+		Expect(region.ValidateContainers()).ToNot(BeNil())
+	})
+})
+
+var _ = Describe("Container health_check", func() {
+
+	var container *conf.Container
+
+	BeforeEach(func() {
+		container = &conf.Container{
+			Name:        "api",
+			Topology:    conf.Topology_Inet,
+			PidsLimit:   4096,
+			HealthCheck: healthCheck("/health"),
+		}
+	})
+
+	validate := func() error {
+		region := &conf.Region{Containers: []*conf.Container{container}}
+		return region.ValidateContainers()
+	}
+
+	It("accepts a status range", func() {
+		container.HealthCheck.ExpectedStatus = "200-399"
+		Expect(validate()).To(BeNil())
+		Expect(container.HealthCheck.ExpectsStatus(302)).To(BeTrue())
+		Expect(container.HealthCheck.ExpectsStatus(404)).To(BeFalse())
+	})
+
+	It("accepts a list of status codes", func() {
+		container.HealthCheck.ExpectedStatus = "200,204"
+		Expect(validate()).To(BeNil())
+		Expect(container.HealthCheck.ExpectsStatus(204)).To(BeTrue())
+		Expect(container.HealthCheck.ExpectsStatus(201)).To(BeFalse())
+	})
+
+	It("rejects a backwards status range", func() {
+		container.HealthCheck.ExpectedStatus = "399-200"
+		Expect(validate()).ToNot(BeNil())
+	})
+
+	It("rejects a timeout that isn't less than the interval", func() {
+		container.HealthCheck.Timeout = 5
+		Expect(validate()).ToNot(BeNil())
+	})
+
+	It("rejects a path on a tcp check", func() {
+		container.HealthCheck.Type = conf.HealthCheck_TCP
+		container.HealthCheck.Method = ""
+		container.HealthCheck.ExpectedStatus = ""
+		Expect(validate()).ToNot(BeNil())
+
+		container.HealthCheck.Path = ""
+		Expect(validate()).To(BeNil())
+	})
+
+	It("defaults readiness to the liveness path", func() {
+		Expect(container.HealthCheck.Readiness()).To(Equal("/health"))
+
+		container.HealthCheck.ReadinessPath = "/ready"
+		Expect(container.HealthCheck.Readiness()).To(Equal("/ready"))
+	})
+})
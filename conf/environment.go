@@ -39,6 +39,26 @@ func (recv *Environment) GetELBForRegion(reg string, elbTag string) (string, err
 	return "", fmt.Errorf("ELB tagged %s doesn't exist in the config for region %s", elbTag, reg)
 }
 
+func (recv *Environment) GetTargetGroupForRegion(reg string, tag string) (*TargetGroup, error) {
+	region, err := recv.GetRegion(reg)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, targetGroup := range region.TargetGroups {
+		if targetGroup.Tag == tag {
+			return targetGroup, nil
+		}
+	}
+
+	// tag will most often be "" so fall through to a single target group
+	if tag == "" && len(region.TargetGroups) == 1 {
+		return region.TargetGroups[0], nil
+	}
+
+	return nil, fmt.Errorf("Target group tagged %s doesn't exist in the config for region %s", tag, reg)
+}
+
 func (recv *Environment) GetRegion(regionName string) (*Region, error) {
 	for _, region := range recv.Regions {
 		if region.Name == regionName {
@@ -65,6 +85,15 @@ func (recv *Environment) GetRoleARN(regionName string) (string, error) {
 	return recv.RoleARN, nil
 }
 
+// RequiredRegions is the number of regions that must provision successfully
+func (recv *Environment) RequiredRegions() int {
+	if recv.MinSuccessfulRegions == 0 {
+		return len(recv.Regions)
+	}
+
+	return recv.MinSuccessfulRegions
+}
+
 func (recv *Environment) GetStackDefinitionPath(regionName string) (string, error) {
 	region, err := recv.GetRegion(regionName)
 	if err != nil {
@@ -91,28 +120,90 @@ func (recv *Environment) GetInstanceCount(regionName string) (uint, error) {
 	return recv.InstanceCount, nil
 }
 
+// GetAMI returns the region's AMI, the environment's if the region doesn't
+// define one, or nil if neither do
+func (recv *Environment) GetAMI(regionName string) (*AMI, error) {
+	region, err := recv.GetRegion(regionName)
+	if err != nil {
+		return nil, err
+	}
+
+	if region.AMI != nil {
+		return region.AMI, nil
+	}
+
+	return recv.AMI, nil
+}
+
+// GetParameters merges the environment's parameters with the region's
+func (recv *Environment) GetParameters(regionName string) (map[string]*Parameter, error) {
+	region, err := recv.GetRegion(regionName)
+	if err != nil {
+		return nil, err
+	}
+
+	parameters := make(map[string]*Parameter)
+	for name, parameter := range recv.Parameters {
+		parameters[name] = parameter
+	}
+	for name, parameter := range region.Parameters {
+		parameters[name] = parameter
+	}
+
+	return parameters, nil
+}
+
+// GetAutoScaling returns nil if neither the region nor the environment
+// defines auto_scaling
+func (recv *Environment) GetAutoScaling(regionName string) (*AutoScaling, error) {
+	region, err := recv.GetRegion(regionName)
+	if err != nil {
+		return nil, err
+	}
+
+	if region.AutoScaling != nil {
+		return region.AutoScaling, nil
+	}
+
+	return recv.AutoScaling, nil
+}
+
 func (recv *Environment) IsWithinBlackoutWindow() error {
 	now := time.Now()
 
-	for _, window := range recv.BlackoutWindows {
-		startTime, err := time.Parse(time.RFC3339, window.StartTime)
-		if err != nil {
-			return err
-		}
+	window, err := recv.ActiveBlackoutWindow(now)
+	if err != nil {
+		return err
+	}
 
-		endTime, err := time.Parse(time.RFC3339, window.EndTime)
-		if err != nil {
-			return err
+	if window != nil {
+		msg := now.Format(time.RFC3339) + " is currently within the blackout window " + window.String()
+		if window.Reason != "" {
+			msg += ": " + window.Reason
 		}
+		return errors.New(msg)
+	}
 
-		if startTime.After(endTime) {
-			return errors.New("start_time is after end_time")
+	return nil
+}
+
+// ActiveBlackoutWindow returns the first window that now falls within. An
+// error is returned for a window that can't be parsed along with the window
+// itself since it's considered active.
+func (recv *Environment) ActiveBlackoutWindow(now time.Time) (*BlackoutWindow, error) {
+
+	for i := range recv.BlackoutWindows {
+		window := &recv.BlackoutWindows[i]
+
+		active, err := window.IsActive(now)
+		if err != nil {
+			return window, fmt.Errorf("blackout window %s: %s", window.String(), err)
 		}
 
-		if now.After(startTime) && now.Before(endTime) {
-			return errors.New(now.Format(time.RFC3339) + " is currently within a blackout window")
+		if active {
+			return window, nil
 		}
 	}
 
-	return nil
+	return nil, nil
 }
@@ -0,0 +1,74 @@
+package conf_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/adobe-platform/porter/conf"
+)
+
+var _ = Describe("Environment", func() {
+
+	Describe("RequiredRegions", func() {
+
+		var environment *conf.Environment
+
+		BeforeEach(func() {
+			environment = &conf.Environment{
+				Regions: []*conf.Region{
+					{Name: "us-east-1"},
+					{Name: "us-west-2"},
+					{Name: "eu-west-1"},
+				},
+			}
+		})
+
+		It("requires every region by default", func() {
+			Expect(environment.RequiredRegions()).To(Equal(3))
+		})
+
+		It("requires min_successful_regions when it's set", func() {
+			environment.MinSuccessfulRegions = 2
+			Expect(environment.RequiredRegions()).To(Equal(2))
+		})
+	})
+
+	Describe("AMI", func() {
+
+		It("overrides the environment's AMI with the region's", func() {
+			environment := &conf.Environment{
+				AMI: &conf.AMI{SSMParameter: "/aws/service/ami-amazon-linux-latest/amzn2-ami-hvm-x86_64-gp2"},
+				Regions: []*conf.Region{
+					{Name: "us-east-1"},
+					{Name: "us-west-2", AMI: &conf.AMI{ID: "ami-0123456789abcdef0"}},
+				},
+			}
+
+			ami, err := environment.GetAMI("us-east-1")
+			Expect(err).To(BeNil())
+			Expect(ami.SSMParameter).ToNot(BeEmpty())
+
+			ami, err = environment.GetAMI("us-west-2")
+			Expect(err).To(BeNil())
+			Expect(ami.ID).To(Equal("ami-0123456789abcdef0"))
+		})
+
+		It("needs exactly one of id, ssm_parameter, or filter", func() {
+			var ami *conf.AMI
+			Expect(ami.Validate()).To(BeNil())
+
+			Expect((&conf.AMI{ID: "ami-12345678"}).Validate()).To(BeNil())
+			Expect((&conf.AMI{Filter: &conf.AMIFilter{Owner: "amazon", Name: "amzn2-ami-hvm-*"}}).Validate()).To(BeNil())
+
+			Expect((&conf.AMI{}).Validate()).ToNot(BeNil())
+			Expect((&conf.AMI{ID: "ami-12345678", SSMParameter: "/some/path"}).Validate()).ToNot(BeNil())
+			Expect((&conf.AMI{ID: "not-an-ami"}).Validate()).ToNot(BeNil())
+			Expect((&conf.AMI{Filter: &conf.AMIFilter{Owner: "amazon"}}).Validate()).ToNot(BeNil())
+		})
+
+		It("rejects prebaked with ssm_parameter", func() {
+			Expect((&conf.AMI{ID: "ami-12345678", Prebaked: true}).Validate()).To(BeNil())
+			Expect((&conf.AMI{SSMParameter: "/aws/service/ami-amazon-linux-latest/amzn2-ami-hvm-x86_64-gp2", Prebaked: true}).Validate()).ToNot(BeNil())
+		})
+	})
+})
@@ -0,0 +1,244 @@
+/*
+ * (c) 2016-2018 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adobe-platform/porter/constants"
+)
+
+var (
+	healthCheckPathRegex   = regexp.MustCompile(`^/[!-~]*$`)
+	healthCheckHeaderRegex = regexp.MustCompile(`^[-a-zA-Z0-9]+$`)
+	statusCodeRegex        = regexp.MustCompile(`^[1-5]\d\d$`)
+)
+
+// setDefaults fills in an inet container's health check
+func (recv *HealthCheck) setDefaults() {
+	if recv.Type == "" {
+		recv.Type = HealthCheck_HTTP
+	}
+
+	if recv.Type == HealthCheck_HTTP {
+		if recv.Method == "" {
+			recv.Method = "GET"
+		}
+		if recv.Path == "" {
+			recv.Path = "/health"
+		}
+		if recv.ExpectedStatus == "" {
+			recv.ExpectedStatus = "200"
+		}
+	}
+
+	if recv.Interval == 0 {
+		recv.Interval = constants.HC_Interval
+	}
+	if recv.Timeout == 0 {
+		recv.Timeout = constants.HC_Timeout
+	}
+	if recv.HealthyThreshold == 0 {
+		recv.HealthyThreshold = constants.HC_HealthyThreshold
+	}
+	if recv.UnhealthyThreshold == 0 {
+		recv.UnhealthyThreshold = constants.HC_UnhealthyThreshold
+	}
+}
+
+// validate keeps a health check within what both HAProxy and the load
+// balancers accept
+func (recv *HealthCheck) validate() error {
+
+	switch recv.Type {
+	case HealthCheck_HTTP:
+
+		if !healthMethodRegex.MatchString(recv.Method) {
+			return fmt.Errorf("invalid method %s", recv.Method)
+		}
+
+		if !healthCheckPathRegex.MatchString(recv.Path) {
+			return fmt.Errorf("invalid path %s", recv.Path)
+		}
+
+		if recv.ReadinessPath != "" && !healthCheckPathRegex.MatchString(recv.ReadinessPath) {
+			return fmt.Errorf("invalid readiness_path %s", recv.ReadinessPath)
+		}
+
+		for name, value := range recv.Headers {
+			if !healthCheckHeaderRegex.MatchString(name) {
+				return fmt.Errorf("invalid header name %s", name)
+			}
+			if strings.ContainsAny(value, "\r\n\t") {
+				return fmt.Errorf("header %s can't contain tabs or line breaks", name)
+			}
+		}
+
+		if _, err := recv.StatusRanges(); err != nil {
+			return err
+		}
+
+	case HealthCheck_TCP:
+
+		if recv.Method != "" || recv.Path != "" || recv.ReadinessPath != "" ||
+			len(recv.Headers) > 0 || recv.ExpectedStatus != "" {
+			return errors.New("method, path, readiness_path, headers, and expected_status only apply to http health checks")
+		}
+
+	default:
+		return fmt.Errorf("invalid type %s. Valid values are [%s, %s]",
+			recv.Type, HealthCheck_HTTP, HealthCheck_TCP)
+	}
+
+	if recv.Interval < 5 || recv.Interval > 300 {
+		return errors.New("interval must be between 5 and 300")
+	}
+
+	if recv.Timeout < 2 || recv.Timeout > 60 {
+		return errors.New("timeout must be between 2 and 60")
+	}
+
+	if recv.Timeout >= recv.Interval {
+		return errors.New("timeout must be less than interval")
+	}
+
+	if recv.HealthyThreshold < 2 || recv.HealthyThreshold > 10 {
+		return errors.New("healthy_threshold must be between 2 and 10")
+	}
+
+	if recv.UnhealthyThreshold < 2 || recv.UnhealthyThreshold > 10 {
+		return errors.New("unhealthy_threshold must be between 2 and 10")
+	}
+
+	return nil
+}
+
+// Readiness is the path polled before a container receives traffic. It
+// defaults to the liveness path.
+func (recv *HealthCheck) Readiness() string {
+	if recv.ReadinessPath != "" {
+		return recv.ReadinessPath
+	}
+	return recv.Path
+}
+
+// StatusRanges parses expected_status which is either a list of status codes
+// like 200,204 or a single range like 200-399. Each range is inclusive.
+func (recv *HealthCheck) StatusRanges() ([][2]int, error) {
+	if recv.ExpectedStatus == "" {
+		return [][2]int{{200, 200}}, nil
+	}
+
+	if bounds := strings.Split(recv.ExpectedStatus, "-"); len(bounds) == 2 {
+
+		if !statusCodeRegex.MatchString(bounds[0]) || !statusCodeRegex.MatchString(bounds[1]) {
+			return nil, fmt.Errorf("invalid expected_status %s", recv.ExpectedStatus)
+		}
+
+		low, _ := strconv.Atoi(bounds[0])
+		high, _ := strconv.Atoi(bounds[1])
+		if low > high {
+			return nil, fmt.Errorf("invalid expected_status %s", recv.ExpectedStatus)
+		}
+		return [][2]int{{low, high}}, nil
+	}
+
+	ranges := make([][2]int, 0)
+	for _, code := range strings.Split(recv.ExpectedStatus, ",") {
+
+		if !statusCodeRegex.MatchString(code) {
+			return nil, fmt.Errorf("invalid expected_status %s", recv.ExpectedStatus)
+		}
+
+		status, _ := strconv.Atoi(code)
+		ranges = append(ranges, [2]int{status, status})
+	}
+	return ranges, nil
+}
+
+// ExpectsStatus is true if the status code counts as healthy
+func (recv *HealthCheck) ExpectsStatus(statusCode int) bool {
+	ranges, err := recv.StatusRanges()
+	if err != nil {
+		return false
+	}
+
+	for _, statusRange := range ranges {
+		if statusCode >= statusRange[0] && statusCode <= statusRange[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// NewRequest builds the readiness request for a server listening on addr
+func (recv *HealthCheck) NewRequest(addr string) (*http.Request, error) {
+	req, err := http.NewRequest(recv.Method, "http://"+addr+recv.Readiness(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, value := range recv.Headers {
+		if strings.EqualFold(name, "Host") {
+			req.Host = value
+		} else {
+			req.Header.Set(name, value)
+		}
+	}
+	return req, nil
+}
+
+// Probe runs the readiness check once against a server listening on addr.
+// The client is only used by http health checks.
+func (recv *HealthCheck) Probe(client *http.Client, addr string) error {
+
+	if recv.Type == HealthCheck_TCP {
+
+		conn, err := net.DialTimeout("tcp", addr, time.Duration(recv.Timeout)*time.Second)
+		if err != nil {
+			return err
+		}
+		conn.Close()
+		return nil
+	}
+
+	req, err := recv.NewRequest(addr)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if !recv.ExpectsStatus(resp.StatusCode) {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// String describes the readiness check for logging
+func (recv *HealthCheck) String() string {
+	if recv.Type == HealthCheck_TCP {
+		return "TCP connect"
+	}
+	return recv.Method + " " + recv.Readiness()
+}
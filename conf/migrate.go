@@ -0,0 +1,577 @@
+/*
+ * (c) 2016-2018 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+var yamlKeyRegex = regexp.MustCompile(`^("[^"]*"|'[^']*'|[a-zA-Z0-9_][-a-zA-Z0-9_.]*)[ \t]*:([ \t]|$)`)
+
+type (
+	// Migration rewrites config written for porter version From into config
+	// for porter version To.
+	//
+	// Steps edit the YAML line by line rather than round-tripping it through a
+	// parser so comments, ordering, and formatting are preserved.
+	Migration struct {
+		From string
+		To   string
+
+		// Names of environment variables that hooks receive through the
+		// deprecated PORTER_ passthrough, without the prefix
+		HookEnv []string
+	}
+
+	// MigratedFile is a config file and what migrating it changed
+	MigratedFile struct {
+		Path     string
+		Original []byte
+		Migrated []byte
+		Changes  []string
+	}
+
+	migrationStep struct {
+		version string
+		apply   func(lines []string, migration Migration) ([]string, []string, error)
+	}
+
+	// yamlLine is what migration steps know about a line of YAML
+	yamlLine struct {
+		// spaces before the line's content including any "- "
+		leading int
+
+		// the line starts a sequence item
+		dash bool
+
+		// the column the key or scalar starts at
+		indent int
+
+		key     string
+		value   string
+		comment string
+
+		// keys of the enclosing mappings ending with key
+		path []string
+
+		// the line belongs to a block scalar
+		continuation bool
+	}
+)
+
+// migrationSteps are applied in order. A step runs when its version is after
+// the version being migrated from and no later than the version being
+// migrated to
+var migrationSteps = []migrationStep{
+	{
+		version: "v5.4.0",
+		apply:   migrateELB,
+	},
+	{
+		version: "v5.4.0",
+		apply:   migrateHookEnvironment,
+	},
+}
+
+// MigrateConfig migrates the config at configPath and its fragments in
+// configPath.d/. Nothing is written. If From is empty porter_version is read
+// from the config.
+func MigrateConfig(configPath string, migration Migration) ([]MigratedFile, error) {
+
+	fragmentPaths, err := fragmentPaths(configPath + ".d")
+	if err != nil {
+		return nil, err
+	}
+
+	paths := append([]string{configPath}, fragmentPaths...)
+
+	files := make([]MigratedFile, 0, len(paths))
+	versionFile := 0
+	for i, path := range paths {
+		configBytes, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if version, exists := PorterVersion(configBytes); exists {
+			if migration.From == "" {
+				migration.From = version
+			}
+			versionFile = i
+		}
+
+		files = append(files, MigratedFile{
+			Path:     path,
+			Original: configBytes,
+		})
+	}
+
+	if migration.From == "" {
+		// porter_version is optional in DEV_MODE
+		migration.From = "v0.0.0"
+	}
+
+	steps, err := migration.steps()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range files {
+		file := &files[i]
+
+		lines := strings.Split(string(file.Original), "\n")
+		for _, step := range steps {
+			var changes []string
+
+			lines, changes, err = step.apply(lines, migration)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", file.Path, err)
+			}
+
+			for _, change := range changes {
+				file.Changes = append(file.Changes, fmt.Sprintf("%s:%s (%s)", file.Path, change, step.version))
+			}
+		}
+
+		if i == versionFile {
+			var change string
+
+			lines, change = setPorterVersion(lines, migration.To)
+			if change != "" {
+				file.Changes = append(file.Changes, fmt.Sprintf("%s:%s", file.Path, change))
+			}
+		}
+
+		file.Migrated = []byte(strings.Join(lines, "\n"))
+
+		var doc yaml.MapSlice
+		err = yaml.Unmarshal(file.Migrated, &doc)
+		if err != nil {
+			return nil, fmt.Errorf("%s: migrated config doesn't parse: %s", file.Path, err)
+		}
+	}
+
+	return files, nil
+}
+
+// PorterVersion returns the top-level porter_version of a config file
+func PorterVersion(configBytes []byte) (string, bool) {
+	for _, line := range outline(strings.Split(string(configBytes), "\n")) {
+		if line != nil && line.leading == 0 && line.key == "porter_version" {
+			return unquote(line.value), true
+		}
+	}
+	return "", false
+}
+
+func (recv Migration) steps() ([]migrationStep, error) {
+
+	from, ok := parseVersion(recv.From)
+	if !ok {
+		return nil, errors.New("invalid porter_version " + recv.From)
+	}
+
+	to, ok := parseVersion(recv.To)
+	if !ok {
+		return nil, errors.New("invalid version to migrate to " + recv.To)
+	}
+
+	if compareVersions(to, from) < 0 {
+		return nil, fmt.Errorf("can't migrate backward from %s to %s", recv.From, recv.To)
+	}
+
+	steps := make([]migrationStep, 0)
+	for _, step := range migrationSteps {
+		version, _ := parseVersion(step.version)
+		if compareVersions(version, from) > 0 && compareVersions(version, to) <= 0 {
+			steps = append(steps, step)
+		}
+	}
+	return steps, nil
+}
+
+// migrateELB moves a region's legacy elb into an elbs list
+func migrateELB(lines []string, migration Migration) ([]string, []string, error) {
+	var changes []string
+
+	for {
+		parsed := outline(lines)
+
+		i := -1
+		for j, line := range parsed {
+			if line == nil || line.key != "elb" || parentKey(line) != "regions" {
+				continue
+			}
+
+			name := unquote(line.value)
+			if name != "" && name != "none" {
+				i = j
+				break
+			}
+		}
+
+		if i == -1 {
+			return lines, changes, nil
+		}
+
+		elb := parsed[i]
+
+		itemStart := i
+		for ; itemStart >= 0; itemStart-- {
+			line := parsed[itemStart]
+			if line != nil && line.dash && line.indent == elb.indent {
+				break
+			}
+		}
+
+		if itemStart >= 0 {
+			for j := itemStart; j < itemEnd(parsed, itemStart); j++ {
+				line := parsed[j]
+				if line != nil && line.key == "elbs" && line.indent == elb.indent {
+					return nil, nil, fmt.Errorf("%d: the region defines both elb and elbs. Move elb into elbs by hand", i+1)
+				}
+			}
+		}
+
+		nameLine := strings.Repeat(" ", elb.indent) + "- name: " + elb.value
+		if elb.comment != "" {
+			nameLine += " " + elb.comment
+		}
+
+		lines[i] = lines[i][:elb.indent] + "elbs:"
+		lines = insertLines(lines, i+1, nameLine)
+
+		changes = append(changes, fmt.Sprintf("%d: moved elb %s into elbs", i+1, unquote(elb.value)))
+	}
+}
+
+// migrateHookEnvironment adds the variables hooks received through the
+// deprecated PORTER_ passthrough to each hook's environment. The value is left
+// empty so the hook reads it from the environment when it runs. Interpolating
+// ${PORTER_NAME} would resolve it while packing and bake it into the service
+// payload
+func migrateHookEnvironment(lines []string, migration Migration) ([]string, []string, error) {
+	var changes []string
+
+	if len(migration.HookEnv) == 0 {
+		return lines, changes, nil
+	}
+
+	names := make([]string, len(migration.HookEnv))
+	copy(names, migration.HookEnv)
+	sort.Strings(names)
+
+	// each hook is visited once. indexes shift as lines are inserted so
+	// re-outline after every change
+	for hookIndex := 0; ; hookIndex++ {
+		parsed := outline(lines)
+
+		i, n := -1, 0
+		for j, line := range parsed {
+			if line != nil && line.dash && len(line.path) == 3 && line.path[0] == "hooks" {
+				if n == hookIndex {
+					i = j
+					break
+				}
+				n++
+			}
+		}
+
+		if i == -1 {
+			return lines, changes, nil
+		}
+
+		hook := parsed[i]
+		end := itemEnd(parsed, i)
+
+		environment := -1
+		existing := make(map[string]bool)
+		for j := i; j < end; j++ {
+			line := parsed[j]
+			if line != nil && line.key == "environment" && line.indent == hook.indent {
+				environment = j
+				break
+			}
+		}
+
+		var insertAt int
+		var childIndent string
+		var newLines []string
+
+		if environment == -1 {
+
+			insertAt = end
+			newLines = append(newLines, strings.Repeat(" ", hook.indent)+"environment:")
+			childIndent = strings.Repeat(" ", hook.indent+2)
+		} else {
+
+			environmentLine := parsed[environment]
+			switch environmentLine.value {
+			case "", "{}":
+				lines[environment] = lines[environment][:environmentLine.indent] + "environment:"
+				if environmentLine.comment != "" {
+					lines[environment] += " " + environmentLine.comment
+				}
+			default:
+				return nil, nil, fmt.Errorf("%d: environment must be a block mapping to add hook environment to it", environment+1)
+			}
+
+			insertAt = keyEnd(parsed, environment)
+			childIndent = strings.Repeat(" ", environmentLine.indent+2)
+			for j := environment + 1; j < insertAt; j++ {
+				line := parsed[j]
+				if line != nil && !line.continuation && len(line.path) == len(environmentLine.path)+1 {
+					childIndent = strings.Repeat(" ", line.indent)
+					existing[line.key] = true
+				}
+			}
+		}
+
+		var added []string
+		for _, name := range names {
+			if existing[name] {
+				continue
+			}
+			added = append(added, name)
+			newLines = append(newLines, fmt.Sprintf("%s%s:", childIndent, name))
+		}
+
+		if len(added) == 0 {
+			continue
+		}
+
+		lines = insertLines(lines, insertAt, newLines...)
+		changes = append(changes, fmt.Sprintf("%d: added %s to the %s hook's environment",
+			i+1, strings.Join(added, ", "), hook.path[1]))
+	}
+}
+
+// setPorterVersion sets the top-level porter_version and describes the change
+// if there was one
+func setPorterVersion(lines []string, version string) ([]string, string) {
+
+	parsed := outline(lines)
+	for i, line := range parsed {
+		if line == nil || line.leading != 0 || line.key != "porter_version" {
+			continue
+		}
+
+		if unquote(line.value) == version {
+			return lines, ""
+		}
+
+		lines[i] = "porter_version: " + version
+		if line.comment != "" {
+			lines[i] += " " + line.comment
+		}
+		return lines, fmt.Sprintf("%d: porter_version %s -> %s", i+1, unquote(line.value), version)
+	}
+
+	insertAt := 0
+	for i, line := range parsed {
+		if line != nil && line.leading == 0 && line.key == "service_name" {
+			insertAt = i + 1
+			break
+		}
+	}
+
+	lines = insertLines(lines, insertAt, "porter_version: "+version)
+	return lines, fmt.Sprintf("%d: added porter_version %s", insertAt+1, version)
+}
+
+// outline parses enough of each line to find keys and the mappings they're
+// in. Blank and comment lines are nil. Flow collections spanning lines and
+// multi-line plain scalars aren't understood.
+func outline(lines []string) []*yamlLine {
+	type frame struct {
+		indent int
+		key    string
+	}
+
+	parsed := make([]*yamlLine, len(lines))
+	stack := make([]frame, 0)
+	blockScalarIndent := -1
+
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		leading := len(line) - len(strings.TrimLeft(line, " "))
+
+		if blockScalarIndent >= 0 {
+			if trimmed == "" {
+				continue
+			}
+			if leading > blockScalarIndent {
+				parsed[i] = &yamlLine{
+					leading:      leading,
+					indent:       leading,
+					continuation: true,
+				}
+				continue
+			}
+			blockScalarIndent = -1
+		}
+
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || trimmed == "---" {
+			continue
+		}
+
+		parsedLine := &yamlLine{
+			leading: leading,
+			indent:  leading,
+		}
+
+		content := line[leading:]
+		if content == "-" || strings.HasPrefix(content, "- ") {
+			parsedLine.dash = true
+			content = strings.TrimLeft(content[1:], " ")
+			parsedLine.indent = len(line) - len(content)
+
+			for len(stack) > 0 && stack[len(stack)-1].indent > leading {
+				stack = stack[:len(stack)-1]
+			}
+		} else {
+			for len(stack) > 0 && stack[len(stack)-1].indent >= leading {
+				stack = stack[:len(stack)-1]
+			}
+		}
+
+		match := yamlKeyRegex.FindStringSubmatch(content)
+		if match != nil {
+			parsedLine.key = unquote(match[1])
+			content = content[len(match[0]):]
+			stack = append(stack, frame{
+				indent: parsedLine.indent,
+				key:    parsedLine.key,
+			})
+		}
+
+		parsedLine.value, parsedLine.comment = splitComment(strings.TrimSpace(content))
+		if parsedLine.key != "" &&
+			(strings.HasPrefix(parsedLine.value, "|") || strings.HasPrefix(parsedLine.value, ">")) {
+			blockScalarIndent = parsedLine.indent
+		}
+
+		for _, frame := range stack {
+			parsedLine.path = append(parsedLine.path, frame.key)
+		}
+		parsed[i] = parsedLine
+	}
+
+	return parsed
+}
+
+// itemEnd returns the index after the last line of the sequence item started
+// at i
+func itemEnd(parsed []*yamlLine, i int) int {
+	end := i + 1
+	for j := i + 1; j < len(parsed); j++ {
+		line := parsed[j]
+		if line == nil {
+			continue
+		}
+		if line.leading <= parsed[i].leading {
+			break
+		}
+		end = j + 1
+	}
+	return end
+}
+
+// keyEnd returns the index after the last line of the value of the key at i.
+// Sequence items at the key's indent are part of the value
+func keyEnd(parsed []*yamlLine, i int) int {
+	key := parsed[i]
+
+	end := i + 1
+	for j := i + 1; j < len(parsed); j++ {
+		line := parsed[j]
+		if line == nil {
+			continue
+		}
+		if line.leading < key.indent ||
+			(line.leading == key.indent && (!line.dash || key.value != "")) {
+			break
+		}
+		end = j + 1
+	}
+	return end
+}
+
+func parentKey(line *yamlLine) string {
+	if len(line.path) < 2 {
+		return ""
+	}
+	return line.path[len(line.path)-2]
+}
+
+// splitComment separates a trailing comment from a scalar
+func splitComment(value string) (string, string) {
+	searchFrom := 0
+	if len(value) > 0 && (value[0] == '"' || value[0] == '\'') {
+		if end := strings.IndexByte(value[1:], value[0]); end != -1 {
+			searchFrom = end + 2
+		}
+	}
+
+	if strings.HasPrefix(value, "#") {
+		return "", value
+	}
+
+	if index := strings.Index(value[searchFrom:], " #"); index != -1 {
+		index += searchFrom
+		return strings.TrimSpace(value[:index]), value[index+1:]
+	}
+	return value, ""
+}
+
+func unquote(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+func insertLines(lines []string, at int, newLines ...string) []string {
+	inserted := make([]string, 0, len(lines)+len(newLines))
+	inserted = append(inserted, lines[:at]...)
+	inserted = append(inserted, newLines...)
+	return append(inserted, lines[at:]...)
+}
+
+func parseVersion(version string) (parsed [3]int, ok bool) {
+	if !porterVersionRegex.MatchString(version) {
+		return
+	}
+
+	for i, part := range strings.Split(version[1:], ".") {
+		parsed[i], _ = strconv.Atoi(part)
+	}
+	ok = true
+	return
+}
+
+func compareVersions(a, b [3]int) int {
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] - b[i]
+		}
+	}
+	return 0
+}
@@ -0,0 +1,117 @@
+package conf_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/adobe-platform/porter/conf"
+)
+
+var _ = Describe("MigrateConfig", func() {
+
+	var dir, configPath string
+
+	migrate := func(config string, migration conf.Migration) []conf.MigratedFile {
+		Expect(ioutil.WriteFile(configPath, []byte(config), 0644)).To(Succeed())
+
+		files, err := conf.MigrateConfig(configPath, migration)
+		Expect(err).To(BeNil())
+		return files
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "porter-migrate")
+		Expect(err).To(BeNil())
+		configPath = filepath.Join(dir, "config")
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("moves elb into elbs and preserves comments", func() {
+		files := migrate(`# comment
+service_name: foo
+porter_version: v5.3.0 # pinned
+environments:
+- name: dev
+  regions:
+  - name: us-west-2
+    elb: dev-elb # the dev elb
+    instance_count: 2
+  - name: us-east-1
+    elb: none
+`, conf.Migration{To: "v5.4.0"})
+
+		Expect(files).To(HaveLen(1))
+		Expect(files[0].Changes).To(HaveLen(2))
+		Expect(string(files[0].Migrated)).To(Equal(`# comment
+service_name: foo
+porter_version: v5.4.0 # pinned
+environments:
+- name: dev
+  regions:
+  - name: us-west-2
+    elbs:
+    - name: dev-elb # the dev elb
+    instance_count: 2
+  - name: us-east-1
+    elb: none
+`))
+	})
+
+	It("adds passthrough variables to hook environments", func() {
+		files := migrate(`service_name: foo
+porter_version: v5.3.0
+hooks:
+  pre_pack:
+  - dockerfile: a
+    environment:
+      BAR: baz
+      TOKEN:
+  post_pack:
+  - dockerfile: b
+`, conf.Migration{To: "v5.4.0", HookEnv: []string{"TOKEN", "ACCOUNT"}})
+
+		Expect(string(files[0].Migrated)).To(Equal(`service_name: foo
+porter_version: v5.4.0
+hooks:
+  pre_pack:
+  - dockerfile: a
+    environment:
+      BAR: baz
+      TOKEN:
+      ACCOUNT:
+  post_pack:
+  - dockerfile: b
+    environment:
+      ACCOUNT:
+      TOKEN:
+`))
+	})
+
+	It("skips steps at or before porter_version", func() {
+		files := migrate(`service_name: foo
+porter_version: v5.4.0
+environments:
+- name: dev
+  regions:
+  - name: us-west-2
+    elb: dev-elb
+`, conf.Migration{To: "v5.4.0"})
+
+		Expect(files[0].Changes).To(BeEmpty())
+	})
+
+	It("refuses to migrate backward", func() {
+		Expect(ioutil.WriteFile(configPath, []byte("porter_version: v5.4.0\n"), 0644)).To(Succeed())
+
+		_, err := conf.MigrateConfig(configPath, conf.Migration{To: "v5.3.0"})
+		Expect(err).ToNot(BeNil())
+	})
+})
@@ -0,0 +1,54 @@
+/*
+ * (c) 2016-2018 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// CloudFormation parameter names are alphanumeric
+var parameterNameRegex = regexp.MustCompile(`^[a-zA-Z0-9]+$`)
+
+// Secret is true if the value comes from secrets_exec_name
+func (recv *Parameter) Secret() bool {
+	return recv.SecretsExecName != ""
+}
+
+func validateParameters(parameters map[string]*Parameter) error {
+	for name, parameter := range parameters {
+
+		if !parameterNameRegex.MatchString(name) {
+			return fmt.Errorf("Invalid parameter name %s. Valid characters are [0-9a-zA-Z]", name)
+		}
+
+		// porter's own parameters start with Porter
+		if strings.HasPrefix(name, "Porter") {
+			return fmt.Errorf("Parameter %s uses the reserved prefix Porter", name)
+		}
+
+		if parameter == nil {
+			return errors.New("Parameter " + name + " needs a value or secrets_exec_name")
+		}
+
+		if parameter.Value != "" && parameter.Secret() {
+			return errors.New("Parameter " + name + " can't define both value and secrets_exec_name")
+		}
+
+		if parameter.Value == "" && !parameter.Secret() {
+			return errors.New("Parameter " + name + " needs a value or secrets_exec_name")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package conf_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/adobe-platform/porter/conf"
+)
+
+var _ = Describe("Parameters", func() {
+
+	It("overrides environment parameters with region parameters", func() {
+		environment := &conf.Environment{
+			Parameters: map[string]*conf.Parameter{
+				"DomainName": {Value: "example.com"},
+				"ApiKey":     {SecretsExecName: "get-api-key"},
+			},
+			Regions: []*conf.Region{
+				{
+					Name: "us-west-2",
+					Parameters: map[string]*conf.Parameter{
+						"DomainName": {Value: "us-west-2.example.com"},
+					},
+				},
+			},
+		}
+
+		parameters, err := environment.GetParameters("us-west-2")
+		Expect(err).To(BeNil())
+		Expect(parameters).To(HaveLen(2))
+		Expect(parameters["DomainName"].Value).To(Equal("us-west-2.example.com"))
+		Expect(parameters["ApiKey"].Secret()).To(BeTrue())
+	})
+})
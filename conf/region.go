@@ -11,10 +11,45 @@
  */
 package conf
 
+import (
+	"fmt"
+	"strings"
+)
+
 func (recv *Region) HasELB() bool {
 	return recv.ELB != "" && recv.ELB != "none"
 }
 
+func (recv *Region) HasTargetGroup() bool {
+	return len(recv.TargetGroups) > 0
+}
+
+// TargetGroupType is the type shared by all of a region's target groups
+func (recv *Region) TargetGroupType() string {
+	if len(recv.TargetGroups) == 0 {
+		return ""
+	}
+	return recv.TargetGroups[0].Type
+}
+
+// IngressCIDRs are the CIDRs allowed to reach instances behind a region's
+// network target groups
+func (recv *Region) IngressCIDRs() []string {
+	cidrs := make([]string, 0)
+	seen := make(map[string]interface{})
+
+	for _, targetGroup := range recv.TargetGroups {
+		for _, cidr := range targetGroup.IngressCIDRs {
+			if _, exists := seen[cidr]; !exists {
+				seen[cidr] = nil
+				cidrs = append(cidrs, cidr)
+			}
+		}
+	}
+
+	return cidrs
+}
+
 // inet is a superset of worker which are almost identical to cron
 func (recv *Region) PrimaryTopology() (dominant string) {
 	for _, container := range recv.Containers {
@@ -30,20 +65,131 @@ func (recv *Region) PrimaryTopology() (dominant string) {
 	return
 }
 
-func (recv *Region) HealthCheckMethod() string {
+// HealthCheck is the health check of HAProxy's default backend which the load
+// balancer and porterd check through HAProxy. It's nil if there are no inet
+// containers.
+func (recv *Region) HealthCheck() *HealthCheck {
 	for _, container := range recv.Containers {
-		if container.Topology == Topology_Inet {
-			return container.HealthCheck.Method
+		if container.Topology == Topology_Inet && container.Routing == nil {
+			return container.HealthCheck
 		}
 	}
+	return nil
+}
+
+func (recv *Region) HealthCheckMethod() string {
+	if healthCheck := recv.HealthCheck(); healthCheck != nil {
+		return healthCheck.Method
+	}
 	return ""
 }
 
 func (recv *Region) HealthCheckPath() string {
-	for _, container := range recv.Containers {
-		if container.Topology == Topology_Inet {
-			return container.HealthCheck.Path
-		}
+	if healthCheck := recv.HealthCheck(); healthCheck != nil {
+		return healthCheck.Path
 	}
 	return ""
 }
+
+// StartOrder is the order a region's containers are started in. Containers
+// start after the containers they depend on, and sidecars start before the
+// other containers unless they depend on them. Otherwise the order in the
+// config is kept. Containers are stopped in the reverse order.
+func (recv *Region) StartOrder() ([]*Container, error) {
+
+	indexes := make(map[string]int)
+	for i, container := range recv.Containers {
+		indexes[container.ConfigName()] = i
+	}
+
+	// dependencies[i] are the indexes of the containers i waits on
+	dependencies := make([][]int, len(recv.Containers))
+	for i, container := range recv.Containers {
+		for _, dependency := range container.DependsOn {
+
+			j, exists := indexes[dependency.Container]
+			if !exists {
+				return nil, fmt.Errorf("container %s depends on undefined container %s",
+					container.ConfigName(), dependency.Container)
+			}
+
+			if i == j {
+				return nil, fmt.Errorf("container %s depends on itself", container.ConfigName())
+			}
+
+			dependencies[i] = append(dependencies[i], j)
+		}
+	}
+
+	// sidecars share the lifecycle of the other containers so they start
+	// first and stop last
+	implicit := make([][]int, len(recv.Containers))
+	for i, sidecar := range recv.Containers {
+		if sidecar.Topology != Topology_Sidecar {
+			continue
+		}
+
+		dependsOn := make(map[int]bool)
+		var visit func(int)
+		visit = func(k int) {
+			for _, j := range dependencies[k] {
+				if !dependsOn[j] {
+					dependsOn[j] = true
+					visit(j)
+				}
+			}
+		}
+		visit(i)
+
+		for j, container := range recv.Containers {
+			if container.Topology != Topology_Sidecar && !dependsOn[j] {
+				implicit[j] = append(implicit[j], i)
+			}
+		}
+	}
+
+	for i := range dependencies {
+		dependencies[i] = append(dependencies[i], implicit[i]...)
+	}
+
+	started := make([]bool, len(recv.Containers))
+	order := make([]*Container, 0, len(recv.Containers))
+	for len(order) < len(recv.Containers) {
+
+		next := -1
+		for i := range recv.Containers {
+			if started[i] {
+				continue
+			}
+
+			ready := true
+			for _, j := range dependencies[i] {
+				if !started[j] {
+					ready = false
+					break
+				}
+			}
+
+			if ready {
+				next = i
+				break
+			}
+		}
+
+		if next == -1 {
+			var names []string
+			for i, container := range recv.Containers {
+				if !started[i] {
+					names = append(names, container.ConfigName())
+				}
+			}
+			return nil, fmt.Errorf("depends_on has a cycle between containers %s",
+				strings.Join(names, ", "))
+		}
+
+		started[next] = true
+		order = append(order, recv.Containers[next])
+	}
+
+	return order, nil
+}
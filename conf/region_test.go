@@ -0,0 +1,129 @@
+package conf_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/adobe-platform/porter/conf"
+)
+
+var _ = Describe("StartOrder", func() {
+
+	names := func(containers []*conf.Container) []string {
+		var names []string
+		for _, container := range containers {
+			names = append(names, container.Name)
+		}
+		return names
+	}
+
+	It("starts dependencies and sidecars first", func() {
+		region := &conf.Region{
+			Containers: []*conf.Container{
+				{
+					Name:     "web",
+					Topology: conf.Topology_Inet,
+					DependsOn: []*conf.Dependency{
+						{Container: "cache", Condition: conf.Condition_Healthy},
+					},
+				},
+				{Name: "cache", Topology: conf.Topology_Worker},
+				{Name: "logs", Topology: conf.Topology_Sidecar},
+				{
+					Name:     "proxy",
+					Topology: conf.Topology_Sidecar,
+					DependsOn: []*conf.Dependency{
+						{Container: "web", Condition: conf.Condition_Healthy},
+					},
+				},
+			},
+		}
+
+		order, err := region.StartOrder()
+		Expect(err).To(BeNil())
+		Expect(names(order)).To(Equal([]string{"logs", "cache", "web", "proxy"}))
+	})
+
+	It("rejects cycles", func() {
+		region := &conf.Region{
+			Containers: []*conf.Container{
+				{
+					Name:      "a",
+					Topology:  conf.Topology_Worker,
+					DependsOn: []*conf.Dependency{{Container: "b"}},
+				},
+				{
+					Name:      "b",
+					Topology:  conf.Topology_Worker,
+					DependsOn: []*conf.Dependency{{Container: "a"}},
+				},
+			},
+		}
+
+		_, err := region.StartOrder()
+		Expect(err).ToNot(BeNil())
+	})
+
+	It("rejects undefined dependencies", func() {
+		region := &conf.Region{
+			Containers: []*conf.Container{
+				{
+					Name:      "a",
+					Topology:  conf.Topology_Worker,
+					DependsOn: []*conf.Dependency{{Container: "b"}},
+				},
+			},
+		}
+
+		_, err := region.StartOrder()
+		Expect(err).ToNot(BeNil())
+	})
+})
+
+var _ = Describe("Target groups", func() {
+
+	region := func(targetGroups ...*conf.TargetGroup) *conf.Region {
+		return &conf.Region{
+			Name:         "us-west-2",
+			VpcId:        "vpc-12345678",
+			Containers:   []*conf.Container{{Name: "web", Topology: conf.Topology_Inet}},
+			TargetGroups: targetGroups,
+		}
+	}
+
+	It("requires ingress_cidrs for network target groups", func() {
+		Expect(region(&conf.TargetGroup{
+			ListenerARN: "arn:aws:elasticloadbalancing:us-west-2:123456789012:listener/net/nlb/0123456789abcdef/0123456789abcdef",
+			Type:        conf.TargetGroup_Network,
+		}).ValidateTargetGroups()).ToNot(BeNil())
+
+		Expect(region(&conf.TargetGroup{
+			ListenerARN:  "arn:aws:elasticloadbalancing:us-west-2:123456789012:listener/net/nlb/0123456789abcdef/0123456789abcdef",
+			Type:         conf.TargetGroup_Network,
+			IngressCIDRs: []string{"10.0.0.0/16"},
+		}).ValidateTargetGroups()).To(BeNil())
+	})
+
+	It("rejects invalid ingress_cidrs", func() {
+		Expect(region(&conf.TargetGroup{
+			ListenerARN:  "arn:aws:elasticloadbalancing:us-west-2:123456789012:listener/net/nlb/0123456789abcdef/0123456789abcdef",
+			Type:         conf.TargetGroup_Network,
+			IngressCIDRs: []string{"10.0.0.0"},
+		}).ValidateTargetGroups()).ToNot(BeNil())
+	})
+
+	It("rejects ingress_cidrs on application target groups", func() {
+		Expect(region(&conf.TargetGroup{
+			ARN:          "arn:aws:elasticloadbalancing:us-west-2:123456789012:targetgroup/tg/0123456789abcdef",
+			Type:         conf.TargetGroup_Application,
+			IngressCIDRs: []string{"10.0.0.0/16"},
+		}).ValidateTargetGroups()).ToNot(BeNil())
+	})
+
+	It("merges the ingress_cidrs of every target group", func() {
+		Expect(region(
+			&conf.TargetGroup{Tag: "a", IngressCIDRs: []string{"10.0.0.0/16", "192.168.0.0/24"}},
+			&conf.TargetGroup{Tag: "b", IngressCIDRs: []string{"10.0.0.0/16"}},
+		).IngressCIDRs()).To(Equal([]string{"10.0.0.0/16", "192.168.0.0/24"}))
+	})
+})
@@ -0,0 +1,246 @@
+/*
+ * (c) 2016-2018 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+package conf
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+const schemaDraft = "http://json-schema.org/draft-07/schema#"
+
+// Schema returns a JSON Schema for .porter/config generated from the yaml
+// struct tags. Fields without a yaml tag are computed by porter and left out.
+// The keys used by config composition are included since they're valid
+// until the config is composed.
+func Schema() map[string]interface{} {
+	definitions := make(map[string]interface{})
+
+	root := schemaFor(reflect.TypeOf(Config{}), definitions)
+
+	for _, name := range []string{"Environment", "Region"} {
+		definition := definitions[name].(map[string]interface{})
+		properties := definition["properties"].(map[string]interface{})
+		properties[composeExtendsKey] = map[string]interface{}{
+			"type":        "string",
+			"description": "Name of the " + strings.ToLower(name) + " or template this extends",
+		}
+	}
+
+	rootDefinition := definitions["Config"].(map[string]interface{})
+	properties := rootDefinition["properties"].(map[string]interface{})
+
+	properties[composeVarsKey] = map[string]interface{}{
+		"type": "object",
+		"additionalProperties": map[string]interface{}{
+			"type": []string{"string", "number", "boolean"},
+		},
+	}
+
+	properties[composeTemplatesKey] = map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"environments": map[string]interface{}{
+				"type":  "array",
+				"items": map[string]interface{}{"$ref": "#/definitions/Environment"},
+			},
+			"regions": map[string]interface{}{
+				"type":  "array",
+				"items": map[string]interface{}{"$ref": "#/definitions/Region"},
+			},
+		},
+	}
+
+	return map[string]interface{}{
+		"$schema":     schemaDraft,
+		"title":       "porter config",
+		"$ref":        root["$ref"],
+		"definitions": definitions,
+	}
+}
+
+func schemaFor(t reflect.Type, definitions map[string]interface{}) map[string]interface{} {
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return schemaFor(t.Elem(), definitions)
+
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "minimum": 0}
+
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{
+			"type":  "array",
+			"items": schemaFor(t.Elem(), definitions),
+		}
+
+	case reflect.Map:
+		return map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": schemaFor(t.Elem(), definitions),
+		}
+
+	case reflect.Struct:
+
+		ref := map[string]interface{}{"$ref": "#/definitions/" + t.Name()}
+		if _, exists := definitions[t.Name()]; exists {
+			return ref
+		}
+
+		properties := make(map[string]interface{})
+		definition := map[string]interface{}{
+			"type":       "object",
+			"properties": properties,
+		}
+		// set before recursing in case of cycles
+		definitions[t.Name()] = definition
+
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+
+			name, _ := yamlName(field)
+			if name == "" {
+				continue
+			}
+
+			properties[name] = schemaFor(field.Type, definitions)
+		}
+
+		return ref
+	}
+
+	// interface{} and anything else can be any type
+	return map[string]interface{}{}
+}
+
+// Document converts config structs into an ordered YAML document with only
+// the fields that have a yaml tag. Nil pointers, slices, and maps are left out
+// as are fields tagged omitempty that are empty.
+func Document(value interface{}) interface{} {
+	return document(reflect.ValueOf(value))
+}
+
+func document(v reflect.Value) interface{} {
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return document(v.Elem())
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+
+		items := make([]interface{}, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			items = append(items, document(v.Index(i)))
+		}
+		return items
+
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+
+		// sort for deterministic output
+		keys := make([]string, 0, v.Len())
+		values := make(map[string]reflect.Value)
+		for _, key := range v.MapKeys() {
+			keyString := key.String()
+			keys = append(keys, keyString)
+			values[keyString] = v.MapIndex(key)
+		}
+		sort.Strings(keys)
+
+		doc := make(yaml.MapSlice, 0, len(keys))
+		for _, key := range keys {
+			doc = append(doc, yaml.MapItem{Key: key, Value: document(values[key])})
+		}
+		return doc
+
+	case reflect.Struct:
+
+		doc := make(yaml.MapSlice, 0)
+		t := v.Type()
+
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+
+			name, omitEmpty := yamlName(field)
+			if name == "" {
+				continue
+			}
+
+			fieldValue := v.Field(i)
+			if omitEmpty && isZero(fieldValue) {
+				continue
+			}
+
+			fieldDoc := document(fieldValue)
+			if fieldDoc == nil {
+				continue
+			}
+
+			doc = append(doc, yaml.MapItem{Key: name, Value: fieldDoc})
+		}
+		return doc
+	}
+
+	return v.Interface()
+}
+
+// yamlName returns the key of a field or "" if it doesn't have one
+func yamlName(field reflect.StructField) (name string, omitEmpty bool) {
+	if field.PkgPath != "" {
+		return
+	}
+
+	tag := field.Tag.Get("yaml")
+	if tag == "" || tag == "-" {
+		return
+	}
+
+	parts := strings.Split(tag, ",")
+	name = parts[0]
+	for _, flag := range parts[1:] {
+		if flag == "omitempty" {
+			omitEmpty = true
+		}
+	}
+	return
+}
+
+func isZero(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map:
+		return v.IsNil() || (v.Kind() != reflect.Ptr && v.Kind() != reflect.Interface && v.Len() == 0)
+	}
+	return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+}
@@ -0,0 +1,43 @@
+package conf_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v2"
+
+	"github.com/adobe-platform/porter/conf"
+)
+
+var _ = Describe("Schema", func() {
+
+	It("describes tagged fields and composition keys", func() {
+		schema := conf.Schema()
+		Expect(schema["$ref"]).To(Equal("#/definitions/Config"))
+
+		definitions := schema["definitions"].(map[string]interface{})
+		config := definitions["Config"].(map[string]interface{})["properties"].(map[string]interface{})
+		Expect(config).To(HaveKey("service_name"))
+		Expect(config).To(HaveKey("vars"))
+		Expect(config).To(HaveKey("templates"))
+		Expect(config).ToNot(HaveKey("HAProxyStatsPassword"))
+
+		region := definitions["Region"].(map[string]interface{})["properties"].(map[string]interface{})
+		Expect(region).To(HaveKey("extends"))
+		Expect(region["instance_count"]).To(Equal(map[string]interface{}{"type": "integer", "minimum": 0}))
+	})
+})
+
+var _ = Describe("Document", func() {
+
+	It("only includes tagged fields", func() {
+		doc := conf.Document(conf.Config{
+			ServiceName:          "foo",
+			HAProxyStatsPassword: "secret",
+		})
+
+		docBytes, err := yaml.Marshal(doc)
+		Expect(err).To(BeNil())
+		Expect(string(docBytes)).To(ContainSubstring("service_name: foo"))
+		Expect(string(docBytes)).ToNot(ContainSubstring("secret"))
+	})
+})
@@ -0,0 +1,53 @@
+package conf_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/adobe-platform/porter/conf"
+)
+
+var _ = Describe("Template transforms", func() {
+
+	It("accepts an exec_name, a dockerfile, or a repo and ref", func() {
+		config := &conf.Config{
+			TemplateTransforms: []*conf.TemplateTransform{
+				{ExecName: "./transform.sh", ExecArgs: []string{"--tag"}},
+				{Dockerfile: "transform/Dockerfile"},
+				{Repo: "https://github.com/example/transform.git", Ref: "v1.0.0"},
+			},
+		}
+
+		Expect(config.ValidateTemplateTransforms()).To(BeNil())
+	})
+
+	It("rejects a transform that is both an executable and a container", func() {
+		config := &conf.Config{
+			TemplateTransforms: []*conf.TemplateTransform{
+				{ExecName: "./transform.sh", Dockerfile: "Dockerfile"},
+			},
+		}
+
+		Expect(config.ValidateTemplateTransforms()).ToNot(BeNil())
+	})
+
+	It("rejects a transform that is neither", func() {
+		config := &conf.Config{
+			TemplateTransforms: []*conf.TemplateTransform{
+				{ExecArgs: []string{"--tag"}},
+			},
+		}
+
+		Expect(config.ValidateTemplateTransforms()).ToNot(BeNil())
+	})
+
+	It("rejects a repo without a ref", func() {
+		config := &conf.Config{
+			TemplateTransforms: []*conf.TemplateTransform{
+				{Repo: "https://github.com/example/transform.git"},
+			},
+		}
+
+		Expect(config.ValidateTemplateTransforms()).ToNot(BeNil())
+	})
+})
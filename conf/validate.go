@@ -14,8 +14,13 @@ package conf
 import (
 	"errors"
 	"fmt"
+	"net"
 	"os"
+	"path"
+	"reflect"
+	"strconv"
 	"strings"
+	"text/template"
 	"time"
 
 	"github.com/adobe-platform/porter/constants"
@@ -38,6 +43,16 @@ func (recv *Config) Validate() (err error) {
 		return
 	}
 
+	err = recv.ValidateNotifications()
+	if err != nil {
+		return
+	}
+
+	err = recv.ValidateTemplateTransforms()
+	if err != nil {
+		return
+	}
+
 	err = recv.ValidateEnvironments()
 	if err != nil {
 		return
@@ -151,6 +166,94 @@ func (recv *Config) ValidateHooks() (err error) {
 	return nil
 }
 
+func (recv *Config) ValidateNotifications() error {
+
+	for i, notification := range recv.Notifications {
+
+		switch notification.Type {
+		case Notification_Webhook, Notification_Slack, Notification_Teams:
+			if notification.URL == "" {
+				return fmt.Errorf("notification %d of type %s is missing a url", i, notification.Type)
+			}
+		case Notification_PagerDuty:
+			if notification.RoutingKeyEnv == "" {
+				return fmt.Errorf("notification %d of type %s is missing a routing_key_env", i, notification.Type)
+			}
+
+			switch notification.Severity {
+			case "critical", "error", "warning", "info":
+			default:
+				return fmt.Errorf("Invalid severity [%s] on notification %d", notification.Severity, i)
+			}
+		case Notification_Email:
+			if notification.SMTP == nil || notification.SMTP.Host == "" ||
+				notification.SMTP.From == "" || len(notification.SMTP.To) == 0 {
+				return fmt.Errorf("notification %d of type %s needs an smtp host, from, and to", i, notification.Type)
+			}
+		default:
+			return fmt.Errorf("Invalid type [%s] on notification %d. Valid values are [%s]", notification.Type, i,
+				strings.Join([]string{Notification_Webhook, Notification_Slack, Notification_Teams,
+					Notification_PagerDuty, Notification_Email}, ", "))
+		}
+
+		for _, phase := range notification.Phases {
+			switch phase {
+			case "pack", "provision", "promote", "prune", "rollback":
+			default:
+				return fmt.Errorf("Invalid phase [%s] on notification %d", phase, i)
+			}
+		}
+
+		for _, outcome := range notification.Outcomes {
+			switch outcome {
+			case Outcome_Success, Outcome_Failure:
+			default:
+				return fmt.Errorf("Invalid outcome [%s] on notification %d", outcome, i)
+			}
+		}
+
+		if notification.Template != "" {
+			if _, err := template.New("").Parse(notification.Template); err != nil {
+				return fmt.Errorf("Invalid template on notification %d %s", i, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (recv *Config) ValidateTemplateTransforms() error {
+
+	for i, transform := range recv.TemplateTransforms {
+
+		if transform.ExecName != "" {
+
+			if transform.Repo != "" || transform.Dockerfile != "" {
+				return fmt.Errorf("template transform %d has an exec_name and a repo or dockerfile", i)
+			}
+			continue
+		}
+
+		if len(transform.ExecArgs) > 0 {
+			return fmt.Errorf("template transform %d has exec_args but no exec_name", i)
+		}
+
+		if transform.Repo == "" {
+
+			if transform.Dockerfile == "" {
+				return fmt.Errorf("template transform %d has neither an exec_name, a dockerfile, nor a repo", i)
+			}
+		} else {
+
+			if transform.Ref == "" {
+				return fmt.Errorf("template transform %d has a configured repo but no ref", i)
+			}
+		}
+	}
+
+	return nil
+}
+
 func (recv *Config) ValidateEnvironments() error {
 	if len(recv.Environments) == 0 {
 		return errors.New("No environments defined")
@@ -158,101 +261,338 @@ func (recv *Config) ValidateEnvironments() error {
 
 	for _, environment := range recv.Environments {
 
-		if len(environment.Regions) == 0 {
-			return errors.New("Environment [" + environment.Name + "] doesn't define any regions")
+		errs := validateEnvironment(environment)
+		if len(errs) > 0 {
+			return errs[0]
 		}
+	}
 
-		validateRegionRoleArn := true
+	return nil
+}
 
-		if environment.RoleARN != "" {
+// ValidateAll runs the same validation as Validate but returns every error
+// instead of stopping at the first one
+func (recv *Config) ValidateAll() (errs []error) {
 
-			validateRegionRoleArn = false
-			if !roleARNRegex.MatchString(environment.RoleARN) {
-				return errors.New("Invalid role_arn for environment " + environment.Name)
-			}
+	validators := []func() error{
+		recv.ValidateRegistryConfig,
+		recv.ValidateTopLevelKeys,
+		recv.ValidateHooks,
+		recv.ValidateNotifications,
+		recv.ValidateTemplateTransforms,
+	}
+
+	for _, validator := range validators {
+		if err := validator(); err != nil {
+			errs = append(errs, err)
 		}
+	}
 
-		for _, region := range environment.Regions {
-			err := ValidateRegion(region, validateRegionRoleArn)
-			if err != nil {
-				return errors.New("Error in environment [" + environment.Name + "] " + err.Error())
+	if len(recv.Environments) == 0 {
+		errs = append(errs, errors.New("No environments defined"))
+	}
+
+	for _, environment := range recv.Environments {
+		errs = append(errs, validateEnvironment(environment)...)
+	}
+
+	return
+}
+
+func validateEnvironment(environment *Environment) (errs []error) {
+
+	if len(environment.Regions) == 0 {
+		errs = append(errs, errors.New("Environment ["+environment.Name+"] doesn't define any regions"))
+		return
+	}
+
+	validateRegionRoleArn := true
+
+	if environment.RoleARN != "" {
+
+		validateRegionRoleArn = false
+		if !roleARNRegex.MatchString(environment.RoleARN) {
+			errs = append(errs, errors.New("Invalid role_arn for environment "+environment.Name))
+		}
+	}
+
+	for _, region := range environment.Regions {
+		for _, err := range ValidateRegion(region, validateRegionRoleArn) {
+			errs = append(errs, errors.New("Error in environment ["+environment.Name+"] "+err.Error()))
+		}
+	}
+
+	errs = append(errs, validateEnvironmentSettings(environment)...)
+
+	return
+}
+
+func validateEnvironmentSettings(environment *Environment) (errs []error) {
+
+	if !instanceTypeRegex.MatchString(environment.InstanceType) {
+		errs = append(errs, errors.New("Invalid instance_type for environment ["+environment.Name+"]"))
+	}
+
+	if !environmentNameRegex.MatchString(environment.Name) {
+		errs = append(errs, errors.New("Invalid name for environment ["+environment.Name+"]. Valid characters are [0-9a-zA-Z]"))
+	}
+
+	if environment.MinSuccessfulRegions < 0 || environment.MinSuccessfulRegions > len(environment.Regions) {
+		errs = append(errs, fmt.Errorf("min_successful_regions for environment [%s] must be between 0 and the number of regions (%d)",
+			environment.Name, len(environment.Regions)))
+	}
+
+	if environment.HAProxy.UsingSSL() {
+		if environment.HAProxy.SSL.Pem == nil || environment.HAProxy.SSL.Pem.SecretsExecName == "" {
+			errs = append(errs, errors.New("haproxy ssl pem defined but no pem secrets_exec_name was defined"))
+		}
+
+		if environment.HAProxy.SSL.HTTPS_Only {
+
+			for _, region := range environment.Regions {
+				if !region.HasELB() && !region.HasTargetGroup() {
+					errs = append(errs, errors.New("https_only is incompatible with elb: none"))
+					break
+				}
 			}
 		}
+	}
+
+	var err error
+	timeoutsParsed := true
+	if environment.HAProxy.Timeout.Client_, err = time.ParseDuration(*environment.HAProxy.Timeout.Client); err != nil {
+		errs = append(errs, errors.New("ParseDuration(timeout_client) "+err.Error()))
+		timeoutsParsed = false
+	}
+
+	if environment.HAProxy.Timeout.Server_, err = time.ParseDuration(*environment.HAProxy.Timeout.Server); err != nil {
+		errs = append(errs, errors.New("ParseDuration(timeout_server) "+err.Error()))
+		timeoutsParsed = false
+	}
+
+	if environment.HAProxy.Timeout.Tunnel_, err = time.ParseDuration(*environment.HAProxy.Timeout.Tunnel); err != nil {
+		errs = append(errs, errors.New("ParseDuration(timeout_tunnel) "+err.Error()))
+	}
 
-		if !instanceTypeRegex.MatchString(environment.InstanceType) {
-			return errors.New("Invalid instance_type for environment [" + environment.Name + "]")
+	if environment.HAProxy.Timeout.HttpRequest_, err = time.ParseDuration(*environment.HAProxy.Timeout.HttpRequest); err != nil {
+		errs = append(errs, errors.New("ParseDuration(timeout_http_request) "+err.Error()))
+	}
+
+	if environment.HAProxy.Timeout.HttpKeepAlive_, err = time.ParseDuration(*environment.HAProxy.Timeout.HttpKeepAlive); err != nil {
+		errs = append(errs, errors.New("ParseDuration(timeout_http_keep_alive) "+err.Error()))
+	}
+
+	if timeoutsParsed && environment.HAProxy.Timeout.Client_ != environment.HAProxy.Timeout.Server_ {
+		errs = append(errs, errors.New("timeout_client != timeout_server"))
+	}
+
+	err = environment.Promotion.Validate()
+	if err != nil {
+		errs = append(errs, errors.New("Error in environment ["+environment.Name+"] "+err.Error()))
+	}
+
+	err = validateParameters(environment.Parameters)
+	if err != nil {
+		errs = append(errs, errors.New("Error in environment ["+environment.Name+"] "+err.Error()))
+	}
+
+	err = environment.AMI.Validate()
+	if err != nil {
+		errs = append(errs, errors.New("Error in environment ["+environment.Name+"] "+err.Error()))
+	}
+
+	blackoutWindowNames := make(map[string]interface{})
+	for _, window := range environment.BlackoutWindows {
+
+		err = window.Validate()
+		if err != nil {
+			errs = append(errs, errors.New("Error in blackout_windows for environment ["+environment.Name+"] "+err.Error()))
 		}
 
-		if !environmentNameRegex.MatchString(environment.Name) {
-			return errors.New("Invalid name for environment [" + environment.Name + "]. Valid characters are [0-9a-zA-Z]")
+		if window.Name != "" {
+			if _, exists := blackoutWindowNames[window.Name]; exists {
+				errs = append(errs, errors.New("Duplicate blackout window name "+window.Name+" in environment ["+environment.Name+"]"))
+			}
+			blackoutWindowNames[window.Name] = nil
+		}
+	}
+
+	if environment.Hotswap {
+
+		for _, region := range environment.Regions {
+			if len(region.ELBs) > 1 {
+				errs = append(errs, errors.New("hot_swap is incompatible with multiple elbs"))
+				break
+			}
 		}
 
-		if environment.HAProxy.UsingSSL() {
-			if environment.HAProxy.SSL.Pem == nil || environment.HAProxy.SSL.Pem.SecretsExecName == "" {
-				return errors.New("haproxy ssl pem defined but no pem secrets_exec_name was defined")
+		for _, region := range environment.Regions {
+			if len(region.TargetGroups) > 1 {
+				errs = append(errs, errors.New("hot_swap is incompatible with multiple target_groups"))
+				break
 			}
+		}
+	}
 
-			if environment.HAProxy.SSL.HTTPS_Only {
+	if environment.Promotion.Strategy == Promotion_Canary {
 
-				for _, region := range environment.Regions {
-					if !region.HasELB() {
-						return errors.New("https_only is incompatible with elb: none")
-					}
-				}
+		for _, region := range environment.Regions {
+			if region.HasTargetGroup() {
+				errs = append(errs, errors.New("Error in environment ["+environment.Name+"] canary promotion is incompatible with target_groups"))
+				break
 			}
 		}
+	}
 
-		var err error
-		if environment.HAProxy.Timeout.Client_, err = time.ParseDuration(*environment.HAProxy.Timeout.Client); err != nil {
-			return errors.New("ParseDuration(timeout_client) " + err.Error())
+	for _, region := range environment.Regions {
+
+		autoScaling, err := environment.GetAutoScaling(region.Name)
+		if err != nil {
+			errs = append(errs, err)
+			continue
 		}
 
-		if environment.HAProxy.Timeout.Server_, err = time.ParseDuration(*environment.HAProxy.Timeout.Server); err != nil {
-			return errors.New("ParseDuration(timeout_server) " + err.Error())
+		if autoScaling == nil {
+			continue
 		}
 
-		if environment.HAProxy.Timeout.Tunnel_, err = time.ParseDuration(*environment.HAProxy.Timeout.Tunnel); err != nil {
-			return errors.New("ParseDuration(timeout_tunnel) " + err.Error())
+		instanceCount, err := environment.GetInstanceCount(region.Name)
+		if err != nil {
+			errs = append(errs, err)
+			continue
 		}
 
-		if environment.HAProxy.Timeout.HttpRequest_, err = time.ParseDuration(*environment.HAProxy.Timeout.HttpRequest); err != nil {
-			return errors.New("ParseDuration(timeout_http_request) " + err.Error())
+		err = autoScaling.Validate(region, instanceCount)
+		if err != nil {
+			errs = append(errs, errors.New("Error in auto_scaling for environment ["+environment.Name+"] region "+region.Name+" "+err.Error()))
 		}
+	}
+
+	return
+}
 
-		if environment.HAProxy.Timeout.HttpKeepAlive_, err = time.ParseDuration(*environment.HAProxy.Timeout.HttpKeepAlive); err != nil {
-			return errors.New("ParseDuration(timeout_http_keep_alive) " + err.Error())
+func (recv *Promotion) Validate() (err error) {
+
+	switch recv.Strategy {
+	case Promotion_All:
+		return
+	case Promotion_Canary:
+		// valid
+	default:
+		return fmt.Errorf("Invalid promotion strategy. Valid values are [%s, %s]",
+			Promotion_All, Promotion_Canary)
+	}
+
+	lastStep := 0
+	for _, step := range recv.CanarySteps {
+		if step <= lastStep || step >= 100 {
+			return errors.New("canary_steps must be increasing percentages between 1 and 99")
 		}
+		lastStep = step
+	}
+
+	if recv.BakeTime_, err = time.ParseDuration(recv.BakeTime); err != nil {
+		return errors.New("ParseDuration(bake_time) " + err.Error())
+	}
+
+	if recv.BakeTime_ <= 0 {
+		return errors.New("bake_time must be positive")
+	}
+
+	if recv.MaxBackend5XX < 0 {
+		return errors.New("max_backend_5xx must be greater than or equal to 0")
+	}
+
+	return
+}
 
-		if environment.HAProxy.Timeout.Client_ != environment.HAProxy.Timeout.Server_ {
-			return errors.New("timeout_client != timeout_server")
+func (recv BlackoutWindow) Validate() error {
+
+	if recv.Name != "" && !blackoutWindowNameRegex.MatchString(recv.Name) {
+		return errors.New("Invalid name " + recv.Name + ". Valid characters are [-_0-9a-zA-Z]")
+	}
+
+	absolute := recv.StartTime != "" || recv.EndTime != ""
+	cron := recv.Cron != "" || recv.Duration != ""
+	weekly := recv.From != "" || recv.To != "" || len(recv.Days) > 0
+
+	kinds := 0
+	for _, kind := range []bool{absolute, cron, weekly} {
+		if kind {
+			kinds++
 		}
+	}
 
-		if environment.Hotswap {
+	if kinds != 1 {
+		return errors.New(recv.String() + " must define exactly one of start_time and end_time, cron and duration, or from and to")
+	}
 
-			for _, region := range environment.Regions {
-				if len(region.ELBs) > 1 {
-					return errors.New("hot_swap is incompatible with multiple elbs")
-				}
-			}
+	if absolute && recv.Timezone != "" {
+		return errors.New(recv.String() + " timezone only applies to recurring windows. Put the offset in start_time and end_time")
+	}
+
+	if recv.Timezone != "" {
+		if _, err := time.LoadLocation(recv.Timezone); err != nil {
+			return errors.New(recv.String() + " invalid timezone " + err.Error())
+		}
+	}
+
+	switch {
+	case absolute:
+
+		if recv.StartTime == "" || recv.EndTime == "" {
+			return errors.New(recv.String() + " both start_time and end_time are required")
+		}
+
+	case cron:
+
+		if recv.Cron == "" || recv.Duration == "" {
+			return errors.New(recv.String() + " both cron and duration are required")
+		}
+
+		if _, err := parseCron(recv.Cron); err != nil {
+			return errors.New(recv.String() + " " + err.Error())
+		}
+
+		duration, err := time.ParseDuration(recv.Duration)
+		if err != nil {
+			return errors.New(recv.String() + " ParseDuration(duration) " + err.Error())
+		}
+
+		if duration <= 0 || duration > maxBlackoutCronDuration {
+			return fmt.Errorf("%s duration must be greater than 0 and at most %s", recv.String(), maxBlackoutCronDuration)
 		}
+
+	case weekly:
+
+		if recv.From == "" || recv.To == "" {
+			return errors.New(recv.String() + " both from and to are required")
+		}
+	}
+
+	// catches everything else like unparseable times and days
+	if _, err := recv.IsActive(time.Now()); err != nil {
+		return errors.New(recv.String() + " " + err.Error())
 	}
 
 	return nil
 }
 
-func ValidateRegion(region *Region, validateRoleArn bool) error {
+// ValidateRegion returns every error in the region. Validate only reports the
+// first.
+func ValidateRegion(region *Region, validateRoleArn bool) (errs []error) {
 
 	err := region.ValidateContainers()
 	if err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
 	if !regionRegex.MatchString(region.Name) {
-		return errors.New("Invalid region name " + region.Name)
+		errs = append(errs, errors.New("Invalid region name "+region.Name))
 	}
 
 	if validateRoleArn && !roleARNRegex.MatchString(region.RoleARN) {
-		return errors.New("Invalid role_arn for region " + region.Name)
+		errs = append(errs, errors.New("Invalid role_arn for region "+region.Name))
 	}
 
 	// TODO validate characters
@@ -263,42 +603,75 @@ func ValidateRegion(region *Region, validateRoleArn bool) error {
 
 	// TODO validate the bucket prefix is one that S3 allows
 	if region.S3Bucket == "" {
-		return errors.New("Empty or missing s3_bucket")
+		errs = append(errs, errors.New("Empty or missing s3_bucket"))
 	}
 
 	if len(region.AZs) == 0 {
-		return errors.New("Missing availability zone for region " + region.Name)
+		errs = append(errs, errors.New("Missing availability zone for region "+region.Name))
 	}
 
 	if region.PrimaryTopology() != Topology_Inet && region.HasELB() {
-		return errors.New("primary container topology can not have an elb for region " + region.Name)
+		errs = append(errs, errors.New("primary container topology can not have an elb for region "+region.Name))
+	}
+
+	// a classic elb's health check target only accepts 200
+	if healthCheck := region.HealthCheck(); region.HasELB() && healthCheck != nil &&
+		healthCheck.Type == HealthCheck_HTTP && healthCheck.ExpectedStatus != "" && healthCheck.ExpectedStatus != "200" {
+		errs = append(errs, errors.New("health_check expected_status must be 200 with an elb for region "+region.Name))
+	}
+
+	err = region.ValidateTargetGroups()
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	err = validateParameters(region.Parameters)
+	if err != nil {
+		errs = append(errs, errors.New("Error in region "+region.Name+" "+err.Error()))
+	}
+
+	err = region.AMI.Validate()
+	if err != nil {
+		errs = append(errs, errors.New("Error in region "+region.Name+" "+err.Error()))
+	}
+
+	if region.MixedInstancesPolicy != nil {
+		err = region.MixedInstancesPolicy.Validate()
+		if err != nil {
+			errs = append(errs, errors.New("Error in mixed_instances_policy for region "+region.Name+" "+err.Error()))
+		}
 	}
 
 	definedVPC := false
 	if region.VpcId != "" {
 		definedVPC = true
 		if !vpcIdRegex.MatchString(region.VpcId) {
-			return errors.New("Invalid vpc_id for region " + region.Name)
+			errs = append(errs, errors.New("Invalid vpc_id for region "+region.Name))
 		}
 	}
 
 	for _, az := range region.AZs {
 		if az.Name == "" {
-			return errors.New("Empty AZ name for region " + region.Name)
+			errs = append(errs, errors.New("Empty AZ name for region "+region.Name))
+			break
 		}
+	}
 
+	for _, az := range region.AZs {
 		if definedVPC {
 			if !subnetIdRegex.MatchString(az.SubnetID) {
-				return errors.New("Invalid subnet_id for region " + region.Name)
+				errs = append(errs, errors.New("Invalid subnet_id for region "+region.Name))
+				break
 			}
 		} else {
 			if az.SubnetID != "" {
-				return errors.New("Defined subnet_id but no vpc_id for region " + region.Name)
+				errs = append(errs, errors.New("Defined subnet_id but no vpc_id for region "+region.Name))
+				break
 			}
 		}
 	}
 
-	return nil
+	return
 }
 
 func (recv *Region) ValidateContainers() error {
@@ -309,10 +682,11 @@ func (recv *Region) ValidateContainers() error {
 		return errors.New("No containers are defined. Was SetDefaults() run?")
 	}
 
-	var healthCheckMethod string
-	var healthCheckPath string
+	var defaultHealthCheck *HealthCheck
 
 	containerNames := make(map[string]interface{})
+	aliases := make(map[string]interface{})
+	routes := make(map[string]interface{})
 	for _, container := range recv.Containers {
 
 		if container.SrcEnvFile != nil {
@@ -338,6 +712,11 @@ func (recv *Region) ValidateContainers() error {
 			return errors.New("pids_limit must be greater than or equal to 1")
 		}
 
+		err := container.validateRuntime()
+		if err != nil {
+			return fmt.Errorf("container %s %s", container.Name, err)
+		}
+
 		if containerCount > 1 && !containerNameRegex.MatchString(container.Name) {
 			return errors.New("Invalid container name")
 		}
@@ -348,31 +727,65 @@ func (recv *Region) ValidateContainers() error {
 
 		if container.Topology == Topology_Inet {
 
-			if !healthMethodRegex.MatchString(container.HealthCheck.Method) {
-				return fmt.Errorf("Invalid health check method %s on container %s", container.HealthCheck.Method, container.Name)
+			err := container.HealthCheck.validate()
+			if err != nil {
+				return fmt.Errorf("container %s health_check %s", container.Name, err)
 			}
 
-			if healthCheckMethod == "" {
-				healthCheckMethod = container.HealthCheck.Method
-			} else if healthCheckMethod != container.HealthCheck.Method {
-				return fmt.Errorf("All inet containers must have the same health check")
-			}
+			if container.Routing != nil {
+
+				err := container.Routing.validate()
+				if err != nil {
+					return fmt.Errorf("container %s routing %s", container.Name, err)
+				}
+
+				route := strings.Join(container.Routing.Hosts, ",") + container.Routing.PathPrefix
+				if _, exists := routes[route]; exists {
+					return fmt.Errorf("container %s has the same routing as another container", container.Name)
+				}
+				routes[route] = nil
+			} else {
 
-			if healthCheckPath == "" {
-				healthCheckPath = container.HealthCheck.Path
-			} else if healthCheckPath != container.HealthCheck.Path {
-				return fmt.Errorf("All inet containers must have the same health check")
+				// unrouted containers share HAProxy's default backend
+				if defaultHealthCheck == nil {
+					defaultHealthCheck = container.HealthCheck
+				} else if !reflect.DeepEqual(defaultHealthCheck, container.HealthCheck) {
+					return fmt.Errorf("All inet containers without routing must have the same health check")
+				}
 			}
+		} else if container.Routing != nil {
+			return fmt.Errorf("container %s routing is only supported on inet containers", container.Name)
 		}
 
 		containerNames[container.Name] = nil
 
 		switch container.Topology {
-		case Topology_Inet, Topology_Worker:
+		case Topology_Inet, Topology_Worker, Topology_Sidecar:
 			// valid
 		default:
-			return fmt.Errorf("Missing or invalid topology. Valid values are [%s, %s]",
-				Topology_Inet, Topology_Worker)
+			return fmt.Errorf("Missing or invalid topology. Valid values are [%s, %s, %s]",
+				Topology_Inet, Topology_Worker, Topology_Sidecar)
+		}
+
+		for _, dependency := range container.DependsOn {
+			switch dependency.Condition {
+			case Condition_Started, Condition_Healthy:
+				// valid
+			default:
+				return fmt.Errorf("Invalid depends_on condition %s on container %s. Valid values are [%s, %s]",
+					dependency.Condition, container.Name, Condition_Started, Condition_Healthy)
+			}
+		}
+
+		for _, alias := range container.Aliases {
+			if !networkAliasRegex.MatchString(alias) {
+				return fmt.Errorf("Invalid alias %s on container %s", alias, container.Name)
+			}
+
+			if _, exists := aliases[alias]; exists {
+				return fmt.Errorf("Duplicate alias %s", alias)
+			}
+			aliases[alias] = nil
 		}
 
 		// TODO check if Dockerfile EXPOSEs more than one port.
@@ -382,5 +795,466 @@ func (recv *Region) ValidateContainers() error {
 		}*/
 	}
 
+	if len(routes) > 0 && defaultHealthCheck == nil {
+		return errors.New("An inet container without routing is required for the default backend")
+	}
+
+	// application load balancers only health check over HTTP(S)
+	if defaultHealthCheck != nil && defaultHealthCheck.Type == HealthCheck_TCP &&
+		recv.TargetGroupType() == TargetGroup_Application {
+		return errors.New("tcp health checks aren't supported with application target groups")
+	}
+
+	if recv.PrimaryTopology() == "" {
+		return fmt.Errorf("At least one %s or %s container is required", Topology_Inet, Topology_Worker)
+	}
+
+	_, err := recv.StartOrder()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (recv *Routing) validate() error {
+
+	if len(recv.Hosts) == 0 && recv.PathPrefix == "" {
+		return errors.New("needs hosts or a path_prefix")
+	}
+
+	for _, host := range recv.Hosts {
+		if !routeHostRegex.MatchString(host) {
+			return errors.New("invalid host " + host)
+		}
+	}
+
+	if recv.PathPrefix != "" && !pathPrefixRegex.MatchString(recv.PathPrefix) {
+		return errors.New("path_prefix must start with / and not end with / like /admin")
+	}
+
+	if recv.StripPrefix && recv.PathPrefix == "" {
+		return errors.New("strip_prefix needs a path_prefix")
+	}
+
+	return nil
+}
+
+// validateRuntime validates the settings translated into docker run arguments
+func (recv *Container) validateRuntime() error {
+
+	var memory int64
+	if recv.Memory != "" {
+		var err error
+		memory, err = parseMemory(recv.Memory)
+		if err != nil {
+			return err
+		}
+
+		if memory < minContainerMemory {
+			return errors.New("memory must be at least 4m")
+		}
+	}
+
+	if recv.MemoryReservation != "" {
+		reservation, err := parseMemory(recv.MemoryReservation)
+		if err != nil {
+			return err
+		}
+
+		if memory > 0 && reservation > memory {
+			return errors.New("memory_reservation must be less than or equal to memory")
+		}
+	}
+
+	if recv.CPUs < 0 {
+		return errors.New("cpus must be greater than 0")
+	}
+
+	if recv.CPUShares != 0 && recv.CPUShares < 2 {
+		return errors.New("cpu_shares must be greater than or equal to 2")
+	}
+
+	for _, capability := range recv.CapAdd {
+		capability = Capability(capability)
+		if capability == "ALL" {
+			return errors.New("cap_add can't be ALL")
+		}
+		if _, exists := capabilities[capability]; !exists {
+			return errors.New("invalid capability in cap_add " + capability)
+		}
+	}
+
+	for _, capability := range recv.CapDrop {
+		capability = Capability(capability)
+		if _, exists := capabilities[capability]; !exists && capability != "ALL" {
+			return errors.New("invalid capability in cap_drop " + capability)
+		}
+	}
+
+	tmpfsPaths := make(map[string]interface{})
+	for _, tmpfs := range recv.Tmpfs {
+
+		if !strings.HasPrefix(tmpfs.Path, "/") || tmpfs.Path == "/" {
+			return errors.New("tmpfs path must be an absolute path other than /")
+		}
+
+		if _, exists := tmpfsPaths[tmpfs.Path]; exists {
+			return errors.New("duplicate tmpfs path " + tmpfs.Path)
+		}
+		tmpfsPaths[tmpfs.Path] = nil
+
+		if tmpfs.Size != "" {
+			if _, err := parseMemory(tmpfs.Size); err != nil {
+				return fmt.Errorf("tmpfs %s %s", tmpfs.Path, err)
+			}
+		}
+
+		if tmpfs.Mode != "" && !tmpfsModeRegex.MatchString(tmpfs.Mode) {
+			return fmt.Errorf("tmpfs %s mode must be octal like 1777", tmpfs.Path)
+		}
+	}
+
+	containerPaths := make(map[string]interface{})
+	for _, volume := range recv.Volumes {
+
+		if !strings.HasPrefix(volume.HostPath, "/") {
+			return errors.New("volume host_path must be an absolute path")
+		}
+
+		if _, exists := sensitiveHostPaths[path.Clean(volume.HostPath)]; exists {
+			return errors.New("volume host_path can't be the sensitive host path " + volume.HostPath)
+		}
+
+		if !strings.HasPrefix(volume.ContainerPath, "/") || volume.ContainerPath == "/" {
+			return errors.New("volume container_path must be an absolute path other than /")
+		}
+
+		if _, exists := containerPaths[volume.ContainerPath]; exists {
+			return errors.New("duplicate volume container_path " + volume.ContainerPath)
+		}
+		if _, exists := tmpfsPaths[volume.ContainerPath]; exists {
+			return errors.New("volume container_path is also a tmpfs path " + volume.ContainerPath)
+		}
+		containerPaths[volume.ContainerPath] = nil
+	}
+
+	for key := range recv.Environment {
+		if !variableNameRegex.MatchString(key) {
+			return errors.New("invalid environment variable name " + key)
+		}
+
+		if _, exists := reservedEnvironment[key]; exists {
+			return errors.New("environment variable " + key + " is set by porter")
+		}
+	}
+
+	if recv.LogDriver != "" {
+		if _, exists := logDrivers[recv.LogDriver]; !exists {
+			return errors.New("invalid log_driver " + recv.LogDriver)
+		}
+	}
+
+	for key := range recv.LogOptions {
+		if key == "" {
+			return errors.New("log_options can't have an empty key")
+		}
+	}
+
+	ulimits := make(map[string]interface{})
+	for _, ulimit := range recv.Ulimits {
+
+		if _, exists := ulimitNames[ulimit.Name]; !exists {
+			return errors.New("invalid ulimit " + ulimit.Name)
+		}
+
+		if _, exists := ulimits[ulimit.Name]; exists {
+			return errors.New("duplicate ulimit " + ulimit.Name)
+		}
+		ulimits[ulimit.Name] = nil
+
+		if ulimit.Soft < 0 || ulimit.Hard < 0 {
+			return fmt.Errorf("ulimit %s soft and hard must be greater than or equal to 0", ulimit.Name)
+		}
+
+		if ulimit.Hard != 0 && ulimit.Soft > ulimit.Hard {
+			return fmt.Errorf("ulimit %s soft must be less than or equal to hard", ulimit.Name)
+		}
+	}
+
+	return nil
+}
+
+func (recv *Region) ValidateTargetGroups() error {
+	if !recv.HasTargetGroup() {
+		return nil
+	}
+
+	if recv.HasELB() {
+		return errors.New("elb and target_groups are mutually exclusive for region " + recv.Name)
+	}
+
+	if recv.PrimaryTopology() != Topology_Inet {
+		return errors.New("primary container topology can not have target_groups for region " + recv.Name)
+	}
+
+	if recv.VpcId == "" {
+		return errors.New("target_groups require a vpc_id for region " + recv.Name)
+	}
+
+	if recv.HostedZoneName != "" {
+		return errors.New("hosted_zone_name is incompatible with target_groups for region " + recv.Name)
+	}
+
+	tags := make(map[string]interface{})
+	for _, targetGroup := range recv.TargetGroups {
+
+		if _, exists := tags[targetGroup.Tag]; exists {
+			return fmt.Errorf("Duplicate target group tag %s for region %s", targetGroup.Tag, recv.Name)
+		}
+		tags[targetGroup.Tag] = nil
+
+		switch targetGroup.Type {
+		case TargetGroup_Application, TargetGroup_Network:
+		default:
+			return fmt.Errorf("Invalid target group type. Valid values are [%s, %s]",
+				TargetGroup_Application, TargetGroup_Network)
+		}
+
+		if targetGroup.Type != recv.TargetGroupType() {
+			return errors.New("target_groups must all be the same type for region " + recv.Name)
+		}
+
+		if (targetGroup.ARN == "") == (targetGroup.ListenerARN == "") {
+			return errors.New("Exactly one of arn or listener_arn is required for each target group in region " + recv.Name)
+		}
+
+		if targetGroup.Type == TargetGroup_Network {
+
+			if len(targetGroup.IngressCIDRs) == 0 {
+				return errors.New("ingress_cidrs is required for network target groups in region " + recv.Name)
+			}
+
+			for _, cidr := range targetGroup.IngressCIDRs {
+				if _, _, err := net.ParseCIDR(cidr); err != nil {
+					return fmt.Errorf("Invalid ingress_cidrs %s in region %s", cidr, recv.Name)
+				}
+			}
+		} else if len(targetGroup.IngressCIDRs) > 0 {
+			return errors.New("ingress_cidrs is only valid for network target groups in region " + recv.Name)
+		}
+
+		if targetGroup.ARN != "" && !targetGroupARNRegex.MatchString(targetGroup.ARN) {
+			return errors.New("Invalid target group arn for region " + recv.Name)
+		}
+
+		if targetGroup.ListenerARN != "" && !listenerARNRegex.MatchString(targetGroup.ListenerARN) {
+			return errors.New("Invalid target group listener_arn for region " + recv.Name)
+		}
+	}
+
+	return nil
+}
+
+func (recv *MixedInstancesPolicy) Validate() error {
+
+	if len(recv.InstanceTypes) == 0 {
+		return errors.New("At least one instance_types is required")
+	}
+
+	for _, instanceType := range recv.InstanceTypes {
+		if !instanceTypeRegex.MatchString(instanceType) {
+			return errors.New("Invalid instance type " + instanceType)
+		}
+	}
+
+	if recv.OnDemandBaseCapacity < 0 {
+		return errors.New("on_demand_base_capacity must be positive")
+	}
+
+	if recv.SpotPercentage < 0 || recv.SpotPercentage > 100 {
+		return errors.New("spot_percentage must be between 0 and 100")
+	}
+
+	switch recv.SpotAllocationStrategy {
+	case "", SpotAllocation_LowestPrice, SpotAllocation_CapacityOptimized:
+	default:
+		return fmt.Errorf("Invalid spot_allocation_strategy. Valid values are [%s, %s]",
+			SpotAllocation_LowestPrice, SpotAllocation_CapacityOptimized)
+	}
+
+	if recv.SpotInstancePools < 0 || recv.SpotInstancePools > 20 {
+		return errors.New("spot_instance_pools must be between 0 and 20")
+	}
+
+	if recv.SpotInstancePools > 0 && recv.SpotAllocationStrategy == SpotAllocation_CapacityOptimized {
+		return errors.New("spot_instance_pools only applies to the lowest-price spot_allocation_strategy")
+	}
+
+	if recv.SpotMaxPrice != "" {
+		if _, err := strconv.ParseFloat(recv.SpotMaxPrice, 64); err != nil {
+			return errors.New("Invalid spot_max_price " + recv.SpotMaxPrice)
+		}
+	}
+
+	return nil
+}
+
+func (recv *AutoScaling) Validate(region *Region, instanceCount uint) error {
+
+	if recv.MaxSize == 0 {
+		return errors.New("max_size must be greater than 0")
+	}
+
+	if recv.MinSize > recv.MaxSize {
+		return errors.New("min_size is greater than max_size")
+	}
+
+	if instanceCount < recv.MinSize || instanceCount > recv.MaxSize {
+		return errors.New("instance_count must be between min_size and max_size")
+	}
+
+	// policies and scheduled actions share a namespace since their names
+	// become part of CloudFormation logical names
+	names := make(map[string]interface{})
+	validateName := func(name string) error {
+		if !scalingNameRegex.MatchString(name) {
+			return errors.New("Invalid name [" + name + "]. Valid characters are [0-9a-zA-Z]")
+		}
+
+		if _, exists := names[name]; exists {
+			return errors.New("Duplicate name " + name)
+		}
+		names[name] = nil
+
+		return nil
+	}
+
+	for _, policy := range recv.TargetTracking {
+
+		if err := validateName(policy.Name); err != nil {
+			return err
+		}
+
+		switch policy.Metric {
+		case ScalingMetric_CPU:
+		case ScalingMetric_RequestCount:
+			if region.TargetGroupType() != TargetGroup_Application {
+				return errors.New(ScalingMetric_RequestCount + " requires application target_groups")
+			}
+
+			if len(region.TargetGroups) > 1 {
+				return errors.New(ScalingMetric_RequestCount + " is incompatible with multiple target_groups")
+			}
+		default:
+			return fmt.Errorf("Invalid target_tracking metric. Valid values are [%s, %s]",
+				ScalingMetric_CPU, ScalingMetric_RequestCount)
+		}
+
+		if policy.TargetValue <= 0 {
+			return errors.New("target_value must be positive")
+		}
+
+		if policy.EstimatedInstanceWarmup < 0 {
+			return errors.New("estimated_instance_warmup must be positive")
+		}
+	}
+
+	for _, policy := range recv.StepScaling {
+
+		if err := validateName(policy.Name); err != nil {
+			return err
+		}
+
+		switch policy.AdjustmentType {
+		case ScalingAdjustment_Change, ScalingAdjustment_Percent, ScalingAdjustment_Exact:
+		default:
+			return fmt.Errorf("Invalid adjustment_type. Valid values are [%s, %s, %s]",
+				ScalingAdjustment_Change, ScalingAdjustment_Percent, ScalingAdjustment_Exact)
+		}
+
+		switch policy.MetricAggregationType {
+		case "Average", "Minimum", "Maximum":
+		default:
+			return errors.New("Invalid metric_aggregation_type. Valid values are [Average, Minimum, Maximum]")
+		}
+
+		if policy.EstimatedInstanceWarmup < 0 {
+			return errors.New("estimated_instance_warmup must be positive")
+		}
+
+		if len(policy.Steps) == 0 {
+			return errors.New("At least one step is required for step_scaling policy " + policy.Name)
+		}
+
+		for _, step := range policy.Steps {
+			if step.LowerBound == nil && step.UpperBound == nil {
+				return errors.New("Each step requires a lower_bound, upper_bound, or both")
+			}
+
+			if step.LowerBound != nil && step.UpperBound != nil && *step.LowerBound >= *step.UpperBound {
+				return errors.New("A step's lower_bound must be less than its upper_bound")
+			}
+		}
+
+		if policy.Alarm.MetricName == "" {
+			return errors.New("Missing alarm metric_name for step_scaling policy " + policy.Name)
+		}
+
+		switch policy.Alarm.Statistic {
+		case "Average", "Sum", "SampleCount", "Minimum", "Maximum":
+		default:
+			return errors.New("Invalid alarm statistic. Valid values are [Average, Sum, SampleCount, Minimum, Maximum]")
+		}
+
+		switch policy.Alarm.ComparisonOperator {
+		case "GreaterThanOrEqualToThreshold", "GreaterThanThreshold",
+			"LessThanThreshold", "LessThanOrEqualToThreshold":
+		default:
+			return errors.New("Invalid alarm comparison_operator. Valid values are [GreaterThanOrEqualToThreshold, GreaterThanThreshold, LessThanThreshold, LessThanOrEqualToThreshold]")
+		}
+
+		if policy.Alarm.Period <= 0 || policy.Alarm.Period%60 != 0 {
+			return errors.New("alarm period must be a multiple of 60")
+		}
+
+		if policy.Alarm.EvaluationPeriods <= 0 {
+			return errors.New("alarm evaluation_periods must be positive")
+		}
+	}
+
+	for _, action := range recv.ScheduledActions {
+
+		if err := validateName(action.Name); err != nil {
+			return err
+		}
+
+		if action.Recurrence == "" && action.StartTime == "" {
+			return errors.New("At least one of recurrence or start_time is required for scheduled action " + action.Name)
+		}
+
+		if action.Recurrence != "" && !recurrenceRegex.MatchString(action.Recurrence) {
+			return errors.New("Invalid recurrence [" + action.Recurrence + "]. Use a cron expression with 5 fields")
+		}
+
+		for _, timeStr := range []string{action.StartTime, action.EndTime} {
+			if timeStr == "" {
+				continue
+			}
+
+			if _, err := time.Parse(time.RFC3339, timeStr); err != nil {
+				return errors.New("Invalid start_time or end_time " + timeStr + ". Use RFC 3339 e.g. 2018-01-02T15:04:05Z")
+			}
+		}
+
+		if action.MinSize == nil && action.MaxSize == nil && action.DesiredCapacity == nil {
+			return errors.New("At least one of min_size, max_size, or desired_capacity is required for scheduled action " + action.Name)
+		}
+
+		if action.MinSize != nil && action.MaxSize != nil && *action.MinSize > *action.MaxSize {
+			return errors.New("min_size is greater than max_size for scheduled action " + action.Name)
+		}
+	}
+
 	return nil
 }
@@ -0,0 +1,94 @@
+package conf_test
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v2"
+
+	"github.com/adobe-platform/porter/conf"
+)
+
+var _ = Describe("ValidateAll", func() {
+
+	parse := func(contents string) *conf.Config {
+		config := &conf.Config{}
+		Expect(yaml.Unmarshal([]byte(contents), config)).To(Succeed())
+		config.SetDefaults()
+		return config
+	}
+
+	It("reports every error in an environment", func() {
+		config := parse(`
+service_name: foo
+environments:
+- name: stage
+  instance_type: not-a-type
+  min_successful_regions: 3
+  regions:
+  - name: us-west-2
+    role_arn: arn:aws:iam::123456789012:role/porter-deployment
+    vpc_id: vpc-1234
+`)
+
+		errs := config.ValidateAll()
+
+		var messages []string
+		for _, err := range errs {
+			messages = append(messages, err.Error())
+		}
+		joined := strings.Join(messages, "\n")
+
+		Expect(joined).To(ContainSubstring("s3_bucket"))
+		Expect(joined).To(ContainSubstring("Missing availability zone for region us-west-2"))
+		Expect(joined).To(ContainSubstring("Invalid vpc_id for region us-west-2"))
+		Expect(joined).To(ContainSubstring("Invalid instance_type for environment [stage]"))
+		Expect(joined).To(ContainSubstring("min_successful_regions for environment [stage]"))
+	})
+
+	It("agrees with Validate on the first error", func() {
+		config := parse(`
+service_name: foo
+environments:
+- name: stage
+  instance_type: not-a-type
+  regions:
+  - name: us-west-2
+    role_arn: arn:aws:iam::123456789012:role/porter-deployment
+`)
+
+		errs := config.ValidateAll()
+		Expect(errs).ToNot(BeEmpty())
+		Expect(config.Validate()).To(Equal(errs[0]))
+	})
+
+	errorText := func(config *conf.Config) string {
+		var messages []string
+		for _, err := range config.ValidateAll() {
+			messages = append(messages, err.Error())
+		}
+		return strings.Join(messages, "\n")
+	}
+
+	It("only accepts an expected_status of 200 with a classic elb", func() {
+		config := parse(`
+service_name: foo
+environments:
+- name: stage
+  regions:
+  - name: us-west-2
+    elbs:
+    - name: stage-elb
+    containers:
+    - name: web
+      health_check:
+        expected_status: 200-399
+`)
+
+		Expect(errorText(config)).To(ContainSubstring("expected_status must be 200 with an elb for region us-west-2"))
+
+		config.Environments[0].Regions[0].Containers[0].HealthCheck.ExpectedStatus = "200"
+		Expect(errorText(config)).ToNot(ContainSubstring("expected_status"))
+	})
+})
@@ -28,6 +28,8 @@ const (
 	ProvisionOutputPath        = TempDir + "/provision_state.json"
 	CreateStackOutputPath      = TempDir + "/create_stack_output.json"
 	CloudFormationTemplatePath = TempDir + "/CloudFormationTemplate.json"
+	PlanOutputPath             = TempDir + "/plan.json"
+	DevRunDir                  = TempDir + "/dev-run"
 	EnvFile                    = "/dockerfile.env"
 
 	// Debug/config
@@ -114,6 +116,11 @@ const (
 	// which is provided automatically and tied to a provisioned stack.
 	PorterStackIdTag = "porter-aws-cloudformation-stack-id"
 
+	// The value of PorterStackIdTag before the most recent promotion. This is
+	// the stack `porter build rollback` promotes and `porter build prune`
+	// won't delete.
+	PorterPreviousStackIdTag = "porter-aws-cloudformation-previous-stack-id"
+
 	// Replaced by the release_porter script.
 	//
 	// Don't change this.
@@ -28,6 +28,11 @@ func Call() {
 	stackId := os.Getenv("AWS_STACKID")
 	log := logger.Daemon("AWS_STACKID", stackId)
 
+	if targetGroupCSV := os.Getenv("TARGET_GROUPS"); targetGroupCSV != "" {
+		registerWithTargetGroups(log, stackId, strings.Split(targetGroupCSV, ","))
+		return
+	}
+
 	log.Info("Auto ELB instance registration")
 
 	elbCSV := os.Getenv("ELBS")
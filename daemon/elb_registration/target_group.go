@@ -0,0 +1,83 @@
+/*
+ * (c) 2016-2018 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+package elb_registration
+
+import (
+	"github.com/adobe-platform/porter/aws/elbv2"
+	"github.com/adobe-platform/porter/aws_session"
+	"github.com/adobe-platform/porter/constants"
+	"github.com/adobe-platform/porter/daemon/identity"
+	"github.com/adobe-platform/porter/util"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+func registerWithTargetGroups(log log15.Logger, stackId string, targetGroupArns []string) {
+
+	log.Info("Auto target group instance registration")
+
+	ii, err := identity.Get(log)
+	if err != nil {
+		return
+	}
+	instanceIds := []string{ii.Instance.InstanceID}
+
+	client := elbv2.New(aws_session.Get(ii.AwsCreds.Region))
+
+	for _, targetGroupArn := range targetGroupArns {
+		log := log.New("TargetGroupARN", targetGroupArn)
+
+		var tags map[string]string
+
+		retryMsg := func(i int) { log.Warn("elbv2.GetTags retrying", "Count", i) }
+		if !util.SuccessRetryer(8, retryMsg, func() bool {
+
+			tags, err = elbv2.GetTags(client, targetGroupArn)
+			if err != nil {
+				log.Error("elbv2.GetTags", "Error", err)
+				return false
+			}
+
+			return true
+		}) {
+			log.Warn("elbv2.GetTags failed")
+			continue
+		}
+
+		promotedStackId, exists := tags[constants.PorterStackIdTag]
+		if !exists {
+			log.Warn("Didn't find tag key " + constants.PorterStackIdTag)
+			continue
+		}
+
+		if promotedStackId != stackId {
+			log.Info("Instance is NOT associated with a stack that was promoted into this target group")
+			continue
+		}
+
+		log.Info("Instance IS associated with a stack that was promoted into this target group")
+		log.Info("RegisterTargets", "InstanceId", ii.Instance.InstanceID)
+
+		retryMsg = func(i int) { log.Warn("elbv2.RegisterTargets retrying", "Count", i) }
+		if !util.SuccessRetryer(8, retryMsg, func() bool {
+
+			err = elbv2.RegisterTargets(client, targetGroupArn, instanceIds)
+			if err != nil {
+				log.Error("RegisterTargets", "Error", err)
+				return false
+			}
+
+			return true
+		}) {
+			log.Error("Instance Registration failed")
+		}
+	}
+}
@@ -11,9 +11,13 @@
  */
 package flags
 
+import "github.com/adobe-platform/porter/conf"
+
 var (
-	Environment       string
-	ServiceName       string
-	HealthCheckMethod string
-	HealthCheckPath   string
+	Environment string
+	ServiceName string
+
+	// HealthCheck is the readiness check polled through HAProxy. It's nil
+	// if there are no inet containers
+	HealthCheck *conf.HealthCheck
 )
@@ -25,6 +25,7 @@ import (
 	"time"
 
 	"github.com/adobe-platform/porter/aws_session"
+	"github.com/adobe-platform/porter/conf"
 	"github.com/adobe-platform/porter/constants"
 	"github.com/adobe-platform/porter/daemon/flags"
 	"github.com/adobe-platform/porter/daemon/identity"
@@ -54,6 +55,28 @@ const (
 	fastSleepDuration = 2 * time.Second
 )
 
+// probe checks the default backend through HAProxy. A TCP connection to
+// HAProxy always succeeds so a tcp health check instead waits until HAProxy
+// stops answering for the backend with a 502, 503, or 504
+func probe(client *http.Client, healthCheck *conf.HealthCheck) error {
+
+	if healthCheck.Type != conf.HealthCheck_TCP {
+		return healthCheck.Probe(client, "localhost")
+	}
+
+	resp, err := client.Get("http://localhost/")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
 func Call() {
 	log := logger.Daemon(
 		"package", "wait_handle",
@@ -80,55 +103,42 @@ func Call() {
 	sleepDuration := fastSleepDuration
 	consecutiveHealth := 0
 
-	healthCheckClient := &http.Client{
-		Timeout: constants.HC_Timeout * time.Second,
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: true,
+	// nil for a worker or cron primary topology
+	healthCheck := flags.HealthCheck
+
+	var healthCheckClient *http.Client
+	if healthCheck != nil {
+		healthCheckClient = &http.Client{
+			Timeout: time.Duration(healthCheck.Timeout) * time.Second,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{
+					InsecureSkipVerify: true,
+				},
 			},
-		},
+		}
 	}
 
-	msg := flags.HealthCheckMethod + " " + flags.HealthCheckPath
-	hcUrl := "http://localhost" + flags.HealthCheckPath
-
-	for {
-		// assume a worker or cron primary topology
-		if flags.HealthCheckMethod == "" && flags.HealthCheckPath == "" {
-			break
-		}
+	for healthCheck != nil {
 
 		time.Sleep(sleepDuration)
 
-		req, err := http.NewRequest(flags.HealthCheckMethod, hcUrl, nil)
-		if err != nil {
-			log.Warn("http.NewRequest", "Error", err)
-			continue
-		}
-
-		resp, err := healthCheckClient.Do(req)
-
-		if err != nil {
-			log.Warn(msg, "Error", err)
-			continue
-		}
-
-		if resp.StatusCode == 200 {
+		err := probe(healthCheckClient, healthCheck)
+		if err == nil {
 
 			consecutiveHealth++
-			sleepDuration = constants.HC_Interval * time.Second
+			sleepDuration = time.Duration(healthCheck.Interval) * time.Second
 
 			log.Info(fmt.Sprintf("successful health check %d/%d",
-				consecutiveHealth, constants.HC_HealthyThreshold))
+				consecutiveHealth, healthCheck.HealthyThreshold))
 
 		} else {
 
 			consecutiveHealth = 0
 			sleepDuration = fastSleepDuration
-			log.Warn(msg, "StatusCode", resp.StatusCode)
+			log.Warn(healthCheck.String(), "Error", err)
 		}
 
-		if consecutiveHealth >= constants.HC_HealthyThreshold {
+		if consecutiveHealth >= healthCheck.HealthyThreshold {
 			log.Info("health threshold met. calling wait handle")
 			break
 		}
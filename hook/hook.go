@@ -18,6 +18,7 @@ import (
 	"os"
 	"os/exec"
 	"path"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -27,6 +28,7 @@ import (
 	"github.com/adobe-platform/porter/conf"
 	"github.com/adobe-platform/porter/constants"
 	"github.com/adobe-platform/porter/logger"
+	"github.com/adobe-platform/porter/output"
 	"github.com/adobe-platform/porter/provision_state"
 	"gopkg.in/inconshreveable/log15.v2"
 )
@@ -37,6 +39,7 @@ type (
 
 		serviceName string
 		hookName    string
+		regionName  string
 
 		commandSuccess bool
 	}
@@ -53,8 +56,20 @@ var (
 	// Multi-region deployment means we need a globally unique id for git clones
 	// and image names
 	globalCounter *uint32 = new(uint32)
+
+	// set when a command is run during a blackout window with
+	// --override-blackout
+	blackoutOverrideWindow string
+	blackoutOverrideReason string
 )
 
+// SetBlackoutOverride exposes an overridden blackout window to hooks that run
+// after it's called
+func SetBlackoutOverride(window, reason string) {
+	blackoutOverrideWindow = window
+	blackoutOverrideReason = reason
+}
+
 func Execute(log log15.Logger,
 	hookName, environment string,
 	provisionedRegions map[string]*provision_state.Region,
@@ -194,16 +209,33 @@ func ExecuteWithRunCapture(log log15.Logger,
 					"-e", "AWS_ELASTICLOADBALANCING_LOADBALANCER_DNS="+elbDNS)
 			}
 
+			if regionState.ProvisionedTargetGroupARN != "" {
+				runArgs = append(runArgs,
+					"-e", "AWS_ELASTICLOADBALANCINGV2_TARGETGROUP_ARN="+regionState.ProvisionedTargetGroupARN)
+			}
+
 			if regionState.StackId != "" {
 				runArgs = append(runArgs,
 					"-e", "AWS_CLOUDFORMATION_STACKID="+regionState.StackId)
 			}
 
+			outputKeys := make([]string, 0)
+			for outputKey := range regionState.Outputs {
+				outputKeys = append(outputKeys, outputKey)
+			}
+			sort.Strings(outputKeys)
+
+			for _, outputKey := range outputKeys {
+				runArgs = append(runArgs,
+					"-e", "AWS_CLOUDFORMATION_OUTPUT_"+outputKey+"="+regionState.Outputs[outputKey])
+			}
+
 			hookRunner := &regionHookRunner{
 				runOutput: runOutput,
 
 				serviceName: config.ServiceName,
 				hookName:    hookName,
+				regionName:  regionName,
 
 				commandSuccess: commandSuccess,
 			}
@@ -267,6 +299,14 @@ func runArgsFactory(log log15.Logger, config *conf.Config, workingDir string) []
 		"-e", "HAPROXY_STATS_URL=" + constants.HAProxyStatsUrl,
 	}
 
+	if blackoutOverrideReason != "" {
+		runArgs = append(runArgs,
+			"-e", "PORTER_BLACKOUT_OVERRIDE=true",
+			"-e", "PORTER_BLACKOUT_WINDOW="+blackoutOverrideWindow,
+			"-e", "PORTER_BLACKOUT_OVERRIDE_REASON="+blackoutOverrideReason,
+		)
+	}
+
 	revParseOutput, err := exec.Command("git", "rev-parse", "--short", "HEAD").Output()
 	if err == nil {
 		sha1 := strings.TrimSpace(string(revParseOutput))
@@ -400,6 +440,17 @@ func (recv *regionHookRunner) runConfigHook(log log15.Logger,
 	log.Debug("runConfigHook() BEGIN")
 	defer log.Debug("runConfigHook() END")
 
+	defer func() {
+		output.AddHook(output.Hook{
+			Name:       recv.hookName,
+			Region:     recv.regionName,
+			Repo:       hook.Repo,
+			Ref:        hook.Ref,
+			Dockerfile: hook.Dockerfile,
+			Success:    success,
+		})
+	}()
+
 	for envKey, envValue := range hook.Environment {
 		if envValue == "" {
 			envValue = os.Getenv(envKey)
@@ -79,6 +79,12 @@ func Daemon(kvps ...interface{}) log15.Logger {
 	return Host(kvps...)
 }
 
+// SetCLIOutput changes where CLI logs are written. This is used to keep stdout
+// free for machine-readable output
+func SetCLIOutput(writer io.Writer) {
+	SetHandlerWithFormat(cliLog, writer, getLogFmt())
+}
+
 func SetHandler(log log15.Logger, writer io.Writer) {
 	SetHandlerWithFormat(log, writer, getLogFmt())
 }
@@ -13,10 +13,13 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"net/http"
+	"os"
 	"time"
 
 	"github.com/adobe-platform/porter/commands"
+	"github.com/adobe-platform/porter/output"
 	"github.com/phylake/go-cli"
 )
 
@@ -26,8 +29,13 @@ func main() {
 		Timeout: 20 * time.Minute,
 	}
 
-	var err error
-	cliDriver := cli.New(flag.ExitOnError)
+	args, err := output.Init(os.Args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	cliDriver := cli.NewWithEnv(flag.ExitOnError, args, nil)
 
 	if err = cliDriver.RegisterRoot(commands.GetRootCommand()); err != nil {
 		panic(err)
@@ -0,0 +1,157 @@
+/*
+ * (c) 2016-2018 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+package notify
+
+import (
+	"fmt"
+	"os"
+)
+
+const (
+	CI_Jenkins       = "jenkins"
+	CI_GitLab        = "gitlab"
+	CI_GitHubActions = "github_actions"
+	CI_GO            = "go"
+)
+
+// CIMetadata is discovered from the environment variables CI systems set on
+// their jobs. Fields a CI system doesn't provide are empty
+type CIMetadata struct {
+	System      string `json:"System"`
+	Pipeline    string `json:"Pipeline"`
+	BuildNumber string `json:"BuildNumber"`
+	Job         string `json:"Job"`
+	URL         string `json:"URL"`
+	Commit      string `json:"Commit"`
+	Branch      string `json:"Branch"`
+}
+
+// DiscoverCI finds CI metadata using getenv which is usually os.Getenv
+func DiscoverCI(getenv func(string) string) (ci CIMetadata) {
+
+	switch {
+	case getenv("GITHUB_ACTIONS") == "true":
+		ci.System = CI_GitHubActions
+		ci.Pipeline = getenv("GITHUB_WORKFLOW")
+		ci.BuildNumber = getenv("GITHUB_RUN_NUMBER")
+		ci.Job = getenv("GITHUB_JOB")
+		ci.Commit = getenv("GITHUB_SHA")
+		ci.Branch = getenv("GITHUB_REF_NAME")
+		if ci.Branch == "" {
+			ci.Branch = getenv("GITHUB_REF")
+		}
+
+		serverURL := getenv("GITHUB_SERVER_URL")
+		if serverURL == "" {
+			serverURL = "https://github.com"
+		}
+		if getenv("GITHUB_REPOSITORY") != "" && getenv("GITHUB_RUN_ID") != "" {
+			ci.URL = fmt.Sprintf("%s/%s/actions/runs/%s",
+				serverURL, getenv("GITHUB_REPOSITORY"), getenv("GITHUB_RUN_ID"))
+		}
+
+	case getenv("GITLAB_CI") != "":
+		ci.System = CI_GitLab
+		ci.Pipeline = getenv("CI_PROJECT_PATH")
+		ci.BuildNumber = getenv("CI_PIPELINE_ID")
+		ci.Job = getenv("CI_JOB_NAME")
+		ci.Commit = getenv("CI_COMMIT_SHA")
+		ci.Branch = getenv("CI_COMMIT_REF_NAME")
+		ci.URL = getenv("CI_JOB_URL")
+		if ci.URL == "" {
+			ci.URL = getenv("CI_PIPELINE_URL")
+		}
+
+	case getenv("JENKINS_URL") != "" || getenv("JENKINS_HOME") != "":
+		ci.System = CI_Jenkins
+		ci.Pipeline = getenv("JOB_NAME")
+		ci.BuildNumber = getenv("BUILD_NUMBER")
+		ci.Job = getenv("JOB_BASE_NAME")
+		ci.Commit = getenv("GIT_COMMIT")
+		ci.Branch = getenv("GIT_BRANCH")
+		ci.URL = getenv("BUILD_URL")
+
+	case getenv("GO_PIPELINE_NAME") != "":
+		ci.System = CI_GO
+		ci.Pipeline = getenv("GO_PIPELINE_NAME")
+		ci.BuildNumber = getenv("GO_PIPELINE_COUNTER")
+		ci.Job = getenv("GO_JOB_NAME")
+		ci.Commit = getenv("GO_REVISION")
+
+		serverURL := getenv("GO_NOTIFICATION_URL")
+		if serverURL == "" {
+			serverURL = getenv("GO_SERVER_URL")
+		}
+		if serverURL != "" {
+			ci.URL = fmt.Sprintf("%s/tab/build/detail/%s/%s/%s/%s/%s",
+				serverURL,
+				getenv("GO_PIPELINE_NAME"),
+				getenv("GO_PIPELINE_COUNTER"),
+				getenv("GO_STAGE_NAME"),
+				getenv("GO_STAGE_COUNTER"),
+				getenv("GO_JOB_NAME"))
+		}
+	}
+
+	return
+}
+
+// goCIMessage is the original Slack message format. It's still used for Slack
+// notifications without a template when running in GO CI
+func goCIMessage(buildPhase string, phaseSuccess bool) string {
+
+	pipelineUrl := fmt.Sprintf("%s/%s/%s",
+		os.Getenv("GO_NOTIFICATION_URL"),
+		"tab/pipeline/history",
+		os.Getenv("GO_PIPELINE_NAME"))
+
+	buildUrl := fmt.Sprintf("%s/%s/%s/%s", os.Getenv("GO_NOTIFICATION_URL"),
+		"pipelines/value_stream_map",
+		os.Getenv("GO_PIPELINE_NAME"),
+		os.Getenv("GO_PIPELINE_COUNTER"))
+
+	stageUrl := fmt.Sprintf("%s/%s/%s/%s/%s/%s", os.Getenv("GO_NOTIFICATION_URL"),
+		"pipelines",
+		os.Getenv("GO_PIPELINE_NAME"),
+		os.Getenv("GO_PIPELINE_COUNTER"),
+		os.Getenv("GO_STAGE_NAME"),
+		os.Getenv("GO_STAGE_COUNTER"))
+
+	jobUrl := fmt.Sprintf("%s/%s/%s/%s/%s/%s/%s%s",
+		os.Getenv("GO_NOTIFICATION_URL"),
+		"tab/build/detail",
+		os.Getenv("GO_PIPELINE_NAME"),
+		os.Getenv("GO_PIPELINE_COUNTER"),
+		os.Getenv("GO_STAGE_NAME"),
+		os.Getenv("GO_STAGE_COUNTER"),
+		os.Getenv("GO_JOB_NAME"),
+		"#tab-console")
+
+	msg := fmt.Sprintf("<%s|%s> >> <%s|%s> >> <%s|%s/%s> >> <%s|%s>",
+		pipelineUrl,
+		os.Getenv("GO_PIPELINE_NAME"),
+		buildUrl,
+		os.Getenv("GO_PIPELINE_COUNTER"),
+		stageUrl,
+		os.Getenv("GO_STAGE_NAME"),
+		os.Getenv("GO_STAGE_COUNTER"),
+		jobUrl,
+		os.Getenv("GO_JOB_NAME"))
+
+	if phaseSuccess {
+		msg = msg + " passed"
+	} else {
+		msg = "*FAILED:* " + msg
+	}
+
+	return msg
+}
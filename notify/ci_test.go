@@ -0,0 +1,44 @@
+package notify_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/adobe-platform/porter/notify"
+)
+
+func getenv(env map[string]string) func(string) string {
+	return func(key string) string {
+		return env[key]
+	}
+}
+
+var _ = Describe("DiscoverCI", func() {
+
+	It("discovers GitHub Actions", func() {
+		ci := notify.DiscoverCI(getenv(map[string]string{
+			"GITHUB_ACTIONS":    "true",
+			"GITHUB_REPOSITORY": "org/repo",
+			"GITHUB_RUN_ID":     "42",
+			"GITHUB_SHA":        "abc123",
+		}))
+		Expect(ci.System).To(Equal(notify.CI_GitHubActions))
+		Expect(ci.URL).To(Equal("https://github.com/org/repo/actions/runs/42"))
+		Expect(ci.Commit).To(Equal("abc123"))
+	})
+
+	It("discovers Jenkins", func() {
+		ci := notify.DiscoverCI(getenv(map[string]string{
+			"JENKINS_URL":  "https://jenkins",
+			"BUILD_URL":    "https://jenkins/job/foo/7/",
+			"BUILD_NUMBER": "7",
+		}))
+		Expect(ci.System).To(Equal(notify.CI_Jenkins))
+		Expect(ci.URL).To(Equal("https://jenkins/job/foo/7/"))
+		Expect(ci.BuildNumber).To(Equal("7"))
+	})
+
+	It("finds nothing outside of CI", func() {
+		Expect(notify.DiscoverCI(getenv(nil))).To(Equal(notify.CIMetadata{}))
+	})
+})
@@ -0,0 +1,229 @@
+/*
+ * (c) 2016-2018 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+package notify
+
+import (
+	"bytes"
+	"os"
+	"sort"
+	"text/template"
+	"time"
+
+	"github.com/adobe-platform/porter/aws/elb"
+	"github.com/adobe-platform/porter/aws_session"
+	"github.com/adobe-platform/porter/conf"
+	"github.com/adobe-platform/porter/provision_state"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+const defaultTemplate = `{{if .Success}}{{.ServiceName}} {{.Phase}} passed{{else}}*FAILED:* {{.ServiceName}} {{.Phase}}{{end}}` +
+	`{{with .Environment}} in {{.}}{{end}}` +
+	`{{range .Regions}}
+{{.Name}}{{with .StackId}} {{.}}{{end}}{{with .ELBDNSName}} {{.}}{{end}}{{end}}` +
+	`{{with .CI.URL}}
+{{.}}{{end}}`
+
+type (
+	// Context is available to notification templates and is sent as JSON to
+	// webhooks
+	Context struct {
+		ServiceName string          `json:"ServiceName"`
+		Environment string          `json:"Environment"`
+		Phase       string          `json:"Phase"`
+		Success     bool            `json:"Success"`
+		Outcome     string          `json:"Outcome"`
+		Regions     []RegionContext `json:"Regions"`
+		CI          CIMetadata      `json:"CI"`
+	}
+
+	RegionContext struct {
+		Name           string `json:"Name"`
+		StackId        string `json:"StackId"`
+		ELBDNSName     string `json:"ELBDNSName"`
+		TargetGroupARN string `json:"TargetGroupARN"`
+	}
+)
+
+// NewContext builds a notification context. stack may be nil if nothing has
+// been provisioned
+func NewContext(log log15.Logger, config *conf.Config, phase string,
+	success bool, stack *provision_state.Stack) Context {
+
+	ctx := Context{
+		ServiceName: config.ServiceName,
+		Phase:       phase,
+		Success:     success,
+		Outcome:     conf.Outcome_Failure,
+		Regions:     make([]RegionContext, 0),
+		CI:          DiscoverCI(os.Getenv),
+	}
+
+	if success {
+		ctx.Outcome = conf.Outcome_Success
+	}
+
+	if stack == nil {
+		return ctx
+	}
+
+	ctx.Environment = stack.Environment
+
+	environment, err := config.GetEnvironment(stack.Environment)
+	if err != nil {
+		log.Warn("GetEnvironment", "Error", err)
+		environment = nil
+	}
+
+	for regionName, regionState := range stack.Regions {
+		regionCtx := RegionContext{
+			Name:           regionName,
+			StackId:        regionState.StackId,
+			TargetGroupARN: regionState.ProvisionedTargetGroupARN,
+		}
+
+		if environment != nil && regionState.ProvisionedELBName != "" {
+			regionCtx.ELBDNSName = getELBDNSName(log.New("Region", regionName),
+				environment, regionName, regionState.ProvisionedELBName)
+		}
+
+		ctx.Regions = append(ctx.Regions, regionCtx)
+	}
+
+	sort.Sort(regionsByName(ctx.Regions))
+
+	return ctx
+}
+
+func getELBDNSName(log log15.Logger, environment *conf.Environment, regionName, elbName string) string {
+
+	roleARN, err := environment.GetRoleARN(regionName)
+	if err != nil {
+		log.Warn("GetRoleARN", "Error", err)
+		return ""
+	}
+
+	roleSession := aws_session.STS(regionName, roleARN, 1*time.Hour)
+	elbClient := elb.New(roleSession)
+
+	output, err := elb.DescribeLoadBalancers(elbClient, elbName)
+	if err != nil {
+		log.Warn("DescribeLoadBalancers", "Error", err)
+		return ""
+	}
+
+	if len(output) != 1 || output[0].DNSName == nil {
+		log.Warn("DescribeLoadBalancers - no ELB found", "LoadBalancerName", elbName)
+		return ""
+	}
+
+	return *output[0].DNSName
+}
+
+// Send delivers ctx to every configured notification routed to its phase and
+// outcome. Failures are logged and returned but every sink is attempted
+func Send(log log15.Logger, config *conf.Config, ctx Context) (success bool) {
+
+	success = true
+
+	for i, notification := range config.Notifications {
+
+		if !routed(notification, ctx) {
+			continue
+		}
+
+		log := log.New("Type", notification.Type, "Index", i)
+
+		message, err := render(notification, ctx)
+		if err != nil {
+			log.Error("Template", "Error", err)
+			success = false
+			continue
+		}
+
+		var sent bool
+		switch notification.Type {
+		case conf.Notification_Webhook:
+			sent = sendWebhook(log, notification, ctx, message)
+		case conf.Notification_Slack:
+			sent = sendSlack(log, notification, message)
+		case conf.Notification_Teams:
+			sent = sendTeams(log, notification, ctx, message)
+		case conf.Notification_PagerDuty:
+			sent = sendPagerDuty(log, notification, ctx, message)
+		case conf.Notification_Email:
+			sent = sendEmail(log, notification, ctx, message)
+		default:
+			log.Error("Unknown notification type")
+		}
+
+		if sent {
+			log.Info("Notification sent")
+		} else {
+			success = false
+		}
+	}
+
+	return
+}
+
+func routed(notification *conf.Notification, ctx Context) bool {
+	return matches(notification.Phases, ctx.Phase) &&
+		matches(notification.Outcomes, ctx.Outcome)
+}
+
+// empty means everything
+func matches(list []string, value string) bool {
+	if len(list) == 0 {
+		return true
+	}
+
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+func render(notification *conf.Notification, ctx Context) (string, error) {
+
+	if notification.Template == "" &&
+		notification.Type == conf.Notification_Slack &&
+		ctx.CI.System == CI_GO {
+
+		return goCIMessage(ctx.Phase, ctx.Success), nil
+	}
+
+	templateStr := notification.Template
+	if templateStr == "" {
+		templateStr = defaultTemplate
+	}
+
+	tmpl, err := template.New("").Parse(templateStr)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+type regionsByName []RegionContext
+
+func (recv regionsByName) Len() int           { return len(recv) }
+func (recv regionsByName) Swap(i, j int)      { recv[i], recv[j] = recv[j], recv[i] }
+func (recv regionsByName) Less(i, j int) bool { return recv[i].Name < recv[j].Name }
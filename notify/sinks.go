@@ -0,0 +1,203 @@
+/*
+ * (c) 2016-2018 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/adobe-platform/porter/conf"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+const pagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+func sendWebhook(log log15.Logger, notification *conf.Notification, ctx Context, message string) bool {
+
+	body := struct {
+		Message string  `json:"message"`
+		Context Context `json:"context"`
+	}{
+		Message: message,
+		Context: ctx,
+	}
+
+	return postJSON(log, notification.URL, notification.Headers, body)
+}
+
+// Slack incoming webhooks https://api.slack.com/incoming-webhooks
+func sendSlack(log log15.Logger, notification *conf.Notification, message string) bool {
+
+	messageBodyBytes, err := json.Marshal(map[string]string{"text": message})
+	if err != nil {
+		log.Error("Slack message body json.Marshal failed", "Error", err)
+		return false
+	}
+
+	resp, err := httpClient.PostForm(notification.URL, url.Values{"payload": {string(messageBodyBytes)}})
+	if err != nil {
+		log.Error("Post to slack failed", "Error", err)
+		return false
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		log.Error("Post to slack failed", "StatusCode", resp.StatusCode)
+		return false
+	}
+
+	return true
+}
+
+// Microsoft Teams incoming webhooks accept legacy actionable message cards
+func sendTeams(log log15.Logger, notification *conf.Notification, ctx Context, message string) bool {
+
+	themeColor := "2EB886"
+	if !ctx.Success {
+		themeColor = "D50000"
+	}
+
+	card := map[string]interface{}{
+		"@type":      "MessageCard",
+		"@context":   "http://schema.org/extensions",
+		"summary":    fmt.Sprintf("%s %s %s", ctx.ServiceName, ctx.Phase, ctx.Outcome),
+		"themeColor": themeColor,
+		"title":      fmt.Sprintf("%s %s %s", ctx.ServiceName, ctx.Phase, ctx.Outcome),
+		"text":       strings.Replace(message, "\n", "\n\n", -1),
+	}
+
+	if ctx.CI.URL != "" {
+		card["potentialAction"] = []interface{}{
+			map[string]interface{}{
+				"@type": "OpenUri",
+				"name":  "View build",
+				"targets": []interface{}{
+					map[string]string{"os": "default", "uri": ctx.CI.URL},
+				},
+			},
+		}
+	}
+
+	return postJSON(log, notification.URL, notification.Headers, card)
+}
+
+// PagerDuty Events API v2. Failures trigger an incident and successes resolve
+// it because they share a dedup_key
+func sendPagerDuty(log log15.Logger, notification *conf.Notification, ctx Context, message string) bool {
+
+	routingKey := os.Getenv(notification.RoutingKeyEnv)
+	if routingKey == "" {
+		log.Error("Empty PagerDuty routing key", "Env", notification.RoutingKeyEnv)
+		return false
+	}
+
+	eventAction := "trigger"
+	if ctx.Success {
+		eventAction = "resolve"
+	}
+
+	event := map[string]interface{}{
+		"routing_key":  routingKey,
+		"event_action": eventAction,
+		"dedup_key":    fmt.Sprintf("porter-%s-%s-%s", ctx.ServiceName, ctx.Environment, ctx.Phase),
+		"payload": map[string]interface{}{
+			"summary":        message,
+			"source":         ctx.ServiceName,
+			"severity":       notification.Severity,
+			"component":      ctx.Environment,
+			"group":          ctx.Phase,
+			"custom_details": ctx,
+		},
+	}
+
+	if ctx.CI.URL != "" {
+		event["links"] = []map[string]string{
+			{"href": ctx.CI.URL, "text": "Build"},
+		}
+	}
+
+	return postJSON(log, pagerDutyEventsURL, nil, event)
+}
+
+func sendEmail(log log15.Logger, notification *conf.Notification, ctx Context, message string) bool {
+
+	smtpConf := notification.SMTP
+
+	subject := smtpConf.Subject
+	if subject == "" {
+		subject = fmt.Sprintf("[porter] %s %s %s", ctx.ServiceName, ctx.Phase, ctx.Outcome)
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", smtpConf.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(smtpConf.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&msg, "Content-Type: text/plain; charset=UTF-8\r\n")
+	fmt.Fprintf(&msg, "\r\n%s\r\n", message)
+
+	var auth smtp.Auth
+	if smtpConf.Username != "" {
+		auth = smtp.PlainAuth("", smtpConf.Username, os.Getenv(smtpConf.PasswordEnv), smtpConf.Host)
+	}
+
+	addr := fmt.Sprintf("%s:%d", smtpConf.Host, smtpConf.Port)
+	err := smtp.SendMail(addr, auth, smtpConf.From, smtpConf.To, msg.Bytes())
+	if err != nil {
+		log.Error("smtp.SendMail", "Addr", addr, "Error", err)
+		return false
+	}
+
+	return true
+}
+
+func postJSON(log log15.Logger, postURL string, headers map[string]string, body interface{}) bool {
+
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		log.Error("json.Marshal", "Error", err)
+		return false
+	}
+
+	req, err := http.NewRequest("POST", postURL, bytes.NewReader(bodyBytes))
+	if err != nil {
+		log.Error("http.NewRequest", "Error", err)
+		return false
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		log.Error("POST failed", "Error", err)
+		return false
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		log.Error("POST failed", "StatusCode", resp.StatusCode)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,18 @@
+package output
+
+import (
+	"io"
+	"time"
+)
+
+// Reset starts a new result document written to w in the given format
+func Reset(w io.Writer, outputFormat string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	stdout = w
+	format = outputFormat
+	result = Result{}
+	start = time.Time{}
+	finished = false
+}
@@ -0,0 +1,318 @@
+/*
+ * (c) 2016-2018 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+package output
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adobe-platform/porter/logger"
+	"github.com/adobe-platform/porter/provision_state"
+)
+
+const (
+	Format_Text = "text"
+	Format_JSON = "json"
+)
+
+const (
+	LoadBalancer_Provisioned = "provisioned"
+	LoadBalancer_Destination = "destination"
+)
+
+type (
+	// Result is the document written to stdout when --output json is given.
+	// It's built up as commands run and written once when the command
+	// finishes.
+	Result struct {
+		Phase           string             `json:"phase"`
+		Success         bool               `json:"success"`
+		DurationSeconds float64            `json:"duration_seconds"`
+		Environment     string             `json:"environment,omitempty"`
+		Hotswap         bool               `json:"hotswap,omitempty"`
+		ServicePayload  string             `json:"service_payload,omitempty"`
+		Regions         map[string]*Region `json:"regions,omitempty"`
+		Hooks           []Hook             `json:"hooks,omitempty"`
+	}
+
+	Region struct {
+		StackId                 string            `json:"stack_id,omitempty"`
+		TargetGroupARN          string            `json:"target_group_arn,omitempty"`
+		LoadBalancers           []LoadBalancer    `json:"load_balancers,omitempty"`
+		RegisteredInstanceIds   []string          `json:"registered_instance_ids,omitempty"`
+		DeregisteredInstanceIds []string          `json:"deregistered_instance_ids,omitempty"`
+		PrunedStackIds          []string          `json:"pruned_stack_ids,omitempty"`
+		Outputs                 map[string]string `json:"outputs,omitempty"`
+		ImageId                 string            `json:"image_id,omitempty"`
+		Failure                 string            `json:"failure,omitempty"`
+	}
+
+	LoadBalancer struct {
+		Role    string `json:"role"`
+		Name    string `json:"name"`
+		DNSName string `json:"dns_name,omitempty"`
+	}
+
+	Hook struct {
+		Name       string `json:"name"`
+		Region     string `json:"region,omitempty"`
+		Repo       string `json:"repo,omitempty"`
+		Ref        string `json:"ref,omitempty"`
+		Dockerfile string `json:"dockerfile,omitempty"`
+		Success    bool   `json:"success"`
+	}
+)
+
+var (
+	format = Format_Text
+
+	// stdout is reserved for the result document in JSON mode and everything
+	// else, including the output of docker and hooks, goes to stderr
+	stdout io.Writer = os.Stdout
+
+	mutex    sync.Mutex
+	result   Result
+	start    time.Time
+	finished bool
+)
+
+// Init removes --output <format> from args and configures output accordingly.
+// The flag is global so it's accepted anywhere in the command line.
+func Init(args []string) (remaining []string, err error) {
+
+	remaining = make([]string, 0, len(args))
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		var value string
+		switch {
+		case arg == "--output" || arg == "-output":
+			if i+1 == len(args) {
+				err = errors.New(arg + " requires a value")
+				return
+			}
+			i++
+			value = args[i]
+
+		case strings.HasPrefix(arg, "--output="):
+			value = strings.TrimPrefix(arg, "--output=")
+
+		case strings.HasPrefix(arg, "-output="):
+			value = strings.TrimPrefix(arg, "-output=")
+
+		default:
+			remaining = append(remaining, arg)
+			continue
+		}
+
+		switch value {
+		case Format_Text, Format_JSON:
+			format = value
+		default:
+			err = fmt.Errorf("invalid output format %s. Valid formats are %s and %s",
+				value, Format_Text, Format_JSON)
+			return
+		}
+	}
+
+	if format == Format_JSON {
+		os.Stdout = os.Stderr
+		logger.SetCLIOutput(os.Stderr)
+	}
+
+	return
+}
+
+// JSON is true when a result document will be written. Callers use this to
+// skip API calls whose only purpose is to populate the document.
+func JSON() bool {
+	return format == Format_JSON
+}
+
+// Begin starts the clock on a phase. Only the first call has an effect since
+// commands like provision call into others that would otherwise reset it.
+func Begin(phase string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if result.Phase != "" {
+		return
+	}
+
+	result.Phase = phase
+	start = time.Now()
+}
+
+func SetEnvironment(environment string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	result.Environment = environment
+}
+
+func SetServicePayload(path string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	result.ServicePayload = path
+}
+
+// SetStack records the stack ids and provisioned load balancing resources of
+// each region
+func SetStack(stack provision_state.Stack) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	result.Environment = stack.Environment
+	result.Hotswap = stack.Hotswap
+
+	for regionName, failure := range stack.FailedRegions {
+		getRegion(regionName).Failure = failure
+	}
+
+	for regionName, regionState := range stack.Regions {
+		if regionState == nil {
+			continue
+		}
+
+		region := getRegion(regionName)
+		region.StackId = regionState.StackId
+		region.TargetGroupARN = regionState.ProvisionedTargetGroupARN
+		region.ImageId = regionState.ImageId
+
+		if len(regionState.Outputs) > 0 {
+			region.Outputs = regionState.Outputs
+		}
+
+		if regionState.ProvisionedELBName != "" && !hasLoadBalancer(region,
+			LoadBalancer_Provisioned, regionState.ProvisionedELBName) {
+
+			region.LoadBalancers = append(region.LoadBalancers, LoadBalancer{
+				Role: LoadBalancer_Provisioned,
+				Name: regionState.ProvisionedELBName,
+			})
+		}
+	}
+}
+
+func AddLoadBalancer(regionName, role, name, dnsName string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	region := getRegion(regionName)
+	for i := range region.LoadBalancers {
+		lb := &region.LoadBalancers[i]
+		if lb.Role == role && lb.Name == name {
+			lb.DNSName = dnsName
+			return
+		}
+	}
+
+	region.LoadBalancers = append(region.LoadBalancers, LoadBalancer{
+		Role:    role,
+		Name:    name,
+		DNSName: dnsName,
+	})
+}
+
+func AddRegisteredInstances(regionName string, instanceIds ...string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	region := getRegion(regionName)
+	region.RegisteredInstanceIds = append(region.RegisteredInstanceIds, instanceIds...)
+}
+
+func AddDeregisteredInstances(regionName string, instanceIds ...string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	region := getRegion(regionName)
+	region.DeregisteredInstanceIds = append(region.DeregisteredInstanceIds, instanceIds...)
+}
+
+func AddPrunedStack(regionName, stackId string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	region := getRegion(regionName)
+	region.PrunedStackIds = append(region.PrunedStackIds, stackId)
+}
+
+func AddHook(hook Hook) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	result.Hooks = append(result.Hooks, hook)
+}
+
+// Finish writes the result document in JSON mode. It's safe to call more than
+// once and only the first call writes anything.
+func Finish(success bool) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if finished || format != Format_JSON {
+		return
+	}
+	finished = true
+
+	result.Success = success
+	if !start.IsZero() {
+		result.DurationSeconds = time.Since(start).Seconds()
+	}
+
+	resultBytes, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "json.MarshalIndent", err)
+		return
+	}
+
+	stdout.Write(resultBytes)
+	stdout.Write([]byte("\n"))
+}
+
+// Exit finishes the result document before exiting. Use this in place of
+// os.Exit in commands that support --output
+func Exit(code int) {
+	Finish(code == 0)
+	os.Exit(code)
+}
+
+func getRegion(regionName string) *Region {
+	if result.Regions == nil {
+		result.Regions = make(map[string]*Region)
+	}
+
+	region, exists := result.Regions[regionName]
+	if !exists {
+		region = &Region{}
+		result.Regions[regionName] = region
+	}
+	return region
+}
+
+func hasLoadBalancer(region *Region, role, name string) bool {
+	for _, lb := range region.LoadBalancers {
+		if lb.Role == role && lb.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+package output_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/adobe-platform/porter/output"
+	"github.com/adobe-platform/porter/provision_state"
+)
+
+var _ = Describe("Init", func() {
+
+	It("strips --output from anywhere in the arguments", func() {
+		args, err := output.Init([]string{"porter", "--output", "text", "build", "pack"})
+		Expect(err).To(BeNil())
+		Expect(args).To(Equal([]string{"porter", "build", "pack"}))
+
+		args, err = output.Init([]string{"porter", "build", "provision", "-e", "dev", "--output=text"})
+		Expect(err).To(BeNil())
+		Expect(args).To(Equal([]string{"porter", "build", "provision", "-e", "dev"}))
+		Expect(output.JSON()).To(BeFalse())
+	})
+
+	It("rejects unknown formats", func() {
+		_, err := output.Init([]string{"porter", "--output", "yaml", "build", "pack"})
+		Expect(err).ToNot(BeNil())
+	})
+
+	It("requires a value", func() {
+		_, err := output.Init([]string{"porter", "build", "pack", "--output"})
+		Expect(err).ToNot(BeNil())
+	})
+})
+
+var _ = Describe("Result", func() {
+
+	var buf *bytes.Buffer
+
+	BeforeEach(func() {
+		buf = new(bytes.Buffer)
+		output.Reset(buf, output.Format_JSON)
+	})
+
+	AfterEach(func() {
+		output.Reset(os.Stdout, output.Format_Text)
+	})
+
+	decode := func() output.Result {
+		var result output.Result
+		Expect(json.Unmarshal(buf.Bytes(), &result)).To(Succeed())
+		return result
+	}
+
+	It("records each region of the stack", func() {
+		output.Begin("provision")
+		output.SetStack(provision_state.Stack{
+			Environment: "prod",
+			Hotswap:     true,
+			Regions: map[string]*provision_state.Region{
+				"us-west-2": {
+					StackId:            "arn:aws:cloudformation:us-west-2:123456789012:stack/a/1",
+					ProvisionedELBName: "a-ELB",
+					ImageId:            "ami-0123456789abcdef0",
+					Outputs:            map[string]string{"Key": "Value"},
+				},
+				"us-east-1": {
+					ProvisionedTargetGroupARN: "arn:aws:elasticloadbalancing:us-east-1:123456789012:targetgroup/a/1",
+				},
+			},
+			FailedRegions: map[string]string{
+				"eu-west-1": "failed to create the stack",
+			},
+		})
+		output.Finish(true)
+
+		result := decode()
+		Expect(result.Phase).To(Equal("provision"))
+		Expect(result.Success).To(BeTrue())
+		Expect(result.Environment).To(Equal("prod"))
+		Expect(result.Hotswap).To(BeTrue())
+		Expect(result.Regions).To(HaveLen(3))
+
+		usWest2 := result.Regions["us-west-2"]
+		Expect(usWest2.StackId).To(Equal("arn:aws:cloudformation:us-west-2:123456789012:stack/a/1"))
+		Expect(usWest2.ImageId).To(Equal("ami-0123456789abcdef0"))
+		Expect(usWest2.Outputs).To(Equal(map[string]string{"Key": "Value"}))
+		Expect(usWest2.LoadBalancers).To(Equal([]output.LoadBalancer{
+			{Role: output.LoadBalancer_Provisioned, Name: "a-ELB"},
+		}))
+
+		Expect(result.Regions["us-east-1"].TargetGroupARN).To(Equal("arn:aws:elasticloadbalancing:us-east-1:123456789012:targetgroup/a/1"))
+		Expect(result.Regions["eu-west-1"].Failure).To(Equal("failed to create the stack"))
+	})
+
+	It("doesn't duplicate load balancers when the stack is set twice", func() {
+		stack := provision_state.Stack{
+			Regions: map[string]*provision_state.Region{
+				"us-west-2": {ProvisionedELBName: "a-ELB"},
+			},
+		}
+		output.SetStack(stack)
+		output.AddLoadBalancer("us-west-2", output.LoadBalancer_Provisioned, "a-ELB", "a-ELB.elb.amazonaws.com")
+		output.SetStack(stack)
+		output.Finish(true)
+
+		Expect(decode().Regions["us-west-2"].LoadBalancers).To(Equal([]output.LoadBalancer{
+			{Role: output.LoadBalancer_Provisioned, Name: "a-ELB", DNSName: "a-ELB.elb.amazonaws.com"},
+		}))
+	})
+
+	It("writes the document once", func() {
+		output.Begin("promote")
+		output.Finish(false)
+		output.Finish(true)
+
+		result := decode()
+		Expect(result.Phase).To(Equal("promote"))
+		Expect(result.Success).To(BeFalse())
+	})
+
+	It("keeps the first phase", func() {
+		output.Begin("provision")
+		output.Begin("promote")
+		output.Finish(true)
+
+		Expect(decode().Phase).To(Equal("provision"))
+	})
+
+	It("writes nothing in text mode", func() {
+		output.Reset(buf, output.Format_Text)
+		output.Begin("pack")
+		output.Finish(true)
+
+		Expect(buf.Len()).To(Equal(0))
+	})
+})
@@ -0,0 +1,162 @@
+/*
+ * (c) 2016-2018 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+package promote
+
+import (
+	"time"
+
+	"github.com/adobe-platform/porter/aws/cloudwatch"
+	"github.com/adobe-platform/porter/aws/elb"
+	"github.com/adobe-platform/porter/conf"
+	"github.com/aws/aws-sdk-go/aws"
+	cwlib "github.com/aws/aws-sdk-go/service/cloudwatch"
+	elblib "github.com/aws/aws-sdk-go/service/elb"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+// promoteCanary registers the new instances with the destination ELB in steps.
+// After every step but the last the registered instances bake while their
+// health and the ELB's backend 5XX count are watched. Any failure deregisters
+// every new instance registered so far so the old stack keeps serving traffic
+func promoteCanary(log log15.Logger, elbClient *elblib.ELB, cwClient *cwlib.CloudWatch,
+	elbName string, promotion conf.Promotion, instanceIds []string) (success bool) {
+
+	log = log.New("LoadBalancerName", elbName)
+
+	registered := make([]*elblib.Instance, 0)
+	defer func() {
+		if !success && len(registered) > 0 {
+			log.Warn("Rolling back canary promotion")
+			deregisterInstances(log, elbClient, elbName, registered)
+		}
+	}()
+
+	counts := canaryCounts(len(instanceIds), promotion.CanarySteps)
+	for i, count := range counts {
+
+		stepIds := instanceIds[len(registered):count]
+		log.Info("Canary step",
+			"Step", i+1,
+			"Steps", len(counts),
+			"RegisteredCount", count,
+			"InstanceCount", len(instanceIds))
+
+		// record the instances before the call so a partial registration is
+		// still rolled back
+		instanceIdToInService := make(map[string]bool)
+		for _, instanceId := range stepIds {
+			instanceIdToInService[instanceId] = false
+			registered = append(registered, &elblib.Instance{
+				InstanceId: aws.String(instanceId),
+			})
+		}
+
+		log.Info("RegisterInstancesWithLoadBalancer")
+		_, err := elb.RegisterInstancesWithLoadBalancer(elbClient, elbName, stepIds)
+		if err != nil {
+			log.Error("RegisterInstancesWithLoadBalancer", "Error", err)
+			return
+		}
+
+		log.Info("Waiting for newly registered instances to be InService")
+		if ok := waitForInServiceInstances(log, elbClient, elbName, instanceIdToInService); !ok {
+			log.Error("Instances never became InService in the destination ELB")
+			return
+		}
+
+		if count == len(instanceIds) {
+			break
+		}
+
+		if ok := bake(log, elbClient, cwClient, elbName, promotion, registered); !ok {
+			return
+		}
+	}
+
+	success = true
+	return
+}
+
+// canaryCounts converts cumulative percentages into cumulative instance
+// counts. Each step registers at least one more instance than the previous
+// and the last step always registers every instance
+func canaryCounts(instanceCount int, steps []int) []int {
+	counts := make([]int, 0)
+
+	lastCount := 0
+	for _, step := range steps {
+		count := (instanceCount*step + 99) / 100
+		if count <= lastCount {
+			count = lastCount + 1
+		}
+
+		if count >= instanceCount {
+			break
+		}
+
+		counts = append(counts, count)
+		lastCount = count
+	}
+
+	return append(counts, instanceCount)
+}
+
+func bake(log log15.Logger, elbClient *elblib.ELB, cwClient *cwlib.CloudWatch,
+	elbName string, promotion conf.Promotion, instances []*elblib.Instance) bool {
+
+	log.Info("Baking canary instances", "BakeTime", promotion.BakeTime)
+
+	bakeStart := time.Now()
+	for time.Since(bakeStart) < promotion.BakeTime_ {
+
+		time.Sleep(sleepDuration)
+
+		instanceStates, err := elb.DescribeInstanceHealth(elbClient, elbName, instances...)
+		if err != nil {
+			log.Error("DescribeInstanceHealth", "Error", err)
+			return false
+		}
+
+		for _, instanceState := range instanceStates {
+			if instanceState == nil || instanceState.InstanceId == nil || instanceState.State == nil {
+				continue
+			}
+
+			if *instanceState.State != elb.InService {
+				log.Error("Canary instance left service",
+					"InstanceId", *instanceState.InstanceId,
+					"InstanceState", *instanceState.State)
+				return false
+			}
+		}
+
+		// Classic ELB metrics have no instance dimension so this is the whole
+		// ELB's count and old instances contribute too
+		backend5XX, err := cloudwatch.SumELBMetric(cwClient, elbName,
+			cloudwatch.MetricBackend5XX, bakeStart, time.Now())
+		if err != nil {
+			log.Error("GetMetricStatistics", "Error", err)
+			return false
+		}
+
+		if backend5XX > float64(promotion.MaxBackend5XX) {
+			log.Error("Backend 5XX count exceeded max_backend_5xx",
+				"Count", backend5XX,
+				"Max", promotion.MaxBackend5XX,
+				"Note", "the count includes the old instances' errors")
+			return false
+		}
+	}
+
+	log.Info("Canary instances baked successfully")
+	return true
+}
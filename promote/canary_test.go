@@ -0,0 +1,27 @@
+package promote_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	"github.com/adobe-platform/porter/promote"
+)
+
+var _ = Describe("Canary", func() {
+
+	DescribeTable("canaryCounts",
+		func(instanceCount int, steps []int, expected []int) {
+			Expect(promote.CanaryCounts(instanceCount, steps)).To(Equal(expected))
+		},
+		Entry("default steps", 10, []int{10, 50}, []int{1, 5, 10}),
+		Entry("no steps", 4, []int{}, []int{4}),
+		Entry("rounds up", 3, []int{10, 50}, []int{1, 2, 3}),
+		Entry("rounds up partial instances", 7, []int{25, 50}, []int{2, 4, 7}),
+		Entry("a 100% step is the last step", 4, []int{25, 100}, []int{1, 4}),
+		Entry("a 100% step before other steps ends the steps", 4, []int{100, 50}, []int{4}),
+		Entry("more steps than instances", 2, []int{10, 20, 30}, []int{1, 2}),
+		Entry("steps rounding to the same count still advance", 10, []int{1, 2, 3}, []int{1, 2, 3, 10}),
+		Entry("a single instance", 1, []int{10, 50}, []int{1}),
+	)
+})
@@ -0,0 +1,6 @@
+package promote
+
+// exported for promote_test
+var CanaryCounts = canaryCounts
+var PromotedTargetGroupArn = promotedTargetGroupArn
+var PromotedTags = promotedTags
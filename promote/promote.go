@@ -14,10 +14,12 @@ package promote
 import (
 	"time"
 
+	"github.com/adobe-platform/porter/aws/cloudwatch"
 	"github.com/adobe-platform/porter/aws/elb"
 	"github.com/adobe-platform/porter/aws_session"
 	"github.com/adobe-platform/porter/conf"
 	"github.com/adobe-platform/porter/constants"
+	"github.com/adobe-platform/porter/output"
 	"github.com/adobe-platform/porter/provision_state"
 	"github.com/aws/aws-sdk-go/aws"
 	elblib "github.com/aws/aws-sdk-go/service/elb"
@@ -71,7 +73,8 @@ func promoteService(log log15.Logger, env, regionName string,
 		return
 	}
 
-	if region.PrimaryTopology() != conf.Topology_Inet || !region.HasELB() {
+	if region.PrimaryTopology() != conf.Topology_Inet ||
+		(!region.HasELB() && !region.HasTargetGroup()) {
 		success = true
 		return
 	}
@@ -83,6 +86,12 @@ func promoteService(log log15.Logger, env, regionName string,
 	}
 
 	roleSession := aws_session.STS(region.Name, roleARN, 1*time.Hour)
+
+	if region.HasTargetGroup() {
+		success = promoteTargetGroup(log, roleSession, environment, region, regionState, elbTag)
+		return
+	}
+
 	elbClient := elb.New(roleSession)
 
 	destinationELB, err := environment.GetELBForRegion(region.Name, elbTag)
@@ -93,6 +102,11 @@ func promoteService(log log15.Logger, env, regionName string,
 	log.Info("Destination ELB", "LoadBalancerName", destinationELB)
 	log.Info("Source ELB", "LoadBalancerName", regionState.ProvisionedELBName)
 
+	if output.JSON() {
+		recordLoadBalancers(log, elbClient, region.Name, destinationELB,
+			regionState.ProvisionedELBName)
+	}
+
 	oldInstanceStates, err := elb.DescribeInstanceHealth(elbClient, destinationELB)
 	if err != nil {
 		log.Error("DescribeInstanceHealth", "LoadBalancerName", destinationELB, "Error", err)
@@ -146,29 +160,56 @@ func promoteService(log log15.Logger, env, regionName string,
 		newInstanceIds[i] = *newInstanceStates[i].InstanceId
 	}
 
-	log.Info("RegisterInstancesWithLoadBalancer", "LoadBalancerName", destinationELB)
-	_, err = elb.RegisterInstancesWithLoadBalancer(elbClient, destinationELB, newInstanceIds)
-	if err != nil {
-		log.Error("RegisterInstancesWithLoadBalancer", "Error", err, "LoadBalancerName", destinationELB)
-		return
-	}
+	if environment.Promotion.Strategy == conf.Promotion_Canary {
 
-	// Wait for all newly registered instances (ignore old instances) to be InService
-	// If this fails attempt to deregister the registered instances so there isn't a mixed set waiting around
-	log.Info("Waiting for newly registered instances to be InService", "LoadBalancerName", destinationELB)
-	if ok := waitForInServiceInstances(log, elbClient, destinationELB, newInstanceIdToInService); !ok {
-		log.Error("Instances never became InService in the destination ELB", "LoadBalancerName", destinationELB)
-		deregisterInstances(log, elbClient, destinationELB, newInstances)
-		return
+		cwClient := cloudwatch.New(roleSession)
+		if !promoteCanary(log, elbClient, cwClient, destinationELB, environment.Promotion, newInstanceIds) {
+			return
+		}
+	} else {
+
+		log.Info("RegisterInstancesWithLoadBalancer", "LoadBalancerName", destinationELB)
+		_, err = elb.RegisterInstancesWithLoadBalancer(elbClient, destinationELB, newInstanceIds)
+		if err != nil {
+			log.Error("RegisterInstancesWithLoadBalancer", "Error", err, "LoadBalancerName", destinationELB)
+			return
+		}
+
+		// Wait for all newly registered instances (ignore old instances) to be InService
+		// If this fails attempt to deregister the registered instances so there isn't a mixed set waiting around
+		log.Info("Waiting for newly registered instances to be InService", "LoadBalancerName", destinationELB)
+		if ok := waitForInServiceInstances(log, elbClient, destinationELB, newInstanceIdToInService); !ok {
+			log.Error("Instances never became InService in the destination ELB", "LoadBalancerName", destinationELB)
+			deregisterInstances(log, elbClient, destinationELB, newInstances)
+			return
+		}
 	}
 
+	output.AddRegisteredInstances(region.Name, newInstanceIds...)
+
 	if len(oldInstances) > 0 {
 		deregisterInstances(log, elbClient, destinationELB, oldInstances)
+
+		for _, instance := range oldInstances {
+			output.AddDeregisteredInstances(region.Name, *instance.InstanceId)
+		}
 	} else {
 		log.Warn("Nothing to remove from ELB", "LoadBalancerName", destinationELB)
 	}
 
 	elbTags := make(map[string]string)
+
+	// Keep a history of one stack so it can be rolled back to
+	currentTags, err := elb.GetTags(elbClient, destinationELB)
+	if err != nil {
+		log.Warn("elb.GetTags", "Error", err)
+		log.Warn("Unable to record the previously promoted stack for rollback")
+	} else if currentStackId := currentTags[constants.PorterStackIdTag]; currentStackId != "" &&
+		currentStackId != regionState.StackId {
+
+		elbTags[constants.PorterPreviousStackIdTag] = currentStackId
+	}
+
 	elbTags[constants.PorterStackIdTag] = regionState.StackId
 	elbTags[constants.PorterVersionTag] = constants.Version
 	err = elb.AddTags(elbClient, destinationELB, elbTags)
@@ -182,6 +223,31 @@ func promoteService(log log15.Logger, env, regionName string,
 
 }
 
+// recordLoadBalancers looks up DNS names for the result document
+func recordLoadBalancers(log log15.Logger, elbClient *elblib.ELB, regionName,
+	destinationELB, provisionedELB string) {
+
+	descriptions, err := elb.DescribeLoadBalancers(elbClient, destinationELB, provisionedELB)
+	if err != nil {
+		log.Warn("DescribeLoadBalancers", "Error", err)
+		return
+	}
+
+	for _, description := range descriptions {
+		if description == nil || description.LoadBalancerName == nil {
+			continue
+		}
+
+		role := output.LoadBalancer_Provisioned
+		if *description.LoadBalancerName == destinationELB {
+			role = output.LoadBalancer_Destination
+		}
+
+		output.AddLoadBalancer(regionName, role, *description.LoadBalancerName,
+			aws.StringValue(description.DNSName))
+	}
+}
+
 func waitForInServiceInstances(log log15.Logger, elbClient *elblib.ELB, elbName string, instanceIdToInService map[string]bool) bool {
 	log = log.New("LoadBalancerName", elbName)
 
@@ -0,0 +1,229 @@
+/*
+ * (c) 2016-2018 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+package promote
+
+import (
+	"time"
+
+	"github.com/adobe-platform/porter/aws/cloudformation"
+	"github.com/adobe-platform/porter/aws/elb"
+	"github.com/adobe-platform/porter/aws/elbv2"
+	awsutil "github.com/adobe-platform/porter/aws/util"
+	"github.com/adobe-platform/porter/aws_session"
+	"github.com/adobe-platform/porter/cfn"
+	"github.com/adobe-platform/porter/conf"
+	"github.com/adobe-platform/porter/constants"
+	"github.com/adobe-platform/porter/provision_state"
+	"github.com/adobe-platform/porter/util"
+	"github.com/aws/aws-sdk-go/aws"
+	cfnlib "github.com/aws/aws-sdk-go/service/cloudformation"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+// Rollback promotes the stack that was live before the most recent promotion
+// in every region with an ELB or target group.
+//
+// The previous stack is found through the PorterPreviousStackIdTag on each
+// destination ELB or live target group so the stack state for every region is rebuilt here rather
+// than read from a provision output file
+func Rollback(log log15.Logger, config *conf.Config, environment *conf.Environment,
+	elbTag string) (success bool) {
+
+	type rollbackTarget struct {
+		regionName  string
+		regionState *provision_state.Region
+		success     bool
+	}
+
+	stack := &provision_state.Stack{
+		Environment: environment.Name,
+		Regions:     make(map[string]*provision_state.Region),
+	}
+
+	targetChan := make(chan rollbackTarget)
+	regionCount := 0
+
+	for _, region := range environment.Regions {
+
+		if region.PrimaryTopology() != conf.Topology_Inet ||
+			(!region.HasELB() && !region.HasTargetGroup()) {
+			log.Info("Nothing to roll back without an ELB or target group", "Region", region.Name)
+			continue
+		}
+		regionCount++
+
+		go func(region *conf.Region) {
+
+			regionState, ok := getRollbackTarget(log, config, environment, region, elbTag)
+			targetChan <- rollbackTarget{
+				regionName:  region.Name,
+				regionState: regionState,
+				success:     ok,
+			}
+
+		}(region)
+	}
+
+	// wait for every region so all problems are logged before failing
+	allFound := true
+	for i := 0; i < regionCount; i++ {
+		target := <-targetChan
+		if !target.success {
+			allFound = false
+			continue
+		}
+		stack.Regions[target.regionName] = target.regionState
+	}
+
+	if !allFound {
+		log.Error("Unable to find a rollback target in every region")
+		return
+	}
+
+	if len(stack.Regions) == 0 {
+		log.Warn("No regions to roll back")
+		success = true
+		return
+	}
+
+	// The rollback target already served traffic so there's nothing to gain
+	// from a canary and every reason to restore service quickly
+	environment.Promotion.Strategy = conf.Promotion_All
+
+	success = Promote(log, config, stack, elbTag)
+	return
+}
+
+func getRollbackTarget(log log15.Logger, config *conf.Config, environment *conf.Environment,
+	region *conf.Region, elbTag string) (regionState *provision_state.Region, success bool) {
+
+	log = log.New("Region", region.Name)
+
+	roleARN, err := environment.GetRoleARN(region.Name)
+	if err != nil {
+		log.Error("GetRoleARN", "Error", err)
+		return
+	}
+
+	roleSession := aws_session.STS(region.Name, roleARN, 1*time.Hour)
+	cfnClient := cloudformation.New(roleSession)
+
+	var (
+		tags         map[string]string
+		resourceType string
+	)
+
+	if region.HasTargetGroup() {
+
+		targetGroup, err := environment.GetTargetGroupForRegion(region.Name, elbTag)
+		if err != nil {
+			log.Error("GetTargetGroupForRegion", "Error", err)
+			return
+		}
+		log = log.New("TargetGroupARN", targetGroup.ARN, "ListenerARN", targetGroup.ListenerARN)
+
+		tags, err = elbv2.GetLiveTags(elbv2.New(roleSession), targetGroup.ARN, targetGroup.ListenerARN)
+		if err != nil {
+			log.Error("elbv2.GetLiveTags", "Error", err)
+			return
+		}
+		resourceType = cfn.ElasticLoadBalancingV2_TargetGroup
+	} else {
+
+		destinationELB, err := environment.GetELBForRegion(region.Name, elbTag)
+		if err != nil || destinationELB == "" {
+			log.Error("Unable to find the ELB", "Environment", environment.Name)
+			return
+		}
+		log = log.New("LoadBalancerName", destinationELB)
+
+		tags, err = elb.GetTags(elb.New(roleSession), destinationELB)
+		if err != nil {
+			log.Error("elb.GetTags", "Error", err)
+			return
+		}
+		resourceType = cfn.ElasticLoadBalancing_LoadBalancer
+	}
+
+	previousStackId := tags[constants.PorterPreviousStackIdTag]
+	if previousStackId == "" {
+		log.Error("The ELB or target group has no record of a previously promoted stack",
+			"Tag", constants.PorterPreviousStackIdTag)
+		return
+	}
+	log = log.New("StackId", previousStackId)
+
+	stackList, getStacksSuccess := awsutil.GetStacks(log, config, environment,
+		cfnClient, aws.String(previousStackId), cfn.RollbackStatus)
+	if !getStacksSuccess {
+		return
+	}
+
+	if len(stackList) != 1 {
+		log.Error("The previously promoted stack no longer exists or isn't in a complete state")
+		return
+	}
+
+	var describeStackResourcesOutput *cfnlib.DescribeStackResourcesOutput
+
+	describeStackResourcesInput := &cfnlib.DescribeStackResourcesInput{
+		StackName: aws.String(previousStackId),
+	}
+
+	retryMsg := func(i int) { log.Warn("cloudformation:DescribeStackResources retrying", "Count", i) }
+	if !util.SuccessRetryer(3, retryMsg, func() bool {
+		describeStackResourcesOutput, err = cfnClient.DescribeStackResources(describeStackResourcesInput)
+		if err != nil {
+			log.Error("cloudformation:DescribeStackResources", "Error", err)
+			return false
+		}
+		return true
+	}) {
+		return
+	}
+
+	var physicalId string
+	for _, resource := range describeStackResourcesOutput.StackResources {
+		if resource == nil || resource.ResourceType == nil || resource.PhysicalResourceId == nil {
+			continue
+		}
+
+		if *resource.ResourceType == resourceType {
+			if physicalId != "" {
+				log.Error("The previously promoted stack has more than one " + resourceType)
+				return
+			}
+			physicalId = *resource.PhysicalResourceId
+		}
+	}
+
+	if physicalId == "" {
+		log.Error("The previously promoted stack has no " + resourceType)
+		return
+	}
+
+	regionState = &provision_state.Region{
+		StackId: previousStackId,
+		Outputs: cloudformation.Outputs(stackList[0]),
+	}
+
+	if resourceType == cfn.ElasticLoadBalancingV2_TargetGroup {
+		regionState.ProvisionedTargetGroupARN = physicalId
+		log.Info("Found rollback target", "ProvisionedTargetGroupARN", physicalId)
+	} else {
+		regionState.ProvisionedELBName = physicalId
+		log.Info("Found rollback target", "ProvisionedELBName", physicalId)
+	}
+
+	success = true
+	return
+}
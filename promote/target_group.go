@@ -0,0 +1,413 @@
+/*
+ * (c) 2016-2018 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+package promote
+
+import (
+	"time"
+
+	"github.com/adobe-platform/porter/aws/elbv2"
+	"github.com/adobe-platform/porter/conf"
+	"github.com/adobe-platform/porter/constants"
+	"github.com/adobe-platform/porter/output"
+	"github.com/adobe-platform/porter/provision_state"
+	"github.com/adobe-platform/porter/util"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	elbv2lib "github.com/aws/aws-sdk-go/service/elbv2"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+// promoteTargetGroup is the ELBv2 counterpart to the ELB promotion in
+// promoteService.
+//
+// If the target group has a listener_arn the listener's default action is
+// swapped to the provisioned target group. Otherwise the provisioned target
+// group's instances are registered into the live target group.
+//
+// Either way the live target group is then tagged with the promoted stack so
+// porterd, prune, hot swap, and rollback can find it. Traffic has already moved
+// by then so promotion fails if the tags can't be written. Otherwise prune
+// would consider the stack taking traffic prunable
+func promoteTargetGroup(log log15.Logger, roleSession *session.Session,
+	environment *conf.Environment, region *conf.Region,
+	regionState *provision_state.Region, elbTag string) (success bool) {
+
+	client := elbv2.New(roleSession)
+
+	targetGroup, err := environment.GetTargetGroupForRegion(region.Name, elbTag)
+	if err != nil {
+		log.Error("GetTargetGroupForRegion", "Error", err)
+		return
+	}
+
+	if regionState.ProvisionedTargetGroupARN == "" {
+		log.Error("No provisioned target group to promote")
+		return
+	}
+	log.Info("Source target group", "TargetGroupARN", regionState.ProvisionedTargetGroupARN)
+
+	var (
+		previousTags map[string]string
+		ok           bool
+	)
+
+	liveTargetGroupArn := promotedTargetGroupArn(targetGroup, regionState.ProvisionedTargetGroupARN)
+	if targetGroup.ListenerARN != "" {
+
+		previousTags, ok = swapListener(log, client, targetGroup.ListenerARN,
+			regionState.ProvisionedTargetGroupARN)
+	} else {
+
+		previousTags, ok = registerTargets(log, client, region.Name, targetGroup.ARN,
+			regionState.ProvisionedTargetGroupARN)
+	}
+
+	if !ok {
+		return
+	}
+
+	if output.JSON() {
+		recordTargetGroupLoadBalancers(log, client, region.Name,
+			targetGroup.ARN, targetGroup.ListenerARN)
+	}
+
+	if previousTags == nil {
+		log.Warn("Unable to record the previously promoted stack for rollback")
+	}
+	tags := promotedTags(previousTags, regionState.StackId)
+
+	retryMsg := func(i int) { log.Warn("elbv2:AddTags retrying", "Count", i) }
+	if !util.SuccessRetryer(3, retryMsg, func() bool {
+		err = elbv2.AddTags(client, liveTargetGroupArn, tags)
+		if err != nil {
+			log.Error("elbv2.AddTags", "TargetGroupARN", liveTargetGroupArn, "Error", err)
+			return false
+		}
+		return true
+	}) {
+		log.Error("The live target group isn't tagged with the promoted stack. "+
+			"Instance autoregistration, prune, and rollback depend on the tags",
+			"TargetGroupARN", liveTargetGroupArn)
+		return
+	}
+
+	success = true
+	return
+}
+
+// promotedTargetGroupArn is the target group that takes traffic after
+// promotion. A listener is swapped to the provisioned target group while
+// targets are registered into the configured one
+func promotedTargetGroupArn(targetGroup *conf.TargetGroup, provisionedArn string) string {
+	if targetGroup.ListenerARN != "" {
+		return provisionedArn
+	}
+	return targetGroup.ARN
+}
+
+// promotedTags are the tags written to the live target group for the promoted
+// stack. A history of one stack is kept so it can be rolled back to
+func promotedTags(previousTags map[string]string, stackId string) map[string]string {
+	tags := make(map[string]string)
+
+	if previousStackId := previousTags[constants.PorterStackIdTag]; previousStackId != "" &&
+		previousStackId != stackId {
+
+		tags[constants.PorterPreviousStackIdTag] = previousStackId
+	} else if previousStackId := previousTags[constants.PorterPreviousStackIdTag]; previousStackId != "" {
+
+		// promote re-run
+		tags[constants.PorterPreviousStackIdTag] = previousStackId
+	}
+
+	tags[constants.PorterStackIdTag] = stackId
+	tags[constants.PorterVersionTag] = constants.Version
+	return tags
+}
+
+// swapListener forwards a listener to the provisioned target group and
+// returns the tags of the target group it previously forwarded to.
+//
+// Health checks only start once a target group is attached to a load balancer.
+// ALB listeners attach the provisioned target group with a staging rule first
+// so its targets are healthy before the listener sends them traffic. NLB
+// listeners don't have rules so targets are health checked after the swap and
+// the listener is swapped back if they never become healthy
+func swapListener(log log15.Logger, client *elbv2lib.ELBV2,
+	listenerArn, provisionedArn string) (previousTags map[string]string, success bool) {
+
+	log = log.New("ListenerARN", listenerArn)
+
+	previousArn, err := elbv2.GetListenerTargetGroupArn(client, listenerArn)
+	if err != nil {
+		log.Error("GetListenerTargetGroupArn", "Error", err)
+		return
+	}
+	log.Info("Destination listener", "TargetGroupARN", previousArn)
+
+	previousTags, err = elbv2.GetTags(client, previousArn)
+	if err != nil {
+		log.Warn("elbv2.GetTags", "TargetGroupARN", previousArn, "Error", err)
+		previousTags = nil
+	}
+
+	if previousArn == provisionedArn {
+		log.Warn("The listener already forwards to the provisioned target group")
+		success = true
+		return
+	}
+
+	supportsRules, err := elbv2.ListenerSupportsRules(client, listenerArn)
+	if err != nil {
+		log.Error("DescribeListeners", "Error", err)
+		return
+	}
+
+	if supportsRules {
+
+		log.Info("CreateRule", "Host", elbv2.StagingHost(provisionedArn), "TargetGroupARN", provisionedArn)
+		ruleArn, err := elbv2.CreateStagingRule(client, listenerArn, provisionedArn)
+		if err != nil {
+			log.Error("CreateRule", "Error", err)
+			return
+		}
+
+		// the default action keeps the target group attached after the swap
+		defer func() {
+			log.Info("DeleteRule", "RuleARN", ruleArn)
+			err := elbv2.DeleteRule(client, ruleArn)
+			if err != nil {
+				log.Error("DeleteRule", "RuleARN", ruleArn, "Error", err)
+			}
+		}()
+
+		log.Info("Waiting for provisioned targets to be healthy before taking traffic", "TargetGroupARN", provisionedArn)
+		if !waitForHealthyTargets(log, client, provisionedArn, nil) {
+			log.Error("Targets never became healthy. The listener was not changed", "TargetGroupARN", provisionedArn)
+			return
+		}
+	} else {
+		log.Warn("NLB listeners can't health check a target group before it takes traffic. " +
+			"The provisioned targets receive traffic while they're checked")
+	}
+
+	log.Info("ModifyListener", "TargetGroupARN", provisionedArn)
+	err = elbv2.SetListenerTargetGroupArn(client, listenerArn, provisionedArn)
+	if err != nil {
+		log.Error("ModifyListener", "Error", err)
+		return
+	}
+
+	log.Info("Waiting for provisioned targets to be healthy", "TargetGroupARN", provisionedArn)
+	if !waitForHealthyTargets(log, client, provisionedArn, nil) {
+		log.Error("Targets never became healthy. Swapping the listener back", "TargetGroupARN", previousArn)
+
+		err = elbv2.SetListenerTargetGroupArn(client, listenerArn, previousArn)
+		if err != nil {
+			log.Crit("ModifyListener", "Error", err)
+		}
+		return
+	}
+
+	success = true
+	return
+}
+
+// registerTargets registers the provisioned target group's instances with the
+// live target group, deregisters everything else, and returns the tags the
+// live target group had beforehand
+func registerTargets(log log15.Logger, client *elbv2lib.ELBV2, regionName,
+	liveArn, provisionedArn string) (previousTags map[string]string, success bool) {
+
+	log = log.New("TargetGroupARN", liveArn)
+
+	previousTags, err := elbv2.GetTags(client, liveArn)
+	if err != nil {
+		log.Warn("elbv2.GetTags", "Error", err)
+		previousTags = nil
+	}
+
+	newTargets, err := elbv2.DescribeTargetHealth(client, provisionedArn)
+	if err != nil {
+		log.Error("DescribeTargetHealth", "TargetGroupARN", provisionedArn, "Error", err)
+		return
+	}
+
+	oldTargets, err := elbv2.DescribeTargetHealth(client, liveArn)
+	if err != nil {
+		log.Error("DescribeTargetHealth", "Error", err)
+		return
+	}
+
+	newInstanceIds := make([]string, 0)
+	newInstanceIdToHealthy := make(map[string]bool)
+	for _, target := range newTargets {
+		if target == nil || target.Target == nil || target.Target.Id == nil {
+			continue
+		}
+
+		newInstanceIds = append(newInstanceIds, *target.Target.Id)
+		newInstanceIdToHealthy[*target.Target.Id] = false
+	}
+
+	if len(newInstanceIds) == 0 {
+		log.Error("The provisioned target group has no targets", "TargetGroupARN", provisionedArn)
+		return
+	}
+
+	//cover cases for new instances that are already in the live target group (promote re-run)
+	oldInstanceIds := make([]string, 0)
+	for _, target := range oldTargets {
+		if target == nil || target.Target == nil || target.Target.Id == nil {
+			continue
+		}
+
+		if _, exists := newInstanceIdToHealthy[*target.Target.Id]; !exists {
+			oldInstanceIds = append(oldInstanceIds, *target.Target.Id)
+		}
+	}
+
+	log.Info("RegisterTargets")
+	err = elbv2.RegisterTargets(client, liveArn, newInstanceIds)
+	if err != nil {
+		log.Error("RegisterTargets", "Error", err)
+		return
+	}
+
+	// Wait for all newly registered instances (ignore old instances) to be healthy
+	// If this fails attempt to deregister the registered instances so there isn't a mixed set waiting around
+	log.Info("Waiting for newly registered targets to be healthy")
+	if !waitForHealthyTargets(log, client, liveArn, newInstanceIdToHealthy) {
+		log.Error("Targets never became healthy in the live target group")
+		deregisterTargets(log, client, liveArn, newInstanceIds)
+		return
+	}
+
+	output.AddRegisteredInstances(regionName, newInstanceIds...)
+
+	if len(oldInstanceIds) > 0 {
+		deregisterTargets(log, client, liveArn, oldInstanceIds)
+		output.AddDeregisteredInstances(regionName, oldInstanceIds...)
+	} else {
+		log.Warn("Nothing to remove from the target group")
+	}
+
+	success = true
+	return
+}
+
+// recordTargetGroupLoadBalancers looks up the load balancers in front of the
+// live target group for the result document
+func recordTargetGroupLoadBalancers(log log15.Logger, client *elbv2lib.ELBV2,
+	regionName, targetGroupArn, listenerArn string) {
+
+	arns, err := elbv2.GetLoadBalancerArns(client, targetGroupArn, listenerArn)
+	if err != nil {
+		log.Warn("elbv2.GetLoadBalancerArns", "Error", err)
+		return
+	}
+	if len(arns) == 0 {
+		return
+	}
+
+	loadBalancers, err := elbv2.DescribeLoadBalancers(client, arns...)
+	if err != nil {
+		log.Warn("elbv2.DescribeLoadBalancers", "Error", err)
+		return
+	}
+
+	for _, loadBalancer := range loadBalancers {
+		if loadBalancer == nil || loadBalancer.LoadBalancerName == nil {
+			continue
+		}
+
+		output.AddLoadBalancer(regionName, output.LoadBalancer_Destination,
+			*loadBalancer.LoadBalancerName, aws.StringValue(loadBalancer.DNSName))
+	}
+}
+
+func waitForHealthyTargets(log log15.Logger, client *elbv2lib.ELBV2, targetGroupArn string, instanceIdToHealthy map[string]bool) bool {
+	log = log.New("TargetGroupARN", targetGroupArn)
+
+	iterations := int(pollDuration.Seconds() / sleepDuration.Seconds())
+	for i := 0; i < iterations; i++ {
+
+		if i > 0 {
+			time.Sleep(sleepDuration)
+		}
+
+		targets, err := elbv2.DescribeTargetHealth(client, targetGroupArn)
+		if err != nil {
+			log.Crit("DescribeTargetHealth", "Error", err)
+			return false
+		}
+
+		allHealthy := len(targets) > 0
+		for _, target := range targets {
+
+			if target == nil || target.Target == nil || target.Target.Id == nil ||
+				target.TargetHealth == nil || target.TargetHealth.State == nil {
+				allHealthy = false
+				continue
+			}
+
+			healthy := *target.TargetHealth.State == elbv2.Healthy
+			log.Info("Target",
+				"InstanceId", *target.Target.Id,
+				"TargetHealth", *target.TargetHealth.State,
+			)
+
+			if _, exists := instanceIdToHealthy[*target.Target.Id]; exists {
+				instanceIdToHealthy[*target.Target.Id] = healthy
+			}
+
+			// draining targets are on their way out
+			if !healthy && *target.TargetHealth.State != elbv2.Draining {
+				allHealthy = false
+			}
+		}
+
+		// if a list of instances was input, only check if those are all
+		// healthy
+		if len(instanceIdToHealthy) > 0 {
+
+			allInputHealthy := true
+			for _, healthy := range instanceIdToHealthy {
+				if !healthy {
+					allInputHealthy = false
+					break
+				}
+			}
+
+			if allInputHealthy {
+				log.Info("All targets are healthy")
+				return true
+			}
+		} else if allHealthy {
+			log.Info("All targets are healthy")
+			return true
+		}
+	}
+
+	log.Crit("Never found all healthy targets")
+	return false
+}
+
+func deregisterTargets(log log15.Logger, client *elbv2lib.ELBV2, targetGroupArn string, instanceIds []string) {
+	log = log.New("TargetGroupARN", targetGroupArn)
+
+	log.Info("DeregisterTargets", "InstanceIds", instanceIds)
+	err := elbv2.DeregisterTargets(client, targetGroupArn, instanceIds)
+	if err != nil {
+		log.Error("DeregisterTargets", "Error", err)
+	}
+}
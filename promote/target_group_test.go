@@ -0,0 +1,73 @@
+package promote_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/adobe-platform/porter/conf"
+	"github.com/adobe-platform/porter/constants"
+	"github.com/adobe-platform/porter/promote"
+)
+
+var _ = Describe("Target group promotion", func() {
+
+	const provisionedArn = "arn:aws:elasticloadbalancing:us-west-2:123456789012:targetgroup/provisioned/1"
+
+	Context("promotedTargetGroupArn", func() {
+
+		It("tags the provisioned target group when a listener is swapped", func() {
+			targetGroup := &conf.TargetGroup{
+				ARN:         "arn:aws:elasticloadbalancing:us-west-2:123456789012:targetgroup/live/1",
+				ListenerARN: "arn:aws:elasticloadbalancing:us-west-2:123456789012:listener/app/lb/1/1",
+			}
+
+			Expect(promote.PromotedTargetGroupArn(targetGroup, provisionedArn)).To(Equal(provisionedArn))
+		})
+
+		It("tags the configured target group when targets are registered", func() {
+			targetGroup := &conf.TargetGroup{
+				ARN: "arn:aws:elasticloadbalancing:us-west-2:123456789012:targetgroup/live/1",
+			}
+
+			Expect(promote.PromotedTargetGroupArn(targetGroup, provisionedArn)).To(Equal(targetGroup.ARN))
+		})
+	})
+
+	Context("promotedTags", func() {
+
+		It("records the previously promoted stack for rollback", func() {
+			tags := promote.PromotedTags(map[string]string{
+				constants.PorterStackIdTag:         "stack-2",
+				constants.PorterPreviousStackIdTag: "stack-1",
+			}, "stack-3")
+
+			Expect(tags).To(Equal(map[string]string{
+				constants.PorterStackIdTag:         "stack-3",
+				constants.PorterPreviousStackIdTag: "stack-2",
+				constants.PorterVersionTag:         constants.Version,
+			}))
+		})
+
+		It("keeps the rollback stack when promote is re-run", func() {
+			tags := promote.PromotedTags(map[string]string{
+				constants.PorterStackIdTag:         "stack-2",
+				constants.PorterPreviousStackIdTag: "stack-1",
+			}, "stack-2")
+
+			Expect(tags).To(Equal(map[string]string{
+				constants.PorterStackIdTag:         "stack-2",
+				constants.PorterPreviousStackIdTag: "stack-1",
+				constants.PorterVersionTag:         constants.Version,
+			}))
+		})
+
+		It("tags the promoted stack without previous tags", func() {
+			tags := promote.PromotedTags(nil, "stack-1")
+
+			Expect(tags).To(Equal(map[string]string{
+				constants.PorterStackIdTag: "stack-1",
+				constants.PorterVersionTag: constants.Version,
+			}))
+		})
+	})
+})
@@ -13,6 +13,8 @@ package provision
 
 import (
 	"os/exec"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -34,10 +36,48 @@ type (
 		SecretsKey  string
 		SecretsLoc  string
 		TemplateUrl string
+
+		// values for the parameters in .porter/config
+		Parameters map[string]string
 	}
 )
 
-func CreateStack(log log15.Logger, config *conf.Config, stack *provision_state.Stack) bool {
+// parameters are porter's parameters followed by the ones in .porter/config
+func (recv CfnApiInput) parameters(stackName string) []*cfnlib.Parameter {
+	parameters := []*cfnlib.Parameter{
+		{
+			ParameterKey:   aws.String(constants.ParameterStackName),
+			ParameterValue: aws.String(stackName),
+		},
+		{
+			ParameterKey:   aws.String(constants.ParameterSecretsKey),
+			ParameterValue: aws.String(recv.SecretsKey),
+		},
+		{
+			ParameterKey:   aws.String(constants.ParameterSecretsLoc),
+			ParameterValue: aws.String(recv.SecretsLoc),
+		},
+	}
+
+	names := make([]string, 0)
+	for name := range recv.Parameters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		parameters = append(parameters, &cfnlib.Parameter{
+			ParameterKey:   aws.String(name),
+			ParameterValue: aws.String(recv.Parameters[name]),
+		})
+	}
+
+	return parameters
+}
+
+// CreateStack creates the stack in every region. checkpoint, if not nil, is
+// called whenever the stack's state changes so it can be persisted
+func CreateStack(log log15.Logger, config *conf.Config, stack *provision_state.Stack, checkpoint func()) bool {
 
 	var err error
 
@@ -55,20 +95,7 @@ func CreateStack(log log15.Logger, config *conf.Config, stack *provision_state.S
 		fLock.Lock()
 		defer fLock.Unlock()
 
-		parameters := []*cfnlib.Parameter{
-			{
-				ParameterKey:   aws.String(constants.ParameterStackName),
-				ParameterValue: aws.String(stack.Name),
-			},
-			{
-				ParameterKey:   aws.String(constants.ParameterSecretsKey),
-				ParameterValue: aws.String(input.SecretsKey),
-			},
-			{
-				ParameterKey:   aws.String(constants.ParameterSecretsLoc),
-				ParameterValue: aws.String(input.SecretsLoc),
-			},
-		}
+		parameters := input.parameters(stack.Name)
 
 		stackId, err := cloudformation.CreateStack(client, stack.Name, input.TemplateUrl, parameters)
 		if err != nil {
@@ -79,52 +106,112 @@ func CreateStack(log log15.Logger, config *conf.Config, stack *provision_state.S
 		return
 	}
 
-	return createUpdateStack(log, stack, config, false, cfnAPI)
+	return createUpdateStack(log, stack, config, false, cfnAPI, checkpoint)
 }
 
-func UpdateStack(log log15.Logger, config *conf.Config, stack provision_state.Stack) bool {
+// UpdateStack updates the stack in every region through change sets. No change
+// set is executed until every region's change set is created and, if an
+// approval file is configured, approved.
+//
+// Every region's change set is executed even if one fails. The change sets
+// that weren't executed are deleted. executedRegions are the regions whose
+// stacks are updating so the caller can poll them whether or not this
+// succeeds.
+func UpdateStack(log log15.Logger, config *conf.Config, stack provision_state.Stack, opts ChangeSetOptions) (executedRegions []string, success bool) {
 
 	var fLock sync.RWMutex
 
 	log.Debug("UpdateStack", "stack.Name", stack.Name)
 
-	cfnAPI := func(client *cfnlib.CloudFormation, input CfnApiInput) (stackId string, success bool) {
-		fLock.Lock()
-		defer fLock.Unlock()
+	changeSets := make(map[string]regionChangeSet)
 
+	cfnAPI := func(client *cfnlib.CloudFormation, input CfnApiInput) (stackId string, success bool) {
+		fLock.RLock()
 		regionOutput, exists := stack.Regions[input.Region]
+		fLock.RUnlock()
+
 		if !exists {
 			log.Error("Missing stack name for region", "region", input.Region)
 			return
 		}
 
-		parameters := []*cfnlib.Parameter{
-			{
-				ParameterKey:   aws.String(constants.ParameterStackName),
-				ParameterValue: aws.String(stack.Name),
-			},
-			{
-				ParameterKey:   aws.String(constants.ParameterSecretsKey),
-				ParameterValue: aws.String(input.SecretsKey),
-			},
-			{
-				ParameterKey:   aws.String(constants.ParameterSecretsLoc),
-				ParameterValue: aws.String(input.SecretsLoc),
-			},
-		}
+		parameters := input.parameters(stack.Name)
 
-		err := cloudformation.UpdateStack(client, regionOutput.StackId, input.TemplateUrl, parameters)
-		if err != nil {
-			log.Error("UpdateStack API call failed", "Error", err)
+		regionLog := log.New("Region", input.Region)
+		changeSetId, success := createChangeSet(regionLog, client, regionOutput.StackId,
+			input.TemplateUrl, parameters, opts)
+		if !success {
 			return
 		}
 
+		fLock.Lock()
+		changeSets[input.Region] = regionChangeSet{
+			client:      client,
+			changeSetId: changeSetId,
+		}
+		fLock.Unlock()
+
 		stackId = regionOutput.StackId
-		success = true
 		return
 	}
 
-	return createUpdateStack(log, &stack, config, true, cfnAPI)
+	if !createUpdateStack(log, &stack, config, true, cfnAPI, nil) {
+		LogFailedRegions(log, stack.FailedRegions)
+		for _, changeSet := range changeSets {
+			cloudformation.DeleteChangeSet(changeSet.client, changeSet.changeSetId)
+		}
+		return
+	}
+
+	if opts.ApprovalFile != "" && !waitForApproval(log, opts.ApprovalFile, time.Now()) {
+		for _, changeSet := range changeSets {
+			cloudformation.DeleteChangeSet(changeSet.client, changeSet.changeSetId)
+		}
+		return
+	}
+
+	regionNames := make([]string, 0)
+	for regionName := range changeSets {
+		regionNames = append(regionNames, regionName)
+	}
+	sort.Strings(regionNames)
+
+	success = true
+	for _, regionName := range regionNames {
+		changeSet := changeSets[regionName]
+
+		log.Info("cloudformation:ExecuteChangeSet", "Region", regionName)
+		err := cloudformation.ExecuteChangeSet(changeSet.client, changeSet.changeSetId)
+		if err != nil {
+			log.Error("ExecuteChangeSet API call failed", "Region", regionName, "Error", err)
+			cloudformation.DeleteChangeSet(changeSet.client, changeSet.changeSetId)
+			success = false
+			continue
+		}
+
+		executedRegions = append(executedRegions, regionName)
+	}
+
+	if !success {
+		log.Error("Some change sets weren't executed. The executed regions are updating",
+			"ExecutedRegions", strings.Join(executedRegions, ","))
+	}
+
+	return
+}
+
+// LogFailedRegions logs which regions failed and why
+func LogFailedRegions(log log15.Logger, failedRegions map[string]string) {
+
+	regionNames := make([]string, 0)
+	for regionName := range failedRegions {
+		regionNames = append(regionNames, regionName)
+	}
+	sort.Strings(regionNames)
+
+	for _, regionName := range regionNames {
+		log.Error("Region failed", "Region", regionName, "Reason", failedRegions[regionName])
+	}
 }
 
 func createUpdateStack(
@@ -132,7 +219,12 @@ func createUpdateStack(
 	stack *provision_state.Stack,
 	config *conf.Config,
 	updateStack bool,
-	cfnAPI func(*cfnlib.CloudFormation, CfnApiInput) (string, bool)) (success bool) {
+	cfnAPI func(*cfnlib.CloudFormation, CfnApiInput) (string, bool),
+	checkpoint func()) (success bool) {
+
+	if checkpoint == nil {
+		checkpoint = func() {}
+	}
 
 	environment, err := config.GetEnvironment(stack.Environment)
 	if err != nil {
@@ -144,15 +236,35 @@ func createUpdateStack(
 		stack.Regions = make(map[string]*provision_state.Region)
 	}
 
-	successChan := make(chan bool)
+	if stack.FailedRegions == nil {
+		stack.FailedRegions = make(map[string]string)
+	}
+
+	type regionResult struct {
+		region  string
+		stackId string
+		imageId string
+		failure string
+	}
+
+	// buffered so regions already in flight don't block if a later region
+	// fails before its goroutine starts
+	resultChan := make(chan regionResult, len(environment.Regions))
+	inFlight := 0
+	success = true
+
+	checkpoint()
 
 	for _, region := range environment.Regions {
 
 		roleARN, err := environment.GetRoleARN(region.Name)
 		if err != nil {
-			log.Error("GetRoleARN", "Error", err)
-			return
+			log.Error("GetRoleARN", "Region", region.Name, "Error", err)
+			stack.FailedRegions[region.Name] = "failed to get the role ARN"
+			success = false
+			continue
 		}
+		inFlight++
 
 		roleSession := aws_session.STS(region.Name, roleARN, 1*time.Hour)
 
@@ -181,16 +293,31 @@ func createUpdateStack(
 
 		go func(recv *stackCreator, regionState *provision_state.Region) {
 
-			successChan <- recv.createUpdateStackForRegion(regionState)
+			result := regionResult{region: recv.region.Name}
+			if recv.createUpdateStackForRegion(regionState) {
+				result.stackId = recv.stackId
+				result.imageId = recv.imageId
+			} else {
+				result.failure = recv.failure
+				if result.failure == "" {
+					result.failure = "unknown failure"
+				}
+			}
+			resultChan <- result
 
 		}(recv, regionState)
 	}
 
-	success = true
-
-	for i := 0; i < len(environment.Regions); i++ {
-		regionSuccess := <-successChan
-		success = success && regionSuccess
+	for i := 0; i < inFlight; i++ {
+		result := <-resultChan
+		if result.failure != "" {
+			stack.FailedRegions[result.region] = result.failure
+			success = false
+		} else {
+			stack.Regions[result.region].StackId = result.stackId
+			stack.Regions[result.region].ImageId = result.imageId
+		}
+		checkpoint()
 	}
 
 	return
@@ -0,0 +1,172 @@
+/*
+ * (c) 2016-2018 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+package provision
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/adobe-platform/porter/aws/cloudformation"
+	"github.com/adobe-platform/porter/constants"
+	"github.com/aws/aws-sdk-go/aws"
+	cfnlib "github.com/aws/aws-sdk-go/service/cloudformation"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+const changeSetPollInterval = 5 * time.Second
+
+type (
+	// ChangeSetOptions control how UpdateStack applies its change sets
+	ChangeSetOptions struct {
+		// AllowReplacement executes change sets that replace or remove
+		// resources
+		AllowReplacement bool
+
+		// ApprovalFile pauses before any change set is executed until the
+		// file is created or touched
+		ApprovalFile string
+	}
+
+	// regionChangeSet is a change set that's ready to execute
+	regionChangeSet struct {
+		client      *cfnlib.CloudFormation
+		changeSetId string
+	}
+)
+
+// createChangeSet creates a change set, waits for CloudFormation to compute
+// it, and logs a summary of the changes. A change set that replaces or removes
+// a resource is deleted unless replacement is allowed.
+func createChangeSet(log log15.Logger, client *cfnlib.CloudFormation, stackId string,
+	templateUrl string, parameters []*cfnlib.Parameter, opts ChangeSetOptions) (changeSetId string, success bool) {
+
+	changeSetName := fmt.Sprintf("porter-%d", time.Now().Unix())
+
+	log.Info("cloudformation:CreateChangeSet", "ChangeSetName", changeSetName)
+	changeSetId, err := cloudformation.CreateChangeSet(client, stackId, changeSetName, templateUrl, parameters)
+	if err != nil {
+		log.Error("cloudformation:CreateChangeSet", "Error", err)
+		return
+	}
+
+	changeSet, err := waitForChangeSet(client, changeSetId)
+	if err != nil {
+		log.Error("Change set wasn't created", "ChangeSetName", changeSetName, "Error", err)
+		cloudformation.DeleteChangeSet(client, changeSetId)
+		return
+	}
+
+	destructive := logChangeSet(log, changeSet)
+
+	if destructive > 0 && !opts.AllowReplacement {
+		log.Error(fmt.Sprintf("Change set replaces or removes %d resources. Use --allow-replacement to allow it", destructive))
+		cloudformation.DeleteChangeSet(client, changeSetId)
+		return
+	}
+
+	success = true
+	return
+}
+
+// waitForChangeSet polls until CloudFormation finishes computing a change set
+func waitForChangeSet(client *cfnlib.CloudFormation, changeSetId string) (*cfnlib.DescribeChangeSetOutput, error) {
+
+	n := int(constants.StackCreationTimeout().Seconds() / changeSetPollInterval.Seconds())
+	for i := 0; i < n; i++ {
+
+		changeSet, err := cloudformation.DescribeChangeSet(client, changeSetId)
+		if err != nil {
+			return nil, err
+		}
+
+		switch aws.StringValue(changeSet.Status) {
+		case cfnlib.ChangeSetStatusCreateComplete:
+			return changeSet, nil
+		case cfnlib.ChangeSetStatusFailed:
+			return nil, errors.New(aws.StringValue(changeSet.StatusReason))
+		}
+
+		time.Sleep(changeSetPollInterval)
+	}
+
+	return nil, errors.New("timed out")
+}
+
+// logChangeSet logs each change and returns how many replace or remove a
+// resource. A conditional replacement counts because CloudFormation can't
+// rule it out.
+func logChangeSet(log log15.Logger, changeSet *cfnlib.DescribeChangeSetOutput) (destructive int) {
+
+	log.Info(fmt.Sprintf("Change set has %d changes", len(changeSet.Changes)),
+		"ChangeSetName", aws.StringValue(changeSet.ChangeSetName))
+
+	for _, change := range changeSet.Changes {
+		if change == nil || change.ResourceChange == nil {
+			continue
+		}
+		resourceChange := change.ResourceChange
+
+		action := aws.StringValue(resourceChange.Action)
+		replacement := aws.StringValue(resourceChange.Replacement)
+
+		ctx := []interface{}{
+			"Action", action,
+			"LogicalResourceId", aws.StringValue(resourceChange.LogicalResourceId),
+			"ResourceType", aws.StringValue(resourceChange.ResourceType),
+		}
+		if replacement != "" {
+			ctx = append(ctx, "Replacement", replacement)
+		}
+		if len(resourceChange.Scope) > 0 {
+			ctx = append(ctx, "Scope", strings.Join(aws.StringValueSlice(resourceChange.Scope), ","))
+		}
+
+		if action == cfnlib.ChangeActionRemove ||
+			replacement == cfnlib.ReplacementTrue ||
+			replacement == cfnlib.ReplacementConditional {
+
+			destructive++
+			log.Warn("Change", ctx...)
+		} else {
+			log.Info("Change", ctx...)
+		}
+	}
+
+	return
+}
+
+// waitForApproval waits until the approval file is created or touched after
+// the change sets were created
+func waitForApproval(log log15.Logger, approvalFile string, since time.Time) (success bool) {
+
+	log.Info("Waiting for approval. Create or touch the approval file to execute the change sets",
+		"ApprovalFile", approvalFile)
+
+	n := int(constants.StackCreationTimeout().Seconds() / changeSetPollInterval.Seconds())
+	for i := 0; i < n; i++ {
+
+		fileInfo, err := os.Stat(approvalFile)
+		if err == nil && fileInfo.ModTime().After(since) {
+			log.Info("Change sets approved")
+			success = true
+			return
+		}
+
+		time.Sleep(changeSetPollInterval)
+	}
+
+	log.Error("Change sets weren't approved in " + constants.StackCreationTimeout().String())
+	return
+}
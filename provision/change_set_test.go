@@ -0,0 +1,66 @@
+package provision_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/adobe-platform/porter/provision"
+	"github.com/aws/aws-sdk-go/aws"
+	cfnlib "github.com/aws/aws-sdk-go/service/cloudformation"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+var _ = Describe("Change sets", func() {
+
+	log := log15.New()
+	log.SetHandler(log15.DiscardHandler())
+
+	change := func(action, replacement string) *cfnlib.Change {
+		resourceChange := &cfnlib.ResourceChange{
+			Action:            aws.String(action),
+			LogicalResourceId: aws.String("Resource"),
+			ResourceType:      aws.String("AWS::EC2::SecurityGroup"),
+		}
+		if replacement != "" {
+			resourceChange.Replacement = aws.String(replacement)
+		}
+		return &cfnlib.Change{ResourceChange: resourceChange}
+	}
+
+	destructive := func(changes ...*cfnlib.Change) int {
+		return provision.LogChangeSet(log, &cfnlib.DescribeChangeSetOutput{
+			ChangeSetName: aws.String("porter-1"),
+			Changes:       changes,
+		})
+	}
+
+	It("doesn't count adds and in-place modifications", func() {
+		Expect(destructive(
+			change(cfnlib.ChangeActionAdd, ""),
+			change(cfnlib.ChangeActionModify, cfnlib.ReplacementFalse),
+		)).To(Equal(0))
+	})
+
+	It("counts removals", func() {
+		Expect(destructive(change(cfnlib.ChangeActionRemove, ""))).To(Equal(1))
+	})
+
+	It("counts replacements", func() {
+		Expect(destructive(change(cfnlib.ChangeActionModify, cfnlib.ReplacementTrue))).To(Equal(1))
+	})
+
+	It("counts conditional replacements", func() {
+		Expect(destructive(change(cfnlib.ChangeActionModify, cfnlib.ReplacementConditional))).To(Equal(1))
+	})
+
+	It("counts every destructive change and skips empty ones", func() {
+		Expect(destructive(
+			change(cfnlib.ChangeActionRemove, ""),
+			change(cfnlib.ChangeActionModify, cfnlib.ReplacementTrue),
+			change(cfnlib.ChangeActionModify, cfnlib.ReplacementConditional),
+			change(cfnlib.ChangeActionModify, cfnlib.ReplacementFalse),
+			&cfnlib.Change{},
+			nil,
+		)).To(Equal(3))
+	})
+})
@@ -12,6 +12,9 @@
 package provision
 
 import (
+	"sort"
+
+	"github.com/adobe-platform/porter/aws/elbv2"
 	"github.com/adobe-platform/porter/cfn"
 	"github.com/adobe-platform/porter/cfn_template"
 	"github.com/adobe-platform/porter/conf"
@@ -49,6 +52,10 @@ func (recv *stackCreator) ensureResources(template *cfn.Template) (success bool)
 		return
 	}
 
+	if !recv.ensureAutoScalingPolicies(template) {
+		return
+	}
+
 	// overloaded to include metadata which is why it applies
 	// to all topologies
 	if !recv.ensureWaitConditionHandle(template) {
@@ -85,6 +92,29 @@ func (recv *stackCreator) ensureResources(template *cfn.Template) (success bool)
 			if !recv.ensureDNSResources(template) {
 				return
 			}
+		} else if recv.region.HasTargetGroup() {
+
+			if !recv.ensureTargetGroup(template) {
+				return
+			}
+
+			if recv.environment.CreateSecurityGroups == nil || *recv.environment.CreateSecurityGroups == true {
+
+				if recv.region.TargetGroupType() == conf.TargetGroup_Application {
+
+					if !recv.ensureDestinationTargetGroupSecurityGroup(template) {
+						return
+					}
+				} else {
+
+					// NLBs don't have security groups and preserve the
+					// client IP so instances receive traffic directly from
+					// the internet
+					if !recv.ensureInetSG(template) {
+						return
+					}
+				}
+			}
 		} else {
 			if recv.environment.CreateSecurityGroups == nil || *recv.environment.CreateSecurityGroups == true {
 
@@ -134,6 +164,31 @@ func (recv *stackCreator) ensureParameters(template *cfn.Template) bool {
 		Type:        "String",
 	}
 
+	parameters, err := recv.environment.GetParameters(recv.region.Name)
+	if err != nil {
+		recv.log.Error("GetParameters", "Error", err)
+		return false
+	}
+
+	// a custom stack definition declares its own parameters. declare any it
+	// doesn't so CloudFormation accepts the value
+	for name, parameter := range parameters {
+
+		parameterInput, exists := template.Parameters[name]
+		if !exists {
+			parameterInput = cfn.ParameterInput{
+				Description: "From .porter/config",
+				Type:        "String",
+			}
+		}
+
+		if parameter.Secret() {
+			parameterInput.NoEcho = true
+		}
+
+		template.Parameters[name] = parameterInput
+	}
+
 	return true
 }
 
@@ -200,11 +255,37 @@ func (recv *stackCreator) ensureELB(template *cfn.Template) bool {
 	return true
 }
 
+func (recv *stackCreator) ensureTargetGroup(template *cfn.Template) bool {
+	if exists := template.ResourceExists(cfn.ElasticLoadBalancingV2_TargetGroup); exists {
+		return true
+	}
+
+	resource := map[string]interface{}{
+		"Type": cfn.ElasticLoadBalancingV2_TargetGroup,
+	}
+	template.SetResource("ProvisionedTargetGroup", resource)
+
+	return true
+}
+
 func (recv *stackCreator) ensureAutoScalingLaunchConfig(template *cfn.Template) bool {
 	if exists := template.ResourceExists(cfn.AutoScaling_LaunchConfiguration); exists {
 		return true
 	}
 
+	if exists := template.ResourceExists(cfn.EC2_LaunchTemplate); exists {
+		return true
+	}
+
+	if recv.useLaunchTemplate() {
+		resource := map[string]interface{}{
+			"Type": cfn.EC2_LaunchTemplate,
+		}
+		template.SetResource("AutoScalingLaunchTemplate", resource)
+
+		return true
+	}
+
 	resource := map[string]interface{}{
 		"Type": cfn.AutoScaling_LaunchConfiguration,
 	}
@@ -226,6 +307,194 @@ func (recv *stackCreator) ensureAutoScalingGroup(template *cfn.Template) bool {
 	return true
 }
 
+// ensureAutoScalingPolicies adds the scaling policies, alarms, and scheduled
+// actions defined by auto_scaling. Properties that refer to other resources
+// are set by MapResource functions
+func (recv *stackCreator) ensureAutoScalingPolicies(template *cfn.Template) (success bool) {
+
+	autoScaling, err := recv.environment.GetAutoScaling(recv.region.Name)
+	if err != nil {
+		recv.log.Error("GetAutoScaling", "Error", err)
+		return
+	}
+
+	if autoScaling == nil {
+		success = true
+		return
+	}
+
+	autoScalingGroup, err := template.GetResourceName(cfn.AutoScaling_AutoScalingGroup)
+	if err != nil {
+		recv.log.Error("template.GetResourceName", "Error", err)
+		return
+	}
+
+	setResource := func(logicalName string, resource map[string]interface{}) bool {
+		if _, exists := template.Resources[logicalName]; exists {
+			recv.log.Error("auto_scaling conflicts with a resource in the stack definition", "LogicalName", logicalName)
+			return false
+		}
+
+		template.SetResource(logicalName, resource)
+		return true
+	}
+
+	for _, policy := range autoScaling.TargetTracking {
+
+		metricType := "ASGAverageCPUUtilization"
+		if policy.Metric == conf.ScalingMetric_RequestCount {
+			metricType = "ALBRequestCountPerTarget"
+		}
+
+		props := map[string]interface{}{
+			"PolicyType": "TargetTrackingScaling",
+			"TargetTrackingConfiguration": map[string]interface{}{
+				"PredefinedMetricSpecification": map[string]interface{}{
+					"PredefinedMetricType": metricType,
+				},
+				"TargetValue":    policy.TargetValue,
+				"DisableScaleIn": policy.DisableScaleIn,
+			},
+		}
+
+		if policy.EstimatedInstanceWarmup > 0 {
+			props["EstimatedInstanceWarmup"] = policy.EstimatedInstanceWarmup
+		}
+
+		if !setResource("ScalingPolicy"+policy.Name, map[string]interface{}{
+			"Type":       cfn.AutoScaling_ScalingPolicy,
+			"Properties": props,
+		}) {
+			return
+		}
+	}
+
+	for _, policy := range autoScaling.StepScaling {
+
+		stepAdjustments := make([]interface{}, 0)
+		for _, step := range policy.Steps {
+
+			stepAdjustment := map[string]interface{}{
+				"ScalingAdjustment": step.Adjustment,
+			}
+
+			if step.LowerBound != nil {
+				stepAdjustment["MetricIntervalLowerBound"] = *step.LowerBound
+			}
+
+			if step.UpperBound != nil {
+				stepAdjustment["MetricIntervalUpperBound"] = *step.UpperBound
+			}
+
+			stepAdjustments = append(stepAdjustments, stepAdjustment)
+		}
+
+		props := map[string]interface{}{
+			"PolicyType":            "StepScaling",
+			"AdjustmentType":        policy.AdjustmentType,
+			"MetricAggregationType": policy.MetricAggregationType,
+			"StepAdjustments":       stepAdjustments,
+		}
+
+		if policy.EstimatedInstanceWarmup > 0 {
+			props["EstimatedInstanceWarmup"] = policy.EstimatedInstanceWarmup
+		}
+
+		policyName := "ScalingPolicy" + policy.Name
+		if !setResource(policyName, map[string]interface{}{
+			"Type":       cfn.AutoScaling_ScalingPolicy,
+			"Properties": props,
+		}) {
+			return
+		}
+
+		dimensions := make([]interface{}, 0)
+		if len(policy.Alarm.Dimensions) == 0 {
+
+			if policy.Alarm.Namespace == "AWS/EC2" {
+				dimensions = append(dimensions, map[string]interface{}{
+					"Name":  "AutoScalingGroupName",
+					"Value": map[string]interface{}{"Ref": autoScalingGroup},
+				})
+			}
+		} else {
+
+			// sorted so the template doesn't change between provisions
+			names := make([]string, 0)
+			for name := range policy.Alarm.Dimensions {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			for _, name := range names {
+				dimensions = append(dimensions, map[string]interface{}{
+					"Name":  name,
+					"Value": policy.Alarm.Dimensions[name],
+				})
+			}
+		}
+
+		if !setResource("ScalingAlarm"+policy.Name, map[string]interface{}{
+			"Type": cfn.CloudWatch_Alarm,
+			"Properties": map[string]interface{}{
+				"AlarmDescription":   "Triggers step_scaling policy " + policy.Name,
+				"Namespace":          policy.Alarm.Namespace,
+				"MetricName":         policy.Alarm.MetricName,
+				"Statistic":          policy.Alarm.Statistic,
+				"Dimensions":         dimensions,
+				"Period":             policy.Alarm.Period,
+				"EvaluationPeriods":  policy.Alarm.EvaluationPeriods,
+				"Threshold":          policy.Alarm.Threshold,
+				"ComparisonOperator": policy.Alarm.ComparisonOperator,
+				"AlarmActions": []interface{}{
+					map[string]interface{}{"Ref": policyName},
+				},
+			},
+		}) {
+			return
+		}
+	}
+
+	for _, action := range autoScaling.ScheduledActions {
+
+		props := make(map[string]interface{})
+
+		if action.Recurrence != "" {
+			props["Recurrence"] = action.Recurrence
+		}
+
+		if action.StartTime != "" {
+			props["StartTime"] = action.StartTime
+		}
+
+		if action.EndTime != "" {
+			props["EndTime"] = action.EndTime
+		}
+
+		if action.MinSize != nil {
+			props["MinSize"] = *action.MinSize
+		}
+
+		if action.MaxSize != nil {
+			props["MaxSize"] = *action.MaxSize
+		}
+
+		if action.DesiredCapacity != nil {
+			props["DesiredCapacity"] = *action.DesiredCapacity
+		}
+
+		if !setResource("ScheduledAction"+action.Name, map[string]interface{}{
+			"Type":       cfn.AutoScaling_ScheduledAction,
+			"Properties": props,
+		}) {
+			return
+		}
+	}
+
+	success = true
+	return
+}
+
 func (recv *stackCreator) ensureWaitCondition(template *cfn.Template) bool {
 	if exists := template.ResourceExists(cfn.CloudFormation_WaitCondition); exists {
 		return true
@@ -306,7 +575,13 @@ func (recv *stackCreator) ensureInetSG(template *cfn.Template) bool {
 		metadataKey = constants.MetadataAsLc
 		resourceName = constants.AsgSgLogicalName
 	}
-	resource := cfn_template.InetSg(vpc, https, httpsOnly, metadataKey)
+	// NLB ingress is restricted to the target groups' ingress_cidrs
+	var cidrs []string
+	if recv.region.TargetGroupType() == conf.TargetGroup_Network {
+		cidrs = recv.region.IngressCIDRs()
+	}
+
+	resource := cfn_template.InetSg(vpc, https, httpsOnly, metadataKey, cidrs)
 
 	template.SetResource(resourceName, resource)
 
@@ -452,3 +727,72 @@ func (recv *stackCreator) ensureDestinationELBSecurityGroup(template *cfn.Templa
 	success = true
 	return
 }
+
+// Unlike a classic ELB, traffic from an ALB to its targets has the source
+// security group of the ALB itself
+func (recv *stackCreator) ensureDestinationTargetGroupSecurityGroup(template *cfn.Template) (success bool) {
+
+	sgProperties := make(map[string]interface{})
+	sgMetadata := make(map[string]interface{})
+	sgMetadata[constants.MetadataAsLc] = true
+	sg := map[string]interface{}{
+		"Type":       cfn.EC2_SecurityGroup,
+		"Properties": sgProperties,
+		"Metadata":   sgMetadata,
+	}
+	sgProperties["GroupDescription"] = "Ingress for destination target groups"
+
+	client := elbv2.New(recv.roleSession)
+
+	port, _ := recv.targetGroupPortAndProtocol()
+
+	ingressRules := make([]interface{}, 0)
+
+	for _, targetGroup := range recv.region.TargetGroups {
+
+		log := recv.log.New("TargetGroupARN", targetGroup.ARN, "ListenerARN", targetGroup.ListenerARN)
+
+		loadBalancerArns, err := elbv2.GetLoadBalancerArns(client, targetGroup.ARN, targetGroup.ListenerARN)
+		if err != nil {
+			log.Error("GetLoadBalancerArns", "Error", err)
+			return
+		}
+
+		if len(loadBalancerArns) == 0 {
+			log.Error("The target group isn't associated with a load balancer")
+			return
+		}
+
+		loadBalancers, err := elbv2.DescribeLoadBalancers(client, loadBalancerArns...)
+		if err != nil {
+			log.Error("DescribeLoadBalancers", "Error", err)
+			return
+		}
+
+		for _, loadBalancer := range loadBalancers {
+
+			if loadBalancer == nil || len(loadBalancer.SecurityGroups) == 0 {
+				log.Error("SecurityGroups field is nil")
+				return
+			}
+
+			// We only need one security group from the load balancer to
+			// enable instances to receive traffic from it
+			ingressRule := map[string]interface{}{
+				"IpProtocol":            "tcp",
+				"FromPort":              port,
+				"ToPort":                port,
+				"SourceSecurityGroupId": *loadBalancer.SecurityGroups[0],
+			}
+
+			ingressRules = append(ingressRules, ingressRule)
+		}
+	}
+
+	sgProperties["SecurityGroupIngress"] = ingressRules
+
+	template.SetResource(constants.DstELBSecurityGroup, sg)
+
+	success = true
+	return
+}
@@ -0,0 +1,285 @@
+package provision_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/adobe-platform/porter/cfn"
+	"github.com/adobe-platform/porter/conf"
+	"github.com/adobe-platform/porter/provision"
+)
+
+var _ = Describe("Auto scaling policies", func() {
+
+	var (
+		environment conf.Environment
+		region      conf.Region
+		template    *cfn.Template
+	)
+
+	uintPtr := func(u uint) *uint { return &u }
+	floatPtr := func(f float64) *float64 { return &f }
+
+	properties := func(logicalName string) map[string]interface{} {
+		resource, ok := template.Resources[logicalName].(map[string]interface{})
+		Expect(ok).To(BeTrue(), logicalName+" is missing")
+		props, ok := resource["Properties"].(map[string]interface{})
+		Expect(ok).To(BeTrue(), logicalName+" has no Properties")
+		return props
+	}
+
+	resourceType := func(logicalName string) interface{} {
+		resource, ok := template.Resources[logicalName].(map[string]interface{})
+		Expect(ok).To(BeTrue(), logicalName+" is missing")
+		return resource["Type"]
+	}
+
+	ensure := func(autoScaling *conf.AutoScaling) bool {
+		region.AutoScaling = autoScaling
+		environment.Regions = []*conf.Region{&region}
+		return provision.EnsureAutoScalingPolicies(environment, region, template)
+	}
+
+	BeforeEach(func() {
+		environment = conf.Environment{Name: "prod"}
+		region = conf.Region{Name: "us-west-2"}
+
+		template = cfn.NewTemplate()
+		template.SetResource("AutoScalingGroup", map[string]interface{}{
+			"Type": cfn.AutoScaling_AutoScalingGroup,
+		})
+	})
+
+	It("adds nothing without auto_scaling", func() {
+		Expect(ensure(nil)).To(BeTrue())
+		Expect(template.Resources).To(HaveLen(1))
+	})
+
+	It("uses the environment's auto_scaling when the region has none", func() {
+		environment.AutoScaling = &conf.AutoScaling{
+			MinSize: 1,
+			MaxSize: 2,
+			TargetTracking: []*conf.TargetTrackingPolicy{
+				{Name: "cpu", Metric: conf.ScalingMetric_CPU, TargetValue: 50},
+			},
+		}
+
+		Expect(ensure(nil)).To(BeTrue())
+		Expect(resourceType("ScalingPolicycpu")).To(Equal(cfn.AutoScaling_ScalingPolicy))
+	})
+
+	It("adds target tracking policies", func() {
+		Expect(ensure(&conf.AutoScaling{
+			TargetTracking: []*conf.TargetTrackingPolicy{
+				{Name: "cpu", Metric: conf.ScalingMetric_CPU, TargetValue: 50, EstimatedInstanceWarmup: 120},
+				{Name: "requests", Metric: conf.ScalingMetric_RequestCount, TargetValue: 1000, DisableScaleIn: true},
+			},
+		})).To(BeTrue())
+
+		Expect(resourceType("ScalingPolicycpu")).To(Equal(cfn.AutoScaling_ScalingPolicy))
+		Expect(properties("ScalingPolicycpu")).To(Equal(map[string]interface{}{
+			"PolicyType": "TargetTrackingScaling",
+			"TargetTrackingConfiguration": map[string]interface{}{
+				"PredefinedMetricSpecification": map[string]interface{}{
+					"PredefinedMetricType": "ASGAverageCPUUtilization",
+				},
+				"TargetValue":    float64(50),
+				"DisableScaleIn": false,
+			},
+			"EstimatedInstanceWarmup": 120,
+		}))
+
+		Expect(properties("ScalingPolicyrequests")).To(Equal(map[string]interface{}{
+			"PolicyType": "TargetTrackingScaling",
+			"TargetTrackingConfiguration": map[string]interface{}{
+				"PredefinedMetricSpecification": map[string]interface{}{
+					"PredefinedMetricType": "ALBRequestCountPerTarget",
+				},
+				"TargetValue":    float64(1000),
+				"DisableScaleIn": true,
+			},
+		}))
+	})
+
+	It("adds a step scaling policy and the alarm that triggers it", func() {
+		Expect(ensure(&conf.AutoScaling{
+			StepScaling: []*conf.StepScalingPolicy{
+				{
+					Name:                  "cpu",
+					AdjustmentType:        conf.ScalingAdjustment_Change,
+					MetricAggregationType: "Average",
+					Steps: []*conf.ScalingStep{
+						{LowerBound: floatPtr(0), UpperBound: floatPtr(20), Adjustment: 1},
+						{LowerBound: floatPtr(20), Adjustment: 3},
+					},
+					Alarm: conf.ScalingAlarm{
+						Namespace:          "AWS/EC2",
+						MetricName:         "CPUUtilization",
+						Statistic:          "Average",
+						Period:             60,
+						EvaluationPeriods:  2,
+						Threshold:          70,
+						ComparisonOperator: "GreaterThanOrEqualToThreshold",
+					},
+				},
+			},
+		})).To(BeTrue())
+
+		Expect(resourceType("ScalingPolicycpu")).To(Equal(cfn.AutoScaling_ScalingPolicy))
+		Expect(properties("ScalingPolicycpu")).To(Equal(map[string]interface{}{
+			"PolicyType":            "StepScaling",
+			"AdjustmentType":        conf.ScalingAdjustment_Change,
+			"MetricAggregationType": "Average",
+			"StepAdjustments": []interface{}{
+				map[string]interface{}{
+					"ScalingAdjustment":        1,
+					"MetricIntervalLowerBound": float64(0),
+					"MetricIntervalUpperBound": float64(20),
+				},
+				map[string]interface{}{
+					"ScalingAdjustment":        3,
+					"MetricIntervalLowerBound": float64(20),
+				},
+			},
+		}))
+
+		Expect(resourceType("ScalingAlarmcpu")).To(Equal(cfn.CloudWatch_Alarm))
+		Expect(properties("ScalingAlarmcpu")).To(Equal(map[string]interface{}{
+			"AlarmDescription": "Triggers step_scaling policy cpu",
+			"Namespace":        "AWS/EC2",
+			"MetricName":       "CPUUtilization",
+			"Statistic":        "Average",
+			"Dimensions": []interface{}{
+				map[string]interface{}{
+					"Name":  "AutoScalingGroupName",
+					"Value": map[string]interface{}{"Ref": "AutoScalingGroup"},
+				},
+			},
+			"Period":             60,
+			"EvaluationPeriods":  2,
+			"Threshold":          float64(70),
+			"ComparisonOperator": "GreaterThanOrEqualToThreshold",
+			"AlarmActions": []interface{}{
+				map[string]interface{}{"Ref": "ScalingPolicycpu"},
+			},
+		}))
+	})
+
+	It("sorts an alarm's dimensions instead of scoping it to the ASG", func() {
+		Expect(ensure(&conf.AutoScaling{
+			StepScaling: []*conf.StepScalingPolicy{
+				{
+					Name:  "queue",
+					Steps: []*conf.ScalingStep{{LowerBound: floatPtr(0), Adjustment: 1}},
+					Alarm: conf.ScalingAlarm{
+						Namespace:  "Custom",
+						MetricName: "Depth",
+						Dimensions: map[string]string{
+							"Queue": "jobs",
+							"App":   "worker",
+						},
+					},
+				},
+			},
+		})).To(BeTrue())
+
+		Expect(properties("ScalingAlarmqueue")["Dimensions"]).To(Equal([]interface{}{
+			map[string]interface{}{"Name": "App", "Value": "worker"},
+			map[string]interface{}{"Name": "Queue", "Value": "jobs"},
+		}))
+	})
+
+	It("only scopes AWS/EC2 alarms to the ASG", func() {
+		Expect(ensure(&conf.AutoScaling{
+			StepScaling: []*conf.StepScalingPolicy{
+				{
+					Name:  "queue",
+					Steps: []*conf.ScalingStep{{LowerBound: floatPtr(0), Adjustment: 1}},
+					Alarm: conf.ScalingAlarm{Namespace: "AWS/SQS", MetricName: "ApproximateNumberOfMessagesVisible"},
+				},
+			},
+		})).To(BeTrue())
+
+		Expect(properties("ScalingAlarmqueue")["Dimensions"]).To(BeEmpty())
+	})
+
+	It("adds scheduled actions with only the sizes that are set", func() {
+		Expect(ensure(&conf.AutoScaling{
+			ScheduledActions: []*conf.ScheduledAction{
+				{Name: "nightly", Recurrence: "0 2 * * *", MinSize: uintPtr(0), MaxSize: uintPtr(2)},
+				{Name: "launch", StartTime: "2018-01-02T15:04:05Z", EndTime: "2018-01-03T15:04:05Z", DesiredCapacity: uintPtr(8)},
+			},
+		})).To(BeTrue())
+
+		Expect(resourceType("ScheduledActionnightly")).To(Equal(cfn.AutoScaling_ScheduledAction))
+		Expect(properties("ScheduledActionnightly")).To(Equal(map[string]interface{}{
+			"Recurrence": "0 2 * * *",
+			"MinSize":    uint(0),
+			"MaxSize":    uint(2),
+		}))
+
+		Expect(properties("ScheduledActionlaunch")).To(Equal(map[string]interface{}{
+			"StartTime":       "2018-01-02T15:04:05Z",
+			"EndTime":         "2018-01-03T15:04:05Z",
+			"DesiredCapacity": uint(8),
+		}))
+	})
+
+	It("fails on a conflict with the stack definition", func() {
+		template.SetResource("ScheduledActionnightly", map[string]interface{}{
+			"Type": cfn.AutoScaling_ScheduledAction,
+		})
+
+		Expect(ensure(&conf.AutoScaling{
+			ScheduledActions: []*conf.ScheduledAction{
+				{Name: "nightly", Recurrence: "0 2 * * *", MinSize: uintPtr(0)},
+			},
+		})).To(BeFalse())
+	})
+
+	It("fails without an ASG", func() {
+		template = cfn.NewTemplate()
+
+		Expect(ensure(&conf.AutoScaling{
+			ScheduledActions: []*conf.ScheduledAction{
+				{Name: "nightly", Recurrence: "0 2 * * *", MinSize: uintPtr(0)},
+			},
+		})).To(BeFalse())
+	})
+
+	Context("setAutoScalingGroupName", func() {
+
+		It("refers policies and scheduled actions to the ASG", func() {
+			resource := map[string]interface{}{
+				"Type": cfn.AutoScaling_ScheduledAction,
+				"Properties": map[string]interface{}{
+					"Recurrence": "0 2 * * *",
+				},
+			}
+
+			Expect(provision.RunMapResource(provision.SetAutoScalingGroupName,
+				conf.Config{}, environment, region, template, resource)).To(BeTrue())
+
+			Expect(resource["Properties"]).To(Equal(map[string]interface{}{
+				"Recurrence":           "0 2 * * *",
+				"AutoScalingGroupName": map[string]interface{}{"Ref": "AutoScalingGroup"},
+			}))
+		})
+
+		It("keeps an AutoScalingGroupName from the stack definition", func() {
+			resource := map[string]interface{}{
+				"Type": cfn.AutoScaling_ScalingPolicy,
+				"Properties": map[string]interface{}{
+					"AutoScalingGroupName": "other",
+				},
+			}
+
+			Expect(provision.RunMapResource(provision.SetAutoScalingGroupName,
+				conf.Config{}, environment, region, template, resource)).To(BeTrue())
+
+			Expect(resource["Properties"]).To(Equal(map[string]interface{}{
+				"AutoScalingGroupName": "other",
+			}))
+		})
+	})
+})
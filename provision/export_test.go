@@ -0,0 +1,70 @@
+package provision
+
+import (
+	"github.com/adobe-platform/porter/cfn"
+	"github.com/adobe-platform/porter/conf"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+var LogChangeSet = logChangeSet
+var TemplateImageId = templateImageId
+var PorterManagedResources = porterManagedResources
+var ValidateTransformedTemplate = validateTransformedTemplate
+var SetAutoScalingGroupName = setAutoScalingGroupName
+var SetLaunchConfigurationName = setLaunchConfigurationName
+var SetLaunchTemplate = setLaunchTemplate
+var LaunchTemplateData = launchTemplateData
+var AddASGSecurityGroups = addASGSecurityGroups
+var SetInstanceType = setInstanceType
+var SetKeyName = setKeyName
+var SetIamInstanceProfile = setIamInstanceProfile
+var SetImageId = setImageId
+
+func newTestStackCreator(config conf.Config, environment conf.Environment, region conf.Region) *stackCreator {
+	log := log15.New()
+	log.SetHandler(log15.DiscardHandler())
+	return &stackCreator{
+		log:         log,
+		config:      config,
+		environment: environment,
+		region:      region,
+	}
+}
+
+// RunTemplateTransforms runs the config's template transforms on template
+func RunTemplateTransforms(config conf.Config, template *cfn.Template) bool {
+	recv := newTestStackCreator(config, conf.Environment{}, conf.Region{})
+	return recv.runTemplateTransforms(template)
+}
+
+// EnsureAutoScalingPolicies adds the region's auto_scaling resources to template
+func EnsureAutoScalingPolicies(environment conf.Environment, region conf.Region, template *cfn.Template) bool {
+	recv := newTestStackCreator(conf.Config{}, environment, region)
+	return recv.ensureAutoScalingPolicies(template)
+}
+
+// RunMapResource runs a MapResource function on one of template's resources
+func RunMapResource(fn MapResource, config conf.Config, environment conf.Environment,
+	region conf.Region, template *cfn.Template, resource map[string]interface{}) bool {
+
+	recv := newTestStackCreator(config, environment, region)
+	return fn(recv, template, resource)
+}
+
+// EnsureAutoScalingLaunchConfig adds a launch configuration or launch template
+// to template
+func EnsureAutoScalingLaunchConfig(environment conf.Environment, region conf.Region, template *cfn.Template) bool {
+	recv := newTestStackCreator(conf.Config{}, environment, region)
+	return recv.ensureAutoScalingLaunchConfig(template)
+}
+
+// RunSetPoolSize runs setPoolSize as if the promoted stack's ASG had
+// asgDesired instances. asgDesired is 0 without hot swap
+func RunSetPoolSize(environment conf.Environment, region conf.Region, template *cfn.Template,
+	resource map[string]interface{}, asgDesired int, asgLaunchTemplate bool) bool {
+
+	recv := newTestStackCreator(conf.Config{}, environment, region)
+	recv.asgDesired = asgDesired
+	recv.asgLaunchTemplate = asgLaunchTemplate
+	return setPoolSize(recv, template, resource)
+}
@@ -13,12 +13,15 @@ package provision
 
 import (
 	"bytes"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"os"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/adobe-platform/porter/aws/elbv2"
 	awsutil "github.com/adobe-platform/porter/aws/util"
 	"github.com/adobe-platform/porter/cfn"
 	"github.com/adobe-platform/porter/cfn_template"
@@ -47,12 +50,26 @@ func (recv *stackCreator) mapResources(template *cfn.Template) (success bool) {
 			setUserData,
 			overwriteASGSecurityGroupEgress,
 		}
+		ops[cfn.EC2_LaunchTemplate] = []MapResource{
+			launchTemplateData(
+				addASGSecurityGroups,
+				setInstanceType,
+				setKeyName,
+				setIamInstanceProfile,
+				setImageId,
+				setAutoScalingLaunchConfigurationMetadata,
+				setUserData,
+				overwriteASGSecurityGroupEgress,
+			),
+		}
 		ops[cfn.AutoScaling_AutoScalingGroup] = []MapResource{
 			addAutoScaleGroupTags,
 			setPoolSize,
 			setAutoScalingGroupMultiAZ,
 			setLaunchConfigurationName,
+			setLaunchTemplate,
 			setLoadBalancerNames,
+			setTargetGroupARNs,
 		}
 		ops[cfn.ElasticLoadBalancing_LoadBalancer] = []MapResource{
 			addELBSecurityGroups,
@@ -63,6 +80,19 @@ func (recv *stackCreator) mapResources(template *cfn.Template) (success bool) {
 			setConnectionDrainingPolicy,
 			setHealthCheck,
 		}
+		ops[cfn.ElasticLoadBalancingV2_TargetGroup] = []MapResource{
+			setVpcId,
+			setTargetGroupPortAndProtocol,
+			setTargetGroupHealthCheck,
+			setDeregistrationDelay,
+		}
+		ops[cfn.AutoScaling_ScalingPolicy] = []MapResource{
+			setAutoScalingGroupName,
+			setRequestCountResourceLabel,
+		}
+		ops[cfn.AutoScaling_ScheduledAction] = []MapResource{
+			setAutoScalingGroupName,
+		}
 		ops[cfn.EC2_SecurityGroup] = []MapResource{
 			setVpcId,
 		}
@@ -89,11 +119,29 @@ func (recv *stackCreator) mapResources(template *cfn.Template) (success bool) {
 			setAutoScalingLaunchConfigurationMetadata,
 			setUserData,
 		}
+		ops[cfn.EC2_LaunchTemplate] = []MapResource{
+			launchTemplateData(
+				addASGSecurityGroups,
+				setInstanceType,
+				setKeyName,
+				setIamInstanceProfile,
+				setImageId,
+				setAutoScalingLaunchConfigurationMetadata,
+				setUserData,
+			),
+		}
 		ops[cfn.AutoScaling_AutoScalingGroup] = []MapResource{
 			addAutoScaleGroupTags,
 			setPoolSize,
 			setAutoScalingGroupMultiAZ,
 			setLaunchConfigurationName,
+			setLaunchTemplate,
+		}
+		ops[cfn.AutoScaling_ScalingPolicy] = []MapResource{
+			setAutoScalingGroupName,
+		}
+		ops[cfn.AutoScaling_ScheduledAction] = []MapResource{
+			setAutoScalingGroupName,
 		}
 		ops[cfn.EC2_SecurityGroup] = []MapResource{
 			setVpcId,
@@ -344,6 +392,17 @@ func setLaunchConfigurationName(recv *stackCreator, template *cfn.Template, reso
 		ok    bool
 	)
 
+	if template.ResourceExists(cfn.EC2_LaunchTemplate) {
+		success = true
+		return
+	}
+
+	if recv.region.MixedInstancesPolicy != nil {
+		recv.log.Error("mixed_instances_policy requires a " + cfn.EC2_LaunchTemplate +
+			" but the stack definition has a " + cfn.AutoScaling_LaunchConfiguration)
+		return
+	}
+
 	if props, ok = resource["Properties"].(map[string]interface{}); !ok {
 		props = make(map[string]interface{})
 		resource["Properties"] = props
@@ -366,6 +425,166 @@ func setLaunchConfigurationName(recv *stackCreator, template *cfn.Template, reso
 	return
 }
 
+// setLaunchTemplate is the launch template counterpart to
+// setLaunchConfigurationName. A MixedInstancesPolicy wraps the launch template
+// specification
+func setLaunchTemplate(recv *stackCreator, template *cfn.Template, resource map[string]interface{}) (success bool) {
+	var (
+		props map[string]interface{}
+		ok    bool
+	)
+
+	if !template.ResourceExists(cfn.EC2_LaunchTemplate) {
+		success = true
+		return
+	}
+
+	if props, ok = resource["Properties"].(map[string]interface{}); !ok {
+		props = make(map[string]interface{})
+		resource["Properties"] = props
+	}
+
+	_, launchTemplateExists := props["LaunchTemplate"]
+	_, mixedInstancesPolicyExists := props["MixedInstancesPolicy"]
+	if launchTemplateExists || mixedInstancesPolicyExists {
+		success = true
+		return
+	}
+
+	launchTemplateName, err := template.GetResourceName(cfn.EC2_LaunchTemplate)
+	if err != nil {
+		recv.log.Error("template.GetResourceName", "Error", err)
+		return
+	}
+
+	launchTemplateSpecification := map[string]interface{}{
+		"LaunchTemplateId": map[string]interface{}{
+			"Ref": launchTemplateName,
+		},
+		"Version": map[string]interface{}{
+			"Fn::GetAtt": []string{launchTemplateName, "LatestVersionNumber"},
+		},
+	}
+
+	policy := recv.region.MixedInstancesPolicy
+	if policy == nil {
+		props["LaunchTemplate"] = launchTemplateSpecification
+		success = true
+		return
+	}
+
+	overrides := make([]interface{}, 0)
+	for _, instanceType := range policy.InstanceTypes {
+		overrides = append(overrides, map[string]interface{}{
+			"InstanceType": instanceType,
+		})
+	}
+
+	instancesDistribution := map[string]interface{}{
+		"OnDemandBaseCapacity":                policy.OnDemandBaseCapacity,
+		"OnDemandPercentageAboveBaseCapacity": 100 - policy.SpotPercentage,
+	}
+	if policy.SpotAllocationStrategy != "" {
+		instancesDistribution["SpotAllocationStrategy"] = policy.SpotAllocationStrategy
+	}
+	if policy.SpotInstancePools > 0 {
+		instancesDistribution["SpotInstancePools"] = policy.SpotInstancePools
+	}
+	if policy.SpotMaxPrice != "" {
+		instancesDistribution["SpotMaxPrice"] = policy.SpotMaxPrice
+	}
+
+	props["MixedInstancesPolicy"] = map[string]interface{}{
+		"LaunchTemplate": map[string]interface{}{
+			"LaunchTemplateSpecification": launchTemplateSpecification,
+			"Overrides":                   overrides,
+		},
+		"InstancesDistribution": instancesDistribution,
+	}
+
+	success = true
+	return
+}
+
+// launchTemplateData runs MapResource functions written for a
+// AWS::AutoScaling::LaunchConfiguration on the LaunchTemplateData of a
+// AWS::EC2::LaunchTemplate and then fixes up the few properties whose shape
+// differs
+func launchTemplateData(fns ...MapResource) MapResource {
+	return func(recv *stackCreator, template *cfn.Template, resource map[string]interface{}) bool {
+		var (
+			props map[string]interface{}
+			data  map[string]interface{}
+			ok    bool
+		)
+
+		if props, ok = resource["Properties"].(map[string]interface{}); !ok {
+			props = make(map[string]interface{})
+			resource["Properties"] = props
+		}
+
+		if data, ok = props["LaunchTemplateData"].(map[string]interface{}); !ok {
+			data = make(map[string]interface{})
+			props["LaunchTemplateData"] = data
+		}
+
+		// Metadata is on the resource itself so cfn-init and cfn-hup work
+		// exactly as they do for a launch configuration
+		launchConfiguration := map[string]interface{}{
+			"Type":       resource["Type"],
+			"Properties": data,
+		}
+		if metadata, exists := resource["Metadata"]; exists {
+			launchConfiguration["Metadata"] = metadata
+		}
+
+		for _, fn := range fns {
+			if !fn(recv, template, launchConfiguration) {
+				return false
+			}
+		}
+
+		if metadata, exists := launchConfiguration["Metadata"]; exists {
+			resource["Metadata"] = metadata
+		}
+
+		// A launch configuration's IamInstanceProfile is a name or ARN
+		if profile, ok := data["IamInstanceProfile"].(map[string]interface{}); ok {
+			if ref, exists := profile["Ref"]; exists {
+				data["IamInstanceProfile"] = map[string]interface{}{
+					"Name": map[string]interface{}{"Ref": ref},
+				}
+			}
+		}
+
+		// In a VPC security groups must be referenced by id
+		if recv.region.VpcId != "" {
+			if securityGroups, ok := data["SecurityGroups"].([]interface{}); ok {
+
+				securityGroupIds, ok := data["SecurityGroupIds"].([]interface{})
+				if !ok {
+					securityGroupIds = make([]interface{}, 0)
+				}
+
+				data["SecurityGroupIds"] = append(securityGroupIds, securityGroups...)
+				delete(data, "SecurityGroups")
+			}
+		}
+
+		return true
+	}
+}
+
+// launchResourceName is the logical id of the resource the ASG launches
+// instances from
+func launchResourceName(template *cfn.Template) (string, error) {
+	if template.ResourceExists(cfn.EC2_LaunchTemplate) {
+		return template.GetResourceName(cfn.EC2_LaunchTemplate)
+	}
+
+	return template.GetResourceName(cfn.AutoScaling_LaunchConfiguration)
+}
+
 func setLoadBalancerNames(recv *stackCreator, template *cfn.Template, resource map[string]interface{}) (success bool) {
 	var (
 		props map[string]interface{}
@@ -405,6 +624,194 @@ func setLoadBalancerNames(recv *stackCreator, template *cfn.Template, resource m
 	return
 }
 
+// Instances join the stack's own target group as the ASG scales. Promotion
+// decides whether that target group or the instances themselves receive
+// traffic
+func setTargetGroupARNs(recv *stackCreator, template *cfn.Template, resource map[string]interface{}) (success bool) {
+	var (
+		props map[string]interface{}
+		ok    bool
+
+		targetGroupARNs []interface{}
+	)
+
+	if !recv.region.HasTargetGroup() {
+		success = true
+		return
+	}
+
+	if props, ok = resource["Properties"].(map[string]interface{}); !ok {
+		props = make(map[string]interface{})
+		resource["Properties"] = props
+	}
+
+	if targetGroupARNs, ok = props["TargetGroupARNs"].([]interface{}); !ok {
+		targetGroupARNs = make([]interface{}, 0)
+	}
+
+	targetGroupLogicalName, err := template.GetResourceName(cfn.ElasticLoadBalancingV2_TargetGroup)
+	if err != nil {
+		recv.log.Error("template.GetResourceName", "Error", err)
+		return
+	}
+
+	targetGroupARN := map[string]interface{}{
+		"Ref": targetGroupLogicalName,
+	}
+	targetGroupARNs = append(targetGroupARNs, targetGroupARN)
+
+	props["TargetGroupARNs"] = targetGroupARNs
+
+	success = true
+	return
+}
+
+func setAutoScalingGroupName(recv *stackCreator, template *cfn.Template, resource map[string]interface{}) (success bool) {
+	var (
+		props map[string]interface{}
+		ok    bool
+	)
+
+	if props, ok = resource["Properties"].(map[string]interface{}); !ok {
+		props = make(map[string]interface{})
+		resource["Properties"] = props
+	}
+
+	if _, exists := props["AutoScalingGroupName"]; !exists {
+
+		autoScalingGroup, err := template.GetResourceName(cfn.AutoScaling_AutoScalingGroup)
+		if err != nil {
+			recv.log.Error("template.GetResourceName", "Error", err)
+			return
+		}
+
+		props["AutoScalingGroupName"] = map[string]interface{}{
+			"Ref": autoScalingGroup,
+		}
+	}
+
+	success = true
+	return
+}
+
+// ALBRequestCountPerTarget needs a ResourceLabel that identifies both the load
+// balancer and the target group
+//
+//	app/<load-balancer-name>/<load-balancer-id>/targetgroup/<target-group-name>/<target-group-id>
+//
+// Instances are registered with a target group defined by arn. When swapping
+// listeners it's the provisioned target group so the policy has no data until
+// the stack is promoted
+func setRequestCountResourceLabel(recv *stackCreator, template *cfn.Template, resource map[string]interface{}) (success bool) {
+	var (
+		props                map[string]interface{}
+		targetTrackingConfig map[string]interface{}
+		predefinedMetricSpec map[string]interface{}
+		ok                   bool
+	)
+
+	if props, ok = resource["Properties"].(map[string]interface{}); !ok {
+		success = true
+		return
+	}
+
+	if targetTrackingConfig, ok = props["TargetTrackingConfiguration"].(map[string]interface{}); !ok {
+		success = true
+		return
+	}
+
+	if predefinedMetricSpec, ok = targetTrackingConfig["PredefinedMetricSpecification"].(map[string]interface{}); !ok {
+		success = true
+		return
+	}
+
+	if metricType, _ := predefinedMetricSpec["PredefinedMetricType"].(string); metricType != "ALBRequestCountPerTarget" {
+		success = true
+		return
+	}
+
+	if _, exists := predefinedMetricSpec["ResourceLabel"]; exists {
+		success = true
+		return
+	}
+
+	if recv.region.TargetGroupType() != conf.TargetGroup_Application || len(recv.region.TargetGroups) != 1 {
+		recv.log.Error("ALBRequestCountPerTarget requires exactly one application target group")
+		return
+	}
+
+	targetGroup := recv.region.TargetGroups[0]
+	log := recv.log.New("TargetGroupARN", targetGroup.ARN, "ListenerARN", targetGroup.ListenerARN)
+
+	client := elbv2.New(recv.roleSession)
+
+	loadBalancerArns, err := elbv2.GetLoadBalancerArns(client, targetGroup.ARN, targetGroup.ListenerARN)
+	if err != nil {
+		log.Error("GetLoadBalancerArns", "Error", err)
+		return
+	}
+
+	if len(loadBalancerArns) != 1 {
+		log.Error("The target group must be associated with exactly one load balancer",
+			"LoadBalancerArns", loadBalancerArns)
+		return
+	}
+
+	loadBalancerLabel, err := arnResourceSuffix(loadBalancerArns[0], "loadbalancer/")
+	if err != nil {
+		log.Error("arnResourceSuffix", "Error", err)
+		return
+	}
+
+	if targetGroup.ARN != "" {
+
+		targetGroupLabel, err := arnResourceSuffix(targetGroup.ARN, "")
+		if err != nil {
+			log.Error("arnResourceSuffix", "Error", err)
+			return
+		}
+
+		predefinedMetricSpec["ResourceLabel"] = loadBalancerLabel + "/" + targetGroupLabel
+	} else {
+
+		targetGroupLogicalName, err := template.GetResourceName(cfn.ElasticLoadBalancingV2_TargetGroup)
+		if err != nil {
+			log.Error("template.GetResourceName", "Error", err)
+			return
+		}
+
+		predefinedMetricSpec["ResourceLabel"] = map[string]interface{}{
+			"Fn::Join": []interface{}{
+				"/",
+				[]interface{}{
+					loadBalancerLabel,
+					map[string]interface{}{
+						"Fn::GetAtt": []string{targetGroupLogicalName, "TargetGroupFullName"},
+					},
+				},
+			},
+		}
+	}
+
+	success = true
+	return
+}
+
+// arnResourceSuffix returns the resource portion of an ARN with an optional
+// prefix removed
+func arnResourceSuffix(arn, prefix string) (string, error) {
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) != 6 {
+		return "", fmt.Errorf("malformed arn %s", arn)
+	}
+
+	if !strings.HasPrefix(parts[5], prefix) {
+		return "", fmt.Errorf("arn %s resource doesn't begin with %s", arn, prefix)
+	}
+
+	return strings.TrimPrefix(parts[5], prefix), nil
+}
+
 func setKeyName(recv *stackCreator, template *cfn.Template, resource map[string]interface{}) bool {
 	var (
 		props map[string]interface{}
@@ -433,13 +840,13 @@ func setUserData(recv *stackCreator, template *cfn.Template, resource map[string
 		resource["Properties"] = props
 	}
 
-	autoScalingLaunchConfiguration, err := template.GetResourceName(cfn.AutoScaling_LaunchConfiguration)
+	autoScalingLaunchConfiguration, err := launchResourceName(template)
 	if err != nil {
-		recv.log.Error("template.GetResourceName", "Error", err)
+		recv.log.Error("launchResourceName", "Error", err)
 		return
 	}
 
-	userData, err := cfn_template.UserData(autoScalingLaunchConfiguration)
+	userData, err := cfn_template.UserData(autoScalingLaunchConfiguration, recv.prebaked)
 	if err != nil {
 		recv.log.Error("cfn_template.UserData", "Error", err)
 		return
@@ -459,6 +866,17 @@ func setAutoScalingLaunchConfigurationMetadata(recv *stackCreator, template *cfn
 	}
 	elbCSV := strings.Join(elbNames, ",")
 
+	// porterd only needs to register instances into target groups that
+	// promotion registers instances into. Swapped listeners forward to the
+	// stack's target group which the ASG keeps up to date
+	targetGroupARNs := make([]string, 0)
+	for _, targetGroup := range recv.region.TargetGroups {
+		if targetGroup.ListenerARN == "" {
+			targetGroupARNs = append(targetGroupARNs, targetGroup.ARN)
+		}
+	}
+	targetGroupCSV := strings.Join(targetGroupARNs, ",")
+
 	var runOutputChan chan bytes.Buffer
 
 	hookSuccess := hook.ExecuteWithRunCapture(recv.log,
@@ -490,6 +908,19 @@ loop:
 		ec2BootstrapScript += "\n" + buf.String()
 	}
 
+	// porterd polls the readiness check through HAProxy. It's passed as
+	// base64 encoded JSON so headers survive the shell
+	var inetHealthCheck string
+	if healthCheck := recv.region.HealthCheck(); healthCheck != nil {
+
+		healthCheckBytes, err := json.Marshal(healthCheck)
+		if err != nil {
+			recv.log.Error("json.Marshal", "Error", err)
+			return
+		}
+		inetHealthCheck = base64.StdEncoding.EncodeToString(healthCheckBytes)
+	}
+
 	cfnInitContext := cfn_template.AWSCloudFormationInitCtx{
 		PorterVersion: constants.Version,
 		Environment:   recv.environment.Name,
@@ -501,7 +932,8 @@ loop:
 
 		EC2BootstrapScript: ec2BootstrapScript,
 
-		Elbs: strconv.Quote(elbCSV),
+		Elbs:         strconv.Quote(elbCSV),
+		TargetGroups: strconv.Quote(targetGroupCSV),
 
 		ServicePayloadBucket:     recv.region.S3Bucket,
 		ServicePayloadKey:        recv.servicePayloadKey,
@@ -512,8 +944,7 @@ loop:
 
 		RegistryDeployment: os.Getenv(constants.EnvDockerRegistry) != "",
 
-		InetHealthCheckMethod: strconv.Quote(recv.region.HealthCheckMethod()),
-		InetHealthCheckPath:   strconv.Quote(recv.region.HealthCheckPath()),
+		InetHealthCheck: strconv.Quote(inetHealthCheck),
 
 		PorterBinaryUrl: constants.BinaryUrl,
 
@@ -521,6 +952,8 @@ loop:
 		LogDebug: os.Getenv(constants.EnvLogDebug) != "",
 
 		ContainerUserUid: constants.ContainerUserUid,
+
+		Prebaked: recv.prebaked,
 	}
 
 	if os.Getenv(constants.EnvDockerInsecureRegistry) != "" {
@@ -531,9 +964,9 @@ loop:
 		cfnInitContext.ImageNames = append(cfnInitContext.ImageNames, container.Name)
 	}
 
-	autoScalingLaunchConfiguration, err := template.GetResourceName(cfn.AutoScaling_LaunchConfiguration)
+	autoScalingLaunchConfiguration, err := launchResourceName(template)
 	if err != nil {
-		recv.log.Error("template.GetResourceName", "Error", err)
+		recv.log.Error("launchResourceName", "Error", err)
 		return
 	}
 
@@ -597,7 +1030,11 @@ func setImageId(recv *stackCreator, template *cfn.Template, resource map[string]
 
 	if _, exists := props["ImageId"]; !exists {
 
-		props["ImageId"] = cfn_template.ImageIdInMap(constants.MappingRegionToAMI)
+		if recv.imageId != "" {
+			props["ImageId"] = recv.imageId
+		} else {
+			props["ImageId"] = cfn_template.ImageIdInMap(constants.MappingRegionToAMI)
+		}
 	}
 	return true
 }
@@ -619,6 +1056,12 @@ func setPoolSize(recv *stackCreator, template *cfn.Template, resource map[string
 		return
 	}
 
+	autoScaling, err := recv.environment.GetAutoScaling(recv.region.Name)
+	if err != nil {
+		recv.log.Error("GetAutoScaling", "Error", err)
+		return
+	}
+
 	var minSize int
 	var maxSize int
 
@@ -638,6 +1081,9 @@ func setPoolSize(recv *stackCreator, template *cfn.Template, resource map[string
 		}
 	} else {
 		minSize = int(instanceCount)
+		if autoScaling != nil {
+			minSize = int(autoScaling.MinSize)
+		}
 		props["MinSize"] = minSize
 	}
 
@@ -657,6 +1103,9 @@ func setPoolSize(recv *stackCreator, template *cfn.Template, resource map[string
 		}
 	} else {
 		maxSize = int(instanceCount)
+		if autoScaling != nil {
+			maxSize = int(autoScaling.MaxSize)
+		}
 		props["MaxSize"] = maxSize
 	}
 
@@ -667,6 +1116,13 @@ func setPoolSize(recv *stackCreator, template *cfn.Template, resource map[string
 		}
 	} else {
 
+		if recv.asgLaunchTemplate != template.ResourceExists(cfn.EC2_LaunchTemplate) {
+
+			recv.log.Error("You're attempting to switch between a launch configuration and a launch template while hot swapping")
+			recv.log.Error("Turn off hot swap to have the new launch template or launch configuration take effect")
+			return
+		}
+
 		if minSize <= recv.asgDesired && recv.asgDesired <= maxSize {
 
 			recv.log.Info("Overwriting template-defined ASG DesiredCapacity to preserve AutoScaling events that may have occurred in the currently promoted stack",
@@ -682,6 +1138,75 @@ func setPoolSize(recv *stackCreator, template *cfn.Template, resource map[string
 		}
 	}
 
+	if policy := recv.region.MixedInstancesPolicy; policy != nil && policy.OnDemandBaseCapacity > maxSize {
+		recv.log.Error("mixed_instances_policy on_demand_base_capacity is greater than the ASG MaxSize",
+			"OnDemandBaseCapacity", policy.OnDemandBaseCapacity, "MaxSize", maxSize)
+		return
+	}
+
+	success = true
+	return
+}
+
+// desiredCapacity is the number of instances a new ASG launches, regardless of
+// how they're split between instance types and Spot, and therefore the number
+// of signals the WaitCondition waits for.
+//
+// The size matched while hot swapping is ignored since CloudFormation doesn't
+// support updating a WaitCondition
+func (recv *stackCreator) desiredCapacity(template *cfn.Template) (desired int, success bool) {
+
+	// setPoolSize overwrites DesiredCapacity while hot swapping
+	if recv.asgDesired == 0 {
+
+		for _, resourceRaw := range template.GetResourcesByType(cfn.AutoScaling_AutoScalingGroup) {
+
+			resource, ok := resourceRaw.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			props, ok := resource["Properties"].(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			value, exists := props["DesiredCapacity"]
+			if !exists {
+				continue
+			}
+
+			var err error
+			switch v := value.(type) {
+			case float64:
+				desired = int(v)
+			case int:
+				desired = v
+			case uint:
+				desired = int(v)
+			case string:
+				desired, err = strconv.Atoi(v)
+				if err != nil {
+					recv.log.Error("strconv.Atoi", "Error", err)
+					return
+				}
+			default:
+				recv.log.Error("DesiredCapacity exists but is not a number or string", "DesiredCapacity", value)
+				return
+			}
+
+			success = true
+			return
+		}
+	}
+
+	instanceCount, err := recv.environment.GetInstanceCount(recv.region.Name)
+	if err != nil {
+		recv.log.Error("GetInstanceCount", "Error", err)
+		return
+	}
+
+	desired = int(instanceCount)
 	success = true
 	return
 }
@@ -697,14 +1222,14 @@ func setCount(recv *stackCreator, template *cfn.Template, resource map[string]in
 		resource["Properties"] = props
 	}
 
-	instanceCount, err := recv.environment.GetInstanceCount(recv.region.Name)
-	if err != nil {
-		recv.log.Error("GetInstanceCount", "Error", err)
-		return false
-	}
-
 	if _, exists := props["Count"]; !exists {
-		props["Count"] = instanceCount
+
+		desired, ok := recv.desiredCapacity(template)
+		if !ok {
+			return false
+		}
+
+		props["Count"] = desired
 	}
 	return true
 }
@@ -999,8 +1524,17 @@ func setHealthCheck(recv *stackCreator, template *cfn.Template, resource map[str
 	if _, exists := props["HealthCheck"]; !exists {
 
 		healthCheckTarget := "TCP:80"
+		healthCheck := recv.region.HealthCheck()
+		if healthCheck == nil {
+			healthCheck = &conf.HealthCheck{
+				Interval:           constants.HC_Interval,
+				Timeout:            constants.HC_Timeout,
+				HealthyThreshold:   constants.HC_HealthyThreshold,
+				UnhealthyThreshold: constants.HC_UnhealthyThreshold,
+			}
+		}
 
-		if recv.region.HealthCheckMethod() == "GET" {
+		if healthCheck.Type == conf.HealthCheck_HTTP {
 			if recv.environment.HAProxy.UsingSSL() {
 
 				healthCheckTarget = fmt.Sprintf("HTTPS:%d/", constants.HTTPS_Port)
@@ -1009,20 +1543,148 @@ func setHealthCheck(recv *stackCreator, template *cfn.Template, resource map[str
 				healthCheckTarget = fmt.Sprintf("HTTP:%d/", constants.HTTP_Port)
 			}
 
-			healthCheckTarget += strings.TrimPrefix(recv.region.HealthCheckPath(), "/")
+			healthCheckTarget += strings.TrimPrefix(healthCheck.Path, "/")
+		} else if healthCheck.Type == conf.HealthCheck_TCP && recv.environment.HAProxy.UsingSSL() {
+
+			healthCheckTarget = fmt.Sprintf("TCP:%d", constants.HTTPS_Port)
 		}
 
 		props["HealthCheck"] = map[string]interface{}{
-			"HealthyThreshold":   strconv.Itoa(constants.HC_HealthyThreshold),
-			"UnhealthyThreshold": strconv.Itoa(constants.HC_UnhealthyThreshold),
-			"Interval":           strconv.Itoa(constants.HC_Interval),
-			"Timeout":            strconv.Itoa(constants.HC_Timeout),
+			"HealthyThreshold":   strconv.Itoa(healthCheck.HealthyThreshold),
+			"UnhealthyThreshold": strconv.Itoa(healthCheck.UnhealthyThreshold),
+			"Interval":           strconv.Itoa(healthCheck.Interval),
+			"Timeout":            strconv.Itoa(healthCheck.Timeout),
 			"Target":             healthCheckTarget,
 		}
 	}
 	return true
 }
 
+func (recv *stackCreator) targetGroupPortAndProtocol() (port int, protocol string) {
+	if recv.environment.HAProxy.UsingSSL() {
+		port = constants.HTTPS_Port
+		protocol = "HTTPS"
+	} else {
+		port = constants.HTTP_Port
+		protocol = "HTTP"
+	}
+
+	if recv.region.TargetGroupType() == conf.TargetGroup_Network {
+		protocol = "TCP"
+	}
+	return
+}
+
+func setTargetGroupPortAndProtocol(recv *stackCreator, template *cfn.Template, resource map[string]interface{}) bool {
+	var (
+		ok    bool
+		props map[string]interface{}
+	)
+
+	if props, ok = resource["Properties"].(map[string]interface{}); !ok {
+		props = make(map[string]interface{})
+		resource["Properties"] = props
+	}
+
+	port, protocol := recv.targetGroupPortAndProtocol()
+
+	if _, exists := props["Port"]; !exists {
+		props["Port"] = port
+	}
+
+	if _, exists := props["Protocol"]; !exists {
+		props["Protocol"] = protocol
+	}
+	return true
+}
+
+func setTargetGroupHealthCheck(recv *stackCreator, template *cfn.Template, resource map[string]interface{}) bool {
+	var (
+		ok    bool
+		props map[string]interface{}
+	)
+
+	if props, ok = resource["Properties"].(map[string]interface{}); !ok {
+		props = make(map[string]interface{})
+		resource["Properties"] = props
+	}
+
+	if _, exists := props["HealthCheckProtocol"]; exists {
+		return true
+	}
+
+	healthCheck := recv.region.HealthCheck()
+	if healthCheck == nil {
+		healthCheck = &conf.HealthCheck{
+			Type:               conf.HealthCheck_HTTP,
+			Interval:           constants.HC_Interval,
+			Timeout:            constants.HC_Timeout,
+			HealthyThreshold:   constants.HC_HealthyThreshold,
+			UnhealthyThreshold: constants.HC_UnhealthyThreshold,
+		}
+	}
+
+	if healthCheck.Type == conf.HealthCheck_TCP {
+		props["HealthCheckProtocol"] = "TCP"
+	} else {
+		if recv.environment.HAProxy.UsingSSL() {
+			props["HealthCheckProtocol"] = "HTTPS"
+		} else {
+			props["HealthCheckProtocol"] = "HTTP"
+		}
+		props["HealthCheckPath"] = recv.region.HealthCheckPath()
+	}
+
+	if recv.region.TargetGroupType() == conf.TargetGroup_Network {
+
+		// NLB health checks have a fixed timeout, an interval of 10 or 30
+		// seconds, and equal thresholds
+		if healthCheck.Interval > 10 {
+			props["HealthCheckIntervalSeconds"] = 30
+		} else {
+			props["HealthCheckIntervalSeconds"] = 10
+		}
+		props["HealthyThresholdCount"] = healthCheck.HealthyThreshold
+		props["UnhealthyThresholdCount"] = healthCheck.HealthyThreshold
+	} else {
+
+		props["HealthCheckIntervalSeconds"] = healthCheck.Interval
+		props["HealthCheckTimeoutSeconds"] = healthCheck.Timeout
+		props["HealthyThresholdCount"] = healthCheck.HealthyThreshold
+		props["UnhealthyThresholdCount"] = healthCheck.UnhealthyThreshold
+
+		if healthCheck.ExpectedStatus != "" {
+			props["Matcher"] = map[string]interface{}{
+				"HttpCode": healthCheck.ExpectedStatus,
+			}
+		}
+	}
+	return true
+}
+
+// The target group equivalent of setConnectionDrainingPolicy
+func setDeregistrationDelay(recv *stackCreator, template *cfn.Template, resource map[string]interface{}) bool {
+	var (
+		ok    bool
+		props map[string]interface{}
+	)
+
+	if props, ok = resource["Properties"].(map[string]interface{}); !ok {
+		props = make(map[string]interface{})
+		resource["Properties"] = props
+	}
+
+	if _, exists := props["TargetGroupAttributes"]; !exists {
+		props["TargetGroupAttributes"] = []interface{}{
+			map[string]interface{}{
+				"Key":   "deregistration_delay.timeout_seconds",
+				"Value": "300",
+			},
+		}
+	}
+	return true
+}
+
 func setHandle(recv *stackCreator, template *cfn.Template, resource map[string]interface{}) (success bool) {
 	var (
 		ok    bool
@@ -1169,6 +1831,7 @@ func addInlinePolicies(recv *stackCreator, template *cfn.Template, resource map[
 						"ec2:DescribeTags",
 						"elasticloadbalancing:DescribeTags",
 						"elasticloadbalancing:RegisterInstancesWithLoadBalancer",
+						"elasticloadbalancing:RegisterTargets",
 
 						// decrypt .env-file
 						"kms:Decrypt",
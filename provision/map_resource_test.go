@@ -4,7 +4,10 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
-	_ "github.com/adobe-platform/porter/provision"
+	"github.com/adobe-platform/porter/cfn"
+	"github.com/adobe-platform/porter/conf"
+	"github.com/adobe-platform/porter/constants"
+	"github.com/adobe-platform/porter/provision"
 )
 
 var _ = Describe("Map resources", func() {
@@ -14,3 +17,275 @@ var _ = Describe("Map resources", func() {
 	})
 
 })
+
+var _ = Describe("Launch templates", func() {
+
+	var (
+		environment conf.Environment
+		region      conf.Region
+		template    *cfn.Template
+		asg         map[string]interface{}
+	)
+
+	mixedInstancesPolicy := func() *conf.MixedInstancesPolicy {
+		return &conf.MixedInstancesPolicy{
+			InstanceTypes:          []string{"m5.large", "m4.large"},
+			OnDemandBaseCapacity:   1,
+			SpotPercentage:         75,
+			SpotAllocationStrategy: conf.SpotAllocation_LowestPrice,
+			SpotInstancePools:      2,
+			SpotMaxPrice:           "0.05",
+		}
+	}
+
+	launchTemplateSpecification := map[string]interface{}{
+		"LaunchTemplateId": map[string]interface{}{
+			"Ref": "AutoScalingLaunchTemplate",
+		},
+		"Version": map[string]interface{}{
+			"Fn::GetAtt": []string{"AutoScalingLaunchTemplate", "LatestVersionNumber"},
+		},
+	}
+
+	run := func(fn provision.MapResource, resource map[string]interface{}) bool {
+		environment.Regions = []*conf.Region{&region}
+		return provision.RunMapResource(fn, conf.Config{}, environment, region, template, resource)
+	}
+
+	ensureLaunchConfig := func() {
+		environment.Regions = []*conf.Region{&region}
+		Expect(provision.EnsureAutoScalingLaunchConfig(environment, region, template)).To(BeTrue())
+	}
+
+	BeforeEach(func() {
+		environment = conf.Environment{Name: "prod", InstanceType: "m4.large", InstanceCount: 2}
+		region = conf.Region{Name: "us-west-2"}
+
+		template = cfn.NewTemplate()
+		asg = map[string]interface{}{
+			"Type": cfn.AutoScaling_AutoScalingGroup,
+		}
+		template.SetResource("AutoScalingGroup", asg)
+	})
+
+	Context("ensureAutoScalingLaunchConfig", func() {
+
+		It("adds a launch configuration by default", func() {
+			ensureLaunchConfig()
+
+			Expect(template.ResourceExists(cfn.AutoScaling_LaunchConfiguration)).To(BeTrue())
+			Expect(template.ResourceExists(cfn.EC2_LaunchTemplate)).To(BeFalse())
+		})
+
+		It("adds a launch template with launch_template", func() {
+			environment.LaunchTemplate = true
+			ensureLaunchConfig()
+
+			Expect(template.ResourceExists(cfn.EC2_LaunchTemplate)).To(BeTrue())
+			Expect(template.ResourceExists(cfn.AutoScaling_LaunchConfiguration)).To(BeFalse())
+		})
+
+		It("adds a launch template with a mixed_instances_policy", func() {
+			region.MixedInstancesPolicy = mixedInstancesPolicy()
+			ensureLaunchConfig()
+
+			Expect(template.ResourceExists(cfn.EC2_LaunchTemplate)).To(BeTrue())
+			Expect(template.ResourceExists(cfn.AutoScaling_LaunchConfiguration)).To(BeFalse())
+		})
+	})
+
+	Context("launchTemplateData", func() {
+
+		It("maps launch configuration properties into LaunchTemplateData", func() {
+			region.VpcId = "vpc-1234"
+			region.KeyPairName = "deploy"
+
+			template.SetResource("InstanceSecurityGroup", map[string]interface{}{
+				"Type":     cfn.EC2_SecurityGroup,
+				"Metadata": map[string]interface{}{constants.MetadataAsLc: true},
+			})
+			template.SetResource("InstanceProfile", map[string]interface{}{
+				"Type": cfn.IAM_InstanceProfile,
+			})
+
+			metadata := map[string]interface{}{"AWS::CloudFormation::Init": map[string]interface{}{}}
+			launchTemplate := map[string]interface{}{
+				"Type":     cfn.EC2_LaunchTemplate,
+				"Metadata": metadata,
+			}
+			template.SetResource("AutoScalingLaunchTemplate", launchTemplate)
+
+			Expect(run(provision.LaunchTemplateData(
+				provision.AddASGSecurityGroups,
+				provision.SetInstanceType,
+				provision.SetKeyName,
+				provision.SetIamInstanceProfile,
+				provision.SetImageId,
+			), launchTemplate)).To(BeTrue())
+
+			Expect(launchTemplate["Metadata"]).To(Equal(metadata))
+
+			props := launchTemplate["Properties"].(map[string]interface{})
+			Expect(props).To(HaveLen(1))
+
+			data := props["LaunchTemplateData"].(map[string]interface{})
+			Expect(data).To(HaveKey("ImageId"))
+			Expect(data).ToNot(HaveKey("SecurityGroups"))
+			Expect(data["SecurityGroupIds"]).To(Equal([]interface{}{
+				map[string]interface{}{"Ref": "InstanceSecurityGroup"},
+			}))
+			Expect(data["IamInstanceProfile"]).To(Equal(map[string]interface{}{
+				"Name": map[string]interface{}{"Ref": "InstanceProfile"},
+			}))
+			Expect(data["InstanceType"]).To(Equal("m4.large"))
+			Expect(data["KeyName"]).To(Equal("deploy"))
+		})
+
+		It("keeps SecurityGroups outside of a VPC", func() {
+			launchTemplate := map[string]interface{}{
+				"Type": cfn.EC2_LaunchTemplate,
+			}
+			template.SetResource("AutoScalingLaunchTemplate", launchTemplate)
+
+			Expect(run(provision.LaunchTemplateData(provision.AddASGSecurityGroups), launchTemplate)).To(BeTrue())
+
+			data := launchTemplate["Properties"].(map[string]interface{})["LaunchTemplateData"].(map[string]interface{})
+			Expect(data).To(HaveKey("SecurityGroups"))
+			Expect(data).ToNot(HaveKey("SecurityGroupIds"))
+		})
+	})
+
+	Context("setLaunchTemplate", func() {
+
+		BeforeEach(func() {
+			template.SetResource("AutoScalingLaunchTemplate", map[string]interface{}{
+				"Type": cfn.EC2_LaunchTemplate,
+			})
+		})
+
+		It("launches from the latest version of the launch template", func() {
+			Expect(run(provision.SetLaunchTemplate, asg)).To(BeTrue())
+			Expect(run(provision.SetLaunchConfigurationName, asg)).To(BeTrue())
+
+			Expect(asg["Properties"]).To(Equal(map[string]interface{}{
+				"LaunchTemplate": launchTemplateSpecification,
+			}))
+		})
+
+		It("wraps the launch template in a MixedInstancesPolicy", func() {
+			region.MixedInstancesPolicy = mixedInstancesPolicy()
+
+			Expect(run(provision.SetLaunchTemplate, asg)).To(BeTrue())
+			Expect(run(provision.SetLaunchConfigurationName, asg)).To(BeTrue())
+
+			Expect(asg["Properties"]).To(Equal(map[string]interface{}{
+				"MixedInstancesPolicy": map[string]interface{}{
+					"LaunchTemplate": map[string]interface{}{
+						"LaunchTemplateSpecification": launchTemplateSpecification,
+						"Overrides": []interface{}{
+							map[string]interface{}{"InstanceType": "m5.large"},
+							map[string]interface{}{"InstanceType": "m4.large"},
+						},
+					},
+					"InstancesDistribution": map[string]interface{}{
+						"OnDemandBaseCapacity":                1,
+						"OnDemandPercentageAboveBaseCapacity": 25,
+						"SpotAllocationStrategy":              conf.SpotAllocation_LowestPrice,
+						"SpotInstancePools":                   2,
+						"SpotMaxPrice":                        "0.05",
+					},
+				},
+			}))
+		})
+
+		It("keeps a MixedInstancesPolicy from the stack definition", func() {
+			region.MixedInstancesPolicy = mixedInstancesPolicy()
+			asg["Properties"] = map[string]interface{}{
+				"MixedInstancesPolicy": "defined",
+			}
+
+			Expect(run(provision.SetLaunchTemplate, asg)).To(BeTrue())
+			Expect(asg["Properties"]).To(Equal(map[string]interface{}{
+				"MixedInstancesPolicy": "defined",
+			}))
+		})
+	})
+
+	Context("setLaunchConfigurationName", func() {
+
+		BeforeEach(func() {
+			template.SetResource("AutoScalingLaunchConfiguration", map[string]interface{}{
+				"Type": cfn.AutoScaling_LaunchConfiguration,
+			})
+		})
+
+		It("launches from the launch configuration", func() {
+			Expect(run(provision.SetLaunchConfigurationName, asg)).To(BeTrue())
+			Expect(run(provision.SetLaunchTemplate, asg)).To(BeTrue())
+
+			Expect(asg["Properties"]).To(Equal(map[string]interface{}{
+				"LaunchConfigurationName": map[string]interface{}{"Ref": "AutoScalingLaunchConfiguration"},
+			}))
+		})
+
+		It("rejects a mixed_instances_policy with a launch configuration", func() {
+			region.MixedInstancesPolicy = mixedInstancesPolicy()
+
+			Expect(run(provision.SetLaunchConfigurationName, asg)).To(BeFalse())
+		})
+	})
+
+	Context("setPoolSize", func() {
+
+		setPoolSize := func(asgDesired int, asgLaunchTemplate bool) bool {
+			environment.Regions = []*conf.Region{&region}
+			return provision.RunSetPoolSize(environment, region, template, asg, asgDesired, asgLaunchTemplate)
+		}
+
+		BeforeEach(func() {
+			template.SetResource("AutoScalingLaunchTemplate", map[string]interface{}{
+				"Type": cfn.EC2_LaunchTemplate,
+			})
+			region.MixedInstancesPolicy = mixedInstancesPolicy()
+		})
+
+		It("sizes a MixedInstancesPolicy ASG from auto_scaling", func() {
+			region.AutoScaling = &conf.AutoScaling{MinSize: 1, MaxSize: 6}
+
+			Expect(setPoolSize(0, false)).To(BeTrue())
+			Expect(asg["Properties"]).To(Equal(map[string]interface{}{
+				"MinSize":         1,
+				"MaxSize":         6,
+				"DesiredCapacity": uint(2),
+			}))
+		})
+
+		It("rejects an on_demand_base_capacity greater than MaxSize", func() {
+			region.MixedInstancesPolicy.OnDemandBaseCapacity = 3
+
+			Expect(setPoolSize(0, false)).To(BeFalse())
+		})
+
+		It("keeps the promoted ASG's size while hot swapping", func() {
+			region.AutoScaling = &conf.AutoScaling{MinSize: 1, MaxSize: 6}
+
+			Expect(setPoolSize(4, true)).To(BeTrue())
+			Expect(asg["Properties"].(map[string]interface{})["DesiredCapacity"]).To(Equal(4))
+		})
+
+		It("rejects a hot swap from a launch configuration to a launch template", func() {
+			Expect(setPoolSize(2, false)).To(BeFalse())
+		})
+
+		It("rejects a hot swap from a launch template to a launch configuration", func() {
+			template = cfn.NewTemplate()
+			template.SetResource("AutoScalingGroup", asg)
+			template.SetResource("AutoScalingLaunchConfiguration", map[string]interface{}{
+				"Type": cfn.AutoScaling_LaunchConfiguration,
+			})
+			region.MixedInstancesPolicy = nil
+
+			Expect(setPoolSize(2, true)).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,254 @@
+/*
+ * (c) 2016-2018 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+package provision
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"sort"
+	"time"
+
+	"github.com/adobe-platform/porter/aws/cloudformation"
+	"github.com/adobe-platform/porter/aws/elb"
+	"github.com/adobe-platform/porter/aws/elbv2"
+	awsutil "github.com/adobe-platform/porter/aws/util"
+	"github.com/adobe-platform/porter/aws_session"
+	"github.com/adobe-platform/porter/cfn"
+	"github.com/adobe-platform/porter/conf"
+	"github.com/adobe-platform/porter/constants"
+	"github.com/adobe-platform/porter/util"
+	"github.com/aws/aws-sdk-go/aws"
+	cfnlib "github.com/aws/aws-sdk-go/service/cloudformation"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+type (
+	// RegionPlan is what would change in a region if the environment were
+	// provisioned
+	RegionPlan struct {
+		Region string `json:"Region"`
+
+		// The currently promoted stack the diff is against. Empty if nothing
+		// has been deployed
+		StackId string `json:"StackId"`
+
+		Diff cfn.TemplateDiff `json:"Diff"`
+	}
+)
+
+// Plan creates the template for every region in an environment exactly like
+// CreateStack would but makes no changes. Nothing is uploaded to S3 and no
+// CloudFormation stacks are created or updated.
+//
+// Each template is diffed against the template of the currently promoted stack
+func Plan(log log15.Logger, config *conf.Config, environment *conf.Environment) (plans []RegionPlan, success bool) {
+
+	payloadBytes, err := ioutil.ReadFile(constants.PayloadPath)
+	if err != nil {
+		log.Error("ReadFile payload", "Error", err)
+		return
+	}
+
+	type planResult struct {
+		plan    RegionPlan
+		success bool
+	}
+
+	// buffered so regions already planning don't block if a later region
+	// fails before its goroutine starts
+	resultChan := make(chan planResult, len(environment.Regions))
+	planning := 0
+	success = true
+
+	for _, region := range environment.Regions {
+
+		roleARN, err := environment.GetRoleARN(region.Name)
+		if err != nil {
+			log.Error("GetRoleARN", "Region", region.Name, "Error", err)
+			success = false
+			continue
+		}
+		planning++
+
+		roleSession := aws_session.STS(region.Name, roleARN, 1*time.Hour)
+
+		recv := &stackCreator{
+			log: log.New("Region", region.Name),
+
+			config:      *config,
+			environment: *environment,
+			region:      *region,
+
+			roleSession: roleSession,
+
+			templateTransforms: make(map[string][]MapResource),
+		}
+
+		go func(recv *stackCreator) {
+
+			plan, ok := recv.plan(payloadBytes)
+			resultChan <- planResult{
+				plan:    plan,
+				success: ok,
+			}
+
+		}(recv)
+	}
+
+	plans = make([]RegionPlan, 0)
+
+	for i := 0; i < planning; i++ {
+		result := <-resultChan
+		success = success && result.success
+		plans = append(plans, result.plan)
+	}
+
+	sort.Sort(regionPlansByName(plans))
+
+	return
+}
+
+func (recv *stackCreator) plan(payloadBytes []byte) (regionPlan RegionPlan, success bool) {
+
+	regionPlan.Region = recv.region.Name
+
+	// the payload key is part of the template so set it without uploading
+	recv.setServicePayloadKey(payloadBytes)
+
+	if !recv.resolveAMI() {
+		return
+	}
+
+	templateBytes, creationSuccess := recv.createTemplate()
+	if !creationSuccess {
+		return
+	}
+
+	// round-trip the new template so its values compare equally to the
+	// deployed template's values
+	newTemplate := cfn.NewTemplate()
+	err := json.Unmarshal(templateBytes, newTemplate)
+	if err != nil {
+		recv.log.Error("json.Unmarshal", "Error", err)
+		return
+	}
+
+	cfnClient := cloudformation.New(recv.roleSession)
+
+	stackId, ok := recv.getPromotedStackId(cfnClient)
+	if !ok {
+		return
+	}
+
+	oldTemplate := cfn.NewTemplate()
+
+	if stackId == "" {
+		recv.log.Warn("No promoted stack found. Every resource is new")
+	} else {
+		log := recv.log.New("StackId", stackId)
+
+		var templateBody string
+
+		retryMsg := func(i int) { log.Warn("cloudformation:GetTemplate retrying", "Count", i) }
+		if !util.SuccessRetryer(3, retryMsg, func() bool {
+
+			log.Info("cloudformation:GetTemplate")
+			templateBody, err = cloudformation.GetTemplate(cfnClient, stackId)
+			if err != nil {
+				log.Error("cloudformation:GetTemplate", "Error", err)
+				return false
+			}
+			return true
+		}) {
+			return
+		}
+
+		err = json.Unmarshal([]byte(templateBody), oldTemplate)
+		if err != nil {
+			log.Error("json.Unmarshal", "Error", err)
+			return
+		}
+	}
+
+	regionPlan.StackId = stackId
+	regionPlan.Diff = cfn.DiffResources(oldTemplate.Resources, newTemplate.Resources)
+
+	success = true
+	return
+}
+
+// getPromotedStackId follows the ELB or target group tag of the currently
+// promoted stack. In regions without either the newest stack is used
+func (recv *stackCreator) getPromotedStackId(cfnClient *cfnlib.CloudFormation) (stackId string, success bool) {
+	log := recv.log
+
+	var stackIdFilter *string
+
+	if recv.region.HasELB() {
+
+		elbName := recv.region.ELBs[0].Name
+		log = log.New("LoadBalancerName", elbName)
+
+		elbTags, err := elb.GetTags(elb.New(recv.roleSession), elbName)
+		if err != nil {
+			log.Error("elb.GetTags", "Error", err)
+			return
+		}
+
+		promotedStackId := elbTags[constants.PorterStackIdTag]
+		if promotedStackId == "" {
+			log.Warn("Did not find ELB tag of currently promoted stack")
+			success = true
+			return
+		}
+
+		stackIdFilter = aws.String(promotedStackId)
+	} else if recv.region.HasTargetGroup() {
+
+		targetGroup := recv.region.TargetGroups[0]
+		log = log.New("TargetGroupARN", targetGroup.ARN, "ListenerARN", targetGroup.ListenerARN)
+
+		tags, err := elbv2.GetLiveTags(elbv2.New(recv.roleSession), targetGroup.ARN, targetGroup.ListenerARN)
+		if err != nil {
+			log.Error("elbv2.GetLiveTags", "Error", err)
+			return
+		}
+
+		promotedStackId := tags[constants.PorterStackIdTag]
+		if promotedStackId == "" {
+			log.Warn("Did not find target group tag of currently promoted stack")
+			success = true
+			return
+		}
+
+		stackIdFilter = aws.String(promotedStackId)
+	}
+
+	stackList, getStacksSuccess := awsutil.GetStacks(log, &recv.config,
+		&recv.environment, cfnClient, stackIdFilter, cfn.CheckHotswapStatus)
+	if !getStacksSuccess {
+		return
+	}
+
+	if len(stackList) > 0 {
+		stackId = *stackList[0].StackId
+	}
+
+	success = true
+	return
+}
+
+type regionPlansByName []RegionPlan
+
+func (recv regionPlansByName) Len() int           { return len(recv) }
+func (recv regionPlansByName) Swap(i, j int)      { recv[i], recv[j] = recv[j], recv[i] }
+func (recv regionPlansByName) Less(i, j int) bool { return recv[i].Region < recv[j].Region }
@@ -22,6 +22,7 @@ import (
 	"runtime"
 	"strings"
 
+	"github.com/adobe-platform/porter/aws/elbv2"
 	awsutil "github.com/adobe-platform/porter/aws/util"
 	"github.com/adobe-platform/porter/cfn"
 	"github.com/adobe-platform/porter/conf"
@@ -53,6 +54,9 @@ type (
 		secretsKey      string
 		secretsLocation string
 
+		// values for the parameters in .porter/config
+		parameterValues map[string]string
+
 		roleSession *session.Session
 
 		// Stack creation is mostly the same between CreateStack and UpdateStack
@@ -61,9 +65,28 @@ type (
 
 		updateStack bool
 
+		// set by createUpdateStackForRegion. The caller copies them to the
+		// region's state so state can be persisted while other regions are
+		// in flight
+		stackId string
+		imageId string
+
+		// set by createUpdateStackForRegion and passed to template transforms
+		regionState *provision_state.Region
+
+		// set by resolveAMI when the AMI was made by porter bootstrap ami
+		prebaked bool
+
+		// why createUpdateStackForRegion failed
+		failure string
+
 		templateTransforms map[string][]MapResource
 
 		asgDesired int
+
+		// whether the promoted stack's ASG launches from a launch template.
+		// only meaningful if asgDesired != 0
+		asgLaunchTemplate bool
 	}
 )
 
@@ -74,16 +97,20 @@ const (
 
 func (recv *stackCreator) createUpdateStackForRegion(regionState *provision_state.Region) bool {
 
+	recv.regionState = regionState
+
 	if recv.updateStack {
 
 		asgId := new(string)
 
 		if !recv.getAsgId(asgId) {
+			recv.failure = "failed to get the promoted stack's ASG"
 			return false
 		}
 
 		if *asgId != "" {
 			if !recv.getAsgSize(*asgId, regionState) {
+				recv.failure = "failed to get the promoted stack's ASG size"
 				return false
 			}
 		}
@@ -92,21 +119,35 @@ func (recv *stackCreator) createUpdateStackForRegion(regionState *provision_stat
 	checksum, success := recv.uploadServicePayload()
 	if !success {
 		// uploadServicePayload logs errors. all we care about is success
+		recv.failure = "failed to upload the service payload"
 		return false
 	}
 
 	if !recv.uploadSecrets(checksum) {
 		// uploadSecrets logs errors. all we care about is success
+		recv.failure = "failed to upload secrets"
+		return false
+	}
+
+	if !recv.getParameterValues() {
+		// getParameterValues logs errors. all we care about is success
+		recv.failure = "failed to get parameter values"
+		return false
+	}
+
+	if !recv.resolveAMI() {
+		// resolveAMI logs errors. all we care about is success
+		recv.failure = "failed to resolve the AMI"
 		return false
 	}
 
 	stackId, success := recv.createStack()
 	if !success {
-		// createStack logs errors. all we care about is success
+		// createStack logs errors and sets recv.failure
 		return false
 	}
 
-	regionState.StackId = stackId
+	recv.stackId = stackId
 
 	return true
 }
@@ -114,8 +155,8 @@ func (recv *stackCreator) createUpdateStackForRegion(regionState *provision_stat
 func (recv *stackCreator) getAsgId(asgId *string) (success bool) {
 	log := recv.log
 
-	if len(recv.region.ELBs) > 1 {
-		log.Info("ASG size matching only works on regions where a single ELB is defined")
+	if len(recv.region.ELBs) > 1 || len(recv.region.TargetGroups) > 1 {
+		log.Info("ASG size matching only works on regions where a single ELB or target group is defined")
 		success = true
 		return
 	}
@@ -183,6 +224,43 @@ func (recv *stackCreator) getAsgId(asgId *string) (success bool) {
 			success = true
 			return
 		}
+	} else if len(recv.region.TargetGroups) == 1 {
+
+		targetGroup := recv.region.TargetGroups[0]
+
+		log = log.New("TargetGroupARN", targetGroup.ARN, "ListenerARN", targetGroup.ListenerARN)
+		log.Info("Getting target group tags")
+
+		elbv2Client := elbv2.New(recv.roleSession)
+
+		var tags map[string]string
+
+		retryMsg := func(i int) { log.Warn("elbv2.GetLiveTags retrying", "Count", i) }
+		if !util.SuccessRetryer(5, retryMsg, func() bool {
+
+			log.Info("elbv2.GetLiveTags")
+			tags, err = elbv2.GetLiveTags(elbv2Client, targetGroup.ARN, targetGroup.ListenerARN)
+			if err != nil {
+				log.Error("elbv2.GetLiveTags", "Error", err)
+				return false
+			}
+
+			return true
+		}) {
+			log.Crit("Failed to elbv2.GetLiveTags")
+			return
+		}
+
+		if tags[constants.PorterStackIdTag] == "" {
+			log.Warn("Did not find target group tag of currently promoted stack")
+			log.Warn("If this is the first deployment into this target group then this is normal")
+			log.Warn("ASG size matching will not occur meaning whatever is in the CloudFormation template will be used")
+			success = true
+			return
+		}
+
+		log.Info("Found target group tag of currently promoted stack. Getting ASG physical id")
+		stackId = aws.String(tags[constants.PorterStackIdTag])
 	}
 
 	stacksList, getStacksSuccess := awsutil.GetStacks(log, &recv.config,
@@ -324,6 +402,11 @@ func (recv *stackCreator) getAsgSize(asgName string, regionState *provision_stat
 
 		recv.asgDesired = asgDesired
 
+		// This version of the SDK predates launch templates on ASGs but an
+		// ASG without a launch configuration must have one
+		recv.asgLaunchTemplate = asg.LaunchConfigurationName == nil ||
+			*asg.LaunchConfigurationName == ""
+
 		return true
 	}) {
 		log.Crit("Failed to autoscaling:DescribeAutoScalingGroups")
@@ -334,6 +417,12 @@ func (recv *stackCreator) getAsgSize(asgName string, regionState *provision_stat
 	return
 }
 
+// useLaunchTemplate is true if porter should generate a AWS::EC2::LaunchTemplate
+// instead of a AWS::AutoScaling::LaunchConfiguration
+func (recv *stackCreator) useLaunchTemplate() bool {
+	return recv.environment.LaunchTemplate || recv.region.MixedInstancesPolicy != nil
+}
+
 func (recv *stackCreator) uploadServicePayload() (checksum string, success bool) {
 
 	payloadBytes, err := ioutil.ReadFile(constants.PayloadPath)
@@ -344,11 +433,7 @@ func (recv *stackCreator) uploadServicePayload() (checksum string, success bool)
 
 	s3Client := s3.New(recv.roleSession)
 
-	// TODO don't use a digest that requires everything to be in memory
-	checksumArray := sha256.Sum256(payloadBytes)
-	checksum = hex.EncodeToString(checksumArray[:])
-	recv.servicePayloadChecksum = checksum
-	recv.servicePayloadKey = fmt.Sprintf("%s/%s.tar", recv.s3KeyRoot(s3KeyOptDeployment), checksum)
+	checksum = recv.setServicePayloadKey(payloadBytes)
 
 	headObjectInput := &s3.HeadObjectInput{
 		Bucket: aws.String(recv.region.S3Bucket),
@@ -402,12 +487,23 @@ func (recv *stackCreator) uploadServicePayload() (checksum string, success bool)
 	return
 }
 
+func (recv *stackCreator) setServicePayloadKey(payloadBytes []byte) (checksum string) {
+
+	// TODO don't use a digest that requires everything to be in memory
+	checksumArray := sha256.Sum256(payloadBytes)
+	checksum = hex.EncodeToString(checksumArray[:])
+	recv.servicePayloadChecksum = checksum
+	recv.servicePayloadKey = fmt.Sprintf("%s/%s.tar", recv.s3KeyRoot(s3KeyOptDeployment), checksum)
+	return
+}
+
 func (recv *stackCreator) createStack() (stackId string, success bool) {
 
 	client := cloudformation.New(recv.roleSession)
 
 	templateBytes, creationSuccess := recv.createTemplate()
 	if !creationSuccess {
+		recv.failure = "failed to create the CloudFormation template"
 		return
 	}
 
@@ -415,6 +511,7 @@ func (recv *stackCreator) createStack() (stackId string, success bool) {
 	if err != nil {
 		errorMessage := fmt.Sprintf("Unable to write %s file", constants.CloudFormationTemplatePath)
 		recv.log.Error(errorMessage, "Error", err)
+		recv.failure = "failed to write the CloudFormation template"
 		return
 	}
 
@@ -446,6 +543,7 @@ func (recv *stackCreator) createStack() (stackId string, success bool) {
 	_, err = s3Manager.Upload(uploadInput)
 	if err != nil {
 		recv.log.Error("Upload failure", "Error", err)
+		recv.failure = "failed to upload the CloudFormation template"
 		return
 	}
 
@@ -458,9 +556,17 @@ func (recv *stackCreator) createStack() (stackId string, success bool) {
 		SecretsKey:  recv.secretsKey,
 		SecretsLoc:  recv.secretsLocation,
 		TemplateUrl: templateUrl,
+		Parameters:  recv.parameterValues,
 	}
 
 	stackId, success = recv.cfnAPI(client, params)
+	if !success {
+		if recv.updateStack {
+			recv.failure = "failed to update the stack"
+		} else {
+			recv.failure = "failed to create the stack"
+		}
+	}
 	return
 }
 
@@ -526,6 +632,11 @@ func (recv *stackCreator) mutateTemplate(template *cfn.Template) (success bool)
 		return
 	}
 
+	success = recv.runTemplateTransforms(template)
+	if !success {
+		return
+	}
+
 	success = true
 	return
 }
@@ -0,0 +1,166 @@
+/*
+ * (c) 2016-2018 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+package provision
+
+import (
+	"encoding/json"
+
+	"github.com/adobe-platform/porter/aws/cloudformation"
+	"github.com/adobe-platform/porter/aws/ec2"
+	"github.com/adobe-platform/porter/aws/ssm"
+	"github.com/adobe-platform/porter/cfn"
+	"github.com/adobe-platform/porter/util"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// resolveAMI resolves the configured AMI to an id. If no AMI is configured
+// imageId stays empty and setImageId falls back to the region to AMI mapping
+//
+// Hot swap keeps the deployed stack's image for an ssm_parameter or filter.
+// Resolving it again would pick up newer images, and a new ImageId replaces
+// the launch configuration which hot swap rejects. Only a full provision
+// moves to a newer image.
+func (recv *stackCreator) resolveAMI() (success bool) {
+
+	ami, err := recv.environment.GetAMI(recv.region.Name)
+	if err != nil {
+		recv.log.Error("GetAMI", "Error", err)
+		return
+	}
+
+	if ami == nil {
+		success = true
+		return
+	}
+
+	recv.prebaked = ami.Prebaked
+
+	log := recv.log.New("AMI", ami.String(), "Prebaked", ami.Prebaked)
+
+	if recv.updateStack && ami.ID == "" {
+
+		imageId, found := recv.deployedImageId()
+		if !found {
+			return
+		}
+
+		if imageId != "" {
+			recv.imageId = imageId
+			log.Info("Keeping the deployed stack's AMI for hot swap", "ImageId", recv.imageId)
+			success = true
+			return
+		}
+
+		log.Warn("The deployed stack's template has no ImageId. Resolving the AMI")
+	}
+
+	switch {
+	case ami.ID != "":
+		recv.imageId = ami.ID
+
+	case ami.SSMParameter != "":
+		log.Info("ssm:GetParameter")
+		recv.imageId, err = ssm.GetParameter(ssm.New(recv.roleSession), ami.SSMParameter)
+		if err != nil {
+			log.Error("ssm:GetParameter", "Error", err)
+			return
+		}
+
+	case ami.Filter != nil:
+		log.Info("ec2:DescribeImages")
+		image, err := ec2.NewestImage(ec2.New(recv.roleSession), ami.Filter.Owner, ami.Filter.Name)
+		if err != nil {
+			log.Error("ec2:DescribeImages", "Error", err)
+			return
+		}
+		recv.imageId = aws.StringValue(image.ImageId)
+		log = log.New("ImageName", aws.StringValue(image.Name))
+	}
+
+	log.Info("Resolved AMI", "ImageId", recv.imageId)
+
+	success = true
+	return
+}
+
+// deployedImageId reads the ImageId from the template of the stack being hot
+// swapped. It's empty if the template doesn't have one
+func (recv *stackCreator) deployedImageId() (imageId string, success bool) {
+
+	log := recv.log.New("StackId", recv.regionState.StackId)
+	cfnClient := cloudformation.New(recv.roleSession)
+
+	var templateBody string
+	var err error
+
+	retryMsg := func(i int) { log.Warn("cloudformation:GetTemplate retrying", "Count", i) }
+	if !util.SuccessRetryer(3, retryMsg, func() bool {
+
+		log.Info("cloudformation:GetTemplate")
+		templateBody, err = cloudformation.GetTemplate(cfnClient, recv.regionState.StackId)
+		if err != nil {
+			log.Error("cloudformation:GetTemplate", "Error", err)
+			return false
+		}
+		return true
+	}) {
+		return
+	}
+
+	template := cfn.NewTemplate()
+	err = json.Unmarshal([]byte(templateBody), template)
+	if err != nil {
+		log.Error("json.Unmarshal", "Error", err)
+		return
+	}
+
+	imageId = templateImageId(template)
+	success = true
+	return
+}
+
+// templateImageId is the literal ImageId of a template's launch configuration
+// or launch template. It's empty if there's none or it comes from the region
+// to AMI mapping
+func templateImageId(template *cfn.Template) string {
+
+	for _, resourceRaw := range template.Resources {
+
+		resource, ok := resourceRaw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		props, ok := resource["Properties"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		switch resource["Type"] {
+		case cfn.AutoScaling_LaunchConfiguration:
+
+			if imageId, ok := props["ImageId"].(string); ok {
+				return imageId
+			}
+
+		case cfn.EC2_LaunchTemplate:
+
+			if data, ok := props["LaunchTemplateData"].(map[string]interface{}); ok {
+				if imageId, ok := data["ImageId"].(string); ok {
+					return imageId
+				}
+			}
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,47 @@
+package provision_test
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/adobe-platform/porter/cfn"
+	"github.com/adobe-platform/porter/provision"
+)
+
+var _ = Describe("Deployed AMI", func() {
+
+	parse := func(templateBody string) *cfn.Template {
+		template := cfn.NewTemplate()
+		Expect(json.Unmarshal([]byte(templateBody), template)).To(Succeed())
+		return template
+	}
+
+	It("reads a launch configuration's ImageId", func() {
+		Expect(provision.TemplateImageId(parse(`{"Resources": {
+			"LaunchConfiguration": {
+				"Type": "AWS::AutoScaling::LaunchConfiguration",
+				"Properties": {"ImageId": "ami-0123456789abcdef0"}
+			}
+		}}`))).To(Equal("ami-0123456789abcdef0"))
+	})
+
+	It("reads a launch template's ImageId", func() {
+		Expect(provision.TemplateImageId(parse(`{"Resources": {
+			"LaunchTemplate": {
+				"Type": "AWS::EC2::LaunchTemplate",
+				"Properties": {"LaunchTemplateData": {"ImageId": "ami-0123456789abcdef0"}}
+			}
+		}}`))).To(Equal("ami-0123456789abcdef0"))
+	})
+
+	It("ignores an ImageId from the region to AMI mapping", func() {
+		Expect(provision.TemplateImageId(parse(`{"Resources": {
+			"LaunchConfiguration": {
+				"Type": "AWS::AutoScaling::LaunchConfiguration",
+				"Properties": {"ImageId": {"Fn::FindInMap": ["RegionToAMI", {"Ref": "AWS::Region"}, "AMI"]}}
+			}
+		}}`))).To(BeEmpty())
+	})
+})
@@ -20,6 +20,7 @@ import (
 	"os"
 	"os/exec"
 	"runtime"
+	"strings"
 
 	"github.com/adobe-platform/porter/aws_session"
 	"github.com/adobe-platform/porter/conf"
@@ -29,6 +30,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"gopkg.in/inconshreveable/log15.v2"
 )
 
 func (recv *stackCreator) getContainerSecrets() (containerSecrets map[string]string, success bool) {
@@ -75,11 +77,15 @@ func (recv *stackCreator) getContainerSecrets() (containerSecrets map[string]str
 }
 
 func (recv *stackCreator) getExecContainerSecrets(container *conf.Container) (containerSecrets string, success bool) {
+	return execContainerSecrets(recv.log, container)
+}
+
+func execContainerSecrets(log log15.Logger, container *conf.Container) (containerSecrets string, success bool) {
 
 	var stdoutBuf bytes.Buffer
 	var stderrBuf bytes.Buffer
 
-	log := recv.log.New("ContainerName", container.OriginalName)
+	log = log.New("ContainerName", container.OriginalName)
 	log.Info("Getting secrets from exec")
 
 	cmd := exec.Command(container.SrcEnvFile.ExecName, container.SrcEnvFile.ExecArgs...)
@@ -96,6 +102,53 @@ func (recv *stackCreator) getExecContainerSecrets(container *conf.Container) (co
 	return
 }
 
+// LocalSecrets builds the secrets payload a host would download for use on a
+// developer box. Nothing is uploaded and src_env_file in S3 is skipped since
+// it needs AWS credentials
+func LocalSecrets(log log15.Logger, environment *conf.Environment, region *conf.Region) (secretsPayload secrets.Payload, success bool) {
+
+	secretsPayload.ContainerSecrets = make(map[string]string)
+
+	for _, container := range region.Containers {
+
+		if container.SrcEnvFile == nil {
+			continue
+		}
+
+		if container.SrcEnvFile.ExecName == "" {
+			log.Warn("Skipping src_env_file that isn't exec_name", "ContainerName", container.OriginalName)
+			continue
+		}
+
+		envFile, execSuccess := execContainerSecrets(log, container)
+		if !execSuccess {
+			return
+		}
+
+		secretsPayload.ContainerSecrets[container.Name] = dockerutil.CleanEnvFile(envFile)
+	}
+
+	if environment.HAProxy.SSL.Pem != nil && environment.HAProxy.SSL.Pem.SecretsExecName != "" {
+
+		var stdoutBuf bytes.Buffer
+		var stderrBuf bytes.Buffer
+
+		cmd := exec.Command(environment.HAProxy.SSL.Pem.SecretsExecName, environment.HAProxy.SSL.Pem.SecretsExecArgs...)
+		cmd.Stdout = &stdoutBuf
+		cmd.Stderr = &stderrBuf
+		err := cmd.Run()
+		if err != nil {
+			log.Error("exec.Command", "Error", err, "Stderr", stderrBuf.String())
+			return
+		}
+
+		secretsPayload.PemFile = stdoutBuf.Bytes()
+	}
+
+	success = true
+	return
+}
+
 func (recv *stackCreator) getS3ContainerSecrets(container *conf.Container) (containerSecrets string, success bool) {
 	s3DstClient := s3.New(recv.roleSession)
 	log := recv.log.New("ContainerName", container.OriginalName)
@@ -181,6 +234,45 @@ func (recv *stackCreator) getHostSecrets() (hostSecrets []byte, success bool) {
 	return
 }
 
+// getParameterValues runs secrets_exec_name for each secret parameter
+func (recv *stackCreator) getParameterValues() (success bool) {
+	recv.log.Debug("getParameterValues() BEGIN")
+	defer recv.log.Debug("getParameterValues() END")
+
+	parameters, err := recv.environment.GetParameters(recv.region.Name)
+	if err != nil {
+		recv.log.Error("GetParameters", "Error", err)
+		return
+	}
+
+	recv.parameterValues = make(map[string]string)
+
+	for name, parameter := range parameters {
+
+		if !parameter.Secret() {
+			recv.parameterValues[name] = parameter.Value
+			continue
+		}
+
+		var stdoutBuf bytes.Buffer
+		var stderrBuf bytes.Buffer
+
+		cmd := exec.Command(parameter.SecretsExecName, parameter.SecretsExecArgs...)
+		cmd.Stdout = &stdoutBuf
+		cmd.Stderr = &stderrBuf
+		err := cmd.Run()
+		if err != nil {
+			recv.log.Error("exec.Command", "Parameter", name, "Error", err, "Stderr", stderrBuf.String())
+			return
+		}
+
+		recv.parameterValues[name] = strings.TrimRight(stdoutBuf.String(), "\r\n")
+	}
+
+	success = true
+	return
+}
+
 func (recv *stackCreator) getPemFile() (pemFile []byte, success bool) {
 	recv.log.Debug("getPemFile() BEGIN")
 	defer recv.log.Debug("getPemFile() END")
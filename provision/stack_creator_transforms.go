@@ -0,0 +1,308 @@
+/*
+ * (c) 2016-2018 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+package provision
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+
+	"github.com/adobe-platform/porter/cfn"
+	"github.com/adobe-platform/porter/conf"
+	"github.com/adobe-platform/porter/constants"
+	"github.com/adobe-platform/porter/provision_state"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+type (
+	// templateTransformInput is written to a template transform's stdin
+	templateTransformInput struct {
+		Template *cfn.Template            `json:"template"`
+		Context  templateTransformContext `json:"context"`
+	}
+
+	templateTransformContext struct {
+		ServiceName    string `json:"service_name"`
+		ServiceVersion string `json:"service_version"`
+		Environment    string `json:"environment"`
+		Region         string `json:"region"`
+		PorterVersion  string `json:"porter_version"`
+		StackUpdate    bool   `json:"stack_update"`
+
+		// nil during porter build provision --plan
+		ProvisionState *provision_state.Region `json:"provision_state"`
+	}
+)
+
+// runTemplateTransforms pipes the template through each configured transform
+// in order. The resources porter depends on to deploy, signal, and promote
+// have to survive every transform
+func (recv *stackCreator) runTemplateTransforms(template *cfn.Template) (success bool) {
+
+	if len(recv.config.TemplateTransforms) == 0 {
+		success = true
+		return
+	}
+
+	managed := porterManagedResources(template)
+
+	context := templateTransformContext{
+		ServiceName:    recv.config.ServiceName,
+		ServiceVersion: recv.config.ServiceVersion,
+		Environment:    recv.environment.Name,
+		Region:         recv.region.Name,
+		PorterVersion:  constants.Version,
+		StackUpdate:    recv.updateStack,
+		ProvisionState: recv.regionState,
+	}
+
+	for i, transform := range recv.config.TemplateTransforms {
+
+		log := recv.log.New("TemplateTransform", i)
+
+		inputBytes, err := json.Marshal(templateTransformInput{
+			Template: template,
+			Context:  context,
+		})
+		if err != nil {
+			log.Error("json.Marshal", "Error", err)
+			return
+		}
+
+		var outputBytes []byte
+		var ok bool
+		if transform.ExecName != "" {
+			outputBytes, ok = execTemplateTransform(log, transform, inputBytes)
+		} else {
+			outputBytes, ok = recv.dockerTemplateTransform(log, i, transform, inputBytes)
+		}
+		if !ok {
+			return
+		}
+
+		transformed := cfn.NewTemplate()
+		err = json.Unmarshal(outputBytes, transformed)
+		if err != nil {
+			log.Error("The transform's stdout isn't a CloudFormation template", "Error", err)
+			return
+		}
+
+		err = validateTransformedTemplate(managed, transformed)
+		if err != nil {
+			log.Error("Invalid transformed template", "Error", err)
+			return
+		}
+
+		// the transformed template is only serialized from here on so it
+		// isn't parsed and can contain resource types porter doesn't know
+		*template = *transformed
+	}
+
+	success = true
+	return
+}
+
+func execTemplateTransform(log log15.Logger, transform *conf.TemplateTransform, inputBytes []byte) ([]byte, bool) {
+
+	var stdoutBuf bytes.Buffer
+	var stderrBuf bytes.Buffer
+
+	log = log.New("ExecName", transform.ExecName)
+	log.Info("Running template transform")
+
+	cmd := exec.Command(transform.ExecName, transform.ExecArgs...)
+	// an empty value passes through porter's environment which is inherited
+	cmd.Env = os.Environ()
+	for envKey, envValue := range transform.Environment {
+		if envValue != "" {
+			cmd.Env = append(cmd.Env, envKey+"="+envValue)
+		}
+	}
+	cmd.Stdin = bytes.NewReader(inputBytes)
+	cmd.Stdout = &stdoutBuf
+	cmd.Stderr = &stderrBuf
+	err := cmd.Run()
+	if err != nil {
+		log.Error("exec.Command", "Error", err, "Stderr", stderrBuf.String())
+		return nil, false
+	}
+
+	return stdoutBuf.Bytes(), true
+}
+
+func (recv *stackCreator) dockerTemplateTransform(log log15.Logger, index int,
+	transform *conf.TemplateTransform, inputBytes []byte) ([]byte, bool) {
+
+	var stdoutBuf bytes.Buffer
+	var stderrBuf bytes.Buffer
+
+	dockerFilePath := transform.Dockerfile
+
+	if transform.Repo != "" {
+
+		repoDir := fmt.Sprintf("template_transform_clone_%d_%s", index, recv.region.Name)
+		repoDir = path.Join(constants.TempDir, repoDir)
+
+		defer exec.Command("rm", "-fr", repoDir).Run()
+
+		log.Info("git clone",
+			"Repo", transform.Repo,
+			"Ref", transform.Ref,
+			"Directory", repoDir,
+		)
+
+		cloneCmd := exec.Command(
+			"git", "clone",
+			"--branch", transform.Ref,
+			"--depth", "1",
+			transform.Repo, repoDir,
+		)
+		cloneCmd.Stderr = &stderrBuf
+		err := cloneCmd.Run()
+		if err != nil {
+			log.Error("git clone", "Error", err, "Stderr", stderrBuf.String())
+			return nil, false
+		}
+
+		if dockerFilePath == "" {
+			dockerFilePath = "Dockerfile"
+		}
+
+		dockerFilePath = path.Join(repoDir, dockerFilePath)
+	}
+
+	// regions run concurrently so each gets its own image
+	imageName := strings.ToLower(fmt.Sprintf("%s-template-transform-%d-%s",
+		recv.config.ServiceName, index, recv.region.Name))
+
+	log = log.New("Dockerfile", dockerFilePath, "ImageName", imageName)
+	log.Info("Building template transform")
+
+	buildCmd := exec.Command("docker", "build",
+		"-t", imageName,
+		"-f", dockerFilePath,
+		path.Dir(dockerFilePath),
+	)
+	buildCmd.Stdout = &stdoutBuf
+	buildCmd.Stderr = &stdoutBuf
+	err := buildCmd.Run()
+	if err != nil {
+		log.Error("docker build", "Error", err, "Output", stdoutBuf.String())
+		return nil, false
+	}
+
+	stdoutBuf.Reset()
+	stderrBuf.Reset()
+
+	runArgs := []string{"run", "--rm", "-i"}
+	for envKey, envValue := range transform.Environment {
+		if envValue == "" {
+			envValue = os.Getenv(envKey)
+		}
+		runArgs = append(runArgs, "-e", envKey+"="+envValue)
+	}
+	runArgs = append(runArgs, imageName)
+
+	log.Info("Running template transform")
+
+	runCmd := exec.Command("docker", runArgs...)
+	runCmd.Stdin = bytes.NewReader(inputBytes)
+	runCmd.Stdout = &stdoutBuf
+	runCmd.Stderr = &stderrBuf
+	err = runCmd.Run()
+	if err != nil {
+		log.Error("docker run", "Error", err, "Stderr", stderrBuf.String())
+		return nil, false
+	}
+
+	return stdoutBuf.Bytes(), true
+}
+
+// porterManagedResources maps the logical ids of resources porter needs after
+// mapResources to their type. IAM roles carry porter's inline policy
+func porterManagedResources(template *cfn.Template) map[string]string {
+	managed := map[string]string{
+		constants.SignalQueue: cfn.SQS_Queue,
+	}
+
+	for _, resourceType := range []string{
+		cfn.CloudFormation_WaitCondition,
+		cfn.CloudFormation_WaitConditionHandle,
+		cfn.IAM_Role,
+	} {
+		if logicalIds, exists := template.GetResourceNames(resourceType); exists {
+			for _, logicalId := range logicalIds {
+				managed[logicalId] = resourceType
+			}
+		}
+	}
+
+	return managed
+}
+
+func validateTransformedTemplate(managed map[string]string, template *cfn.Template) error {
+
+	for logicalId, resourceRaw := range template.Resources {
+
+		resource, ok := resourceRaw.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("resource %s isn't an object", logicalId)
+		}
+
+		if _, ok := resource["Type"].(string); !ok {
+			return fmt.Errorf("resource %s has no Type", logicalId)
+		}
+	}
+
+	for logicalId, resourceType := range managed {
+
+		resource, exists := template.Resources[logicalId].(map[string]interface{})
+		if !exists {
+			return fmt.Errorf("porter-managed resource %s was removed", logicalId)
+		}
+
+		if resource["Type"] != resourceType {
+			return fmt.Errorf("porter-managed resource %s changed type from %s to %v",
+				logicalId, resourceType, resource["Type"])
+		}
+
+		if resourceType == cfn.IAM_Role && !hasPorterPolicy(resource) {
+			return fmt.Errorf("the porter inline policy was removed from %s", logicalId)
+		}
+	}
+
+	return nil
+}
+
+func hasPorterPolicy(resource map[string]interface{}) bool {
+	props, ok := resource["Properties"].(map[string]interface{})
+	if !ok {
+		return false
+	}
+
+	policies, ok := props["Policies"].([]interface{})
+	if !ok {
+		return false
+	}
+
+	for _, policyRaw := range policies {
+		if policy, ok := policyRaw.(map[string]interface{}); ok && policy["PolicyName"] == "porter" {
+			return true
+		}
+	}
+
+	return false
+}
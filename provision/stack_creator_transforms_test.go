@@ -0,0 +1,153 @@
+package provision_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/adobe-platform/porter/cfn"
+	"github.com/adobe-platform/porter/conf"
+	"github.com/adobe-platform/porter/constants"
+	"github.com/adobe-platform/porter/provision"
+)
+
+// TestTemplateTransformProcess isn't a real test. It's the template transform
+// that the specs below exec by running the test binary again
+func TestTemplateTransformProcess(t *testing.T) {
+	mode := os.Getenv("PORTER_TEST_TRANSFORM")
+	if mode == "" {
+		return
+	}
+
+	var input struct {
+		Template map[string]interface{} `json:"template"`
+	}
+	if err := json.NewDecoder(os.Stdin).Decode(&input); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	// marks the template as transformed
+	input.Template["Description"] = mode
+
+	if mode == "delete" {
+		delete(input.Template["Resources"].(map[string]interface{}), constants.SignalQueue)
+	}
+
+	json.NewEncoder(os.Stdout).Encode(input.Template)
+	os.Exit(0)
+}
+
+var _ = Describe("Template transforms", func() {
+
+	var template *cfn.Template
+
+	BeforeEach(func() {
+		template = cfn.NewTemplate()
+		template.SetResource(constants.SignalQueue, map[string]interface{}{
+			"Type": cfn.SQS_Queue,
+		})
+		template.SetResource("WaitCondition", map[string]interface{}{
+			"Type": cfn.CloudFormation_WaitCondition,
+		})
+		template.SetResource("WaitConditionHandle", map[string]interface{}{
+			"Type": cfn.CloudFormation_WaitConditionHandle,
+		})
+		template.SetResource("InstanceRole", map[string]interface{}{
+			"Type": cfn.IAM_Role,
+			"Properties": map[string]interface{}{
+				"Policies": []interface{}{
+					map[string]interface{}{"PolicyName": "porter"},
+				},
+			},
+		})
+	})
+
+	// round-trips the template like a transform's stdout
+	transformed := func(edit func(resources map[string]interface{})) *cfn.Template {
+		templateBytes, err := json.Marshal(template)
+		Expect(err).To(BeNil())
+
+		transformed := cfn.NewTemplate()
+		Expect(json.Unmarshal(templateBytes, transformed)).To(Succeed())
+		edit(transformed.Resources)
+		return transformed
+	}
+
+	Describe("ValidateTransformedTemplate", func() {
+
+		It("accepts new resources", func() {
+			managed := provision.PorterManagedResources(template)
+
+			Expect(provision.ValidateTransformedTemplate(managed, transformed(func(resources map[string]interface{}) {
+				resources["Bucket"] = map[string]interface{}{"Type": "AWS::S3::Bucket"}
+			}))).To(BeNil())
+		})
+
+		It("rejects removing a managed resource", func() {
+			managed := provision.PorterManagedResources(template)
+
+			Expect(provision.ValidateTransformedTemplate(managed, transformed(func(resources map[string]interface{}) {
+				delete(resources, "WaitCondition")
+			}))).ToNot(BeNil())
+		})
+
+		It("rejects changing a managed resource's type", func() {
+			managed := provision.PorterManagedResources(template)
+
+			Expect(provision.ValidateTransformedTemplate(managed, transformed(func(resources map[string]interface{}) {
+				resources[constants.SignalQueue] = map[string]interface{}{"Type": "AWS::SNS::Topic"}
+			}))).ToNot(BeNil())
+		})
+
+		It("rejects stripping the porter policy", func() {
+			managed := provision.PorterManagedResources(template)
+
+			Expect(provision.ValidateTransformedTemplate(managed, transformed(func(resources map[string]interface{}) {
+				props := resources["InstanceRole"].(map[string]interface{})["Properties"].(map[string]interface{})
+				props["Policies"] = []interface{}{
+					map[string]interface{}{"PolicyName": "custom"},
+				}
+			}))).ToNot(BeNil())
+		})
+	})
+
+	Describe("RunTemplateTransforms", func() {
+
+		config := func(mode string) conf.Config {
+			return conf.Config{
+				ServiceName: "foo",
+				TemplateTransforms: []*conf.TemplateTransform{
+					{
+						ExecName:    os.Args[0],
+						ExecArgs:    []string{"-test.run=TestTemplateTransformProcess"},
+						Environment: map[string]string{"PORTER_TEST_TRANSFORM": mode},
+					},
+				},
+			}
+		}
+
+		It("replaces the template with the transform's output", func() {
+			Expect(provision.RunTemplateTransforms(config("passthrough"), template)).To(BeTrue())
+			Expect(template.Description).To(Equal("passthrough"))
+			Expect(template.Resources).To(HaveKey(constants.SignalQueue))
+			Expect(template.Resources).To(HaveKey("InstanceRole"))
+		})
+
+		It("fails if the transform removes a managed resource", func() {
+			Expect(provision.RunTemplateTransforms(config("delete"), template)).To(BeFalse())
+			Expect(template.Description).To(BeEmpty())
+			Expect(template.Resources).To(HaveKey(constants.SignalQueue))
+		})
+
+		It("fails if the transform fails", func() {
+			Expect(provision.RunTemplateTransforms(conf.Config{
+				TemplateTransforms: []*conf.TemplateTransform{{ExecName: "false"}},
+			}, template)).To(BeFalse())
+		})
+	})
+})
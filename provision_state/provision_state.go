@@ -13,17 +13,36 @@
 // This is here to avoid the import cycle provision -> hook -> provision
 package provision_state
 
+// Phase_Provisioning is the phase of a stack whose state is written while
+// porter build provision is still running. It can be finished with --resume
+const Phase_Provisioning = "provisioning"
+
 type (
 	Stack struct {
 		Name        string
 		Hotswap     bool
 		Environment string
 		Regions     map[string]*Region
+
+		// empty once provisioning is finished
+		Phase string `json:",omitempty"`
+
+		// regions that failed to provision and why. Failed regions are
+		// removed from Regions
+		FailedRegions map[string]string `json:",omitempty"`
 	}
 
 	Region struct {
-		StackId            string
-		ProvisionedELBName string
+		StackId                   string
+		ProvisionedELBName        string
+		ProvisionedTargetGroupARN string
+
+		// the stack's Outputs by OutputKey
+		Outputs map[string]string
+
+		// the AMI id resolved from .porter/config. Empty if the default
+		// Amazon Linux AMI mapping was used
+		ImageId string `json:",omitempty"`
 
 		// info on currently promoted stack
 		AsgDesired int `json:"-"`
@@ -0,0 +1,4 @@
+package prune
+
+// exported for prune_test
+var TargetGroupPruneList = targetGroupPruneList
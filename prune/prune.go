@@ -20,11 +20,13 @@ import (
 	"github.com/adobe-platform/porter/aws/cloudformation"
 	"github.com/adobe-platform/porter/aws/ec2"
 	"github.com/adobe-platform/porter/aws/elb"
+	"github.com/adobe-platform/porter/aws/elbv2"
 	awsutil "github.com/adobe-platform/porter/aws/util"
 	"github.com/adobe-platform/porter/aws_session"
 	"github.com/adobe-platform/porter/cfn"
 	"github.com/adobe-platform/porter/conf"
 	"github.com/adobe-platform/porter/constants"
+	"github.com/adobe-platform/porter/output"
 	"github.com/aws/aws-sdk-go/aws/session"
 	cfnlib "github.com/aws/aws-sdk-go/service/cloudformation"
 	"gopkg.in/inconshreveable/log15.v2"
@@ -42,17 +44,12 @@ func Do(log log15.Logger, config *conf.Config, environment *conf.Environment,
 		return
 	}
 
-	err = environment.IsWithinBlackoutWindow()
-	if err != nil {
-		log.Error("Blackout window is active", "Error", err, "Environment", environment.Name)
-		return
-	}
-
 	regionCount := len(environment.Regions)
 	pruneStackChan := make(chan bool, regionCount)
 
 	for _, region := range environment.Regions {
-		if region.PrimaryTopology() == conf.Topology_Inet && region.HasELB() {
+		if region.PrimaryTopology() == conf.Topology_Inet &&
+			(region.HasELB() || region.HasTargetGroup()) {
 			go pruneStacks(log, config, environment, region,
 				stackName, keepCount, pruneStackChan, elbFilter, elbTag)
 		} else {
@@ -105,8 +102,13 @@ func pruneStacks(log log15.Logger, config *conf.Config,
 	if elbFilter {
 
 		var getListSuccess bool
-		pruneList, getListSuccess = getELBPruneList(log, environment,
-			region, stackList, roleSession, elbTag)
+		if region.HasTargetGroup() {
+			pruneList, getListSuccess = getTargetGroupPruneList(log, environment,
+				region, stackList, roleSession, elbTag)
+		} else {
+			pruneList, getListSuccess = getELBPruneList(log, environment,
+				region, stackList, roleSession, elbTag)
+		}
 
 		if !getListSuccess {
 			pruneStackChan <- false
@@ -130,6 +132,7 @@ func pruneStacks(log log15.Logger, config *conf.Config,
 				pruneStackChan <- false
 				return
 			}
+			output.AddPrunedStack(region.Name, *stack.StackId)
 		} else {
 			log.Info("Keeping stack", "StackId", *stack.StackId)
 		}
@@ -230,6 +233,17 @@ func getELBPruneList(log log15.Logger, environment *conf.Environment,
 		log.Info("Found stack with instances registered to a destination elb", "StackId", stackId)
 	}
 
+	// the stack promoted before the current one is kept for rollback
+	elbTags, err := elb.GetTags(elbClient, elbName)
+	if err != nil {
+		log.Error("elb.GetTags", "Error", err)
+		return
+	}
+	if rollbackStackId := elbTags[constants.PorterPreviousStackIdTag]; rollbackStackId != "" {
+		log.Info("Keeping stack for rollback", "StackId", rollbackStackId)
+		ineligibleStacks[rollbackStackId] = 0
+	}
+
 	// nevertheless this logic prevents bad things from happening if the above
 	// assumption isn't true
 	// i.e. build the prune list don't prune the stackList
@@ -242,3 +256,63 @@ func getELBPruneList(log log15.Logger, environment *conf.Environment,
 	success = true
 	return
 }
+
+// Unlike ELBs, a target group's live stack is read from its tags rather than
+// its targets. Promotion tags the live target group once traffic has moved to
+// the promoted stack and fails if the tags can't be written
+func getTargetGroupPruneList(log log15.Logger, environment *conf.Environment,
+	region *conf.Region, stackList []*cfnlib.Stack,
+	roleSession *session.Session, elbTag string) (pruneList []*cfnlib.Stack, success bool) {
+
+	targetGroup, err := environment.GetTargetGroupForRegion(region.Name, elbTag)
+	if err != nil {
+		log.Error("Unable to find target group tagged "+elbTag, "Environment", environment.Name, "Error", err)
+		return
+	}
+
+	log = log.New("TargetGroupARN", targetGroup.ARN, "ListenerARN", targetGroup.ListenerARN)
+
+	log.Info("elbv2.GetLiveTags")
+	tags, err := elbv2.GetLiveTags(elbv2.New(roleSession), targetGroup.ARN, targetGroup.ListenerARN)
+	if err != nil {
+		log.Error("elbv2.GetLiveTags", "Error", err)
+		return
+	}
+
+	pruneList, success = targetGroupPruneList(log, tags, stackList)
+	return
+}
+
+// targetGroupPruneList is every stack except the one promoted into the target
+// group and the one promoted before it
+func targetGroupPruneList(log log15.Logger, tags map[string]string,
+	stackList []*cfnlib.Stack) (pruneList []*cfnlib.Stack, success bool) {
+
+	pruneList = make([]*cfnlib.Stack, 0)
+
+	promotedStackId := tags[constants.PorterStackIdTag]
+	if promotedStackId == "" {
+		log.Error("No promoted stack found on the target group", "Tag", constants.PorterStackIdTag)
+		return
+	}
+
+	log.Info("Found stack promoted into the target group", "StackId", promotedStackId)
+	ineligibleStacks := map[string]interface{}{
+		promotedStackId: nil,
+	}
+
+	// the stack promoted before the current one is kept for rollback
+	if rollbackStackId := tags[constants.PorterPreviousStackIdTag]; rollbackStackId != "" {
+		log.Info("Keeping stack for rollback", "StackId", rollbackStackId)
+		ineligibleStacks[rollbackStackId] = nil
+	}
+
+	for _, stack := range stackList {
+		if _, exists := ineligibleStacks[*stack.StackId]; !exists {
+			pruneList = append(pruneList, stack)
+		}
+	}
+
+	success = true
+	return
+}
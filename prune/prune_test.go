@@ -0,0 +1,76 @@
+package prune_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/adobe-platform/porter/constants"
+	"github.com/adobe-platform/porter/prune"
+	"github.com/aws/aws-sdk-go/aws"
+	cfnlib "github.com/aws/aws-sdk-go/service/cloudformation"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+var _ = Describe("Prune", func() {
+
+	Context("targetGroupPruneList", func() {
+
+		var (
+			log       log15.Logger
+			stackList []*cfnlib.Stack
+		)
+
+		stackIds := func(stacks []*cfnlib.Stack) []string {
+			ids := make([]string, 0)
+			for _, stack := range stacks {
+				ids = append(ids, *stack.StackId)
+			}
+			return ids
+		}
+
+		BeforeEach(func() {
+			log = log15.New()
+			log.SetHandler(log15.DiscardHandler())
+
+			stackList = []*cfnlib.Stack{
+				{StackId: aws.String("stack-1")},
+				{StackId: aws.String("stack-2")},
+				{StackId: aws.String("stack-3")},
+				{StackId: aws.String("stack-4")},
+			}
+		})
+
+		It("keeps the promoted and rollback stacks", func() {
+			pruneList, success := prune.TargetGroupPruneList(log, map[string]string{
+				constants.PorterStackIdTag:         "stack-3",
+				constants.PorterPreviousStackIdTag: "stack-2",
+			}, stackList)
+
+			Expect(success).To(BeTrue())
+			Expect(stackIds(pruneList)).To(Equal([]string{"stack-1", "stack-4"}))
+		})
+
+		It("keeps only the promoted stack without a rollback stack", func() {
+			pruneList, success := prune.TargetGroupPruneList(log, map[string]string{
+				constants.PorterStackIdTag: "stack-4",
+			}, stackList)
+
+			Expect(success).To(BeTrue())
+			Expect(stackIds(pruneList)).To(Equal([]string{"stack-1", "stack-2", "stack-3"}))
+		})
+
+		It("fails without a promoted stack", func() {
+			_, success := prune.TargetGroupPruneList(log, map[string]string{
+				constants.PorterPreviousStackIdTag: "stack-2",
+			}, stackList)
+
+			Expect(success).To(BeFalse())
+		})
+
+		It("fails on an untagged target group", func() {
+			_, success := prune.TargetGroupPruneList(log, map[string]string{}, stackList)
+
+			Expect(success).To(BeFalse())
+		})
+	})
+})